@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CopyLine copies the l-th line of the diff to the clipboard, without it's +/- marker.
+func (a *DiffArea) CopyLine(l int) error {
+	if l < 0 || l >= len(a.Text) {
+		return fmt.Errorf("no line to copy")
+	}
+	ln := a.Text[l]
+	if _, ok := diffLineAt(a.Text, l); ok {
+		ln = ln[1:]
+	}
+	if err := copyToClipboard(string(ln)); err != nil {
+		return err
+	}
+	dig.Message = "copied the line"
+	return nil
+}
+
+// copyToClipboard copies the text to the clipboard with OSC 52,
+// which works even in a remote terminal through ssh.
+func copyToClipboard(text string) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		// windows doesn't have /dev/tty, but it's terminal is the stdout.
+		tty = os.Stdout
+	} else {
+		defer tty.Close()
+	}
+	_, err = fmt.Fprintf(tty, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
+
+// OpenEditor opens the file of the l-th line of the diff in the user's editor, at the line.
+// It opens the file in the work tree, which could differ from the commit's.
+func (a *DiffArea) OpenEditor(l int) error {
+	dl, ok := diffLineAt(a.Text, l)
+	if !ok {
+		return fmt.Errorf("not on a line of a hunk")
+	}
+	path, line := dl.NewPath, dl.NewLine
+	if line == 0 {
+		// the line is deleted, open near where it was.
+		line = dl.OldLine
+	}
+	if path == "" {
+		// the file is deleted, it may still exist in the work tree.
+		path = dl.OldPath
+	}
+	top, err := gitOutput("rev-parse", "--show-toplevel")
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(strings.TrimSpace(string(top)), filepath.FromSlash(path))
+	return suspend(editorCommand(file, line))
+}
+
+// editorCommand returns a command that opens the file at the line.
+// It uses the editor config, or $VISUAL and $EDITOR those take +<line> like vi.
+func editorCommand(file string, line int) *exec.Cmd {
+	tmpl := config.Editor
+	if tmpl == "" {
+		editor := os.Getenv("VISUAL")
+		if editor == "" {
+			editor = os.Getenv("EDITOR")
+		}
+		if editor == "" {
+			editor = "vi"
+		}
+		tmpl = editor + " +{line} {file}"
+	}
+	r := strings.NewReplacer("{file}", shellQuote(file), "{line}", strconv.Itoa(line))
+	return shellCommand(r.Replace(tmpl))
+}
+
+// shellQuote quotes s for sh, or cmd on windows roughly.
+func shellQuote(s string) string {
+	if os.PathSeparator == '\\' {
+		return `"` + s + `"`
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// setTermModes sets input modes of the terminal by config.
+func setTermModes() {
+	if config.Mouse {
+		enableMouse()
+	}
+}
+
+// suspend gives the terminal to the command until it exits,
+// then takes it back to continue dig.
+func suspend(cmd *exec.Cmd) error {
+	if err := term.Suspend(); err != nil {
+		return err
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	runErr := cmd.Run()
+	if err := term.Resume(); err != nil {
+		// dig can't continue without the terminal.
+		closeTerm()
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	w, h := term.Size()
+	screen.Resize(Pt{h, w})
+	return runErr
+}
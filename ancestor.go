@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// isAncestor reports whether the commit is an ancestor of the ref, or the ref itself.
+func isAncestor(hash, ref string) (bool, error) {
+	return isAncestorIn(dig.RepoDir, hash, ref)
+}
+
+// isAncestorIn is isAncestor in the repository, for checks those run in background.
+func isAncestorIn(repoDir, hash, ref string) (bool, error) {
+	cmd := gitCommand(repoDir, "merge-base", "--is-ancestor", hash, ref)
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return true, nil
+	}
+	// it exits with 1 when it isn't, and others on errors like an unknown ref.
+	if e, ok := err.(*exec.ExitError); ok && e.ExitCode() == 1 {
+		return false, nil
+	}
+	if msg := strings.TrimSpace(string(out)); msg != "" {
+		return false, fmt.Errorf("%s", strings.SplitN(msg, "\n", 2)[0])
+	}
+	return false, err
+}
+
+// checkAncestor shows whether the selected commit is an ancestor of the ref in the status bar.
+func checkAncestor(ref string) error {
+	c := screen.Commit.Commit()
+	ok, err := isAncestor(c.Hash, ref)
+	if err != nil {
+		return err
+	}
+	if ok {
+		dig.Message = fmt.Sprintf("%s is an ancestor of %s", shortHash(c.Hash), ref)
+	} else {
+		dig.Message = fmt.Sprintf("%s is NOT an ancestor of %s", shortHash(c.Hash), ref)
+	}
+	return nil
+}
+
+// refNames returns HEAD, branches and tags of the repository.
+func refNames() ([]string, error) {
+	out, err := gitOutput("for-each-ref", "--format=%(refname:short)", "refs/heads", "refs/remotes", "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+	refs := []string{"HEAD"}
+	for _, r := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if r != "" {
+			refs = append(refs, r)
+		}
+	}
+	return refs, nil
+}
+
+// pickAncestorRef opens a list of refs, to check the selected commit against the picked one.
+func pickAncestorRef() error {
+	refs, err := refNames()
+	if err != nil {
+		return err
+	}
+	openList("is it an ancestor of?", refs, 0, func(i int) {
+		if err := checkAncestor(refs[i]); err != nil {
+			dig.Message = err.Error()
+		}
+	})
+	return nil
+}
+
+// cmdAncestor checks whether the selected commit is an ancestor of the ref.
+// Without a ref, it opens a list of refs to pick one.
+//
+//	ancestor [ref]
+func cmdAncestor(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: ancestor [ref]")
+	}
+	if len(args) == 0 {
+		return pickAncestorRef()
+	}
+	return checkAncestor(args[0])
+}
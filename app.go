@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// App is the main loop of dig, as a cycle of update and view.
+//
+// Everything that happens to dig is a message, like a key, a resize, a loaded preview or a tick.
+// The loop takes a message, updates the state with it, and draws the state. Nothing else.
+// So the loop's goroutine is the only one that touches the state, like dig, screen, tabs and config.
+// Others, like git workers, timers, hooks and the terminal's poller, just send their results with send.
+type App struct {
+	// Messages are messages to the loop.
+	Messages chan Msg
+	// Quit ends the loop, after the current message.
+	Quit bool
+}
+
+// Msg is a message to the app. It's an Event of the terminal or one of the message types in Update.
+// A message is just data. It's producer copies what it needs before it starts,
+// like repoDir, so it doesn't read the state either.
+type Msg interface{}
+
+// app is the running dig.
+var app = newApp()
+
+// newApp creates an app, that isn't polling the terminal yet.
+func newApp() *App {
+	return &App{
+		Messages: make(chan Msg, 20),
+	}
+}
+
+// send sends the message to the app's loop.
+func send(msg Msg) {
+	app.Messages <- msg
+}
+
+// PollEvents starts a goroutine that sends events of the terminal.
+func (a *App) PollEvents() {
+	go func() {
+		for {
+			a.Messages <- pollEvent()
+		}
+	}()
+}
+
+// Run updates and draws the state with each message, until the user quits.
+func (a *App) Run() {
+	for !a.Quit {
+		a.View()
+		a.Update(<-a.Messages)
+	}
+}
+
+// View draws the state.
+func (a *App) View() {
+	commitSelected()
+	notifySelected()
+	term.Clear()
+	screen.Draw()
+	drawCopy()
+	term.Show()
+	drawImage()
+	drawLinks()
+}
+
+// Update updates the state with the message.
+func (a *App) Update(msg Msg) {
+	switch msg := msg.(type) {
+	case Event:
+		switch msg.Type {
+		case EventKey:
+			a.HandleKey(msg)
+		case EventMouse:
+			handleMouse(msg)
+		case EventResize:
+			// cells of the old size could be left, when the terminal becomes fullscreen.
+			// draw the whole screen again.
+			term.Sync()
+			w, h := term.Size()
+			size := Pt{h, w}
+			screen.Resize(size)
+		}
+	case previewMsg:
+		msg.Area.Loaded(msg)
+	case commitsMsg:
+		commitsLoaded(msg)
+	case metaMsg:
+		msg.Loader.Loaded(msg)
+	case trailersMsg:
+		trailersLoaded(msg)
+	case remoteMsg:
+		remoteChecked(msg)
+	case runLineMsg:
+		msg.Popup.Lines = append(msg.Popup.Lines, msg.Line)
+	case runDoneMsg:
+		msg.Popup.Finish(msg.Err)
+	case bisectResetMsg:
+		bisectReset(msg)
+	case hookDoneMsg:
+		hookDone(msg)
+	case difftoolDoneMsg:
+		difftoolDone(msg)
+	case exportDoneMsg:
+		exportDone(msg)
+	case updateMsg:
+		dig.Message = fmt.Sprintf("new version available: %s (current %s)", msg.Tag, version)
+	case autosaveMsg:
+		if err := saveSession(); err != nil {
+			dig.Message = "could not save the session: " + err.Error()
+		}
+	case segmentMsg:
+		segmentsLoaded(msg)
+	case segmentTickMsg:
+		loadSegments()
+	case watchMsg:
+		watchChecked(msg)
+	case watchTickMsg:
+		checkWatch()
+	case controlMsg:
+		msg.Conn.Reply(runControl(msg.Conn, msg.Line))
+	case controlClosedMsg:
+		unsubscribe(msg.Conn)
+		close(msg.Conn.out)
+	case signalMsg:
+		closeControl()
+		stopGit()
+		err := saveSession()
+		closeTerm()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "could not save the session:", err)
+		}
+		os.Exit(1)
+	default:
+		// a message without a case is a bug, but not worth losing the session.
+		dig.Message = fmt.Sprintf("unknown message: %T", msg)
+	}
+}
+
+// HandleKey handles a key event.
+func (a *App) HandleKey(ev Event) {
+	dig.Message = ""
+	if dig.Mode == NormalMode && screen.Popup == nil {
+		ev = mapKey(ev)
+	}
+	if dig.Mode == NormalMode {
+		if ev.Key == KeyCtrlC {
+			// abort, don't let a pipeline use the selection.
+			closeControl()
+			stopGit()
+			closeTerm()
+			os.Exit(1)
+		}
+		// q of a popup closes the popup.
+		if ev.Key == KeyCtrlQ || dig.CurView == CommitView && ev.Ch == 'q' && screen.Popup == nil {
+			// the session is saved after the terminal is closed, to tell if it's failed.
+			a.Quit = true
+			return
+		}
+	}
+	if dig.Mode == NormalMode {
+		handleNormal(ev)
+	} else if dig.Mode == FindMode {
+		handleFind(ev)
+	} else if dig.Mode == CommandMode {
+		handleCommand(ev)
+	} else if dig.Mode == NoteMode {
+		handleNote(ev)
+	} else if dig.Mode == TimelineMode {
+		handleTimeline(ev)
+	} else if dig.Mode == QueryMode {
+		handleQuery(ev)
+	} else if dig.Mode == CopyMode {
+		handleCopy(ev)
+	}
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// These tests run background features against a small repository,
+// while the test goroutine acts as the app's loop. Run them with -race,
+// to check that the features only touch the state through messages.
+//
+//	go test -race ./...
+
+// testRepo creates a repository with n commits, and returns it's directory and commits.
+// Each commit adds a line to one of three files, and every third commit has a Reviewed-by trailer.
+func testRepo(t *testing.T, n int) (string, []*Commit) {
+	t.Helper()
+	contents := make(map[string]string)
+	var steps []step
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("file%d.txt", i%3)
+		contents[name] += fmt.Sprintf("line %d\n", i)
+		s := step{Files: map[string]string{name: contents[name]}, Message: fmt.Sprintf("change %d", i)}
+		if i%3 == 0 {
+			s.Message += fmt.Sprintf("\n\nReviewed-by: Reviewer%d <r%d@example.com>", i, i)
+		}
+		if i == n/2 {
+			s.Tag = "v1.0"
+		}
+		steps = append(steps, s)
+	}
+	r := buildRepo(t, steps...)
+	return r.Dir, r.Commits(false, "HEAD")
+}
+
+// setupApp sets the state as dig does on start, without a terminal.
+func setupApp(t *testing.T, dir string, commits []*Commit) {
+	t.Helper()
+	// drop messages left by the previous test, they're for it's state.
+	for len(app.Messages) != 0 {
+		<-app.Messages
+	}
+	subscribers = nil
+	config = defaultConfig()
+	screen = NewScreen(Pt{24, 80}, -1)
+	dig = &Program{
+		Mode:    NormalMode,
+		CurView: CommitView,
+		RepoDir: dir,
+		Targets: []string{"HEAD"},
+	}
+	dig.SetCommits(commits)
+	if err := dig.FilterCommits(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runUntil updates the state with sent messages as the app's loop does, until done reports true.
+// tick runs between messages, to touch the state while background goroutines work.
+func runUntil(t *testing.T, done func() bool, tick func()) {
+	t.Helper()
+	timeout := time.After(30 * time.Second)
+	for !done() {
+		select {
+		case msg := <-app.Messages:
+			app.Update(msg)
+		case <-time.After(time.Millisecond):
+		case <-timeout:
+			t.Fatal("timed out")
+		}
+		if tick != nil {
+			tick()
+		}
+	}
+}
+
+func TestMetaLoaderRace(t *testing.T) {
+	dir, commits := testRepo(t, 12)
+	setupApp(t, dir, commits)
+	config.Columns = []string{"stat", "signature", "describe"}
+	config.MetaWorkers = 3
+	m := dig.MetaLoader()
+	hashes := make([]string, len(commits))
+	for i, c := range commits {
+		hashes[i] = c.Hash
+	}
+	// scroll through the commits while they're loaded.
+	top := 0
+	loaded := func() bool {
+		return len(m.Values) == len(hashes)*len(config.Columns)
+	}
+	runUntil(t, loaded, func() {
+		top = (top + 1) % (len(hashes) - 3)
+		m.Show(hashes[top:top+4], config.Columns)
+	})
+	last := hashes[len(hashes)-1]
+	if got := m.Values[metaJob{last, "stat"}]; got != "+1 -0" {
+		t.Errorf("stat of the first commit: got %q, want %q", got, "+1 -0")
+	}
+	if got := m.Values[metaJob{last, "signature"}]; got != "N" {
+		t.Errorf("signature of the first commit: got %q, want %q", got, "N")
+	}
+	if got := m.Values[metaJob{hashes[0], "describe"}]; !strings.HasPrefix(got, "v1.0-5-g") {
+		t.Errorf("describe of the last commit: got %q, want v1.0-5-g<hash>", got)
+	}
+}
+
+func TestMetaLoaderStop(t *testing.T) {
+	dir, commits := testRepo(t, 12)
+	setupApp(t, dir, commits)
+	config.Columns = []string{"stat"}
+	config.MetaWorkers = 3
+	m := dig.MetaLoader()
+	m.Show([]string{commits[0].Hash}, config.Columns)
+	runUntil(t, func() bool { return len(m.Values) == 1 }, nil)
+	dig.Close()
+	runUntil(t, func() bool {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.workers == 0
+	}, nil)
+	// a stopped loader doesn't start again.
+	m.Show([]string{commits[1].Hash}, config.Columns)
+	if len(m.queue) != 0 {
+		t.Errorf("queued after stop: %v", m.queue)
+	}
+}
+
+func TestSearchIndexTrailersRace(t *testing.T) {
+	dir, commits := testRepo(t, 12)
+	setupApp(t, dir, commits)
+	dig.Filters = []*Filter{textFilter("reviewer3")}
+	x := dig.SearchIndex()
+	// no commit matches it, until trailers are loaded.
+	dig.FilterCommits()
+	// search while trailers are loaded.
+	runUntil(t, func() bool { return len(x.parts) == 2 }, func() {
+		x.Search("change 1")
+	})
+	if len(dig.Commits) != 1 || dig.Commits[0].Title != "change 3" {
+		t.Errorf("commits matching a trailer: got %d commits, want change 3", len(dig.Commits))
+	}
+}
+
+func TestPreviewFollowRace(t *testing.T) {
+	dir, commits := testRepo(t, 6)
+	setupApp(t, dir, commits)
+	config.PreviewDelay = 0
+	a := &PreviewArea{}
+	// selections change faster than previews are loaded.
+	for _, c := range commits {
+		a.Follow(c.Hash)
+	}
+	want := commits[len(commits)-1].Hash
+	i := 0
+	runUntil(t, func() bool { return a.Hash == want }, func() {
+		if i < len(commits) {
+			a.Follow(commits[i].Hash)
+			i++
+		} else {
+			a.Follow(want)
+		}
+	})
+	if len(a.Text) == 0 {
+		t.Error("preview is empty")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	dir, commits := testRepo(t, 2)
+	setupApp(t, dir, commits)
+	a := &PreviewArea{}
+	a.want = commits[0].Hash
+	// a preview of a commit that isn't selected anymore is dropped.
+	app.Update(previewMsg{Area: a, Hash: commits[1].Hash, Text: [][]byte{[]byte("old")}})
+	if a.Hash != "" {
+		t.Errorf("stale preview is shown: %s", a.Hash)
+	}
+	app.Update(previewMsg{Area: a, Hash: commits[0].Hash, Text: [][]byte{[]byte("new")}})
+	if a.Hash != commits[0].Hash || len(a.Text) != 1 {
+		t.Errorf("preview isn't shown: got %q with %d lines", a.Hash, len(a.Text))
+	}
+	app.Update(remoteMsg{Remote: "origin", Short: "abc1234", Unknown: 2})
+	if want := "abc1234 is NOT found on origin, 2 refs not fetched could have it"; dig.Message != want {
+		t.Errorf("remote message: got %q, want %q", dig.Message, want)
+	}
+	p := &RunPopup{}
+	app.Update(runLineMsg{Popup: p, Line: "ok"})
+	app.Update(runDoneMsg{Popup: p})
+	if !p.Done || len(p.Lines) != 1 {
+		t.Errorf("run popup: done %v with %d lines", p.Done, len(p.Lines))
+	}
+	type strayMsg struct{}
+	app.Update(strayMsg{})
+	if want := "unknown message: main.strayMsg"; dig.Message != want {
+		t.Errorf("unknown message: got %q, want %q", dig.Message, want)
+	}
+}
+
+func TestCommitLoader(t *testing.T) {
+	dir, _ := testRepo(t, 12)
+	defer func(n int) { firstPage = n }(firstPage)
+	firstPage = 5
+	for _, digUp := range []bool{false, true} {
+		want, err := allCommits(dir, []string{"HEAD"}, digUp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		loader, commits, err := startCommits(dir, []string{"HEAD"}, digUp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loader == nil || len(commits) != firstPage || commits[0].Hash != want[0].Hash {
+			t.Fatalf("digUp %v: first page has %d commits from %s, want %d from %s", digUp, len(commits), commits[0].Title, firstPage, want[0].Title)
+		}
+		setupApp(t, dir, commits)
+		dig.DigUp = digUp
+		// the cursor moves to the wanted commit when it's loaded, and stays there.
+		last := want[len(want)-1].Hash
+		loader.Program = dig
+		loader.Screen = screen
+		loader.Want = last
+		dig.Loader = loader
+		// the search index grows with loaded commits.
+		x := dig.SearchIndex()
+		loader.Load()
+		runUntil(t, func() bool { return dig.Loader == nil }, nil)
+		if len(dig.Commits) != len(want) {
+			t.Fatalf("digUp %v: got %d commits, want %d", digUp, len(dig.Commits), len(want))
+		}
+		for i, c := range dig.Commits {
+			if c.Hash != want[i].Hash {
+				t.Fatalf("digUp %v: commit %d is %s, want %s", digUp, i, c.Title, want[i].Title)
+			}
+		}
+		if got := screen.Commit.Commit().Hash; got != last {
+			t.Errorf("digUp %v: cursor is on %s, want %s", digUp, got, last)
+		}
+		if dig.Index != x {
+			t.Errorf("digUp %v: search index is rebuilt", digUp)
+		}
+		// change 10 also matches.
+		var found []string
+		for _, id := range x.Search("change 0") {
+			found = append(found, dig.All[id].Title)
+		}
+		sort.Strings(found)
+		if got := strings.Join(found, ", "); got != "change 0, change 10" {
+			t.Errorf("digUp %v: search loaded commits: got %q", digUp, got)
+		}
+	}
+}
+
+func TestCommitLoaderFilters(t *testing.T) {
+	dir, _ := testRepo(t, 12)
+	defer func(n int) { firstPage = n }(firstPage)
+	firstPage = 5
+	loader, commits, err := startCommits(dir, []string{"HEAD"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setupApp(t, dir, commits)
+	config.TestBadge = true
+	loader.Program = dig
+	loader.Screen = screen
+	dig.Loader = loader
+	if err := cmdFilter([]string{"glob:file0.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	// files and commits of the filter are of all history, they're not loaded again for each batch.
+	f := dig.Filters[0]
+	hashes := reflect.ValueOf(f.Hashes).Pointer()
+	loader.Load()
+	runUntil(t, func() bool { return dig.Loader == nil }, nil)
+	if reflect.ValueOf(f.Hashes).Pointer() != hashes {
+		t.Error("commits of the filter are loaded again")
+	}
+	if got, want := titles(dig.Commits), "change 9, change 6, change 3, change 0"; got != want {
+		t.Errorf("filtered commits: got %q, want %q", got, want)
+	}
+	for _, c := range dig.All {
+		var i int
+		fmt.Sscanf(c.Title, "change %d", &i)
+		if want := fmt.Sprintf("file%d.txt", i%3); len(c.Files) != 1 || c.Files[0] != want {
+			t.Errorf("files of %s: got %q, want %s", c.Title, c.Files, want)
+		}
+	}
+	if !dig.FilesLoaded || len(dig.laterFiles) != 0 {
+		t.Errorf("files loaded %v, with %d left", dig.FilesLoaded, len(dig.laterFiles))
+	}
+}
+
+func TestCommitLoaderDate(t *testing.T) {
+	dir, _ := testRepo(t, 12)
+	defer func(n int) { firstPage = n }(firstPage)
+	firstPage = 5
+	for _, digUp := range []bool{false, true} {
+		want, err := allCommits(dir, []string{"HEAD"}, digUp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		loader, commits, err := startCommits(dir, []string{"HEAD"}, digUp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		setupApp(t, dir, commits)
+		dig.DigUp = digUp
+		loader.Program = dig
+		loader.Screen = screen
+		dig.Loader = loader
+		// the commit isn't in the first page, it goes there after it's loaded.
+		c := want[len(want)-3]
+		if err := cmdDate([]string{c.Time.Local().Format("2006-01-02 15:04")}); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(dig.Message, "loading commits to ") {
+			t.Errorf("digUp %v: message %q while loading", digUp, dig.Message)
+		}
+		loader.Load()
+		runUntil(t, func() bool { return dig.Loader == nil }, nil)
+		if got := screen.Commit.Commit(); got.Hash != c.Hash {
+			t.Errorf("digUp %v: cursor is on %s, want %s", digUp, got.Title, c.Title)
+		}
+		if dig.Message != "" {
+			t.Errorf("digUp %v: message %q after loading", digUp, dig.Message)
+		}
+	}
+}
+
+func TestMouse(t *testing.T) {
+	dir, commits := testRepo(t, 5)
+	setupApp(t, dir, commits)
+	config.Mouse = true
+	a := screen.Commit
+	click := func(l int) {
+		o := a.Bound.Min.O + 1
+		app.Update(Event{Type: EventMouse, Key: MouseLeft, MouseX: o, MouseY: l})
+		app.Update(Event{Type: EventMouse, Key: MouseRelease, MouseX: o, MouseY: l})
+	}
+	click(a.Bound.Min.L + 2)
+	if a.CurIdx != 2 || dig.CurView != CommitView {
+		t.Fatalf("click: got the cursor at %d in view %v", a.CurIdx, dig.CurView)
+	}
+	// a click below the commits doesn't move the cursor.
+	click(a.Bound.Min.L + len(commits))
+	if a.CurIdx != 2 {
+		t.Errorf("click below the commits: got the cursor at %d", a.CurIdx)
+	}
+	wheel := func(key Key) {
+		app.Update(Event{Type: EventMouse, Key: key, MouseX: a.Bound.Min.O + 1, MouseY: a.Bound.Min.L})
+	}
+	wheel(MouseWheelUp)
+	if a.CurIdx != 0 {
+		t.Errorf("wheel up: got the cursor at %d", a.CurIdx)
+	}
+	wheel(MouseWheelDown)
+	if a.CurIdx != wheelLines {
+		t.Errorf("wheel down: got the cursor at %d", a.CurIdx)
+	}
+	click(a.Bound.Min.L + 1)
+	click(a.Bound.Min.L + 1)
+	if a.CurIdx != 1 || dig.CurView != DiffView {
+		t.Errorf("double click: got the cursor at %d in view %v", a.CurIdx, dig.CurView)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	dir, commits := testRepo(t, 5)
+	setupApp(t, dir, commits)
+	config.Keymap = map[rune]rune{'n': 'k', 'k': 'i'}
+	key := func(ch rune) {
+		app.Update(Event{Type: EventKey, Ch: ch})
+	}
+	key('?')
+	h, ok := screen.Popup.(*HelpArea)
+	if !ok {
+		t.Fatalf("?: got popup %T", screen.Popup)
+	}
+	if h.Lines[0].Keys != "commit list" {
+		t.Errorf("first section: got %q", h.Lines[0].Keys)
+	}
+	want := map[string]string{"up": "k i up", "down": "n down"}
+	for _, ln := range h.Lines {
+		if w, ok := want[ln.Desc]; ok && ln.Keys != w {
+			t.Errorf("keys of %s: got %q, want %q", ln.Desc, ln.Keys, w)
+		}
+	}
+	// keys of the help aren't mapped.
+	key('k')
+	if h.TopIdx != 1 {
+		t.Errorf("scroll: got top %d", h.TopIdx)
+	}
+	app.Update(Event{Type: EventKey, Key: KeyEnd})
+	if page := popupBound(h.size()).Size.L; h.TopIdx != len(h.Lines)-page {
+		t.Errorf("end: got top %d, want %d", h.TopIdx, len(h.Lines)-page)
+	}
+	key('q')
+	if screen.Popup != nil || app.Quit {
+		t.Errorf("q: got popup %T, quit %v", screen.Popup, app.Quit)
+	}
+}
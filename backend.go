@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// GitBackend reads commits, diffs and files of a repository, those dig shows.
+//
+// execBackend runs the git binary, and gogitBackend reads the repository with go-git,
+// so dig works where git isn't installed. See newBackend.
+// Commands those change the repository or need what only git does, like cherry-pick or fetch,
+// still run the git binary.
+type GitBackend interface {
+	// Commits returns commits of the targets, those are revisions and paths after --.
+	// They're in the order of digging up when digUp is set.
+	Commits(dir string, targets []string, digUp bool) ([]*Commit, error)
+	// LogCommits starts listing commits of the targets, for the commit loader. See Commits.
+	LogCommits(dir string, targets []string, digUp bool) (CommitLog, error)
+	// CommitDiff returns lines of the commit's header and changes, like git show, in the paths if any.
+	// Tabs are expanded to 4 spaces. The diff could be cut before it's end, unless full is set,
+	// then the lines are returned with a *cutError.
+	CommitDiff(dir, hash string, paths []string, full bool) ([][]byte, error)
+	// ChangedFiles returns files changed by each commit of the targets, by their hashes.
+	// Merge commits don't have changed files, like git log.
+	ChangedFiles(dir string, targets []string) (map[string][]string, error)
+	// PathCommits returns hashes of commits of the targets, those touched the pathspecs.
+	// All of them are returned without pathspecs.
+	PathCommits(dir string, targets, pathspecs []string) ([]string, error)
+	// RevParse returns hashes of the revisions, like git rev-parse.
+	// The start of a range is it's hash after ^.
+	RevParse(dir string, revs []string) ([]string, error)
+	// Blame returns blamed lines of the file at the revision, and author names of the commits.
+	Blame(dir, rev, path string) ([]BlameLine, map[string]string, error)
+	// RenamedFrom returns the path of the file before the commit, as the commit could rename it.
+	RenamedFrom(dir, hash, path string) (string, error)
+	// Tree returns entries of a directory of the commit, with paths from the root.
+	// treeDir should be empty for the root directory, or end with a slash.
+	Tree(dir, hash, treeDir string) ([]*TreeNode, error)
+	// Blob returns contents of the blob.
+	Blob(dir, hash string) ([]byte, error)
+}
+
+// CommitLog lists commits by pages, see GitBackend.LogCommits.
+type CommitLog interface {
+	// Read reads commits at most n, or until the end. It reports whether it's the end.
+	// It's also the end when it failed.
+	Read(n int) ([]*Commit, bool, error)
+	// Stop stops listing, while Read could be reading. Read doesn't return more commits after it.
+	Stop()
+}
+
+// backend is the backend of dig, see newBackend.
+var backend GitBackend = execBackend{}
+
+// execBackend is a GitBackend runs git commands.
+type execBackend struct{}
+
+func (execBackend) Commits(dir string, targets []string, digUp bool) ([]*Commit, error) {
+	cmd := gitCommand(dir, logArgs(targets)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if gitKilled(cmd) {
+			return nil, timeoutError("log")
+		}
+		return nil, errors.New(string(out))
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, errors.New("no commits")
+	}
+	commits := []*Commit{}
+	commitStrings := strings.Split(string(out), "\x00")[1:]
+	last := len(commitStrings) - 1
+	for i := range commitStrings {
+		j := i
+		if digUp {
+			j = last - i
+		}
+		c, err := parseCommit(commitStrings[j]) // first commit live at last.
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
+
+func (execBackend) LogCommits(dir string, targets []string, digUp bool) (CommitLog, error) {
+	args := logArgs(targets)
+	if digUp {
+		args = append([]string{"log", "--reverse"}, args[1:]...)
+	}
+	// loading a huge history takes longer than config.GitTimeout, Stop kills it instead.
+	cmd := gitCommandTimeout(dir, 0, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	l := &execLog{cmd: cmd, out: bufio.NewReader(stdout), stderr: &bytes.Buffer{}}
+	cmd.Stderr = l.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// execLog is a CommitLog of git log.
+type execLog struct {
+	cmd    *exec.Cmd
+	out    *bufio.Reader
+	stderr *bytes.Buffer
+}
+
+func (l *execLog) Read(n int) ([]*Commit, bool, error) {
+	var commits []*Commit
+	for len(commits) < n {
+		rec, err := l.out.ReadString('\x00')
+		rec = strings.TrimSuffix(rec, "\x00")
+		if rec != "" {
+			c, perr := parseCommit(rec)
+			if perr != nil {
+				killProcessGroup(l.cmd)
+				l.cmd.Wait()
+				return nil, true, perr
+			}
+			commits = append(commits, c)
+		}
+		if err == io.EOF {
+			if err := l.cmd.Wait(); err != nil {
+				if gitKilled(l.cmd) {
+					return nil, true, timeoutError("log")
+				}
+				return nil, true, errors.New(strings.TrimSpace(l.stderr.String()))
+			}
+			return commits, true, nil
+		}
+		if err != nil {
+			return nil, true, err
+		}
+	}
+	return commits, false, nil
+}
+
+func (l *execLog) Stop() {
+	killProcessGroup(l.cmd)
+}
+
+func (execBackend) CommitDiff(dir, hash string, paths []string, full bool) ([][]byte, error) {
+	args := pathArgs([]string{"show", hash}, paths)
+	cmd, limit := gitCommand(dir, args...), config.DiffLimit
+	if full {
+		cmd, limit = gitCommandTimeout(dir, 0, args...), 0
+	}
+	out, err := cutDiff(cmd, limit)
+	if _, cut := err.(*cutError); err != nil && !cut {
+		return nil, err
+	}
+	// tab handling in screen is quite awkard. handle it here.
+	out = bytes.Replace(out, []byte("\t"), []byte("    "), -1)
+	out = bytes.TrimRight(out, " \n")
+	return bytes.Split(out, []byte("\n")), err
+}
+
+func (execBackend) ChangedFiles(dir string, targets []string) (map[string][]string, error) {
+	// a record is \x01 <hash> \x00 \n <file> \x00 <file> \x00 ...
+	args := []string{"log", "-z", "--name-only", "--no-renames", "--format=%x01%H"}
+	out, err := gitOutputIn(dir, append(args, targets...)...)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]string)
+	for _, rec := range bytes.Split(out, []byte("\x01"))[1:] {
+		f := bytes.Split(rec, []byte("\x00"))
+		fs := []string{}
+		for _, file := range f[1:] {
+			file = bytes.TrimPrefix(file, []byte("\n"))
+			if len(file) != 0 {
+				fs = append(fs, string(file))
+			}
+		}
+		files[string(f[0])] = fs
+	}
+	return files, nil
+}
+
+func (execBackend) PathCommits(dir string, targets, pathspecs []string) ([]string, error) {
+	args := []string{"log", "--format=%H"}
+	args = append(args, targets...)
+	args = append(args, "--")
+	out, err := gitOutputIn(dir, append(args, pathspecs...)...)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+func (execBackend) RevParse(dir string, revs []string) ([]string, error) {
+	out, err := gitOutputIn(dir, append([]string{"rev-parse"}, revs...)...)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+func (execBackend) Blame(dir, rev, path string) ([]BlameLine, map[string]string, error) {
+	out, err := gitOutputIn(dir, "blame", "--porcelain", rev, "--", path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parseBlame(out)
+}
+
+func (execBackend) RenamedFrom(dir, hash, path string) (string, error) {
+	out, err := gitOutputIn(dir, "diff", "--name-status", "-z", "-M", hash+"^", hash, "--")
+	if err != nil {
+		return "", err
+	}
+	// R<score> NUL <old> NUL <new> NUL, or <status> NUL <path> NUL
+	f := strings.Split(string(out), "\x00")
+	for i := 0; i < len(f); i++ {
+		if strings.HasPrefix(f[i], "R") && i+2 < len(f) {
+			if f[i+2] == path {
+				return f[i+1], nil
+			}
+			i += 2
+			continue
+		}
+		i++
+	}
+	return path, nil
+}
+
+func (execBackend) Tree(dir, hash, treeDir string) ([]*TreeNode, error) {
+	args := []string{"ls-tree", "-l", "-z", hash}
+	if treeDir != "" {
+		args = append(args, "--", treeDir)
+	}
+	out, err := gitOutputIn(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []*TreeNode{}
+	for _, entry := range strings.Split(string(out), "\x00") {
+		// <mode> SP <type> SP <object> SP+ <size> TAB <path>
+		tab := strings.Index(entry, "\t")
+		if tab == -1 {
+			continue
+		}
+		f := strings.Fields(entry[:tab])
+		if len(f) != 4 {
+			continue
+		}
+		nodes = append(nodes, &TreeNode{
+			Mode: f[0],
+			Type: f[1],
+			Hash: f[2],
+			Size: f[3],
+			Path: entry[tab+1:],
+		})
+	}
+	return nodes, nil
+}
+
+func (execBackend) Blob(dir, hash string) ([]byte, error) {
+	return gitOutputIn(dir, "cat-file", "blob", hash)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// memBackend is a GitBackend of a repository in memory, without git.
+// It only has commits and diffs, the other methods panic.
+type memBackend struct {
+	GitBackend
+	commits []*Commit
+	diffs   map[string]string
+}
+
+func (b memBackend) Commits(dir string, targets []string, digUp bool) ([]*Commit, error) {
+	commits := append([]*Commit{}, b.commits...)
+	if digUp {
+		reverseCommits(commits)
+	}
+	return commits, nil
+}
+
+func (b memBackend) CommitDiff(dir, hash string, paths []string, full bool) ([][]byte, error) {
+	d, ok := b.diffs[hash]
+	if !ok {
+		return nil, fmt.Errorf("unknown commit %s", hash)
+	}
+	return bytes.Split([]byte(d), []byte("\n")), nil
+}
+
+func TestBackend(t *testing.T) {
+	mem := memBackend{
+		commits: []*Commit{
+			{Hash: "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", Abbrev: "bbbbbbb", Title: "second", Parents: []string{"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"}},
+			{Hash: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Abbrev: "aaaaaaa", Title: "first"},
+		},
+		diffs: map[string]string{
+			"bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb": "commit bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb\n\n    second\n\ndiff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+b",
+		},
+	}
+	defer func(b GitBackend) { backend = b }(backend)
+	backend = mem
+	up, err := allCommits("", []string{"HEAD"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := titles(up), "first, second"; got != want {
+		t.Errorf("dug up commits: got %q, want %q", got, want)
+	}
+	setupApp(t, "", up)
+	if err := reloadCommits(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := titles(dig.Commits), "second, first"; got != want {
+		t.Errorf("reloaded commits: got %q, want %q", got, want)
+	}
+	text, err := newDiffCache(1).Get(mem.commits[0].Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, ok := diffLineAt(text, len(text)-1)
+	if !ok || d.NewPath != "a.txt" || d.NewLine != 1 {
+		t.Errorf("the added line: got %+v, %v", d, ok)
+	}
+	if _, err := commitDiff(mem.commits[1].Hash); err == nil {
+		t.Error("diff of an unknown commit: no error")
+	}
+}
+
+// commitFields returns what a backend reads of the commit, to compare them.
+// Refs are sorted, as git orders them by it's own way.
+func commitFields(c *Commit) string {
+	refs := append([]string{}, c.Refs...)
+	sort.Strings(refs)
+	return fmt.Sprintf("%s %s %v %d %s <%s> %d %s <%s> %q %q %v",
+		c.Hash, c.Abbrev, c.Parents, c.Time.Unix(), c.Author, c.Email, c.AuthorTime.Unix(),
+		c.Committer, c.CommitterEmail, c.Title, c.Body, refs)
+}
+
+func TestGogitBackend(t *testing.T) {
+	r := historyFixture(t)
+	config = defaultConfig()
+	var git, gogit GitBackend = execBackend{}, newGogitBackend()
+	for _, targets := range [][]string{
+		{"HEAD"},
+		{"--all"},
+		{"master", "--not", "feature"},
+		{"v0.1..master"},
+		{"HEAD", "--", "README"},
+		{"HEAD", "--full-history", "--", "README"},
+	} {
+		for _, digUp := range []bool{false, true} {
+			want, err := git.Commits(r.Dir, targets, digUp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := gogit.Commits(r.Dir, targets, digUp)
+			if err != nil {
+				t.Fatalf("%v: %v", targets, err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("%v, digUp %v: got %q, want %q", targets, digUp, titles(got), titles(want))
+			}
+			for i := range want {
+				if g, w := commitFields(got[i]), commitFields(want[i]); g != w {
+					t.Errorf("%v, digUp %v: commit %d\ngot  %s\nwant %s", targets, digUp, i, g, w)
+				}
+			}
+			// the log reads the same commits by pages.
+			log, err := gogit.LogCommits(r.Dir, targets, digUp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var logged []*Commit
+			for done := false; !done; {
+				var page []*Commit
+				page, done, err = log.Read(2)
+				if err != nil {
+					t.Fatal(err)
+				}
+				logged = append(logged, page...)
+			}
+			if titles(logged) != titles(want) {
+				t.Errorf("%v, digUp %v: logged %q, want %q", targets, digUp, titles(logged), titles(want))
+			}
+		}
+	}
+
+	all := r.Commits(false, "--all")
+	for _, c := range all {
+		want, err := git.CommitDiff(r.Dir, c.Hash, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := gogit.CommitDiff(r.Dir, c.Hash, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// index lines have abbreviated hashes, those could be longer in go-git.
+		if g, w := diffWithoutIndex(got), diffWithoutIndex(want); g != w {
+			t.Errorf("diff of %s:\ngot\n%s\nwant\n%s", c.Title, g, w)
+		}
+	}
+
+	for _, targets := range [][]string{{"--all"}, {"HEAD", "--", "README"}} {
+		want, err := git.ChangedFiles(r.Dir, targets)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := gogit.ChangedFiles(r.Dir, targets)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("changed files of %v: got %v, want %v", targets, got, want)
+		}
+	}
+
+	for _, pathspecs := range [][]string{nil, {"README"}, {"*.go"}, {":(glob)**/*.go"}, {":(literal)main.go"}, {"nothing"}} {
+		want, err := git.PathCommits(r.Dir, []string{"--all"}, pathspecs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := gogit.PathCommits(r.Dir, []string{"--all"}, pathspecs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("commits of %v: got %v, want %v", pathspecs, got, want)
+		}
+	}
+
+	revs := []string{"HEAD", "v0.1..master", "^feature", "master~1^{commit}"}
+	want, err := git.RevParse(r.Dir, revs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := gogit.RevParse(r.Dir, revs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rev-parse %v: got %v, want %v", revs, got, want)
+	}
+	if _, err := gogit.RevParse(r.Dir, []string{"nothing"}); err == nil {
+		t.Error("rev-parse of an unknown revision: no error")
+	}
+
+	wantLines, wantAuthors, err := git.Blame(r.Dir, "HEAD", "main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotLines, gotAuthors, err := gogit.Blame(r.Dir, "HEAD", "main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gotLines) != len(wantLines) {
+		t.Fatalf("blame: got %d lines, want %d", len(gotLines), len(wantLines))
+	}
+	for i, w := range wantLines {
+		// go-git doesn't know lines in the commits.
+		if g := gotLines[i]; g.Hash != w.Hash || g.Text != w.Text {
+			t.Errorf("blame line %d: got %+v, want %+v", i, g, w)
+		}
+	}
+	if !reflect.DeepEqual(gotAuthors, wantAuthors) {
+		t.Errorf("blame authors: got %v, want %v", gotAuthors, wantAuthors)
+	}
+
+	head := all[0].Hash
+	for _, dir := range []string{"", "nothing/"} {
+		want, werr := git.Tree(r.Dir, head, dir)
+		got, gerr := gogit.Tree(r.Dir, head, dir)
+		if (werr != nil) != (gerr != nil) || !reflect.DeepEqual(got, want) {
+			t.Errorf("tree of %q: got %v, %v, want %v, %v", dir, got, gerr, want, werr)
+		}
+	}
+	nodes, _ := gogit.Tree(r.Dir, head, "")
+	for _, n := range nodes {
+		want, err := git.Blob(r.Dir, n.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := gogit.Blob(r.Dir, n.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("blob of %s: got %q, want %q", n.Path, got, want)
+		}
+	}
+}
+
+// diffWithoutIndex returns the diff lines as a text, without index lines.
+func diffWithoutIndex(lines [][]byte) string {
+	var text []string
+	for _, ln := range lines {
+		if !bytes.HasPrefix(ln, []byte("index ")) {
+			text = append(text, string(ln))
+		}
+	}
+	return strings.Join(text, "\n")
+}
+
+func TestGogitLoader(t *testing.T) {
+	dir, _ := testRepo(t, 12)
+	defer func(n int) { firstPage = n }(firstPage)
+	firstPage = 5
+	want, err := allCommits(dir, []string{"HEAD"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(b GitBackend) { backend = b }(backend)
+	// dig reads the repository with go-git, when git isn't installed.
+	t.Setenv("PATH", t.TempDir())
+	backend = newBackend("auto")
+	if _, ok := backend.(*gogitBackend); !ok {
+		t.Fatalf("backend without git: got %T", backend)
+	}
+	loader, commits, err := startCommits(dir, []string{"HEAD"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loader == nil || len(commits) != firstPage {
+		t.Fatalf("first page has %d commits", len(commits))
+	}
+	setupApp(t, dir, commits)
+	loader.Program = dig
+	loader.Screen = screen
+	dig.Loader = loader
+	loader.Load()
+	runUntil(t, func() bool { return dig.Loader == nil }, nil)
+	if got := titles(dig.Commits); got != titles(want) {
+		t.Errorf("loaded commits: got %q, want %q", got, titles(want))
+	}
+	if err := cmdFilter([]string{"glob:file0.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := titles(dig.Commits), "change 9, change 6, change 3, change 0"; got != want {
+		t.Errorf("filtered commits: got %q, want %q", got, want)
+	}
+}
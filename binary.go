@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"strings"
+)
+
+// maxHexDump is the maximum bytes of a binary file to dump.
+const maxHexDump = 64 * 1024
+
+// isBinary reports whether the data is binary, with git's heuristic
+// that a NUL byte is in the first 8000 bytes.
+func isBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) != -1
+}
+
+// binaryText returns lines to show a binary file, metadata and a hex dump of it.
+func binaryText(path, blob string, data []byte) [][]byte {
+	lines := [][]byte{
+		[]byte(fmt.Sprintf("binary file %s, %d bytes (%s), blob %s", path, len(data), humanSize(len(data)), blob)),
+	}
+	if cfg, format, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		lines = append(lines, []byte(fmt.Sprintf("%s image, %dx%d", format, cfg.Width, cfg.Height)))
+	}
+	lines = append(lines, nil)
+	dump := data
+	if len(dump) > maxHexDump {
+		dump = dump[:maxHexDump]
+	}
+	lines = append(lines, hexDump(dump)...)
+	if len(dump) < len(data) {
+		lines = append(lines, []byte(fmt.Sprintf("... (first %s only)", humanSize(maxHexDump))))
+	}
+	return lines
+}
+
+// hexDump returns lines of hex dump of the data, like hexdump -C.
+func hexDump(data []byte) [][]byte {
+	lines := [][]byte{}
+	for off := 0; off < len(data); off += 16 {
+		end := off + 16
+		if end > len(data) {
+			end = len(data)
+		}
+		row := data[off:end]
+		buf := &bytes.Buffer{}
+		fmt.Fprintf(buf, "%08x  ", off)
+		for i := 0; i < 16; i++ {
+			if i < len(row) {
+				fmt.Fprintf(buf, "%02x ", row[i])
+			} else {
+				buf.WriteString("   ")
+			}
+			if i == 7 {
+				buf.WriteString(" ")
+			}
+		}
+		buf.WriteString(" |")
+		for _, b := range row {
+			if b < 0x20 || b >= 0x7F {
+				b = '.'
+			}
+			buf.WriteByte(b)
+		}
+		buf.WriteString("|")
+		lines = append(lines, buf.Bytes())
+	}
+	return lines
+}
+
+// humanSize returns a size in bytes readable, like "12.3 KiB".
+func humanSize(n int) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	f := float64(n)
+	for _, unit := range []string{"KiB", "MiB", "GiB"} {
+		f /= 1024
+		if f < 1024 || unit == "GiB" {
+			return fmt.Sprintf("%.1f %s", f, unit)
+		}
+	}
+	return ""
+}
+
+// imageProtocol returns the inline image protocol the terminal supports,
+// "kitty" or "iterm", or an empty string if it doesn't.
+func imageProtocol() string {
+	if os.Getenv("KITTY_WINDOW_ID") != "" || strings.Contains(os.Getenv("TERM"), "kitty") {
+		return "kitty"
+	}
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm":
+		return "iterm"
+	}
+	return ""
+}
+
+// shownImage is the image drawn over the screen, that should be cleared when it's gone.
+var shownImage string
+
+// drawImage draws the image of the file view with the terminal's image protocol.
+// It's drawn after the screen is shown, as tcell only knows cells.
+// Then tcell doesn't touch the image, since cells under it are not changed.
+func drawImage() {
+	a := screen.File
+	key := ""
+	if dig.CurView == FileView && a.ShowImage() && screen.Popup == nil && screen.Note == nil {
+		key = fmt.Sprintf("%s:%s:%v", a.CommitHash, a.Path, a.Bound)
+	}
+	if key == shownImage {
+		return
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return
+	}
+	defer tty.Close()
+	if shownImage != "" {
+		if imageProtocol() == "kitty" {
+			fmt.Fprint(tty, "\x1b_Ga=d\x1b\\")
+		}
+		// redraw cells those the image covered.
+		term.Sync()
+	}
+	shownImage = key
+	if key == "" {
+		return
+	}
+	// below metadata lines.
+	bound := a.Bound
+	bound.Min.L += 3
+	bound.Size.L -= 3
+	if bound.Size.L <= 0 {
+		return
+	}
+	enc := base64.StdEncoding.EncodeToString(a.Image)
+	fmt.Fprintf(tty, "\x1b7\x1b[%d;%dH", bound.Min.L+1, bound.Min.O+1)
+	if imageProtocol() == "kitty" {
+		// kitty takes png, in chunks.
+		for i := 0; i < len(enc); i += 4096 {
+			end := i + 4096
+			more := 1
+			if end >= len(enc) {
+				end = len(enc)
+				more = 0
+			}
+			if i == 0 {
+				fmt.Fprintf(tty, "\x1b_Ga=T,f=100,c=%d,r=%d,m=%d;%s\x1b\\", bound.Size.O, bound.Size.L, more, enc[i:end])
+			} else {
+				fmt.Fprintf(tty, "\x1b_Gm=%d;%s\x1b\\", more, enc[i:end])
+			}
+		}
+	} else {
+		fmt.Fprintf(tty, "\x1b]1337;File=inline=1;width=%d;height=%d;preserveAspectRatio=1:%s\a", bound.Size.O, bound.Size.L, enc)
+	}
+	fmt.Fprint(tty, "\x1b8")
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// firstBadRe finds the first bad commit from output of git bisect run.
+var firstBadRe = regexp.MustCompile(`^([0-9a-f]{40,64}) is the first bad commit`)
+
+// bisectRun bisects between two marked commits with the shell command, like git bisect run.
+// The older commit is good and the newer one is bad.
+// It runs in a snapshot of the bad commit, so the work tree isn't touched.
+// When it's done, the first bad commit is selected.
+func bisectRun(command string) error {
+	if command == "" {
+		return fmt.Errorf("no command to run: set run config, or give it to :bisect")
+	}
+	hashes := markedHashes()
+	if len(hashes) != 2 {
+		return fmt.Errorf("mark a good and a bad commit with space, to bisect between them")
+	}
+	good, bad := hashes[0], hashes[1]
+	dir, err := snapshot(dig.ByHash[bad])
+	if err != nil {
+		return err
+	}
+	// bisect state is per worktree, reset it even when the run failed.
+	line := fmt.Sprintf("git bisect start %s %s && git bisect run sh -c %s; status=$?; git bisect reset >/dev/null 2>&1; exit $status",
+		bad, good, shellQuote(command))
+	p, err := startRun(dir, "bisect: "+command, line, bad)
+	if err != nil {
+		return err
+	}
+	p.OnDone = func() {
+		if p.Killed {
+			// the line is killed before it resets bisect of the snapshot.
+			cmd := gitCommand(dir, "bisect", "reset")
+			go func() {
+				msg := bisectResetMsg{}
+				if out, err := cmd.CombinedOutput(); err != nil {
+					msg.Err = fmt.Errorf("%s", firstLine(out))
+				}
+				send(msg)
+			}()
+			return
+		}
+		for _, ln := range p.Lines {
+			m := firstBadRe.FindStringSubmatch(strings.TrimSpace(ln))
+			if m == nil {
+				continue
+			}
+			if i := findByHash(dig.Commits, m[1], 0); i != -1 {
+				pushJump()
+				screen.Commit.SetCursor(i)
+			}
+			dig.Message = "first bad commit: " + shortHash(m[1])
+			return
+		}
+		dig.Message = "bisect couldn't find the first bad commit"
+	}
+	return nil
+}
+
+// bisectResetMsg is bisect of a snapshot that is reset, after it's canceled.
+type bisectResetMsg struct {
+	Err error
+}
+
+// bisectReset tells the bisect is canceled, or why the snapshot couldn't be reset.
+func bisectReset(msg bisectResetMsg) {
+	if msg.Err != nil {
+		dig.Message = "bisect reset: " + msg.Err.Error()
+		return
+	}
+	dig.Message = "bisect is canceled"
+}
+
+// cmdBisect bisects between two marked commits with the command, or the run config.
+//
+//	bisect [command]
+func cmdBisect(args []string) error {
+	command := config.Run
+	if len(args) != 0 {
+		command = strings.Join(args, " ")
+	}
+	return bisectRun(command)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBisectCancel(t *testing.T) {
+	dir, commits := testRepo(t, 5)
+	setupApp(t, dir, commits)
+	bad := commits[0]
+	dig.Marks = Marks{commits[len(commits)-1].Hash, bad.Hash}
+	t.Cleanup(func() { os.RemoveAll(snapshotDir(bad)) })
+	if err := bisectRun("sleep 30"); err != nil {
+		t.Fatal(err)
+	}
+	p := screen.Popup.(*RunPopup)
+	// the command runs in a commit between them.
+	runUntil(t, func() bool {
+		return len(p.Lines) != 0 && strings.HasPrefix(p.Lines[len(p.Lines)-1], "running")
+	}, nil)
+	app.Update(Event{Type: EventKey, Key: KeyEsc})
+	if screen.Popup != nil {
+		t.Fatalf("esc: got popup %T", screen.Popup)
+	}
+	runUntil(t, func() bool { return strings.HasPrefix(dig.Message, "bisect") }, nil)
+	if dig.Message != "bisect is canceled" {
+		t.Fatalf("message: got %q", dig.Message)
+	}
+	// the snapshot is back to the bad commit, to be reused.
+	if _, err := snapshot(bad); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// BlameLine is a line of a blamed file.
+type BlameLine struct {
+	// Hash is the commit that last changed the line.
+	Hash string
+	// OrigLine is line number of the line in the commit.
+	OrigLine int
+	Text     string
+}
+
+// BlameArea is an Area for showing blame of a file at a commit.
+type BlameArea struct {
+	Bound Rect
+	// Rev is the revision blamed at.
+	Rev  string
+	Path string
+	// Lines are blamed lines, and Authors are author names by commit hashes.
+	Lines   []BlameLine
+	Authors map[string]string
+	CurIdx  int
+	TopIdx  int
+}
+
+// Load blames the file at the revision, and puts the cursor at the line.
+// The line starts from 1.
+func (a *BlameArea) Load(rev, path string, line int) error {
+	lines, authors, err := blame(rev, path)
+	if err != nil {
+		return err
+	}
+	a.Rev = rev
+	a.Path = path
+	a.Lines = lines
+	a.Authors = authors
+	a.CurIdx = line - 1
+	a.TopIdx = 0
+	a.cursorValidation()
+	// show the line at middle of the area.
+	a.TopIdx = a.CurIdx - a.Bound.Size.L/2
+	if a.TopIdx < 0 {
+		a.TopIdx = 0
+	}
+	return nil
+}
+
+// Line returns the line under the cursor.
+func (a *BlameArea) Line() BlameLine {
+	return a.Lines[a.CurIdx]
+}
+
+func (a *BlameArea) cursorValidation() {
+	if a.CurIdx >= len(a.Lines) {
+		a.CurIdx = len(a.Lines) - 1
+	}
+	if a.CurIdx < 0 {
+		a.CurIdx = 0
+	}
+}
+
+// Handle handles a terminal event.
+func (a *BlameArea) Handle(ev Event) bool {
+	if ev.Key == KeyArrowUp || ev.Ch == 'i' {
+		a.CurIdx -= count()
+	} else if ev.Key == KeyArrowDown || ev.Ch == 'k' {
+		a.CurIdx += count()
+	} else if ev.Key == KeyPgup || ev.Ch == 'b' {
+		a.CurIdx -= a.Bound.Size.L * count()
+	} else if ev.Key == KeyPgdn || ev.Ch == 'f' {
+		a.CurIdx += a.Bound.Size.L * count()
+	} else if ev.Ch == 'u' {
+		a.CurIdx -= a.Bound.Size.L / 2 * count()
+	} else if ev.Ch == 'd' {
+		a.CurIdx += a.Bound.Size.L / 2 * count()
+	} else if ev.Key == KeyHome {
+		a.CurIdx = 0
+	} else if ev.Key == KeyEnd {
+		a.CurIdx = len(a.Lines) - 1
+	} else if len(a.Lines) == 0 {
+		return false
+	} else if ev.Key == KeyEnter {
+		// jump to the commit that last changed the line.
+		i := findByHash(dig.Commits, a.Line().Hash, 0)
+		if i == -1 {
+			// it's filtered out, or not in the targets. it's diff is still worth to see.
+			openDiff(a.Line().Hash)
+			dig.Message = "commit not in the list, showing it's diff: " + shortHash(a.Line().Hash)
+			return true
+		}
+		pushJump()
+		screen.Commit.SetCursor(i)
+		dig.CurView = CommitView
+	} else if ev.Ch == 'B' {
+		// blame before the commit, to dig older changes of the line.
+		l := a.Line()
+		if err := a.blameParent(l); err != nil {
+			dig.Message = err.Error()
+		}
+	} else {
+		return false
+	}
+	a.cursorValidation()
+	return true
+}
+
+// blameParent blames the line's origin in the parent of the commit that changed it.
+func (a *BlameArea) blameParent(l BlameLine) error {
+	parent, err := resolveHash(l.Hash + "^")
+	if err != nil {
+		return fmt.Errorf("%s is the root commit", shortHash(l.Hash))
+	}
+	path, err := blamePath(l.Hash, a.Path)
+	if err != nil {
+		return err
+	}
+	pushJump()
+	b := *a
+	if err := b.Load(parent, path, l.OrigLine); err != nil {
+		popJump()
+		return err
+	}
+	*a = b
+	return nil
+}
+
+// Draw draws it's contents.
+func (a *BlameArea) Draw() {
+	header := fmt.Sprintf("blame %s at %s", a.Path, shortHash(a.Rev))
+	top := Rect{Min: a.Bound.Min, Size: Pt{1, a.Bound.Size.O}}
+	fillColor(top, theme.Header)
+	drawLine(top, 0, []byte(header), 0, theme.Header)
+
+	page := a.Bound.Size.L - 1
+	if a.TopIdx > a.CurIdx {
+		a.TopIdx = a.CurIdx
+	} else if a.TopIdx+page <= a.CurIdx {
+		a.TopIdx = a.CurIdx - page + 1
+	}
+	body := Rect{Min: Pt{a.Bound.Min.L + 1, a.Bound.Min.O}, Size: Pt{page, a.Bound.Size.O}}
+	numWidth := len(strconv.Itoa(len(a.Lines)))
+	for l := 0; l < page; l++ {
+		i := a.TopIdx + l
+		if i >= len(a.Lines) {
+			break
+		}
+		bl := a.Lines[i]
+		c := theme.Normal
+		if i == a.CurIdx {
+			c = theme.Selected
+			fillColor(Rect{Min: Pt{body.Min.L + l, body.Min.O}, Size: Pt{1, body.Size.O}}, c)
+		}
+		author := runewidth.Truncate(a.Authors[bl.Hash], 12, "")
+		author += strings.Repeat(" ", 12-runewidth.StringWidth(author))
+		o := 0
+		prefix := shortHash(bl.Hash) + " "
+		drawLine(body, l, []byte(prefix), -o, Color{theme.Hash, c.Bg})
+		o += len(prefix)
+		meta := fmt.Sprintf("%s %*d ", author, numWidth, i+1)
+		drawLine(body, l, []byte(meta), -o, c)
+		o += runewidth.StringWidth(meta)
+		drawLine(body, l, []byte(bl.Text), -o, c)
+	}
+}
+
+// blame returns blamed lines of the file at the revision,
+// and author names of the commits.
+func blame(rev, path string) ([]BlameLine, map[string]string, error) {
+	return backend.Blame(dig.RepoDir, rev, path)
+}
+
+// parseBlame parses output of git blame --porcelain.
+func parseBlame(out []byte) ([]BlameLine, map[string]string, error) {
+	lines := []BlameLine{}
+	authors := make(map[string]string)
+	var cur BlameLine
+	for _, ln := range bytes.Split(out, []byte("\n")) {
+		if len(ln) == 0 {
+			continue
+		}
+		if ln[0] == '\t' {
+			// tab handling in screen is quite awkard. handle it here.
+			cur.Text = strings.Replace(string(ln[1:]), "\t", "    ", -1)
+			lines = append(lines, cur)
+			continue
+		}
+		f := strings.Fields(string(ln))
+		if isFullHash(f[0]) && len(f) >= 3 {
+			// <hash> <orig line> <final line> [<lines in group>]
+			n, err := strconv.Atoi(f[1])
+			if err != nil {
+				return nil, nil, fmt.Errorf("unexpected git blame output: %q", ln)
+			}
+			cur = BlameLine{Hash: f[0], OrigLine: n}
+			continue
+		}
+		if f[0] == "author" {
+			authors[cur.Hash] = strings.TrimPrefix(string(ln), "author ")
+		}
+	}
+	return lines, authors, nil
+}
+
+// blamePath returns the path of the file before the commit,
+// as the commit could rename the file.
+func blamePath(hash, path string) (string, error) {
+	return backend.RenamedFrom(dig.RepoDir, hash, path)
+}
+
+// blameFromDiff opens blame of the file that the l-th line of the diff belongs to,
+// positioned on the line. A deleted line is blamed at the parent of the commit.
+func blameFromDiff(a *DiffArea, l int) error {
+	dl, ok := diffLineAt(a.Text, l)
+	if !ok {
+		return fmt.Errorf("not on a line of a hunk")
+	}
+	rev := a.CommitHash
+	if i := strings.Index(rev, ".."); i != -1 {
+		// a range diff, blame the file at the end of the range.
+		rev = rev[i+2:]
+	}
+	path, line := dl.NewPath, dl.NewLine
+	if line == 0 {
+		rev += "^"
+		if i := strings.Index(a.CommitHash, ".."); i != -1 {
+			rev = a.CommitHash[:i]
+		}
+		path, line = dl.OldPath, dl.OldLine
+	}
+	pushJump()
+	if err := screen.Blame.Load(rev, path, line); err != nil {
+		popJump()
+		return err
+	}
+	dig.CurView = BlameView
+	return nil
+}
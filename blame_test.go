@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestBlameSHA256(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	// buildRepo makes a SHA-1 repository.
+	r := &fixture{t: t, Dir: t.TempDir()}
+	r.git("init", "-q", "--object-format=sha256")
+	r.git("symbolic-ref", "HEAD", "refs/heads/master")
+	r.apply(step{Files: map[string]string{"main.go": "package main\n\nfunc main() {\n}\n"}, Message: "initial"})
+	r.apply(step{Files: map[string]string{"main.go": "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"}, Message: "say hi"})
+	setupApp(t, r.Dir, r.Commits(false, "HEAD"))
+	lines, authors, err := blame("HEAD", "main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	head := r.Hash("HEAD")
+	if len(head) != 64 || len(lines) != 5 || lines[3].Hash != head || lines[0].Hash != r.Hash("HEAD^") {
+		t.Fatalf("blame: got %+v", lines)
+	}
+	if authors[head] != "Alice" {
+		t.Errorf("author of %s: got %q", head, authors[head])
+	}
+}
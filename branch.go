@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Branch is a local branch.
+type Branch struct {
+	Name string
+	Hash string
+	// Head reports whether it's checked out.
+	Head  bool
+	Date  string
+	Title string
+}
+
+// localBranches returns local branches of the repository, the recently committed first.
+func localBranches() ([]Branch, error) {
+	out, err := gitOutput("for-each-ref", "--sort=-committerdate",
+		"--format=%(refname:short)%00%(objectname)%00%(HEAD)%00%(committerdate:relative)%00%(contents:subject)",
+		"refs/heads")
+	if err != nil {
+		return nil, err
+	}
+	var branches []Branch
+	for _, ln := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		f := strings.Split(ln, "\x00")
+		if len(f) != 5 {
+			continue
+		}
+		branches = append(branches, Branch{Name: f[0], Hash: f[1], Head: f[2] == "*", Date: f[3], Title: f[4]})
+	}
+	return branches, nil
+}
+
+// BranchArea lists local branches, to choose the branch the commit list shows.
+type BranchArea struct {
+	Bound    Rect
+	Branches []Branch
+	CurIdx   int
+	TopIdx   int
+}
+
+// Load loads branches, with the cursor at the one the commit list shows.
+func (a *BranchArea) Load() error {
+	branches, err := localBranches()
+	if err != nil {
+		return err
+	}
+	if len(branches) == 0 {
+		return fmt.Errorf("no local branches")
+	}
+	a.Branches = branches
+	a.CurIdx = 0
+	a.TopIdx = 0
+	for i, b := range branches {
+		if b.Name == shownBranch() || shownBranch() == "" && b.Head {
+			a.CurIdx = i
+		}
+	}
+	return nil
+}
+
+// shownBranch returns the branch the commit list shows, when it's chosen with the branch list.
+func shownBranch() string {
+	if len(dig.Targets) == 1 && !strings.HasPrefix(dig.Targets[0], "-") {
+		return dig.Targets[0]
+	}
+	return ""
+}
+
+// showBranch reloads the commit list with commits of the branch.
+func showBranch(name string) error {
+	prev := dig.Targets
+	dig.Targets = []string{name}
+	if err := reloadCommits(); err != nil {
+		dig.Targets = prev
+		return err
+	}
+	dig.Message = "showing " + name
+	return nil
+}
+
+// Handle handles a terminal event.
+func (a *BranchArea) Handle(ev Event) bool {
+	if ev.Key == KeyArrowUp || ev.Ch == 'i' {
+		a.CurIdx -= count()
+	} else if ev.Key == KeyArrowDown || ev.Ch == 'k' {
+		a.CurIdx += count()
+	} else if ev.Key == KeyHome {
+		a.CurIdx = 0
+	} else if ev.Key == KeyEnd {
+		a.CurIdx = len(a.Branches) - 1
+	} else if len(a.Branches) == 0 {
+		return false
+	} else if ev.Key == KeyEnter {
+		if err := showBranch(a.Branches[a.CurIdx].Name); err != nil {
+			dig.Message = err.Error()
+			return true
+		}
+		dig.CurView = CommitView
+	} else {
+		return false
+	}
+	if a.CurIdx >= len(a.Branches) {
+		a.CurIdx = len(a.Branches) - 1
+	}
+	if a.CurIdx < 0 {
+		a.CurIdx = 0
+	}
+	return true
+}
+
+// Draw draws branches. The checked out one is marked with *, and the shown one with >.
+func (a *BranchArea) Draw() {
+	if a.TopIdx > a.CurIdx {
+		a.TopIdx = a.CurIdx
+	} else if a.TopIdx+a.Bound.Size.L <= a.CurIdx {
+		a.TopIdx = a.CurIdx - a.Bound.Size.L + 1
+	}
+	width := 0
+	for _, b := range a.Branches {
+		if len(b.Name) > width {
+			width = len(b.Name)
+		}
+	}
+	shown := shownBranch()
+	for l := 0; l < a.Bound.Size.L; l++ {
+		i := a.TopIdx + l
+		if i >= len(a.Branches) {
+			break
+		}
+		b := a.Branches[i]
+		c := theme.Normal
+		if i == a.CurIdx {
+			c = theme.Selected
+			fillColor(Rect{Min: Pt{a.Bound.Min.L + l, a.Bound.Min.O}, Size: Pt{1, a.Bound.Size.O}}, c)
+		}
+		mark := "  "
+		if b.Name == shown || shown == "" && b.Head {
+			mark = "> "
+		}
+		if b.Head {
+			mark = mark[:1] + "*"
+		}
+		drawLine(a.Bound, l, []byte(fmt.Sprintf("%s %-*s  %s  %s (%s)", mark, width, b.Name, shortHash(b.Hash), b.Title, b.Date)), 0, c)
+	}
+}
@@ -1,5 +1,9 @@
 #/bin/bash
 
-GOOS=linux GOARCH=amd64 go build -o dig-linux-amd64
-GOOS=darwin GOARCH=amd64 go build -o dig-darwin-amd64
-GOOS=windows GOARCH=amd64 go build -o dig-windows-amd64.exe
+# version informations for dig -version.
+pkg=main
+ldflags="-X $pkg.version=$(git describe --tags --always --dirty) -X $pkg.commit=$(git rev-parse --short HEAD) -X $pkg.date=$(date -u +%Y-%m-%d)"
+
+GOOS=linux GOARCH=amd64 go build -ldflags "$ldflags" -o dig-linux-amd64
+GOOS=darwin GOARCH=amd64 go build -ldflags "$ldflags" -o dig-darwin-amd64
+GOOS=windows GOARCH=amd64 go build -ldflags "$ldflags" -o dig-windows-amd64.exe
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Runs are linear stretches of history, between merges and forks.
+// A run is collapsed into it's anchor, the first non-linear commit after it.
+type Runs struct {
+	// Anchor is the anchor of a linear commit.
+	Anchor map[string]string
+	// Len is the number of commits collapsed into an anchor.
+	Len map[string]int
+	// Width is the width of the longest run's badge.
+	Width int
+}
+
+// linear reports whether the commit has only one parent and only one child.
+func (p *Program) linear(c *Commit) bool {
+	return len(c.Parents) == 1 && len(p.ChildrenOf(c.Hash)) == 1
+}
+
+// LinearRuns returns linear runs of all commits.
+// It's built at the first call, as it's only needed while collapsing.
+func (p *Program) LinearRuns() *Runs {
+	if p.Runs != nil {
+		return p.Runs
+	}
+	r := &Runs{Anchor: make(map[string]string), Len: make(map[string]int)}
+	for _, c := range p.All {
+		if !p.linear(c) {
+			continue
+		}
+		if _, ok := r.Anchor[c.Hash]; ok {
+			continue
+		}
+		// walk to the anchor, reusing anchors those are already found.
+		path := []string{}
+		h := c.Hash
+		for {
+			if a, ok := r.Anchor[h]; ok {
+				h = a
+				break
+			}
+			hc := p.ByHash[h]
+			if hc == nil || !p.linear(hc) {
+				break
+			}
+			path = append(path, h)
+			h = p.ChildrenOf(h)[0]
+		}
+		for _, ph := range path {
+			r.Anchor[ph] = h
+			r.Len[h]++
+		}
+	}
+	for _, n := range r.Len {
+		if w := len(strconv.Itoa(n)) + 1; w > r.Width {
+			r.Width = w
+		}
+	}
+	p.Runs = r
+	return r
+}
+
+// collapseFilter returns a filter that hides linear runs, except expanded ones.
+func collapseFilter() *Filter {
+	p := dig
+	return &Filter{Match: func(c *Commit) bool {
+		a, ok := p.LinearRuns().Anchor[c.Hash]
+		return !ok || p.Expanded[a]
+	}}
+}
+
+// Collapsing reports whether linear runs are collapsed.
+func (p *Program) Collapsing() bool {
+	for _, f := range p.Filters {
+		if f.Name == "collapse" {
+			return true
+		}
+	}
+	return false
+}
+
+// runBadge returns a badge of the commit for it's collapsed run, like "+57",
+// or "-57" when the run is expanded.
+func runBadge(c *Commit) string {
+	r := dig.LinearRuns()
+	b := ""
+	if n := r.Len[c.Hash]; n != 0 {
+		b = "+"
+		if dig.Expanded[c.Hash] {
+			b = "-"
+		}
+		b += strconv.Itoa(n)
+	}
+	return fmt.Sprintf("%*s", r.Width, b)
+}
+
+// toggleRun expands the run collapsed into the selected commit,
+// or collapses the run that the selected commit belongs to.
+func toggleRun() error {
+	if !dig.Collapsing() {
+		return fmt.Errorf("linear runs are not collapsed, collapse them with Z")
+	}
+	c := screen.Commit.Commit()
+	r := dig.LinearRuns()
+	anchor := c.Hash
+	if a, ok := r.Anchor[c.Hash]; ok {
+		anchor = a
+	} else if r.Len[c.Hash] == 0 {
+		return fmt.Errorf("not a collapsed run")
+	}
+	if dig.Expanded == nil {
+		dig.Expanded = make(map[string]bool)
+	}
+	dig.Expanded[anchor] = !dig.Expanded[anchor]
+	if anchor != c.Hash {
+		// the selected commit is going to be hidden.
+		if i := findByHash(dig.Commits, anchor, screen.Commit.CurIdx); i != -1 {
+			screen.Commit.SetCursor(i)
+		}
+	}
+	return setFilters(dig.Filters)
+}
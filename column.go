@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// columns are extra metadata columns those could be shown in the commit list, by their names.
+// Each needs a git command per commit, so they're loaded in background only for visible rows.
+var columns = map[string]struct {
+	// Width is width of the column in the commit list.
+	Width int
+	// Load gets the column's value of the commit.
+	Load func(repoDir, hash string) (string, error)
+}{
+	"stat":      {Width: 11, Load: loadStat},
+	"signature": {Width: 1, Load: loadSignature},
+	"describe":  {Width: 20, Load: loadDescribe},
+}
+
+// parseColumns parses names of columns separated by commas, like "stat, describe".
+// A column could also be one of the log fields.
+func parseColumns(s string, fields []logField) ([]string, error) {
+	names := parseList(s)
+	for _, n := range names {
+		if _, ok := columns[n]; !ok && logFieldIndex(fields, n) == -1 {
+			return nil, fmt.Errorf("unknown column: %s", n)
+		}
+	}
+	return names, nil
+}
+
+// loadStat returns lines added and deleted by the commit, like "+12 -3".
+// A merge is compared to it's first parent.
+func loadStat(repoDir, hash string) (string, error) {
+	cmd := gitCommand(repoDir, "show", "--numstat", "--format=", "-m", "--first-parent", hash)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	add, del := 0, 0
+	for _, ln := range strings.Split(string(out), "\n") {
+		f := strings.Fields(ln)
+		if len(f) < 3 {
+			continue
+		}
+		// binary files have - for their counts.
+		a, _ := strconv.Atoi(f[0])
+		d, _ := strconv.Atoi(f[1])
+		add += a
+		del += d
+	}
+	return fmt.Sprintf("+%d -%d", add, del), nil
+}
+
+// loadSignature returns the signature status of the commit, as git log's %G?.
+// G is a good signature, B is a bad one, and N is no signature. See git help log for others.
+func loadSignature(repoDir, hash string) (string, error) {
+	cmd := gitCommand(repoDir, "log", "-1", "--format=%G?", hash)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// loadDescribe returns the nearest tag of the commit, like "v1.2-3-g1234abc".
+func loadDescribe(repoDir, hash string) (string, error) {
+	cmd := gitCommand(repoDir, "describe", "--tags", "--always", hash)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// metaJob is a column of a commit to load.
+type metaJob struct {
+	Hash   string
+	Column string
+}
+
+// MetaLoader loads columns of commits in background, with a bounded number of workers.
+// Loaded values are cached per commit, and jobs of commits those scrolled away are dropped.
+type MetaLoader struct {
+	repoDir string
+	// Values are loaded values, only touched by the app's loop.
+	Values map[metaJob]string
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []metaJob
+	queued  map[metaJob]bool
+	visible map[string]bool
+	started bool
+	stopped bool
+	// workers is the number of running workers.
+	workers int
+}
+
+// newMetaLoader creates a loader of the repository. Workers start at the first job.
+func newMetaLoader(repoDir string) *MetaLoader {
+	m := &MetaLoader{
+		repoDir: repoDir,
+		Values:  make(map[metaJob]string),
+		queued:  make(map[metaJob]bool),
+		visible: make(map[string]bool),
+	}
+	m.cond = sync.NewCond(&m.mu)
+	return m
+}
+
+// MetaLoader returns the program's loader of columns.
+func (p *Program) MetaLoader() *MetaLoader {
+	if p.Meta == nil {
+		p.Meta = newMetaLoader(p.RepoDir)
+	}
+	return p.Meta
+}
+
+// Show tells which commits are visible now, and queues their columns those aren't loaded.
+// Queued jobs of commits not visible anymore are skipped.
+func (m *MetaLoader) Show(hashes []string, cols []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopped {
+		return
+	}
+	m.visible = make(map[string]bool, len(hashes))
+	for _, h := range hashes {
+		m.visible[h] = true
+	}
+	// the last queued is loaded first, queue from the bottom so the top rows come first.
+	for i := len(hashes) - 1; i >= 0; i-- {
+		for _, col := range cols {
+			if _, ok := columns[col]; !ok {
+				// a log field, it's loaded with the commit.
+				continue
+			}
+			j := metaJob{hashes[i], col}
+			if _, ok := m.Values[j]; ok || m.queued[j] {
+				continue
+			}
+			m.queued[j] = true
+			m.queue = append(m.queue, j)
+		}
+	}
+	if !m.started && len(m.queue) != 0 {
+		m.started = true
+		m.workers = config.MetaWorkers
+		for i := 0; i < config.MetaWorkers; i++ {
+			go m.work()
+		}
+	}
+	m.cond.Broadcast()
+}
+
+// next waits and returns the next job of a visible commit.
+// It returns false when the loader is stopped.
+func (m *MetaLoader) next() (metaJob, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for {
+		if m.stopped {
+			m.workers--
+			return metaJob{}, false
+		}
+		for len(m.queue) != 0 {
+			j := m.queue[len(m.queue)-1]
+			m.queue = m.queue[:len(m.queue)-1]
+			if m.visible[j.Hash] {
+				return j, true
+			}
+			// it could be queued again when it's visible again.
+			delete(m.queued, j)
+		}
+		m.cond.Wait()
+	}
+}
+
+// work is a worker that loads columns, until the loader is stopped.
+func (m *MetaLoader) work() {
+	for {
+		j, ok := m.next()
+		if !ok {
+			return
+		}
+		v, err := columns[j.Column].Load(m.repoDir, j.Hash)
+		if err != nil {
+			v = "?"
+		}
+		send(metaMsg{Loader: m, Job: j, Value: v})
+	}
+}
+
+// Stop stops the workers, after their current jobs.
+// Those jobs' values are still sent, but nothing draws them.
+func (m *MetaLoader) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopped = true
+	m.queue = nil
+	m.cond.Broadcast()
+}
+
+// metaMsg is a loaded column of a commit.
+type metaMsg struct {
+	Loader *MetaLoader
+	Job    metaJob
+	Value  string
+}
+
+// Loaded caches the loaded column.
+func (m *MetaLoader) Loaded(msg metaMsg) {
+	m.Values[msg.Job] = msg.Value
+	m.mu.Lock()
+	delete(m.queued, msg.Job)
+	m.mu.Unlock()
+}
+
+// columnsWidth returns width of the columns, with their spaces.
+func columnsWidth(cols []string) int {
+	w := 0
+	for _, col := range cols {
+		w += columnWidth(col) + 1
+	}
+	return w
+}
+
+// columnWidth returns width of the column.
+func columnWidth(col string) int {
+	if c, ok := columns[col]; ok {
+		return c.Width
+	}
+	return logFieldWidth
+}
+
+// drawColumns draws the commit's columns at the o-th column of the l-th line, and returns their width.
+// A column not loaded yet is drawn as … until it's loaded.
+func drawColumns(bound Rect, l, o int, c *Commit, cols []string, bg Attribute) int {
+	w := 0
+	for _, col := range cols {
+		width := columnWidth(col)
+		v, ok := dig.MetaLoader().Values[metaJob{c.Hash, col}]
+		if i := logFieldIndex(config.LogFields, col); i != -1 {
+			v, ok = c.Field(i), true
+		}
+		fg := theme.Hash
+		switch {
+		case !ok:
+			v = "…"
+		case col == "signature":
+			switch v {
+			case "G", "U":
+				fg = theme.Added.Fg
+			case "B", "R", "X", "Y":
+				fg = theme.Deleted.Fg
+			case "N":
+				v = " "
+			}
+		}
+		if runewidth.StringWidth(v) > width {
+			v = runewidth.Truncate(v, width, "…")
+		}
+		v += strings.Repeat(" ", width-runewidth.StringWidth(v)+1)
+		drawLine(bound, l, []byte(v), -(o + w), Color{fg, bg})
+		w += width + 1
+	}
+	return w
+}
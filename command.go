@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// commands are commands those can be run in CommandMode.
+// A command gets it's arguments splitted by white spaces.
+var commands = map[string]func(args []string) error{
+	"report":   cmdReport,
+	"history":  cmdHistory,
+	"simplify": cmdSimplify,
+	"tabnew":   cmdTabNew,
+	"tabclose": cmdTabClose,
+	"pins":     cmdPins,
+	"marks":    cmdMarks,
+	"goto":     cmdGoto,
+	"date":     cmdDate,
+	"filter":   cmdFilter,
+	"run":      cmdRun,
+	"bisect":   cmdBisect,
+	"snapshot": cmdSnapshot,
+	"ancestor": cmdAncestor,
+	"release":  cmdRelease,
+	"mbox":     cmdMbox,
+	"worddiff": cmdWordDiff,
+	"since":    cmdSince,
+	"send":     cmdSend,
+	"profile":  cmdProfile,
+	"share":    cmdShare,
+	"remote":   cmdRemote,
+	"watch":    cmdWatch,
+
+	"export-report":  cmdExportReport,
+	"export-commits": cmdExportCommits,
+}
+
+// handleCommand handles CommandMode events.
+func handleCommand(ev Event) {
+	switch ev.Key {
+	case KeyEsc, KeyCtrlQ, KeyCtrlK:
+		dig.CommandString = ""
+		dig.Mode = NormalMode
+		return
+	case KeyEnter:
+		cmd := dig.CommandString
+		dig.CommandString = ""
+		dig.Mode = NormalMode
+		if err := runCommand(cmd); err != nil {
+			dig.Message = err.Error()
+		}
+		return
+	case KeyBackspace:
+		if dig.CommandString == "" {
+			dig.Mode = NormalMode
+			return
+		}
+		_, size := utf8.DecodeLastRuneInString(dig.CommandString)
+		dig.CommandString = dig.CommandString[:len(dig.CommandString)-size]
+		return
+	case KeySpace:
+		dig.CommandString += " "
+		return
+	}
+	if ev.Ch != 0 {
+		dig.CommandString += string(ev.Ch)
+	}
+}
+
+// runCommand parses and runs a command line.
+func runCommand(line string) error {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return nil
+	}
+	fn, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+	return fn(args[1:])
+}
+
+// cmdHistory shows history of a file.
+// Without an argument, it will show all commits again.
+func cmdHistory(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: history [file]")
+	}
+	prev := dig.History
+	dig.History = ""
+	if len(args) == 1 {
+		dig.History = args[0]
+	}
+	if err := reloadCommits(); err != nil {
+		dig.History = prev
+		return err
+	}
+	dig.CurView = CommitView
+	return nil
+}
+
+// historyAt shows history of the file that the l-th line of the diff belongs to.
+// The cursor stays on the diff's commit, as it changed the file.
+func historyAt(a *DiffArea, l int) error {
+	path := ""
+	if d, ok := diffLineAt(a.Text, l); ok {
+		path = d.NewPath
+		if path == "" {
+			// the file is deleted.
+			path = d.OldPath
+		}
+	} else if h := fileHeaderAt(a.Text, l); h != -1 {
+		path = fileName(a.Text[h])
+	}
+	if path == "" {
+		return fmt.Errorf("not in a file of the diff")
+	}
+	if err := cmdHistory([]string{path}); err != nil {
+		return err
+	}
+	dig.Message = "history of " + path
+	return nil
+}
+
+// simplifications are history simplifications of git log, in the order of cycling.
+// Empty is git's default.
+var simplifications = []string{"", "full-history", "sparse", "simplify-by-decoration"}
+
+// cmdSimplify sets how history is simplified, mostly for file history.
+// Without an argument, it cycles the simplifications.
+//
+//	simplify [default|full-history|sparse|simplify-by-decoration]
+func cmdSimplify(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: simplify [default|full-history|sparse|simplify-by-decoration]")
+	}
+	prev := dig.Simplify
+	if len(args) == 0 {
+		for i, s := range simplifications {
+			if s == dig.Simplify {
+				dig.Simplify = simplifications[(i+1)%len(simplifications)]
+				break
+			}
+		}
+	} else {
+		s := strings.TrimPrefix(args[0], "--")
+		if s == "default" {
+			s = ""
+		}
+		found := false
+		for _, v := range simplifications {
+			if v == s {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown simplification: %s", args[0])
+		}
+		dig.Simplify = s
+	}
+	if err := reloadCommits(); err != nil {
+		dig.Simplify = prev
+		return err
+	}
+	dig.CurView = CommitView
+	if dig.Simplify == "" {
+		dig.Message = "default history simplification"
+	}
+	return nil
+}
+
+// cmdGoto moves the cursor to a commit.
+// The commit could be any revision git understands, including abbreviated hashes.
+func cmdGoto(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: goto <revision>")
+	}
+	hash, err := resolveHash(args[0])
+	if err != nil {
+		return err
+	}
+	i := findByHash(dig.Commits, hash, 0)
+	if i == -1 {
+		return fmt.Errorf("commit not in the list: %s", shortHash(hash))
+	}
+	screen.Commit.SetCursor(i)
+	return nil
+}
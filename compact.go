@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"unicode/utf8"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// narrowWidth is width of a terminal, under which dig uses the compact layout automatically.
+// It's for narrow terminals like ssh clients of phones.
+const narrowWidth = 60
+
+// minTitleWidth is width of titles, the commit list keeps by dropping it's columns.
+const minTitleWidth = 20
+
+// Compact reports whether the screen uses the compact layout.
+// It hides the side, and diffs have shorter headers and wrapped lines.
+func (s *Screen) Compact() bool {
+	switch config.Compact {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return s.size.O < narrowWidth
+}
+
+// Side returns width of the side in use, the compact layout hides it.
+// SideWidth is kept for a wider terminal.
+func (s *Screen) Side() int {
+	if s.Compact() {
+		return 0
+	}
+	return s.SideWidth
+}
+
+// Columns returns extra columns those fit in the commit list.
+// The last ones are dropped first, to keep titles readable.
+func (a *CommitArea) Columns() []string {
+	cols := config.Columns
+	for len(cols) != 0 && a.Bound.Size.O-dig.GraphWidth()-columnsWidth(cols) < minTitleWidth {
+		cols = cols[:len(cols)-1]
+	}
+	return cols
+}
+
+// ShowHash reports whether the commit list shows hashes.
+// The compact layout hides them, when titles would be too short.
+func (a *CommitArea) ShowHash() bool {
+	if !config.ShowHash {
+		return false
+	}
+	return !screen.Compact() || a.Bound.Size.O-dig.GraphWidth()-columnsWidth(a.Columns())-8 >= minTitleWidth*2
+}
+
+// hunkHeaderRe finds the new start line and the context of a hunk header.
+var hunkHeaderRe = regexp.MustCompile(`^@@+ [^@]* \+(\d+)(,\d+)? @@+ ?(.*)$`)
+
+// compactLine returns the line shortened for the compact layout.
+// It returns nil for a line that isn't shown.
+// inHeader tells the line is in a file header, before it's first hunk.
+func compactLine(ln []byte, inHeader bool) []byte {
+	switch {
+	case isFileHeader(ln):
+		return []byte("» " + fileName(ln))
+	case inHeader && (bytes.HasPrefix(ln, []byte("index ")) || bytes.HasPrefix(ln, []byte("--- ")) || bytes.HasPrefix(ln, []byte("+++ "))):
+		// the file name is in the file header already.
+		return nil
+	case bytes.HasPrefix(ln, []byte("@@")):
+		if m := hunkHeaderRe.FindSubmatch(ln); m != nil {
+			return []byte("@" + string(m[1]) + " " + string(m[3]))
+		}
+	case bytes.HasPrefix(ln, []byte("commit ")):
+		// the hash could be of SHA-1 or SHA-256, refs could follow it.
+		hash, rest := ln[7:], []byte{}
+		if i := bytes.IndexByte(hash, ' '); i != -1 {
+			hash, rest = hash[:i], hash[i:]
+		}
+		if isFullHash(string(hash)) {
+			return []byte("commit " + shortHash(string(hash)) + string(rest))
+		}
+	case bytes.HasPrefix(ln, []byte("Author: ")):
+		if i := bytes.IndexByte(ln, '<'); i != -1 {
+			return bytes.TrimSpace(ln[:i])
+		}
+	case bytes.HasPrefix(ln, []byte("Date:")):
+		return append([]byte("Date: "), bytes.TrimSpace(ln[5:])...)
+	case bytes.HasPrefix(ln, []byte("    ")) && !inHeader:
+		// a line of the commit message, it's indent is just for git log.
+		return ln[4:]
+	}
+	return ln
+}
+
+// wrapLine splits the line to lines, those fit in the width.
+func wrapLine(ln []byte, width int) [][]byte {
+	if width <= 0 {
+		return [][]byte{ln}
+	}
+	var lines [][]byte
+	start, w := 0, 0
+	for i := 0; i < len(ln); {
+		r, size := utf8.DecodeRune(ln[i:])
+		rw := runewidth.RuneWidth(printable(r))
+		if w+rw > width && i > start {
+			lines = append(lines, ln[start:i])
+			start, w = i, 0
+		}
+		w += rw
+		i += size
+	}
+	return append(lines, ln[start:])
+}
+
+// compactLines returns lines of the diff for the compact layout, from the from-th line.
+// Each has it's index in the diff, and the rows it's wrapped to.
+// It stops after rows rows.
+func (a *DiffArea) compactLines(from, rows int) (idx []int, wrapped [][][]byte) {
+	// a line before any file is in the commit message, or it's header.
+	inHeader := false
+	if h := fileHeaderAt(a.Text, from); h != -1 {
+		inHeader = true
+		for i := h + 1; i < from; i++ {
+			if bytes.HasPrefix(a.Text[i], []byte("@@")) {
+				inHeader = false
+				break
+			}
+		}
+	}
+	n := 0
+	for i := from; i < len(a.Text) && n < rows; i++ {
+		ln := a.Text[i]
+		if isFileHeader(ln) {
+			inHeader = true
+		} else if bytes.HasPrefix(ln, []byte("@@")) {
+			inHeader = false
+		}
+		c := compactLine(ln, inHeader)
+		if c == nil && !(a.Win.HasCursor && i == a.Win.Cursor) {
+			continue
+		}
+		if c == nil {
+			// the cursor is on it, show it as is.
+			c = ln
+		}
+		w := wrapLine(c, a.Bound.Size.O)
+		idx = append(idx, i)
+		wrapped = append(wrapped, w)
+		n += len(w)
+	}
+	return idx, wrapped
+}
+
+// drawCompact draws the diff for the compact layout.
+// Long lines are wrapped instead of being scrolled horizontally.
+func (a *DiffArea) drawCompact() {
+	rows := a.Bound.Size.L
+	if a.Win.HasCursor {
+		// wrapped lines take more rows, scroll down until the cursor line is shown.
+		for a.Win.Bound.Min.L < a.Win.Cursor {
+			idx, wrapped := a.compactLines(a.Win.Bound.Min.L, rows)
+			n := 0
+			shown := false
+			for k, i := range idx {
+				n += len(wrapped[k])
+				if i == a.Win.Cursor {
+					shown = n <= rows
+					break
+				}
+			}
+			if shown {
+				break
+			}
+			a.Win.Bound.Min.L++
+		}
+	}
+	word := isWordDiffKey(a.CommitHash)
+	idx, wrapped := a.compactLines(a.Win.Bound.Min.L, rows)
+	l := 0
+	for k, i := range idx {
+		c := diffLineColor(a.Text[i], word)
+		if bytes.HasPrefix(a.Text[i], []byte("@@")) || isFileHeader(a.Text[i]) {
+			c = theme.Header
+		}
+		if a.Win.HasCursor && i == a.Win.Cursor {
+			c.Bg = theme.Selected.Bg
+		}
+		for _, w := range wrapped[k] {
+			if l >= rows {
+				return
+			}
+			if c.Bg != theme.Normal.Bg {
+				fillColor(Rect{Min: Pt{a.Bound.Min.L + l, a.Bound.Min.O}, Size: Pt{1, a.Bound.Size.O}}, c)
+			}
+			drawLine(a.Bound, l, w, 0, c)
+			l++
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompactCommitLine(t *testing.T) {
+	dig = &Program{}
+	sha1 := strings.Repeat("a", 40)
+	sha256 := strings.Repeat("b", 64)
+	for _, tc := range []struct {
+		line, want string
+	}{
+		{"commit " + sha1, "commit aaaaaaaa"},
+		{"commit " + sha256, "commit bbbbbbbb"},
+		{"commit " + sha256 + " (HEAD -> master)", "commit bbbbbbbb (HEAD -> master)"},
+		{"commit message", "commit message"},
+	} {
+		if got := string(compactLine([]byte(tc.line), false)); got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.line, got, tc.want)
+		}
+	}
+}
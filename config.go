@@ -0,0 +1,568 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// config is user configuration of this program.
+var config = defaultConfig()
+
+// Config is user configuration read from ~/.config/dig/config.
+//
+// The file consists of `key = value` lines.
+// Empty lines and lines starting with # are ignored.
+// A value could be quoted with double quotes to keep it's spaces.
+// Lines after a `[name]` line belong to the profile, see readConfig.
+type Config struct {
+	// Profile is name of the profile in use, or "" when dig runs without a profile.
+	Profile string
+	// values are raw values those are set by the file, to compare profiles.
+	values map[string]string
+
+	// ExitSummary prints the selected commit to the primary screen on exit.
+	ExitSummary bool
+
+	// ExitTemplate is printed to stdout on exit, after it's placeholders
+	// are replaced with the selected commit's. See expandTemplate.
+	ExitTemplate string
+
+	// ExitCommand is a shell command run on exit, with placeholders replaced.
+	ExitCommand string
+
+	// OnStartup is dig commands run on start, separated by semicolons.
+	OnStartup string
+
+	// OnCommitSelect is a shell command run in background when a commit is selected,
+	// with placeholders replaced.
+	OnCommitSelect string
+
+	// Run is a shell command run in a snapshot of the selected commit, like "go test ./...".
+	Run string
+
+	// ShowHash shows abbreviated hashes in the commit list.
+	ShowHash bool
+
+	// CursorLine shows a cursor line in diffs, that actions like blame work on.
+	// Otherwise diffs are scrolled by lines.
+	CursorLine bool
+
+	// Editor is a shell command to open a file at a line,
+	// with {file} and {line} placeholders. See editorCommand.
+	Editor string
+	// DiffTools are tools of git difftool, those open diffs of commits in GUIs. See openDifftool.
+	DiffTools []string
+
+	// Encoding is encoding of file contents those aren't UTF-8.
+	// "auto" uses i18n.commitEncoding of the repository, or detects it.
+	// "utf-8" leaves them as they are.
+	Encoding string
+
+	// Preview shows a short diff of the selected commit beside the commit list,
+	// after the cursor stays on the commit for PreviewDelay.
+	Preview      bool
+	PreviewDelay time.Duration
+
+	// Details shows the hash, refs, the author, the committer and the full message
+	// of the selected commit below the commit list.
+	Details bool
+
+	// LFSDiff diffs real contents of text files in Git LFS, instead of their pointers.
+	// It may fetch the objects.
+	LFSDiff bool
+
+	// Mouse enables the mouse, to click commits, to scroll with the wheel and to drag the side divider. See handleMouse.
+	Mouse bool
+
+	// Theme is name of color theme. "auto" chooses by the terminal's background.
+	Theme string
+	// GitColors uses color.diff.* of git config for diffs, over the theme. See applyGitColors.
+	GitColors bool
+
+	// Ellipsis marks long titles those are cut with an ellipsis.
+	// Otherwise they are just clipped at the edge.
+	Ellipsis bool
+
+	// AgeTint tints commit rows by their age, recent commits are brighter.
+	AgeTint bool
+	// AgeBuckets are ascending ages those divide tint levels.
+	AgeBuckets []time.Duration
+
+	// SendPane is a tmux pane that Y sends a hash, a file or a hunk to, like "{right}" or "%3".
+	SendPane string
+	// SendCommand is a shell command that gets the sent text, instead of tmux.
+	SendCommand string
+
+	// Hyperlinks links hashes, files and issue IDs, in terminals those support OSC 8.
+	// It's one of auto, true and false.
+	Hyperlinks string
+	// IssueURL is a URL of issues like JIRA-123, with {id}. Issues like #123 link to the origin.
+	IssueURL string
+
+	// Confirm asks before actions those change the repository, like cherry-pick.
+	Confirm bool
+
+	// LaneColors colors commit titles by their lanes, so lines of development could be followed.
+	LaneColors bool
+	// LanePalette overrides lane colors of the theme.
+	LanePalette []Attribute
+	// Graph draws the graph of commits at the left of the commit list, colored by lanes.
+	Graph bool
+
+	// UpdateCheck checks a newer release of dig on start.
+	UpdateCheck bool
+
+	// TestPatterns are path patterns of test files. See matchPath.
+	TestPatterns []string
+	// TestBadge marks commits those touched tests.
+	TestBadge bool
+
+	// Syntax highlights code in diffs by languages of their files.
+	Syntax bool
+	// EmptyMerge is how a merge is shown when it's combined diff is empty. See emptyMerges.
+	EmptyMerge string
+
+	// DiffDrivers are git diff drivers of files by patterns, for hunk headers of their languages.
+	// .gitattributes of the repository overrides them. See diffDriverArgs.
+	DiffDrivers []diffDriver
+	// DiffFuncnames are regexps of custom diff drivers, those find the function of a hunk.
+	DiffFuncnames map[string]string
+
+	// CollapseGenerated collapses diffs of generated files to a summary line.
+	// Files are generated when they have linguist-generated attribute, or match GeneratedPatterns.
+	CollapseGenerated bool
+	GeneratedPatterns []string
+
+	// SideWidth fixes width of the side, instead of the remembered one. -1 means not fixed.
+	SideWidth int
+	// TitleBar draws a line above the commit list, with the repository, the branch and the number of commits.
+	TitleBar bool
+	// StatusLines is height of the status bar. A taller one draws it's text bold at the middle.
+	StatusLines int
+	// WideStatus draws the status text with full width characters, so it's larger.
+	WideStatus bool
+	// Simple shows what is selected in the status bar, instead of key hints.
+	Simple bool
+	// Keymap maps pressed keys to keys of dig, like 'n' to 'k'.
+	Keymap map[rune]rune
+
+	// LogArgs are arguments of git log for the commit list, with {format} and {targets} placeholders.
+	// See logArgs.
+	LogArgs []string
+	// LogFields are extra fields of git log, those could be shown as columns.
+	LogFields []logField
+
+	// Columns are extra columns of the commit list, like stat or describe. See columns.
+	Columns []string
+	// MetaWorkers is how many git commands run at once to load the columns.
+	MetaWorkers int
+
+	// Compact uses the compact layout for narrow terminals, one of auto, true and false.
+	// auto uses it when the terminal is narrower than narrowWidth.
+	Compact string
+
+	// PaneBreakpoints are widths of a terminal, from those the commit list, the file list
+	// and the diff are shown side by side. See parseBreakpoints.
+	PaneBreakpoints []int
+
+	// StatusSegments are parts of the status bar drawn at the right side of it, in order. See segments.
+	StatusSegments []segment
+	// StatusCommand is a shell command, the first line of it's output is the command segment.
+	StatusCommand string
+	// StatusInterval is how often the branch and command segments are reloaded.
+	StatusInterval time.Duration
+
+	// Backend reads repositories, one of auto, git and go-git. See newBackend.
+	Backend string
+	// GitTimeout kills a git command that reads the repository after it, 0 doesn't. See gitCommand.
+	GitTimeout time.Duration
+	// GitNice is niceness of git commands, those run with lower priority by it.
+	GitNice int
+	// DiffLimit cuts the diff of a commit at the lines, 0 doesn't. See cutDiff.
+	DiffLimit int
+
+	// Watch are pathspecs to watch in commits those come while dig is running. See startWatch.
+	Watch []string
+	// WatchInterval is how often the revisions are checked for new commits.
+	WatchInterval time.Duration
+	// WatchFetch fetches all remotes before each check.
+	WatchFetch bool
+	// WatchBell rings the terminal's bell when new commits touched the watched paths.
+	WatchBell bool
+
+	// Control listens on a control socket, so other programs could drive dig. See listenControl.
+	Control bool
+}
+
+// defaultConfig returns a config those are used when not configured.
+func defaultConfig() *Config {
+	return &Config{
+		Theme:          "auto",
+		Encoding:       "auto",
+		PreviewDelay:   150 * time.Millisecond,
+		Ellipsis:       true,
+		Confirm:        true,
+		Hyperlinks:     "auto",
+		CursorLine:     true,
+		SideWidth:      -1,
+		StatusLines:    1,
+		TitleBar:       true,
+		MetaWorkers:    4,
+		Compact:        "auto",
+		StatusInterval: 10 * time.Second,
+		Backend:        "auto",
+		GitTimeout:     time.Minute,
+		DiffLimit:      100000,
+		WatchInterval:  time.Minute,
+		AgeBuckets: []time.Duration{
+			7 * day,
+			30 * day,
+			365 * day,
+		},
+		TestPatterns:      []string{"*_test.go", "test/", "tests/", "*.test.js", "*.spec.js", "*_spec.rb", "test_*.py"},
+		CollapseGenerated: true,
+		Syntax:            true,
+		EmptyMerge:        "first-parent",
+		DiffDrivers:       defaultDiffDrivers(),
+		GeneratedPatterns: []string{"package-lock.json", "yarn.lock", "pnpm-lock.yaml", "go.sum", "Cargo.lock", "Gemfile.lock", "poetry.lock", "composer.lock", "*.pb.go", "*_pb2.py", "*.min.js"},
+	}
+}
+
+// configFile returns path of a config file inside of dig's config directory.
+// It's ~/.config/dig, or DIG_CONFIG_DIR when it's set, like for tests.
+func configFile(name string) (string, error) {
+	if dir := os.Getenv("DIG_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, name), nil
+	}
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(u.HomeDir, ".config", "dig", name), nil
+}
+
+// readConfig reads the user config file, with the profile's settings when it's not "".
+// Settings before any `[name]` line are for all profiles,
+// and a profile's settings override them.
+// When the file doesn't exist, it returns the default config.
+func readConfig(profile string) (*Config, error) {
+	c := defaultConfig()
+	sections, err := readConfigSections()
+	if err != nil {
+		return c, err
+	}
+	if err := c.apply(sections[""]); err != nil {
+		return c, err
+	}
+	if profile == "" || profile == defaultProfile {
+		return c, nil
+	}
+	c.Profile = profile
+	lines, ok := sections[profile]
+	builtin, isBuiltin := builtinProfiles[profile]
+	if !ok && !isBuiltin {
+		return c, fmt.Errorf("unknown profile: %s", profile)
+	}
+	if err := c.apply(builtin); err != nil {
+		return c, err
+	}
+	return c, c.apply(lines)
+}
+
+// configLine is a `key = value` line of the config file.
+type configLine struct {
+	// Where is where the line is, like "config:12".
+	Where string
+	Key   string
+	Value string
+}
+
+// readConfigSections reads lines of the config file by their profiles.
+// Lines before any `[name]` line are in the "" section.
+func readConfigSections() (map[string][]configLine, error) {
+	sections := make(map[string][]configLine)
+	conf, err := configFile("config")
+	if err != nil {
+		return sections, err
+	}
+	content, err := ioutil.ReadFile(conf)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sections, nil
+		}
+		return sections, err
+	}
+	section := ""
+	for i, ln := range strings.Split(string(content), "\n") {
+		ln = strings.TrimSpace(ln)
+		if ln == "" || strings.HasPrefix(ln, "#") {
+			continue
+		}
+		if strings.HasPrefix(ln, "[") && strings.HasSuffix(ln, "]") {
+			section = strings.TrimSpace(ln[1 : len(ln)-1])
+			if section == "" || section == defaultProfile {
+				return sections, fmt.Errorf("%s:%d: invalid profile name: %q", conf, i+1, section)
+			}
+			if _, ok := sections[section]; !ok {
+				// a profile could be empty, it's still a profile.
+				sections[section] = []configLine{}
+			}
+			continue
+		}
+		idx := strings.Index(ln, "=")
+		if idx == -1 {
+			return sections, fmt.Errorf("%s:%d: expected key = value", conf, i+1)
+		}
+		value := strings.TrimSpace(ln[idx+1:])
+		if uq, err := strconv.Unquote(value); err == nil {
+			value = uq
+		}
+		sections[section] = append(sections[section], configLine{
+			Where: fmt.Sprintf("%s:%d", conf, i+1),
+			Key:   strings.TrimSpace(ln[:idx]),
+			Value: value,
+		})
+	}
+	return sections, nil
+}
+
+// apply sets the lines to the config, in order.
+func (c *Config) apply(lines []configLine) error {
+	for _, ln := range lines {
+		if err := c.Set(ln.Key, ln.Value); err != nil {
+			return fmt.Errorf("%s: %v", ln.Where, err)
+		}
+	}
+	return nil
+}
+
+// Set sets a config value by it's key.
+func (c *Config) Set(key, value string) error {
+	var err error
+	switch key {
+	case "exit_summary":
+		c.ExitSummary, err = strconv.ParseBool(value)
+	case "exit_template":
+		c.ExitTemplate = value
+	case "exit_command":
+		c.ExitCommand = value
+	case "on_startup":
+		c.OnStartup = value
+	case "on_commit_select":
+		c.OnCommitSelect = value
+	case "run":
+		c.Run = value
+	case "show_hash":
+		c.ShowHash, err = strconv.ParseBool(value)
+	case "ellipsis":
+		c.Ellipsis, err = strconv.ParseBool(value)
+	case "cursor_line":
+		c.CursorLine, err = strconv.ParseBool(value)
+	case "editor":
+		c.Editor = value
+	case "difftools":
+		c.DiffTools = parseList(value)
+	case "encoding":
+		c.Encoding = value
+	case "preview":
+		c.Preview, err = strconv.ParseBool(value)
+	case "preview_delay":
+		c.PreviewDelay, err = time.ParseDuration(value)
+	case "details":
+		c.Details, err = strconv.ParseBool(value)
+	case "lfs_diff":
+		c.LFSDiff, err = strconv.ParseBool(value)
+	case "mouse":
+		c.Mouse, err = strconv.ParseBool(value)
+	case "theme":
+		c.Theme = value
+	case "git_colors":
+		c.GitColors, err = strconv.ParseBool(value)
+	case "age_tint":
+		c.AgeTint, err = strconv.ParseBool(value)
+	case "age_buckets":
+		c.AgeBuckets, err = parseAges(value)
+	case "send_pane":
+		c.SendPane = value
+	case "send_command":
+		c.SendCommand = value
+	case "hyperlinks":
+		c.Hyperlinks = value
+	case "issue_url":
+		c.IssueURL = value
+	case "confirm":
+		c.Confirm, err = strconv.ParseBool(value)
+	case "lane_colors":
+		c.LaneColors, err = strconv.ParseBool(value)
+	case "lane_palette":
+		c.LanePalette, err = parseColors(value)
+	case "graph":
+		c.Graph, err = strconv.ParseBool(value)
+	case "update_check":
+		c.UpdateCheck, err = strconv.ParseBool(value)
+	case "test_patterns":
+		c.TestPatterns = parseList(value)
+	case "test_badge":
+		c.TestBadge, err = strconv.ParseBool(value)
+	case "collapse_generated":
+		c.CollapseGenerated, err = strconv.ParseBool(value)
+	case "generated_patterns":
+		c.GeneratedPatterns = parseList(value)
+	case "empty_merge":
+		if _, ok := emptyMergeNotes[value]; !ok {
+			err = fmt.Errorf("not one of first-parent, commits and combined")
+		}
+		c.EmptyMerge = value
+	case "diff_drivers":
+		c.DiffDrivers, err = parseDiffDrivers(value)
+	case "diff_funcname":
+		err = c.setDiffFuncname(value)
+	case "side_width":
+		c.SideWidth, err = strconv.Atoi(value)
+	case "title_bar":
+		c.TitleBar, err = strconv.ParseBool(value)
+	case "status_lines":
+		c.StatusLines, err = strconv.Atoi(value)
+		if err == nil && (c.StatusLines < 1 || c.StatusLines > 5) {
+			err = fmt.Errorf("out of range")
+		}
+	case "wide_status":
+		c.WideStatus, err = strconv.ParseBool(value)
+	case "simple":
+		c.Simple, err = strconv.ParseBool(value)
+	case "keymap":
+		c.Keymap, err = parseKeymap(value)
+	case "syntax":
+		c.Syntax, err = strconv.ParseBool(value)
+	case "log_args":
+		c.LogArgs = strings.Fields(value)
+	case "log_fields":
+		c.LogFields, err = parseLogFields(value)
+	case "columns":
+		c.Columns, err = parseColumns(value, c.LogFields)
+	case "compact":
+		if value != "auto" && value != "true" && value != "false" {
+			err = fmt.Errorf("not one of auto, true and false")
+		}
+		c.Compact = value
+	case "panes":
+		c.PaneBreakpoints, err = parseBreakpoints(value)
+	case "status_segments":
+		c.StatusSegments, err = parseSegments(value)
+	case "status_command":
+		c.StatusCommand = value
+	case "status_interval":
+		c.StatusInterval, err = time.ParseDuration(value)
+		if err == nil && c.StatusInterval < time.Second {
+			err = fmt.Errorf("out of range")
+		}
+	case "backend":
+		if value != "auto" && value != "git" && value != "go-git" {
+			err = fmt.Errorf("not one of auto, git and go-git")
+		}
+		c.Backend = value
+	case "git_timeout":
+		c.GitTimeout, err = time.ParseDuration(value)
+		if err == nil && c.GitTimeout < 0 {
+			err = fmt.Errorf("out of range")
+		}
+	case "diff_limit":
+		c.DiffLimit, err = strconv.Atoi(value)
+		if err == nil && c.DiffLimit < 0 {
+			err = fmt.Errorf("out of range")
+		}
+	case "watch":
+		c.Watch = parseList(value)
+	case "watch_interval":
+		c.WatchInterval, err = time.ParseDuration(value)
+		if err == nil && c.WatchInterval < time.Second {
+			err = fmt.Errorf("out of range")
+		}
+	case "watch_fetch":
+		c.WatchFetch, err = strconv.ParseBool(value)
+	case "watch_bell":
+		c.WatchBell, err = strconv.ParseBool(value)
+	case "git_nice":
+		c.GitNice, err = strconv.Atoi(value)
+		if err == nil && (c.GitNice < 0 || c.GitNice > 19) {
+			err = fmt.Errorf("out of range")
+		}
+	case "control":
+		c.Control, err = strconv.ParseBool(value)
+	case "metadata_workers":
+		c.MetaWorkers, err = strconv.Atoi(value)
+		if err == nil && c.MetaWorkers < 1 {
+			err = fmt.Errorf("out of range")
+		}
+	default:
+		return fmt.Errorf("unknown config: %s", key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %v", key, value)
+	}
+	if c.values == nil {
+		c.values = make(map[string]string)
+	}
+	c.values[key] = value
+	return nil
+}
+
+// parseList parses values separated by commas, like "*_test.go, tests/".
+func parseList(s string) []string {
+	l := []string{}
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f != "" {
+			l = append(l, f)
+		}
+	}
+	return l
+}
+
+// day is a day in time.Duration.
+const day = 24 * time.Hour
+
+// parseAges parses ascending ages separated by commas, like "7d, 30d, 1y".
+func parseAges(s string) ([]time.Duration, error) {
+	ages := []time.Duration{}
+	for _, f := range strings.Split(s, ",") {
+		a, err := parseAge(strings.TrimSpace(f))
+		if err != nil {
+			return nil, err
+		}
+		if len(ages) != 0 && a <= ages[len(ages)-1] {
+			return nil, fmt.Errorf("ages should be ascending")
+		}
+		ages = append(ages, a)
+	}
+	return ages, nil
+}
+
+// parseAge parses an age like "12h", "7d", "2w", "3m" or "1y".
+func parseAge(s string) (time.Duration, error) {
+	units := map[byte]time.Duration{
+		'h': time.Hour,
+		'd': day,
+		'w': 7 * day,
+		'm': 30 * day,
+		'y': 365 * day,
+	}
+	if s == "" {
+		return 0, fmt.Errorf("empty age")
+	}
+	unit, ok := units[s[len(s)-1]]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit of age: %s", s)
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid age: %s", s)
+	}
+	return time.Duration(n) * unit, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// confirmAll is set when the user answered yes to all confirmations in this session.
+var confirmAll bool
+
+// ConfirmPopup asks the user before an action that changes the repository.
+type ConfirmPopup struct {
+	Question string
+	// Warnings are what the user should know before answering, like uncommitted changes.
+	Warnings []string
+	// Action runs after the user said yes.
+	Action func() error
+}
+
+// confirm runs the action after the user confirmed it.
+// It runs the action right away when confirmations are turned off by config.Confirm,
+// or the user answered yes to all of them in this session.
+// Otherwise it only opens a popup, so errors of the action are shown as a message later.
+func confirm(question string, warnings []string, action func() error) error {
+	if !config.Confirm || confirmAll {
+		return action()
+	}
+	screen.Popup = &ConfirmPopup{Question: question, Warnings: warnings, Action: action}
+	return nil
+}
+
+// workTreeWarnings returns warnings about the work tree, for actions those change it.
+func workTreeWarnings() []string {
+	var warnings []string
+	out, err := gitOutput("status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return []string{"could not check the work tree: " + err.Error()}
+	}
+	if len(out) != 0 {
+		n := len(strings.Split(strings.TrimRight(string(out), "\n"), "\n"))
+		warnings = append(warnings, "the work tree has uncommitted changes in "+pluralize(n, "file"))
+	}
+	if _, err := gitOutput("symbolic-ref", "-q", "HEAD"); err != nil {
+		warnings = append(warnings, "HEAD is detached, new commits will not be on a branch")
+	}
+	return warnings
+}
+
+// pluralize returns the count with the noun, like "1 file" or "3 files".
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
+
+// Handle handles a terminal event, while the popup is opened.
+func (p *ConfirmPopup) Handle(ev Event) {
+	switch {
+	case ev.Ch == 'y' || ev.Ch == 'a':
+		if ev.Ch == 'a' {
+			confirmAll = true
+		}
+		screen.Popup = nil
+		if err := p.Action(); err != nil {
+			dig.Message = err.Error()
+		}
+	case ev.Ch == 'n' || ev.Ch == 'q' || ev.Key == KeyEsc:
+		screen.Popup = nil
+		dig.Message = "canceled"
+	}
+}
+
+// Draw draws the popup.
+func (p *ConfirmPopup) Draw() {
+	lines := []string{p.Question}
+	for _, w := range p.Warnings {
+		lines = append(lines, "! "+w)
+	}
+	lines = append(lines, "", "y: yes, n: no, a: yes to all in this session")
+	width := 0
+	for _, ln := range lines {
+		if w := runewidth.StringWidth(ln); w > width {
+			width = w
+		}
+	}
+	bound := drawPopup(popupBound(Pt{len(lines), width + 2}), "confirm")
+	for l, ln := range lines {
+		c := theme.Popup
+		if strings.HasPrefix(ln, "! ") {
+			c.Fg = theme.Error.Fg
+		}
+		drawLine(Rect{Min: Pt{bound.Min.L, bound.Min.O + 1}, Size: Pt{bound.Size.L, bound.Size.O - 1}}, l, []byte(ln), 0, c)
+	}
+}
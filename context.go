@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// hunkContext returns where the context of a hunk header starts, like "func main() {" of
+// "@@ -1,5 +1,6 @@ func main() {". git finds it with xfuncname of the file's diff driver.
+// It returns false when the line isn't a hunk header, or it doesn't have a context.
+func hunkContext(ln []byte) (int, bool) {
+	if !bytes.HasPrefix(ln, []byte("@@")) {
+		return 0, false
+	}
+	m := hunkHeaderRe.FindSubmatchIndex(ln)
+	if m == nil || m[6] == -1 || m[6] == m[7] {
+		return 0, false
+	}
+	return m[6], true
+}
+
+// hunkContextAt returns the context of the hunk that the l-th line of the diff belongs to,
+// or nil if it's not in a hunk, or the hunk doesn't have one.
+func hunkContextAt(text [][]byte, l int) []byte {
+	for ; l >= 0 && l < len(text); l-- {
+		ln := text[l]
+		if isFileHeader(ln) {
+			return nil
+		}
+		if bytes.HasPrefix(ln, []byte("@@")) {
+			if start, ok := hunkContext(ln); ok {
+				return ln[start:]
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// drawHunkContext draws the context of the hunk header over the l-th line, with theme.Func.
+func drawHunkContext(bound Rect, l int, ln []byte, shift int, c Color) {
+	start, ok := hunkContext(ln)
+	if !ok {
+		return
+	}
+	o := runewidth.StringWidth(string(ln[:start]))
+	drawLine(bound, l, ln[start:], shift-o, Color{theme.Func, c.Bg})
+}
+
+// NextHunkInContext moves the window to n-th next hunk of the same context, like the same function.
+// Hunks of the other files are not looked, as the same function name could be in them.
+// A negative n moves to previous ones.
+func (a *DiffArea) NextHunkInContext(n int) error {
+	line := a.Win.Line()
+	ctx := hunkContextAt(a.Text, line)
+	if ctx == nil {
+		return fmt.Errorf("not in a hunk with a function")
+	}
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	// the hunk of the line isn't another one.
+	cur := hunkHeaderAt(a.Text, line)
+	found := -1
+	for l := line + step; l >= 0 && l < len(a.Text) && n > 0; l += step {
+		ln := a.Text[l]
+		if isFileHeader(ln) {
+			break
+		}
+		if start, ok := hunkContext(ln); ok && bytes.Equal(ln[start:], ctx) && l != cur {
+			found = l
+			n--
+		}
+	}
+	if found == -1 {
+		return fmt.Errorf("no other hunk in %s", ctx)
+	}
+	a.Win.Goto(found)
+	return nil
+}
+
+// hunkHeaderAt returns index of the hunk header that the l-th line belongs to, or -1.
+func hunkHeaderAt(text [][]byte, l int) int {
+	for ; l >= 0 && l < len(text); l-- {
+		if isFileHeader(text[l]) {
+			return -1
+		}
+		if bytes.HasPrefix(text[l], []byte("@@")) {
+			return l
+		}
+	}
+	return -1
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// control is the listener of the control socket, nil when it's not listening.
+var control net.Listener
+
+// controlWrites are lines to clients those aren't written yet.
+// They're waited before exit, so a client that quits dig gets it's reply.
+var controlWrites sync.WaitGroup
+
+// controlCommands are commands those only make sense for other programs,
+// they're tried before commands. A command returns what it writes back.
+var controlCommands = map[string]func(args []string) (string, error){
+	"select":       ctlSelect,
+	"selected":     ctlSelected,
+	"export-patch": ctlExportPatch,
+	"quit":         ctlQuit,
+}
+
+// controlSocket returns path of the control socket of this process.
+// It's in ~/.cache/dig, named by the pid.
+func controlSocket() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(u.HomeDir, ".cache", "dig", fmt.Sprintf("%d.sock", os.Getpid())), nil
+}
+
+// listenControl listens on the control socket, and sends each line of a connection to the app.
+// Commands run by the app's loop one by one, like they're typed. Each gets a line of reply,
+// "ok" with the command's output or "error: " with the error.
+// The path is set as DIG_SOCKET, so commands run by dig, like run and hooks, could find it.
+func listenControl(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	// a socket of a dead dig, that had the same pid or path.
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	control = l
+	os.Setenv("DIG_SOCKET", path)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				// closed.
+				return
+			}
+			go serveControl(conn)
+		}
+	}()
+	return nil
+}
+
+// closeControl stops listening, and removes the socket.
+// It waits a while for replies those aren't written yet.
+func closeControl() {
+	if control == nil {
+		return
+	}
+	control.Close()
+	os.Remove(control.Addr().String())
+	control = nil
+	done := make(chan struct{})
+	go func() {
+		controlWrites.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+	}
+}
+
+// controlConn is a connection to the control socket.
+type controlConn struct {
+	conn net.Conn
+	// out are lines to write to the client, in order.
+	out chan string
+	// Events are names of events the client subscribed, only touched by the app's loop.
+	Events map[string]bool
+}
+
+// write writes lines to the client, until out is closed.
+func (c *controlConn) write() {
+	defer c.conn.Close()
+	broken := false
+	for line := range c.out {
+		if !broken {
+			_, err := fmt.Fprintln(c.conn, line)
+			// keep draining, the app shouldn't be blocked by a broken client.
+			broken = err != nil
+		}
+		controlWrites.Done()
+	}
+}
+
+// Reply queues a reply to the client. The client is disconnected when it doesn't read replies,
+// as a dropped reply would mismatch the following ones, and the app shouldn't wait for a client.
+func (c *controlConn) Reply(line string) {
+	controlWrites.Add(1)
+	select {
+	case c.out <- line:
+	default:
+		controlWrites.Done()
+		// the reader stops, then the app is told it's closed.
+		c.conn.Close()
+	}
+}
+
+// Notify queues an event to the client. It's dropped when the client doesn't read them,
+// as the app shouldn't wait for a client.
+func (c *controlConn) Notify(line string) {
+	controlWrites.Add(1)
+	select {
+	case c.out <- line:
+	default:
+		controlWrites.Done()
+	}
+}
+
+// controlMsg is a command line from a connection to the control socket.
+type controlMsg struct {
+	Conn *controlConn
+	Line string
+}
+
+// controlClosedMsg is a connection to the control socket that is closed by the client.
+type controlClosedMsg struct {
+	Conn *controlConn
+}
+
+// serveControl sends commands of the connection to the app.
+// The app replies them in order, as they're sent in order.
+func serveControl(conn net.Conn) {
+	c := &controlConn{conn: conn, out: make(chan string, 64), Events: make(map[string]bool)}
+	go c.write()
+	sc := bufio.NewScanner(conn)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		send(controlMsg{Conn: c, Line: line})
+	}
+	send(controlClosedMsg{Conn: c})
+}
+
+// runControl runs a command line from the connection, and returns it's reply.
+func runControl(c *controlConn, line string) string {
+	args := strings.Fields(line)
+	var out string
+	var err error
+	dig.Message = ""
+	if fn, ok := controlCommands[args[0]]; ok {
+		out, err = fn(args[1:])
+	} else if fn, ok := rpcCommands[args[0]]; ok {
+		out, err = fn(c, args[1:])
+	} else {
+		err = runCommand(line)
+		// commands tell what they did with the message.
+		out = dig.Message
+	}
+	if err != nil {
+		return "error: " + strings.Replace(err.Error(), "\n", " ", -1)
+	}
+	if out == "" {
+		return "ok"
+	}
+	return "ok " + strings.Replace(out, "\n", " ", -1)
+}
+
+// ctlSelect selects the commit in the commit list, and prints it's hash.
+//
+//	select <revision>
+func ctlSelect(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("usage: select <revision>")
+	}
+	if err := cmdGoto(args); err != nil {
+		return "", err
+	}
+	dig.CurView = CommitView
+	return screen.Commit.Commit().Hash, nil
+}
+
+// ctlSelected prints hash and title of the selected commit.
+//
+//	selected
+func ctlSelected(args []string) (string, error) {
+	if len(args) != 0 {
+		return "", fmt.Errorf("usage: selected")
+	}
+	c := screen.Commit.Commit()
+	return c.Hash + " " + c.Title, nil
+}
+
+// ctlExportPatch exports the commits as patches into the directory, "dig-patches" if not given.
+// Without a revision, it exports the selected commit.
+//
+//	export-patch [-o dir] [revision...]
+func ctlExportPatch(args []string) (string, error) {
+	dir := "dig-patches"
+	if len(args) >= 2 && args[0] == "-o" {
+		dir = args[1]
+		args = args[2:]
+	}
+	var hashes []string
+	for _, rev := range args {
+		h, err := resolveHash(rev)
+		if err != nil {
+			return "", err
+		}
+		hashes = append(hashes, h)
+	}
+	if len(hashes) == 0 {
+		hashes = []string{screen.Commit.Commit().Hash}
+	}
+	if err := exportPatches(hashes, dir); err != nil {
+		return "", err
+	}
+	return dig.Message, nil
+}
+
+// ctlQuit quits dig, as q does.
+//
+//	quit
+func ctlQuit(args []string) (string, error) {
+	app.Quit = true
+	return "", nil
+}
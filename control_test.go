@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestControl(t *testing.T) {
+	dir, commits := testRepo(t, 4)
+	setupApp(t, dir, commits)
+	path := filepath.Join(t.TempDir(), "dig.sock")
+	if err := listenControl(path); err != nil {
+		t.Fatal(err)
+	}
+	defer closeControl()
+	patches := t.TempDir()
+	lines := []string{
+		"select HEAD~2",
+		"selected",
+		"bogus",
+		"goto nothing",
+		"export-patch -o " + patches + " HEAD HEAD~1",
+	}
+	// a client drives dig, while the test runs the app's loop.
+	replies := make(chan []string, 1)
+	go func() {
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			replies <- []string{err.Error()}
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		var got []string
+		for _, ln := range lines {
+			fmt.Fprintln(conn, ln)
+			reply, err := r.ReadString('\n')
+			if err != nil {
+				got = append(got, err.Error())
+				break
+			}
+			got = append(got, strings.TrimSpace(reply))
+		}
+		replies <- got
+	}()
+	var got []string
+	runUntil(t, func() bool {
+		select {
+		case got = <-replies:
+			return true
+		default:
+			return false
+		}
+	}, nil)
+	c := commits[2]
+	want := []string{
+		"ok " + c.Hash,
+		"ok " + c.Hash + " " + c.Title,
+		"error: unknown command: bogus",
+		"error: " + gotoError(t, "nothing"),
+		"ok exported 2 patches to " + patches,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("replies:\ngot  %q\nwant %q", got, want)
+	}
+	files, _ := os.ReadDir(patches)
+	if len(files) != 2 {
+		t.Errorf("exported %d patches, want 2", len(files))
+	}
+}
+
+// gotoError returns the error of goto with the revision.
+func gotoError(t *testing.T, rev string) string {
+	t.Helper()
+	err := cmdGoto([]string{rev})
+	if err == nil {
+		t.Fatalf("goto %s succeeded", rev)
+	}
+	return err.Error()
+}
+
+func TestRPC(t *testing.T) {
+	dir, commits := testRepo(t, 3)
+	setupApp(t, dir, commits)
+	path := filepath.Join(t.TempDir(), "dig.sock")
+	if err := listenControl(path); err != nil {
+		t.Fatal(err)
+	}
+	defer closeControl()
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	lines := make(chan string, 16)
+	go func() {
+		sc := bufio.NewScanner(conn)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+	}()
+	// expect runs the app's loop until the next line from dig.
+	expect := func(want string) {
+		t.Helper()
+		var got string
+		runUntil(t, func() bool {
+			select {
+			case got = <-lines:
+				return true
+			default:
+				return false
+			}
+		}, nil)
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+	c := commits[0]
+	fmt.Fprintln(conn, "subscribe")
+	expect("ok select diff")
+	notifySelected()
+	expect("event select " + c.Hash + " " + c.Title)
+	// the plugin opens the diff instead of dig.
+	enter := Event{Type: EventKey, Key: KeyEnter}
+	app.Update(enter)
+	expect("event diff " + c.Hash)
+	if dig.CurView != CommitView {
+		t.Error("dig opened the diff a plugin should open")
+	}
+	fmt.Fprintln(conn, "subscribe nothing")
+	expect("error: unknown event: nothing, it's one of select, diff")
+	fmt.Fprintln(conn, "unsubscribe diff")
+	expect("ok select")
+	app.Update(enter)
+	if dig.CurView != DiffView {
+		t.Error("dig didn't open the diff without a plugin")
+	}
+}
+
+func TestControlFullReplies(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	// nothing writes out, as the client doesn't read.
+	c := &controlConn{conn: server, out: make(chan string, 1)}
+	c.Reply("first")
+	c.Reply("second")
+	if got := <-c.out; got != "first" {
+		t.Errorf("queued reply: got %q", got)
+	}
+	controlWrites.Done()
+	if len(c.out) != 0 {
+		t.Errorf("replies are queued over the buffer: %d", len(c.out))
+	}
+	if _, err := client.Read(make([]byte, 1)); err == nil {
+		t.Error("the connection isn't closed when it's replies are full")
+	}
+}
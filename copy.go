@@ -0,0 +1,169 @@
+package main
+
+import (
+	"strings"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// CopyCursor is a cell cursor over the screen, for CopyMode.
+// It copies what is drawn, so it works in any view. A terminal's own selection doesn't,
+// as dig takes the whole screen and the mouse.
+type CopyCursor struct {
+	Pos Pt
+	// Anchor is where the selection started, when Selecting.
+	Anchor    Pt
+	Selecting bool
+	// Rect selects a rectangle between the anchor and the cursor, instead of lines.
+	Rect bool
+}
+
+// startCopy starts CopyMode, with the cursor at the top left of the screen.
+func startCopy() {
+	dig.Copy = &CopyCursor{}
+	dig.Mode = CopyMode
+}
+
+// handleCopy handles CopyMode events.
+func handleCopy(ev Event) {
+	c := dig.Copy
+	w, h := term.Size()
+	switch {
+	case ev.Key == KeyArrowUp || ev.Ch == 'i':
+		c.Pos.L--
+	case ev.Key == KeyArrowDown || ev.Ch == 'k':
+		c.Pos.L++
+	case ev.Key == KeyArrowLeft || ev.Ch == 'j':
+		c.Pos.O--
+	case ev.Key == KeyArrowRight || ev.Ch == 'l':
+		c.Pos.O++
+	case ev.Key == KeyPgup || ev.Ch == 'b':
+		c.Pos.L -= h / 2
+	case ev.Key == KeyPgdn || ev.Ch == 'f':
+		c.Pos.L += h / 2
+	case ev.Key == KeyHome || ev.Ch == '0':
+		c.Pos.O = 0
+	case ev.Key == KeyEnd || ev.Ch == '$':
+		c.Pos.O = lineEnd(c.Pos.L)
+	case ev.Ch == 'v' || ev.Key == KeySpace:
+		c.Selecting = !c.Selecting
+		c.Anchor = c.Pos
+	case ev.Ch == 'r':
+		c.Rect = !c.Rect
+	case ev.Ch == 'y' || ev.Key == KeyEnter:
+		text := c.Text()
+		if err := copyToClipboard(text); err != nil {
+			dig.Message = err.Error()
+		} else {
+			dig.Message = "copied " + pluralize(strings.Count(text, "\n")+1, "line")
+		}
+		dig.Copy = nil
+		dig.Mode = NormalMode
+		return
+	case ev.Key == KeyEsc || ev.Ch == 'q':
+		dig.Copy = nil
+		dig.Mode = NormalMode
+		return
+	}
+	if c.Pos.L < 0 {
+		c.Pos.L = 0
+	}
+	if c.Pos.L >= h {
+		c.Pos.L = h - 1
+	}
+	if c.Pos.O < 0 {
+		c.Pos.O = 0
+	}
+	if c.Pos.O >= w {
+		c.Pos.O = w - 1
+	}
+}
+
+// lineEnd returns the last cell of the l-th line of the screen, that isn't a space.
+func lineEnd(l int) int {
+	w, _ := term.Size()
+	for o := w - 1; o > 0; o-- {
+		if r := cellRune(o, l); r != ' ' && r != 0 {
+			return o
+		}
+	}
+	return 0
+}
+
+// Selected reports whether the cell is selected.
+func (c *CopyCursor) Selected(p Pt) bool {
+	if !c.Selecting {
+		return p == c.Pos
+	}
+	from, to := c.Anchor, c.Pos
+	if c.Rect {
+		minL, maxL := from.L, to.L
+		if minL > maxL {
+			minL, maxL = maxL, minL
+		}
+		minO, maxO := from.O, to.O
+		if minO > maxO {
+			minO, maxO = maxO, minO
+		}
+		return p.L >= minL && p.L <= maxL && p.O >= minO && p.O <= maxO
+	}
+	if to.L < from.L || to.L == from.L && to.O < from.O {
+		from, to = to, from
+	}
+	if p.L < from.L || p.L > to.L {
+		return false
+	}
+	if p.L == from.L && p.O < from.O {
+		return false
+	}
+	if p.L == to.L && p.O > to.O {
+		return false
+	}
+	return true
+}
+
+// Text returns the selected text of the screen, or the cell at the cursor when it's not selecting.
+// Trailing spaces of each line are trimmed.
+func (c *CopyCursor) Text() string {
+	w, h := term.Size()
+	var lines []string
+	for l := 0; l < h; l++ {
+		var ln []rune
+		in := false
+		for o := 0; o < w; o++ {
+			if !c.Selected(Pt{l, o}) {
+				continue
+			}
+			in = true
+			r := cellRune(o, l)
+			if r == 0 {
+				r = ' '
+			}
+			ln = append(ln, r)
+			if runewidth.RuneWidth(r) == 2 {
+				// the next cell is covered by the wide rune.
+				o++
+			}
+		}
+		if in {
+			lines = append(lines, strings.TrimRight(string(ln), " "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// drawCopy draws the cursor and the selection of CopyMode over the screen, by reversing their colors.
+func drawCopy() {
+	if dig.Mode != CopyMode {
+		return
+	}
+	w, h := term.Size()
+	for l := 0; l < h; l++ {
+		for o := 0; o < w; o++ {
+			if !dig.Copy.Selected(Pt{l, o}) {
+				continue
+			}
+			reverseCell(o, l)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// cutPrefix starts the line that is added to the end of a diff, those is cut before it's end.
+// A line of a diff doesn't start with it, as lines of commit messages are indented.
+const cutPrefix = "~ "
+
+// cutError is returned with a diff, those is cut before it's end.
+type cutError struct {
+	Why string
+}
+
+func (e *cutError) Error() string {
+	return e.Why
+}
+
+// fullDiffs are commits those diffs are loaded to the end, as the user asked. See loadRest.
+var fullDiffs = make(map[string]bool)
+
+// cutDiff runs the git command and returns it's output, cut at the limit of lines, 0 doesn't.
+// The output is also cut when git is killed by the timeout, then what git wrote until then is returned.
+// A cut output is returned with a *cutError, instead of looking like a complete diff.
+func cutDiff(cmd *exec.Cmd, limit int) ([]byte, error) {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	var out []byte
+	var cut error
+	r := bufio.NewReader(stdout)
+	for n := 0; ; n++ {
+		if limit != 0 && n == limit {
+			if _, err := r.Peek(1); err != io.EOF {
+				// the rest isn't needed, don't let git write it.
+				cut = &cutError{fmt.Sprintf("the diff is cut at %d lines by diff_limit", limit)}
+				killProcessGroup(cmd)
+			}
+			break
+		}
+		ln, err := r.ReadBytes('\n')
+		out = append(out, ln...)
+		if err != nil {
+			break
+		}
+	}
+	err = cmd.Wait()
+	if cut != nil {
+		return out, cut
+	}
+	if gitKilled(cmd) {
+		return out, &cutError{timeoutError("show").Error()}
+	}
+	if err != nil {
+		if stderr.Len() != 0 {
+			return nil, errors.New(strings.TrimSpace(stderr.String()))
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// cutLine returns the line added to the end of a cut diff, that tells why it's cut.
+func cutLine(e *cutError) []byte {
+	return []byte(cutPrefix + e.Why + ". E loads the rest")
+}
+
+// isCutLine reports whether the line is added to the end of a cut diff.
+func isCutLine(ln []byte) bool {
+	return bytes.HasPrefix(ln, []byte(cutPrefix))
+}
+
+// cutNote returns why the diff is cut, for the status bar. It returns "" when the diff isn't cut.
+func cutNote(d [][]byte) string {
+	if len(d) == 0 || !isCutLine(d[len(d)-1]) {
+		return ""
+	}
+	return strings.TrimPrefix(string(d[len(d)-1]), cutPrefix)
+}
+
+// loadRest loads the diff of the area to the end, when it's cut.
+// It isn't cut by diff_limit or killed by git_timeout again, so it could take a while.
+func (a *DiffArea) loadRest() error {
+	if cutNote(a.Full) == "" {
+		return fmt.Errorf("the diff isn't cut")
+	}
+	hash := a.CommitHash
+	fullDiffs[hash] = true
+	a.Cache.Remove(hash)
+	// keep the window where it is, Sync restores it.
+	a.WindowPoses[hash] = a.Win.Bound.Min
+	a.CommitHash = ""
+	a.Sync()
+	return nil
+}
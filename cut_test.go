@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCutDiff(t *testing.T) {
+	var lines []string
+	for i := 0; i < 30; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	r := buildRepo(t,
+		step{Files: map[string]string{"a.txt": strings.Join(lines, "\n") + "\n"}, Message: "add lines"},
+	)
+	setupApp(t, r.Dir, r.Commits(false, "HEAD"))
+	defer func() { fullDiffs = make(map[string]bool) }()
+	config.DiffLimit = 20
+	a := screen.Diff
+	a.Sync()
+	if len(a.Full) != 21 || !isCutLine(a.Full[20]) || !strings.Contains(a.Note, "diff_limit") {
+		t.Fatalf("cut diff: got %d lines, with note %q", len(a.Full), a.Note)
+	}
+	if err := a.loadRest(); err != nil {
+		t.Fatal(err)
+	}
+	if last := string(a.Full[len(a.Full)-1]); last != "+line 29" || a.Note != "" {
+		t.Errorf("rest of the diff: got the last line %q, with note %q", last, a.Note)
+	}
+	if a.loadRest() == nil {
+		t.Error("loaded the rest of a diff that isn't cut")
+	}
+	// a diff that has just the lines isn't cut.
+	n := len(a.Full)
+	a.Cache.Remove(a.CommitHash)
+	fullDiffs = make(map[string]bool)
+	config.DiffLimit = n
+	a.CommitHash = ""
+	a.Sync()
+	if len(a.Full) != n || a.Note != "" {
+		t.Errorf("diff of the limit: got %d lines, want %d, with note %q", len(a.Full), n, a.Note)
+	}
+}
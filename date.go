@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dateLayouts are layouts those can be used for the date command.
+var dateLayouts = []string{
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// parseDate parses a date in local time, with the first matching layout.
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date: %s (want one of %s)", s, strings.Join(dateLayouts, ", "))
+}
+
+// cmdDate moves the cursor to the first commit on or after the date.
+//
+//	date 2022-06
+func cmdDate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: date <yyyy[-mm[-dd[ hh:mm]]]>")
+	}
+	t, err := parseDate(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	if l := dig.Loader; l != nil && !dateLoaded(dig.All, t, dig.DigUp) {
+		// it goes there when the commits are loaded, see commitsLoaded.
+		l.Want = ""
+		l.WantDate = t
+		l.cursor = screen.Commit.Commit().Hash
+		dig.Message = "loading commits to " + t.Format("2006-01-02 15:04") + "..."
+		return nil
+	}
+	return gotoDate(t)
+}
+
+// gotoDate moves the cursor to the first commit on or after the time.
+func gotoDate(t time.Time) error {
+	i := commitAtTime(dig.Commits, t, dig.DigUp)
+	if i == -1 {
+		return fmt.Errorf("no commits on or after %s", t.Format("2006-01-02 15:04"))
+	}
+	screen.Commit.SetCursor(i)
+	return nil
+}
+
+// dateLoaded reports whether the first commit on or after the time is in the commits,
+// those are loaded in the order of commitAtTime. Later commits are still being loaded.
+func dateLoaded(commits []*Commit, t time.Time, up bool) bool {
+	if len(commits) == 0 {
+		return false
+	}
+	last := commits[len(commits)-1]
+	if up {
+		// a later commit is on or after it.
+		return !last.Time.Before(t)
+	}
+	// an older commit is before it.
+	return last.Time.Before(t)
+}
+
+// commitAtTime finds the first commit on or after the time, with binary search.
+// Commits should be ordered from the oldest if up is true, or from the latest.
+// It returns -1 if there isn't such a commit.
+//
+// Commit times are not strictly ordered in git history,
+// but it's good enough for finding a period.
+func commitAtTime(commits []*Commit, t time.Time, up bool) int {
+	n := len(commits)
+	if up {
+		i := sort.Search(n, func(i int) bool { return !commits[i].Time.Before(t) })
+		if i == n {
+			return -1
+		}
+		return i
+	}
+	// the last commit that is not before t.
+	return sort.Search(n, func(i int) bool { return commits[i].Time.Before(t) }) - 1
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// minDetailHeight is the minimum height of the details, with it's divider.
+// A shorter screen doesn't show them.
+const minDetailHeight = 4
+
+// DetailArea shows details of the selected commit below the commit list, when config.Details is on.
+// Those are the hash, refs, the author and the committer, and the full message.
+type DetailArea struct {
+	Bound Rect
+}
+
+// detailDate is the format of dates in the details.
+const detailDate = "2006-01-02 15:04 -0700"
+
+// detailLines returns lines of the details of the commit, with their colors.
+func detailLines(c *Commit) ([]string, []Color) {
+	var lines []string
+	var colors []Color
+	add := func(s string, c Color) {
+		lines = append(lines, s)
+		colors = append(colors, c)
+	}
+	head := c.Hash
+	if len(c.Refs) != 0 {
+		head += " (" + strings.Join(c.Refs, ", ") + ")"
+	}
+	add(head, Color{theme.Hash, theme.Normal.Bg})
+	add(fmt.Sprintf("Author:    %s <%s>, %s", c.Author, c.Email, c.AuthorTime.Format(detailDate)), theme.Normal)
+	if c.Committer != c.Author || c.CommitterEmail != c.Email || !c.Time.Equal(c.AuthorTime) {
+		// it's rebased, amended or applied by other person.
+		add(fmt.Sprintf("Committer: %s <%s>, %s", c.Committer, c.CommitterEmail, c.Time.Format(detailDate)), theme.Normal)
+	}
+	add("", theme.Normal)
+	add(c.Title, Color{theme.Normal.Fg | AttrBold, theme.Normal.Bg})
+	if c.Body != "" {
+		add("", theme.Normal)
+		for _, ln := range strings.Split(c.Body, "\n") {
+			add(ln, theme.Normal)
+		}
+	}
+	return lines, colors
+}
+
+// Draw draws details of the commit with a divider above.
+// The message is cut with … when it doesn't fit.
+func (a *DetailArea) Draw(c *Commit) {
+	if a.Bound.Size.L <= 0 {
+		return
+	}
+	n := theme.Normal
+	for o := 0; o < a.Bound.Size.O; o++ {
+		setCell(a.Bound.Min.O+o, a.Bound.Min.L-1, '─', n.Fg, n.Bg)
+	}
+	lines, colors := detailLines(c)
+	if len(lines) > a.Bound.Size.L {
+		lines, colors = lines[:a.Bound.Size.L], colors[:a.Bound.Size.L]
+		lines[len(lines)-1] = "…"
+	}
+	for l, ln := range lines {
+		drawLine(a.Bound, l, []byte(ln), 0, colors[l])
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommitDetails(t *testing.T) {
+	r := historyFixture(t)
+	r.apply(step{Files: map[string]string{"main.go": "package main\n"}, Message: "empty main\n\nIt's\tcleaner.\n\nSigned-off-by: Alice <alice@example.com>"})
+	r.git("tag", "v0.2")
+	commits := r.Commits(false, "HEAD")
+	c := commits[0]
+	if c.Title != "empty main" || c.Body != "It's    cleaner.\n\nSigned-off-by: Alice <alice@example.com>" {
+		t.Errorf("message: got %q and %q", c.Title, c.Body)
+	}
+	if got := strings.Join(c.Refs, ", "); got != "HEAD -> master, tag: v0.2" {
+		t.Errorf("refs: got %q", got)
+	}
+	if c.Committer != "Alice" || c.CommitterEmail != "alice@example.com" || !c.AuthorTime.Equal(c.Time) {
+		t.Errorf("committer: got %s <%s> at %v, authored at %v", c.Committer, c.CommitterEmail, c.Time, c.AuthorTime)
+	}
+	if hi := commits[3]; hi.Title != "say hi" || hi.Body != "" || len(hi.Refs) != 1 || hi.Refs[0] != "feature" {
+		t.Errorf("%q: got body %q and refs %q", hi.Title, hi.Body, hi.Refs)
+	}
+	setupApp(t, r.Dir, commits)
+	lines, _ := detailLines(commits[3])
+	if len(lines) != 5 || !strings.HasPrefix(lines[1], "Author:    Bob <bob@example.com>") || !strings.HasPrefix(lines[2], "Committer: Alice") {
+		t.Errorf("details: got %q", lines)
+	}
+	config.Details = true
+	screen.Resize(screen.size)
+	if d := screen.Detail.Bound; d.Size.L == 0 || d.Min.L <= screen.Commit.Bound.Min.L+screen.Commit.Bound.Size.L-1 {
+		t.Errorf("details at %v, below the commit list at %v", d, screen.Commit.Bound)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// fileHeaderAt returns index of the `diff --git` line of the file,
+// which the l-th line of a diff belongs to. It returns -1 if there isn't.
+func fileHeaderAt(text [][]byte, l int) int {
+	if l >= len(text) {
+		l = len(text) - 1
+	}
+	for ; l >= 0; l-- {
+		if isFileHeader(text[l]) {
+			return l
+		}
+	}
+	return -1
+}
+
+// fileSection returns the range of lines, [from, to), of the file's diff at line l.
+// When l is before the first file, like in the commit message, it's the first file's.
+func fileSection(text [][]byte, l int) (from, to int) {
+	from = fileHeaderAt(text, l)
+	if from == -1 {
+		for from = 0; from < len(text); from++ {
+			if isFileHeader(text[from]) {
+				break
+			}
+		}
+		if from == len(text) {
+			// not a diff of files.
+			return 0, len(text)
+		}
+	}
+	for to = from + 1; to < len(text); to++ {
+		if isFileHeader(text[to]) {
+			break
+		}
+	}
+	return from, to
+}
+
+// fileName returns the file name from a file header of a diff, like "diff --git a/x b/x".
+// Paths git quoted, like "a/\303\251" "b/\303\251", are unquoted.
+func fileName(header []byte) string {
+	name := strings.TrimPrefix(string(header), "diff --git ")
+	if strings.HasPrefix(name, `"`) {
+		// the new path follows the quoted old path.
+		if end := quoteEnd(name); end != -1 {
+			return strings.TrimPrefix(unquotePath(strings.TrimSpace(name[end:])), "b/")
+		}
+	}
+	if i := strings.Index(name, ` "b/`); i != -1 {
+		return strings.TrimPrefix(unquotePath(name[i+1:]), "b/")
+	}
+	if i := strings.Index(name, " b/"); i != -1 {
+		return name[i+3:]
+	}
+	return name
+}
+
+// quoteEnd returns index after the closing quote of the string, that starts with a quote.
+// It returns -1 when it's not closed.
+func quoteEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// pathEscapes are escapes of a path git quoted, other than octal ones.
+var pathEscapes = map[byte]byte{
+	'a': '\a', 'b': '\b', 't': '\t', 'n': '\n', 'v': '\v', 'f': '\f', 'r': '\r', '"': '"', '\\': '\\',
+}
+
+// unquotePath unquotes a path git quoted like a C string, as it does for a path with unusual bytes.
+// Octal escapes are bytes of the path, those needn't be UTF-8. They're kept as is,
+// so the path could be passed back to git. A path that isn't quoted is returned as is.
+func unquotePath(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	s = s[1 : len(s)-1]
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b = append(b, s[i])
+			continue
+		}
+		i++
+		if c, ok := pathEscapes[s[i]]; ok {
+			b = append(b, c)
+			continue
+		}
+		n, k := 0, 0
+		for ; k < 3 && i+k < len(s) && s[i+k] >= '0' && s[i+k] <= '7'; k++ {
+			n = n*8 + int(s[i+k]-'0')
+		}
+		if k == 0 {
+			// unknown escape, keep it.
+			b = append(b, '\\', s[i])
+			continue
+		}
+		b = append(b, byte(n))
+		i += k - 1
+	}
+	return string(b)
+}
+
+// isFileHeader reports whether the line starts diff of a file.
+func isFileHeader(ln []byte) bool {
+	return bytes.HasPrefix(ln, []byte("diff --git "))
+}
+
+// DiffLine is a line of a diff, located in it's files.
+// Line numbers start from 1, and 0 means the line isn't in that side,
+// like an added line doesn't exist in the old file.
+type DiffLine struct {
+	OldPath string
+	NewPath string
+	OldLine int
+	NewLine int
+}
+
+// diffLineAt locates the l-th line of a diff in it's files.
+// It returns false when the line isn't a line of a hunk.
+// Combined diffs of merge commits are not supported.
+func diffLineAt(text [][]byte, l int) (DiffLine, bool) {
+	var d DiffLine
+	if l < 0 || l >= len(text) {
+		return d, false
+	}
+	h := fileHeaderAt(text, l)
+	if h == -1 {
+		return d, false
+	}
+	oldL, newL := 0, 0
+	inHunk := false
+	for i := h + 1; i <= l; i++ {
+		ln := text[i]
+		if !inHunk {
+			if bytes.HasPrefix(ln, []byte("--- ")) {
+				d.OldPath = diffPath(ln[4:], "a/")
+			} else if bytes.HasPrefix(ln, []byte("+++ ")) {
+				d.NewPath = diffPath(ln[4:], "b/")
+			}
+		}
+		if bytes.HasPrefix(ln, []byte("@@ ")) {
+			var ok bool
+			oldL, newL, ok = parseHunkHeader(ln)
+			if !ok {
+				return d, false
+			}
+			inHunk = true
+			if i == l {
+				return d, false
+			}
+			continue
+		}
+		if !inHunk {
+			continue
+		}
+		if i == l {
+			break
+		}
+		if len(ln) == 0 {
+			continue
+		}
+		switch ln[0] {
+		case '-':
+			oldL++
+		case '+':
+			newL++
+		case ' ':
+			oldL++
+			newL++
+		}
+	}
+	if !inHunk || len(text[l]) == 0 {
+		return d, false
+	}
+	switch text[l][0] {
+	case '-':
+		d.OldLine = oldL
+	case '+':
+		d.NewLine = newL
+	case ' ':
+		d.OldLine = oldL
+		d.NewLine = newL
+	default:
+		return d, false
+	}
+	return d, true
+}
+
+// diffPath returns a file path of `---` or `+++` line of a diff,
+// without it's prefix. It returns empty string for /dev/null.
+func diffPath(p []byte, prefix string) string {
+	// git adds a tab after a path with spaces, dig expands it to spaces.
+	s := strings.TrimSuffix(strings.TrimSuffix(string(p), "\t"), "    ")
+	// git quotes a path having unusual characters.
+	s = unquotePath(s)
+	if s == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(s, prefix)
+}
+
+// parseHunkHeader parses start lines of a hunk header,
+// like "@@ -1,5 +1,6 @@ func main() {".
+func parseHunkHeader(ln []byte) (oldL, newL int, ok bool) {
+	f := strings.Fields(string(ln))
+	if len(f) < 4 || !strings.HasPrefix(f[1], "-") || !strings.HasPrefix(f[2], "+") {
+		return 0, 0, false
+	}
+	start := func(s string) (int, bool) {
+		s = strings.SplitN(s[1:], ",", 2)[0]
+		n, err := strconv.Atoi(s)
+		return n, err == nil
+	}
+	oldL, ok1 := start(f[1])
+	newL, ok2 := start(f[2])
+	return oldL, newL, ok1 && ok2
+}
+
+// addLinks adds hyperlinks of the l-th line of the area, drawn with the color.
+// Hashes of commits, files of headers and issue IDs are linked.
+func (a *DiffArea) addLinks(l int, ln []byte, c Color) {
+	switch {
+	case isFileHeader(ln):
+		addLink(a.Bound, l, 0, string(ln), fileURL(fileName(ln)), c)
+	case bytes.HasPrefix(ln, []byte("commit ")):
+		hash := string(bytes.TrimSpace(ln[len("commit "):]))
+		addLink(a.Bound, l, len("commit "), hash, commitURL(hash), c)
+	case bytes.HasPrefix(ln, []byte("    ")):
+		// commit message.
+		addIssueLinks(a.Bound, l, 0, string(ln), c)
+	}
+}
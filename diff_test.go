@@ -0,0 +1,86 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	r := historyFixture(t)
+	setupApp(t, r.Dir, r.Commits(false, "HEAD"))
+	text, err := commitDiff(r.Hash("feature"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	added := -1
+	for l, ln := range text {
+		if isFileHeader(ln) {
+			files = append(files, fileName(ln))
+		}
+		if strings.HasPrefix(string(ln), "+    println") {
+			added = l
+		}
+	}
+	if len(files) != 1 || files[0] != "main.go" {
+		t.Fatalf("files of the diff: got %v", files)
+	}
+	d, ok := diffLineAt(text, added)
+	if !ok || d.NewPath != "main.go" || d.NewLine != 4 || d.OldLine != 0 {
+		t.Errorf("the added line: got %+v, %v", d, ok)
+	}
+	d, ok = diffLineAt(text, added+1)
+	if !ok || d.OldLine != 4 || d.NewLine != 5 {
+		t.Errorf("the context line after it: got %+v, %v", d, ok)
+	}
+	if _, ok := diffLineAt(text, 0); ok {
+		t.Error("the commit header is located in a file")
+	}
+}
+
+func TestUnusualPaths(t *testing.T) {
+	// a path of latin-1 bytes isn't UTF-8, and git quotes a path with a quote in any case.
+	names := []string{"été.txt", "caf\xe9.txt", `say "hi".txt`, "tab\there.txt", "with space.txt"}
+	files := make(map[string]string)
+	for _, name := range names {
+		files[name] = name + "\n"
+	}
+	r := buildRepo(t, step{Files: files, Message: "unusual paths"})
+	setupApp(t, r.Dir, r.Commits(false, "HEAD"))
+	text, err := commitDiff(r.Hash("HEAD"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for l, ln := range text {
+		if !isFileHeader(ln) {
+			continue
+		}
+		got = append(got, fileName(ln))
+		// the path of the added line is the same.
+		if d, ok := diffLineAt(text, l+6); !ok || d.NewPath != fileName(ln) {
+			t.Errorf("path of a line of %q: got %+v, %v", fileName(ln), d, ok)
+		}
+	}
+	changed, err := changedFiles(r.Hash("HEAD"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	sort.Strings(got)
+	sort.Strings(changed)
+	if !reflect.DeepEqual(got, names) {
+		t.Errorf("files of the diff: got %q, want %q", got, names)
+	}
+	if !reflect.DeepEqual(changed, names) {
+		t.Errorf("changed files: got %q, want %q", changed, names)
+	}
+	// the paths could be passed back to git.
+	for _, name := range names {
+		if out := r.git("show", "HEAD:"+name); out != name {
+			t.Errorf("content of %q: got %q", name, out)
+		}
+	}
+}
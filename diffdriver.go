@@ -0,0 +1,145 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// diffDriver is a git diff driver of files those match the pattern, like golang of *.go.
+// git knows drivers of many languages, their hunk headers show the function of a hunk.
+type diffDriver struct {
+	Pattern string
+	Driver  string
+}
+
+// defaultDiffDrivers are drivers git knows, for files of their languages.
+// git doesn't use them unless .gitattributes says so, and takes the last line that starts with a letter as the function.
+func defaultDiffDrivers() []diffDriver {
+	d, _ := parseDiffDrivers("*.go:golang, *.py:python, *.rs:rust, *.java:java, *.kt:kotlin, *.cs:csharp, " +
+		"*.c:cpp, *.h:cpp, *.cc:cpp, *.cpp:cpp, *.cxx:cpp, *.hpp:cpp, *.m:objc, *.rb:ruby, *.php:php, *.pl:perl, " +
+		"*.sh:bash, *.bash:bash, *.ex:elixir, *.exs:elixir, *.css:css, *.html:html, *.md:markdown, *.tex:tex")
+	return d
+}
+
+// parseDiffDrivers parses drivers separated by commas, those are a pattern and a driver, like "*.go:golang, *.sql:sql".
+func parseDiffDrivers(s string) ([]diffDriver, error) {
+	drivers := []diffDriver{}
+	for _, f := range parseList(s) {
+		i := strings.LastIndex(f, ":")
+		if i == -1 {
+			return nil, fmt.Errorf("expected pattern:driver: %s", f)
+		}
+		d := diffDriver{Pattern: strings.TrimSpace(f[:i]), Driver: strings.TrimSpace(f[i+1:])}
+		// they're written to an attributes file, those are separated by spaces.
+		if d.Pattern == "" || d.Driver == "" || strings.ContainsAny(d.Pattern+d.Driver, " \t") {
+			return nil, fmt.Errorf("invalid driver: %s", f)
+		}
+		drivers = append(drivers, d)
+	}
+	return drivers, nil
+}
+
+// setDiffFuncname sets the regexp of a custom driver, like "sql: ^(CREATE|ALTER) .*".
+// It's set for each driver, so the config could have it more than once.
+func (c *Config) setDiffFuncname(value string) error {
+	i := strings.Index(value, ":")
+	if i == -1 {
+		return fmt.Errorf("expected driver:regexp")
+	}
+	driver, re := strings.TrimSpace(value[:i]), strings.TrimSpace(value[i+1:])
+	if driver == "" || re == "" {
+		return fmt.Errorf("expected driver:regexp")
+	}
+	if c.DiffFuncnames == nil {
+		c.DiffFuncnames = make(map[string]string)
+	}
+	c.DiffFuncnames[driver] = re
+	return nil
+}
+
+// gitDiffs are git commands those make diffs, they get diffDriverArgs.
+var gitDiffs = map[string]bool{
+	"diff":         true,
+	"format-patch": true,
+	"log":          true,
+	"show":         true,
+}
+
+// diffAttrs caches arguments of diffDriverArgs for the config, so the attributes file is written once.
+var diffAttrs struct {
+	sync.Mutex
+	config *Config
+	args   []string
+}
+
+// diffDriverArgs returns arguments of git, those give drivers of config.DiffDrivers and config.DiffFuncnames to it.
+//
+// Drivers are given by an attributes file as core.attributesFile, as git doesn't take attributes by arguments.
+// It has the lowest priority, so .gitattributes of the repository still decides drivers of it's files.
+// The user's own attributes file is copied after the drivers, to keep and override them.
+func diffDriverArgs(dir string) []string {
+	diffAttrs.Lock()
+	defer diffAttrs.Unlock()
+	if diffAttrs.config == config {
+		return diffAttrs.args
+	}
+	var args []string
+	if len(config.DiffDrivers) != 0 {
+		// without the file, hunk headers are just like git's.
+		if path, err := writeDiffAttributes(dir); err == nil {
+			args = append(args, "-c", "core.attributesFile="+path)
+		}
+	}
+	drivers := make([]string, 0, len(config.DiffFuncnames))
+	for d := range config.DiffFuncnames {
+		drivers = append(drivers, d)
+	}
+	sort.Strings(drivers)
+	for _, d := range drivers {
+		args = append(args, "-c", "diff."+d+".xfuncname="+config.DiffFuncnames[d])
+	}
+	diffAttrs.config = config
+	diffAttrs.args = args
+	return args
+}
+
+// writeDiffAttributes writes the attributes file of diffDriverArgs, and returns path of it.
+// It's in the temporary directory and named by it's content, so dig processes with the same drivers share it.
+func writeDiffAttributes(dir string) (string, error) {
+	var b strings.Builder
+	for _, d := range config.DiffDrivers {
+		fmt.Fprintf(&b, "%s diff=%s\n", d.Pattern, d.Driver)
+	}
+	if user := userAttributesFile(dir); user != "" {
+		if data, err := os.ReadFile(user); err == nil {
+			b.Write(data)
+			b.WriteString("\n")
+		}
+	}
+	path := filepath.Join(os.TempDir(), fmt.Sprintf("dig-attributes-%x", sha1.Sum([]byte(b.String()))))
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	return path, writeFileAtomic(path, []byte(b.String()))
+}
+
+// userAttributesFile returns path of the user's attributes file, that is core.attributesFile or git's default.
+func userAttributesFile(dir string) string {
+	out, err := gitCommand(dir, "config", "--path", "core.attributesFile").Output()
+	if path := strings.TrimSpace(string(out)); err == nil && path != "" {
+		return path
+	}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "git", "attributes")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "git", "attributes")
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffDrivers(t *testing.T) {
+	// git's default takes the label for the function, as it starts with a letter.
+	goFile := "package main\n\nfunc alpha() {\nloop:\n\tfor {\n" + strings.Repeat("\tprintln()\n", 10) + "\t\tbreak loop\n\t}\n}\n"
+	sqlFile := "CREATE TABLE users (\n" + strings.Repeat("  name text,\n", 10) + "  id int\n);\n"
+	r := buildRepo(t,
+		step{Files: map[string]string{"main.go": goFile, "schema.sql": sqlFile}, Message: "initial"},
+		step{Files: map[string]string{
+			"main.go":    strings.Replace(goFile, "break loop", "continue loop", 1),
+			"schema.sql": strings.Replace(sqlFile, "id int", "id bigint", 1),
+		}, Message: "change"},
+	)
+	commits := r.Commits(false, "HEAD")
+	// contexts returns contexts of hunk headers of the last commit, by their files.
+	contexts := func() map[string]string {
+		text, err := commitDiff(r.Hash("HEAD"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := make(map[string]string)
+		file := ""
+		for _, ln := range text {
+			if isFileHeader(ln) {
+				file = fileName(ln)
+			} else if start, ok := hunkContext(ln); ok {
+				m[file] = string(ln[start:])
+			}
+		}
+		return m
+	}
+	setupApp(t, r.Dir, commits)
+	got := contexts()
+	if want := "func alpha() {"; got["main.go"] != want {
+		t.Errorf("context of main.go: got %q, want %q", got["main.go"], want)
+	}
+	if want := "CREATE TABLE users ("; got["schema.sql"] != want {
+		t.Errorf("context of schema.sql: got %q, want %q", got["schema.sql"], want)
+	}
+
+	setupApp(t, r.Dir, commits)
+	config.DiffDrivers = nil
+	if got := contexts()["main.go"]; got != "loop:" {
+		t.Errorf("context of main.go without drivers: got %q, want %q", got, "loop:")
+	}
+
+	setupApp(t, r.Dir, commits)
+	if err := config.Set("diff_drivers", "*.sql:sql, *.go:golang"); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Set("diff_funcname", "sql: ^CREATE TABLE ([a-z]+)"); err != nil {
+		t.Fatal(err)
+	}
+	if got := contexts()["schema.sql"]; got != "users" {
+		t.Errorf("context of schema.sql by a custom driver: got %q, want %q", got, "users")
+	}
+	// .gitattributes of the repository is over the config.
+	if err := os.WriteFile(filepath.Join(r.Dir, ".gitattributes"), []byte("*.go diff=plain\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := contexts()["main.go"]; got != "loop:" {
+		t.Errorf("context of main.go by .gitattributes: got %q, want %q", got, "loop:")
+	}
+	for _, v := range []string{"*.go", "*.go:", "a b:c"} {
+		if _, err := parseDiffDrivers(v); err == nil {
+			t.Errorf("drivers %q: no error", v)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// difftoolRange returns the commits to compare in a GUI diff tool, for the current view.
+// It's the range of a range diff, or the commit of the diff or the selected one against it's first parent.
+func difftoolRange() (from, to string, err error) {
+	hash := screen.Commit.Commit().Hash
+	if dig.CurView == DiffView {
+		hash = screen.FocusedDiff().CommitHash
+	}
+	if strings.HasPrefix(hash, patchKeyPrefix) || isWordDiffKey(hash) {
+		return "", "", fmt.Errorf("not a diff of commits")
+	}
+	if i := strings.Index(hash, ".."); i != -1 {
+		return hash[:i], hash[i+2:], nil
+	}
+	out, err := gitOutput("rev-list", "--parents", "-n", "1", hash)
+	if err != nil {
+		return "", "", err
+	}
+	f := strings.Fields(string(out))
+	if len(f) < 2 {
+		// a root commit, it's compared with the empty tree.
+		out, err := gitOutput("hash-object", "-t", "tree", "--stdin")
+		if err != nil {
+			return "", "", err
+		}
+		return strings.TrimSpace(string(out)), hash, nil
+	}
+	return f[1], hash, nil
+}
+
+// openDifftool opens the commits in a GUI diff tool of config.DiffTools.
+// It picks the tool from a list when there are more than one.
+func openDifftool(from, to string) error {
+	tools := config.DiffTools
+	if len(tools) <= 1 {
+		return startDifftool(strings.Join(tools, ""), from, to)
+	}
+	openList("open with", tools, 0, func(i int) {
+		if err := startDifftool(tools[i], from, to); err != nil {
+			dig.Message = err.Error()
+		}
+	})
+	return nil
+}
+
+// startDifftool runs git difftool for the commits with the tool, or git's diff.tool when it's empty.
+// It compares the directories of the commits, and isn't waited, so dig is still usable while the tool is opened.
+// The tool outlives dig, as it's in it's own process group, and isn't killed by config.GitTimeout.
+func startDifftool(tool, from, to string) error {
+	args := []string{"difftool", "--dir-diff", "--no-prompt"}
+	if tool != "" {
+		args = append(args, "--tool="+tool)
+	}
+	cmd := exec.Command("git", pathArgs(append(args, from, to), dig.Paths)...)
+	cmd.Dir = dig.RepoDir
+	setProcessGroup(cmd)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	name := tool
+	if name == "" {
+		name = "difftool"
+	}
+	dig.Message = fmt.Sprintf("opening %s..%s in %s", shortHash(from), shortHash(to), name)
+	go func() {
+		err := cmd.Wait()
+		if err != nil && stderr.Len() != 0 {
+			// the first line tells why, like an unknown tool. git's warning follows.
+			err = errors.New(strings.SplitN(strings.TrimSpace(stderr.String()), "\n", 2)[0])
+		}
+		send(difftoolDoneMsg{Tool: name, Err: err})
+	}()
+	return nil
+}
+
+// difftoolDoneMsg is a GUI diff tool that exited.
+type difftoolDoneMsg struct {
+	Tool string
+	Err  error
+}
+
+// difftoolDone shows the error of the exited tool.
+func difftoolDone(msg difftoolDoneMsg) {
+	if msg.Err != nil {
+		dig.Message = msg.Tool + ": " + msg.Err.Error()
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// candidate is an encoding that could be detected from bytes.
+type candidate struct {
+	Name  string // name for findEncoding
+	Valid func(b []byte) bool
+	Lang  string // language of LANG environment variable, that prefers it
+}
+
+// candidates are encodings detected from non UTF-8 text, in the order of trial.
+// Latin-1 is the last resort, as any bytes are valid Latin-1.
+var candidates = []candidate{
+	{"euc-kr", validEUCKR, "ko"},
+	{"shift_jis", validShiftJIS, "ja"},
+}
+
+// decodeDiff transcodes lines of a diff those aren't UTF-8 into UTF-8.
+// It detects encoding of each file, as files in a diff could have different ones.
+func decodeDiff(lines [][]byte) [][]byte {
+	enc := ""
+	encKnown := false
+	from := 0
+	for from < len(lines) {
+		to := from + 1
+		for to < len(lines) && !isFileHeader(lines[to]) {
+			to++
+		}
+		var idx []int
+		// paths in the file header are kept as they are, to be passed back to git.
+		inHeader := isFileHeader(lines[from])
+		for i := from; i < to; i++ {
+			if bytes.HasPrefix(lines[i], []byte("@@")) {
+				inHeader = false
+			}
+			if !inHeader && !utf8.Valid(lines[i]) {
+				idx = append(idx, i)
+			}
+		}
+		if len(idx) != 0 {
+			// it runs git, find it only when needed.
+			if !encKnown {
+				enc = contentEncoding()
+				encKnown = true
+			}
+			if enc != "utf-8" {
+				decodeLines(lines, idx, enc)
+			}
+		}
+		from = to
+	}
+	return lines
+}
+
+// decodeLines transcodes the lines at idx from the encoding.
+// Empty encoding means it should be detected.
+func decodeLines(lines [][]byte, idx []int, enc string) {
+	bad := make([][]byte, len(idx))
+	for j, i := range idx {
+		bad[j] = lines[i]
+	}
+	if enc == "" {
+		enc = detectEncoding(bytes.Join(bad, []byte("\n")))
+	}
+	dec, err := transcode(bad, enc)
+	if err != nil {
+		// it's still better than replacement characters.
+		dec, _ = transcode(bad, "latin1")
+	}
+	for j, i := range idx {
+		lines[i] = dec[j]
+	}
+}
+
+// contentEncoding returns encoding of file contents in the repository.
+// It's config.Encoding, or i18n.commitEncoding of the repository when it's auto.
+// It returns "utf-8" when transcoding is off, and "" when it should be detected.
+func contentEncoding() string {
+	enc := config.Encoding
+	if enc == "auto" {
+		out, _ := gitOutput("config", "i18n.commitEncoding")
+		enc = strings.TrimSpace(string(out))
+	}
+	switch strings.ToLower(enc) {
+	case "utf-8", "utf8", "none":
+		return "utf-8"
+	}
+	return enc
+}
+
+// detectEncoding guesses encoding of the bytes.
+// When the bytes are valid in more than one encoding,
+// the one for user's language comes first.
+func detectEncoding(b []byte) string {
+	lang := os.Getenv("LC_ALL")
+	if lang == "" {
+		lang = os.Getenv("LANG")
+	}
+	for _, c := range candidates {
+		if strings.HasPrefix(lang, c.Lang) && c.Valid(b) {
+			return c.Name
+		}
+	}
+	for _, c := range candidates {
+		if c.Valid(b) {
+			return c.Name
+		}
+	}
+	return "latin1"
+}
+
+// transcode converts lines from the encoding into UTF-8.
+func transcode(lines [][]byte, enc string) ([][]byte, error) {
+	e, err := findEncoding(enc)
+	if err != nil {
+		return nil, err
+	}
+	d := e.NewDecoder()
+	dec := make([][]byte, len(lines))
+	for i, ln := range lines {
+		dec[i], err = d.Bytes(ln)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert from %s: %v", enc, err)
+		}
+	}
+	return dec, nil
+}
+
+// findEncoding returns the encoding of the name.
+// It finds web names like euc-kr first, then IANA names like cp949.
+// Latin-1 is found here, as the web treats it as Windows-1252.
+func findEncoding(name string) (encoding.Encoding, error) {
+	switch strings.ToLower(name) {
+	case "latin1", "latin-1", "iso-8859-1", "iso8859-1":
+		return charmap.ISO8859_1, nil
+	case "cp949", "uhc":
+		// euc-kr of the web is a superset of it.
+		name = "euc-kr"
+	}
+	if e, err := htmlindex.Get(name); err == nil {
+		return e, nil
+	}
+	e, err := ianaindex.IANA.Encoding(name)
+	if err != nil || e == nil {
+		return nil, fmt.Errorf("unknown encoding: %s", name)
+	}
+	return e, nil
+}
+
+// validEUCKR reports whether the bytes are valid EUC-KR,
+// that non ASCII characters are pairs of bytes in 0xA1-0xFE.
+func validEUCKR(b []byte) bool {
+	for i := 0; i < len(b); i++ {
+		if b[i] < 0x80 {
+			continue
+		}
+		if b[i] < 0xA1 || b[i] == 0xFF || i+1 == len(b) || b[i+1] < 0xA1 || b[i+1] == 0xFF {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+// validShiftJIS reports whether the bytes are valid Shift_JIS.
+// Half-width katakana are single bytes in 0xA1-0xDF,
+// and others are pairs of a lead byte and a trail byte.
+func validShiftJIS(b []byte) bool {
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		if c < 0x80 || c >= 0xA1 && c <= 0xDF {
+			continue
+		}
+		if !(c >= 0x81 && c <= 0x9F || c >= 0xE0 && c <= 0xFC) || i+1 == len(b) {
+			return false
+		}
+		t := b[i+1]
+		if t < 0x40 || t == 0x7F || t > 0xFC {
+			return false
+		}
+		i++
+	}
+	return true
+}
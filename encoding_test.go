@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestTranscode(t *testing.T) {
+	tests := []struct {
+		enc  string
+		in   string
+		want string
+	}{
+		{"euc-kr", "\xc7\xd1\xb1\xdb", "한글"},
+		{"CP949", "\xc7\xd1\xb1\xdb", "한글"},
+		{"shift_jis", "\x93\xfa\x96\x7b", "日本"},
+		{"latin1", "caf\xe9", "café"},
+	}
+	for _, tt := range tests {
+		dec, err := transcode([][]byte{[]byte(tt.in)}, tt.enc)
+		if err != nil {
+			t.Errorf("%s: %v", tt.enc, err)
+			continue
+		}
+		if string(dec[0]) != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.enc, dec[0], tt.want)
+		}
+	}
+	if _, err := transcode([][]byte{[]byte("x")}, "no-such-encoding"); err == nil {
+		t.Error("unknown encoding: want an error")
+	}
+}
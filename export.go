@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// cmdExportReport exports the current session's findings into a Markdown file.
+// It contains pinned commits and notes, and diffs of pinned commits with -diff.
+//
+//	export-report [-diff] [file]
+func cmdExportReport(args []string) error {
+	file := "dig-investigation.md"
+	withDiff := false
+	for _, a := range args {
+		if a == "-diff" {
+			withDiff = true
+		} else {
+			file = a
+		}
+	}
+	if len(dig.Pins) == 0 && len(dig.Notes) == 0 {
+		return fmt.Errorf("nothing to export: pin commits with 'p' or write notes with 'a'")
+	}
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "# Investigation of %s\n\n", filepath.Base(dig.RepoDir))
+	fmt.Fprintf(buf, "Generated by dig at %s.", time.Now().Format("2006-01-02 15:04"))
+	if dig.History != "" {
+		fmt.Fprintf(buf, " History of `%s`.", dig.History)
+	}
+	buf.WriteString("\n")
+
+	if len(dig.Pins) != 0 {
+		buf.WriteString("\n## Pinned commits\n\n")
+		for _, c := range pinnedCommits() {
+			fmt.Fprintf(buf, "- `%s` %s\n", shortHash(c.Hash), c.Title)
+		}
+	}
+
+	if len(dig.Notes) != 0 {
+		buf.WriteString("\n## Notes\n")
+		for _, h := range notedHashes() {
+			title := ""
+			if i := findByHash(dig.Commits, h, 0); i != -1 {
+				title = " " + dig.Commits[i].Title
+			}
+			fmt.Fprintf(buf, "\n### `%s`%s\n\n%s\n", shortHash(h), title, dig.Notes[h])
+		}
+	}
+
+	if withDiff && len(dig.Pins) != 0 {
+		buf.WriteString("\n## Diffs\n")
+		for _, c := range pinnedCommits() {
+			d, err := screen.Diff.Cache.Get(c.Hash)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(buf, "\n### `%s` %s\n\n```diff\n%s\n```\n", shortHash(c.Hash), c.Title, bytes.Join(d, []byte("\n")))
+		}
+	}
+	if err := ioutil.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	dig.Message = "report written to " + file
+	return nil
+}
+
+// notedHashes returns hashes of noted commits in the commit list order.
+// Notes of commits not in the list come last.
+func notedHashes() []string {
+	hashes := make([]string, 0, len(dig.Notes))
+	for h := range dig.Notes {
+		hashes = append(hashes, h)
+	}
+	idx := func(h string) int {
+		if i := findByHash(dig.Commits, h, 0); i != -1 {
+			return i
+		}
+		return len(dig.Commits)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		ii, ij := idx(hashes[i]), idx(hashes[j])
+		if ii != ij {
+			return ii < ij
+		}
+		return strings.Compare(hashes[i], hashes[j]) < 0
+	})
+	return hashes
+}
+
+// exportedCommit is a commit of the exported commit list.
+type exportedCommit struct {
+	Hash    string            `json:"hash"`
+	Abbrev  string            `json:"abbrev"`
+	Date    string            `json:"date"`
+	Author  string            `json:"author"`
+	Email   string            `json:"email"`
+	Title   string            `json:"title"`
+	Parents []string          `json:"parents"`
+	Columns map[string]string `json:"columns"`
+}
+
+// cmdExportCommits exports the commit list as it's shown, with the filters, into a CSV or JSON file.
+// Columns of config.Columns are exported too, those not loaded yet are loaded in background.
+// It's JSON when the file ends with .json.
+//
+//	export-commits [file]
+func cmdExportCommits(args []string) error {
+	file := "dig-commits.csv"
+	switch len(args) {
+	case 0:
+	case 1:
+		file = args[0]
+	default:
+		return fmt.Errorf("usage: export-commits [file]")
+	}
+	if len(dig.Commits) == 0 {
+		return fmt.Errorf("no commit to export")
+	}
+	cols := config.Columns
+	loaded := dig.MetaLoader().Values
+	commits := make([]exportedCommit, len(dig.Commits))
+	for i, c := range dig.Commits {
+		e := exportedCommit{
+			Hash:    c.Hash,
+			Abbrev:  c.Abbrev,
+			Date:    c.Time.Format(time.RFC3339),
+			Author:  c.Author,
+			Email:   c.Email,
+			Title:   c.Title,
+			Parents: c.Parents,
+			Columns: make(map[string]string, len(cols)),
+		}
+		if e.Parents == nil {
+			e.Parents = []string{}
+		}
+		for _, col := range cols {
+			if j := logFieldIndex(config.LogFields, col); j != -1 {
+				e.Columns[col] = c.Field(j)
+			} else if v, ok := loaded[metaJob{c.Hash, col}]; ok {
+				e.Columns[col] = v
+			}
+		}
+		commits[i] = e
+	}
+	repoDir := dig.RepoDir
+	dig.Message = fmt.Sprintf("exporting %s to %s...", pluralize(len(commits), "commit"), file)
+	go func() {
+		err := writeCommits(file, repoDir, commits, cols)
+		send(exportDoneMsg{File: file, N: len(commits), Err: err})
+	}()
+	return nil
+}
+
+// writeCommits writes the commits to the file, after loading their columns those are missing.
+func writeCommits(file, repoDir string, commits []exportedCommit, cols []string) error {
+	for _, e := range commits {
+		for _, col := range cols {
+			if _, ok := e.Columns[col]; ok {
+				continue
+			}
+			v, err := columns[col].Load(repoDir, e.Hash)
+			if err != nil {
+				return fmt.Errorf("%s of %s: %v", col, e.Abbrev, err)
+			}
+			e.Columns[col] = v
+		}
+	}
+	buf := &bytes.Buffer{}
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		data, err := json.MarshalIndent(commits, "", "  ")
+		if err != nil {
+			return err
+		}
+		buf.Write(append(data, '\n'))
+	} else {
+		w := csv.NewWriter(buf)
+		w.Write(append([]string{"hash", "abbrev", "date", "author", "email", "title", "parents"}, cols...))
+		for _, e := range commits {
+			row := []string{e.Hash, e.Abbrev, e.Date, e.Author, e.Email, e.Title, strings.Join(e.Parents, " ")}
+			for _, col := range cols {
+				row = append(row, e.Columns[col])
+			}
+			w.Write(row)
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(file, buf.Bytes())
+}
+
+// exportDoneMsg is the commit list that is exported.
+type exportDoneMsg struct {
+	File string
+	N    int
+	Err  error
+}
+
+// exportDone tells the exported file, or why it failed.
+func exportDone(msg exportDoneMsg) {
+	if msg.Err != nil {
+		dig.Message = "export-commits: " + msg.Err.Error()
+		return
+	}
+	dig.Message = fmt.Sprintf("exported %s to %s", pluralize(msg.N, "commit"), msg.File)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportCommits(t *testing.T) {
+	r := historyFixture(t)
+	setupApp(t, r.Dir, r.Commits(false, "HEAD"))
+	config.Columns = []string{"stat"}
+	if err := toggleFilter("author:bob"); err != nil {
+		t.Fatal(err)
+	}
+	export := func(file string) []byte {
+		t.Helper()
+		if err := cmdExportCommits([]string{file}); err != nil {
+			t.Fatal(err)
+		}
+		runUntil(t, func() bool { return strings.HasPrefix(dig.Message, "exported") }, nil)
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	dir := t.TempDir()
+	rows, err := csv.NewReader(bytes.NewReader(export(filepath.Join(dir, "commits.csv")))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hi := r.Hash("feature")
+	if len(rows) != 2 || rows[0][7] != "stat" || rows[1][0] != hi || rows[1][3] != "Bob" || rows[1][5] != "say hi" || rows[1][7] == "" {
+		t.Errorf("csv: got %q", rows)
+	}
+	var commits []exportedCommit
+	if err := json.Unmarshal(export(filepath.Join(dir, "commits.json")), &commits); err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 || commits[0].Hash != hi || len(commits[0].Parents) != 1 || commits[0].Columns["stat"] != rows[1][7] {
+		t.Errorf("json: got %+v", commits)
+	}
+}
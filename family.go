@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+)
+
+// ChildrenOf returns hashes of children of the commit, among loaded commits.
+// The children index is built at the first call, as it's not needed usually.
+func (p *Program) ChildrenOf(hash string) []string {
+	if p.Children == nil {
+		p.Children = make(map[string][]string)
+		for _, c := range p.All {
+			for _, parent := range c.Parents {
+				p.Children[parent] = append(p.Children[parent], c.Hash)
+			}
+		}
+	}
+	return p.Children[hash]
+}
+
+// handleFamilyPrefixed handles keys after 'g' to walk ancestry of the selected commit.
+// gp goes to the first parent, or count-th parent of a merge.
+// gc goes to a child, and asks which one when there are many.
+// gr goes to the next root commit, when there are unrelated histories.
+// go checks whether the commit is on a remote, before sharing it's hash.
+func handleFamilyPrefixed(ev Event) bool {
+	c := screen.Commit.Commit()
+	switch ev.Ch {
+	case 'p':
+		if len(c.Parents) == 0 {
+			dig.Message = "root commit doesn't have a parent"
+			return true
+		}
+		n := count()
+		if n > len(c.Parents) {
+			dig.Message = fmt.Sprintf("commit has %d parents", len(c.Parents))
+			return true
+		}
+		gotoRelative(c.Parents[n-1], "parent")
+		return true
+	case 'c':
+		children := dig.ChildrenOf(c.Hash)
+		if len(children) == 0 {
+			dig.Message = "no child commit in the list"
+			return true
+		}
+		if len(children) == 1 {
+			gotoRelative(children[0], "child")
+			return true
+		}
+		items := make([]string, len(children))
+		for i, h := range children {
+			items[i] = shortHash(h) + " " + dig.ByHash[h].Title
+		}
+		openList(fmt.Sprintf("%d children", len(children)), items, 0, func(i int) {
+			gotoRelative(children[i], "child")
+		})
+		return true
+	case 'a':
+		if err := pickAncestorRef(); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	case 'r':
+		if err := nextRoot(count()); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	case 'o':
+		if err := pickRemote(); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	}
+	return false
+}
+
+// gotoRelative moves the cursor to the related commit, remembering the jump.
+func gotoRelative(hash, relation string) {
+	i := findByHash(dig.Commits, hash, screen.Commit.CurIdx)
+	if i == -1 {
+		dig.Message = fmt.Sprintf("%s %s is not in the list", relation, shortHash(hash))
+		return
+	}
+	pushJump()
+	screen.Commit.SetCursor(i)
+}
+
+// nextRoot goes to the n-th next root commit in the list. It wraps around at the end.
+func nextRoot(n int) error {
+	roots := []int{}
+	for i, c := range dig.Commits {
+		if len(c.Parents) == 0 {
+			roots = append(roots, i)
+		}
+	}
+	if len(roots) == 0 {
+		return fmt.Errorf("no root commit in the list")
+	}
+	// i is the first root after the cursor.
+	i := 0
+	for i < len(roots) && roots[i] <= screen.Commit.CurIdx {
+		i++
+	}
+	i = (i + n - 1) % len(roots)
+	pushJump()
+	screen.Commit.SetCursor(roots[i])
+	dig.Message = fmt.Sprintf("root %d/%d", i+1, len(roots))
+	return nil
+}
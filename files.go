@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// openFiles opens the file list of the commit by itself, for a commit that changed many files.
+// Enter on a file shows the commit's diff at the file.
+// The three pane layout shows the list already, it's just focused.
+func openFiles(hash string) error {
+	if screen.Panes() == 3 {
+		dig.CurView = FilesView
+		return nil
+	}
+	files, statuses, err := changedFileStatuses(hash)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no files changed in %s", shortHash(hash))
+	}
+	a := screen.Files
+	if a.CommitHash != hash || !a.Alone {
+		a.CurIdx = 0
+		a.TopIdx = 0
+	}
+	a.CommitHash = hash
+	a.Files = files
+	a.Statuses = statuses
+	a.Alone = true
+	dig.CurView = FilesView
+	return nil
+}
+
+// changedFileStatuses returns files changed by the commit in the program's paths, with their statuses
+// like git show --name-status. A merge is compared to it's first parent.
+// A renamed file is it's new path, and R is it's status.
+func changedFileStatuses(hash string) (files, statuses []string, err error) {
+	out, err := gitOutput(pathArgs([]string{"show", "-z", "--name-status", "-M", "--format=", "-m", "--first-parent", hash}, dig.Paths)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	// <status> NUL <path> NUL, or R<score> NUL <old> NUL <new> NUL for renames and copies.
+	f := strings.Split(strings.TrimPrefix(string(out), "\n"), "\x00")
+	for i := 0; i+1 < len(f); i += 2 {
+		status := strings.TrimSpace(f[i])
+		if status == "" {
+			continue
+		}
+		if status[0] == 'R' || status[0] == 'C' {
+			i++
+			if i+1 >= len(f) {
+				break
+			}
+		}
+		files = append(files, f[i+1])
+		statuses = append(statuses, status[:1])
+	}
+	return files, statuses, nil
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Filter is a predicate that chooses commits to show.
+type Filter struct {
+	// Name is the filter as the user typed.
+	Name  string
+	Match func(c *Commit) bool
+	// NeedFiles is true when the filter looks changed files of commits.
+	NeedFiles bool
+
+	// Pathspecs limit git log to find commits those touched the paths.
+	// Hashes of the commits are loaded when the commits are filtered first,
+	// and loaded again after the commits are reloaded. See Program.SetCommits.
+	Pathspecs []string
+	Hashes    map[string]bool
+
+	// Query is words to find with the search index, for the text filter.
+	// Commits those don't have them are not even checked with Match.
+	Query string
+}
+
+// parseFilter parses a filter. A filter starting with ! is negated.
+//
+//	tests         commits those touched tests, see config.TestPatterns
+//	glob:<glob>   commits those touched the files, like glob:*.proto
+//	type:<type>   commits those touched files of the language or extension, like type:go
+//	author:<who>  commits by the author, matched to a part of name or email
+//	email:<email> commits by the author of the email, matched to the whole email
+//	files:<rev>   commits those touched any file the revision touched
+//	collapse      commits those aren't in linear runs, see LinearRuns
+//	text:<words>  commits those have the words in title, author or trailers, see SearchIndex
+func parseFilter(s string) (*Filter, error) {
+	neg := strings.HasPrefix(s, "!")
+	name := strings.TrimPrefix(s, "!")
+	var f *Filter
+	switch {
+	case name == "tests":
+		f = &Filter{Match: touchesTests, NeedFiles: true}
+	case name == "collapse":
+		f = collapseFilter()
+	case strings.HasPrefix(name, "glob:"):
+		glob := strings.TrimPrefix(name, "glob:")
+		if glob == "" {
+			return nil, fmt.Errorf("empty glob: %s", name)
+		}
+		f = pathspecFilter([]string{glob})
+	case strings.HasPrefix(name, "type:"):
+		globs := typeGlobs(strings.TrimPrefix(name, "type:"))
+		if len(globs) == 0 {
+			return nil, fmt.Errorf("unknown type: %s", name)
+		}
+		f = pathspecFilter(globs)
+	case strings.HasPrefix(name, "text:"):
+		query := strings.TrimPrefix(name, "text:")
+		if strings.TrimSpace(query) == "" {
+			return nil, fmt.Errorf("empty text: %s", name)
+		}
+		f = textFilter(query)
+		if neg {
+			// the index finds matches, not the others.
+			return nil, fmt.Errorf("text filter cannot be negated")
+		}
+	case strings.HasPrefix(name, "author:"):
+		who := strings.ToLower(strings.TrimPrefix(name, "author:"))
+		f = &Filter{Match: func(c *Commit) bool {
+			return strings.Contains(strings.ToLower(c.Author), who) || strings.Contains(strings.ToLower(c.Email), who)
+		}}
+	case strings.HasPrefix(name, "email:"):
+		email := strings.TrimPrefix(name, "email:")
+		if email == "" {
+			return nil, fmt.Errorf("empty email: %s", name)
+		}
+		f = &Filter{Match: func(c *Commit) bool {
+			return strings.EqualFold(c.Email, email)
+		}}
+	case strings.HasPrefix(name, "files:"):
+		hash, err := resolveHash(strings.TrimPrefix(name, "files:"))
+		if err != nil {
+			return nil, err
+		}
+		files, err := changedFiles(hash)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no files changed in %s", shortHash(hash))
+		}
+		specs := make([]string, len(files))
+		for i, file := range files {
+			specs[i] = ":(literal)" + file
+		}
+		f = pathspecFilter(specs)
+	default:
+		return nil, fmt.Errorf("unknown filter: %s", name)
+	}
+	f.Name = s
+	if neg {
+		match := f.Match
+		f.Match = func(c *Commit) bool { return !match(c) }
+	}
+	return f, nil
+}
+
+// cmdFilter shows only commits those match all the filters.
+// Without an argument, it will show all commits again.
+//
+//	filter [filter...]
+func cmdFilter(args []string) error {
+	filters := []*Filter{}
+	for _, a := range args {
+		f, err := parseFilter(a)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, f)
+	}
+	return setFilters(filters)
+}
+
+// toggleFilter adds a filter, or removes it when it's already added.
+// Other filters of the same kind are replaced, like author:.
+func toggleFilter(name string) error {
+	kind := name
+	if i := strings.Index(name, ":"); i != -1 {
+		kind = name[:i+1]
+	}
+	filters := []*Filter{}
+	found := false
+	for _, f := range dig.Filters {
+		if f.Name == name {
+			found = true
+			continue
+		}
+		if strings.HasPrefix(f.Name, kind) {
+			continue
+		}
+		filters = append(filters, f)
+	}
+	if !found {
+		f, err := parseFilter(name)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, f)
+	}
+	return setFilters(filters)
+}
+
+// setFilters sets filters of the program, and shows commits those match them.
+// The filters are kept as before when no commit matches.
+// The cursor stays on the commit or the nearest one, and goes back to where it was when filters are removed.
+func setFilters(filters []*Filter) error {
+	prev := dig.Filters
+	dig.Filters = filters
+	cur := screen.Commit.Commit().Hash
+	var err error
+	keepCursor(func() {
+		err = dig.FilterCommits()
+		if err != nil {
+			dig.Filters = prev
+			dig.FilterCommits()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if back := dig.restackSelections(len(filters), cur); back != "" {
+		if i := nearestCommit(dig.Commits, dig.All, back); i != -1 {
+			screen.Commit.SetCursor(i)
+		}
+	}
+	dig.CurView = CommitView
+	return nil
+}
+
+// FilterCommits sets commits to show from all commits with the program's filters.
+// When no commit matches, it shows all commits and returns an error.
+func (p *Program) FilterCommits() error {
+	if p.needFiles() {
+		if err := p.LoadFiles(); err != nil {
+			p.Commits = p.All
+			return err
+		}
+	}
+	if len(p.Filters) == 0 {
+		p.Commits = p.All
+		return nil
+	}
+	for _, f := range p.Filters {
+		if f.Pathspecs == nil || f.Hashes != nil {
+			continue
+		}
+		hashes, err := p.pathspecHashes(f.Pathspecs)
+		if err != nil {
+			p.Commits = p.All
+			return err
+		}
+		f.Hashes = hashes
+	}
+	candidates := p.All
+	if ids := p.queryMatches(); ids != nil {
+		candidates = make([]*Commit, len(ids))
+		for i, id := range ids {
+			candidates[i] = p.All[id]
+		}
+	}
+	commits := []*Commit{}
+	for _, c := range candidates {
+		match := true
+		for _, f := range p.Filters {
+			if !f.Match(c) {
+				match = false
+				break
+			}
+		}
+		if match {
+			commits = append(commits, c)
+		}
+	}
+	if len(commits) == 0 {
+		p.Commits = p.All
+		return errors.New("no commit matches the filter")
+	}
+	p.Commits = commits
+	return nil
+}
+
+// textFilter returns a filter for commits those have the words. See SearchIndex.
+func textFilter(query string) *Filter {
+	return &Filter{Query: query, Match: func(c *Commit) bool { return true }}
+}
+
+// queryMatches returns indexes of all commits those match all text filters, in ascending order.
+// It returns nil when there isn't a text filter.
+func (p *Program) queryMatches() []int {
+	var ids []int
+	for _, f := range p.Filters {
+		if f.Query == "" {
+			continue
+		}
+		found := p.SearchIndex().Search(f.Query)
+		if ids == nil {
+			ids = found
+			continue
+		}
+		// both are ascending.
+		both := []int{}
+		for i, j := 0, 0; i < len(ids) && j < len(found); {
+			switch {
+			case ids[i] < found[j]:
+				i++
+			case ids[i] > found[j]:
+				j++
+			default:
+				both = append(both, ids[i])
+				i++
+				j++
+			}
+		}
+		ids = both
+	}
+	return ids
+}
+
+// pathspecFilter returns a filter for commits those touched the pathspecs.
+func pathspecFilter(pathspecs []string) *Filter {
+	f := &Filter{Pathspecs: pathspecs}
+	f.Match = func(c *Commit) bool { return f.Hashes[c.Hash] }
+	return f
+}
+
+// typeGlobs returns globs for files of a language, or an extension.
+// Languages are the ones of the stat view, like go or markdown.
+func typeGlobs(typ string) []string {
+	typ = strings.ToLower(typ)
+	globs := []string{}
+	for ext, lang := range languages {
+		if strings.ToLower(lang) == typ || ext == "."+typ {
+			globs = append(globs, "*"+ext)
+		}
+	}
+	if len(globs) == 0 && typ != "" && !strings.ContainsAny(typ, "*?[/") {
+		// an extension stat view doesn't know.
+		globs = append(globs, "*."+typ)
+	}
+	sort.Strings(globs)
+	return globs
+}
+
+// pathspecHashes returns hashes of commits those touched the pathspecs.
+// History of the program is ignored, since it's commits are already limited.
+func (p *Program) pathspecHashes(pathspecs []string) (map[string]bool, error) {
+	out, err := backend.PathCommits(p.RepoDir, p.Targets, pathspecs)
+	if err != nil {
+		return nil, fmt.Errorf("could not get commits of %s: %v", strings.Join(pathspecs, " "), err)
+	}
+	hashes := make(map[string]bool)
+	for _, h := range out {
+		hashes[h] = true
+	}
+	return hashes, nil
+}
+
+// changedFiles returns files changed by the commit.
+// A merge commit is compared to it's first parent.
+func changedFiles(hash string) ([]string, error) {
+	out, err := gitOutput("show", "-z", "--name-only", "--no-renames", "--format=", "-m", "--first-parent", hash)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, f := range strings.Split(string(out), "\x00") {
+		f = strings.TrimPrefix(f, "\n")
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// FilterString returns the program's filters as the user typed.
+func (p *Program) FilterString() string {
+	names := make([]string, len(p.Filters))
+	for i, f := range p.Filters {
+		names[i] = f.Name
+	}
+	return strings.Join(names, " ")
+}
+
+// needFiles reports whether changed files of commits are needed,
+// for the filters or the commit badges.
+func (p *Program) needFiles() bool {
+	if config.TestBadge {
+		return true
+	}
+	for _, f := range p.Filters {
+		if f.NeedFiles {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadFiles loads changed files of all commits, if they aren't loaded yet.
+// Merge commits don't have changed files, like git log.
+// Files of commits those are still being loaded are kept until they're added.
+func (p *Program) LoadFiles() error {
+	if p.FilesLoaded {
+		return nil
+	}
+	files, err := backend.ChangedFiles(p.RepoDir, p.LogTargets())
+	if err != nil {
+		return fmt.Errorf("could not get changed files: %v", err)
+	}
+	p.laterFiles = make(map[string][]string)
+	for h, fs := range files {
+		if c, ok := p.ByHash[h]; ok {
+			c.Files = fs
+		} else if p.Loader != nil {
+			p.laterFiles[h] = fs
+		}
+	}
+	p.FilesLoaded = true
+	return nil
+}
+
+// touchesTests reports whether the commit changed any test file.
+func touchesTests(c *Commit) bool {
+	for _, f := range c.Files {
+		for _, p := range config.TestPatterns {
+			if matchPath(p, f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchPath reports whether the file path matches the pattern.
+//
+// A pattern ending with / matches a directory of the name in any depth, like "tests/".
+// A pattern having / matches from the root, like "src/*.go".
+// Otherwise it matches the file's name, like "*_test.go".
+func matchPath(pattern, file string) bool {
+	if strings.HasSuffix(pattern, "/") {
+		dir := strings.TrimSuffix(pattern, "/")
+		return strings.HasPrefix(file, dir+"/") || strings.Contains(file, "/"+dir+"/")
+	}
+	if strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, file)
+		return ok
+	}
+	ok, _ := path.Match(pattern, path.Base(file))
+	return ok
+}
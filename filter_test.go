@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func TestEmailFilter(t *testing.T) {
+	bob := &Commit{Author: "Bob", Email: "bob@example.com"}
+	jimbob := &Commit{Author: "Jim Bob", Email: "jimbob@example.com"}
+	for _, tc := range []struct {
+		filter   string
+		bob, jim bool
+	}{
+		{"author:bob", true, true},
+		{"email:bob@example.com", true, false},
+		{"email:Bob@Example.com", true, false},
+		{"!email:bob@example.com", false, true},
+	} {
+		f, err := parseFilter(tc.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Match(bob) != tc.bob || f.Match(jimbob) != tc.jim {
+			t.Errorf("%s: got %v and %v, want %v and %v", tc.filter, f.Match(bob), f.Match(jimbob), tc.bob, tc.jim)
+		}
+	}
+	if _, err := parseFilter("email:"); err == nil {
+		t.Error("expected an error of an empty email")
+	}
+}
+
+func TestPathspecFilterCache(t *testing.T) {
+	r := historyFixture(t)
+	setupApp(t, r.Dir, r.Commits(false, "HEAD"))
+	if err := cmdFilter([]string{"glob:README"}); err != nil {
+		t.Fatal(err)
+	}
+	f := dig.Filters[0]
+	if got, want := titles(dig.Commits), "fix readme, add readme"; got != want {
+		t.Fatalf("commits of README: got %q, want %q", got, want)
+	}
+	// the hashes aren't loaded again for each keystroke of a query.
+	f.Hashes[r.Hash("HEAD")] = true
+	dig.Query = "readme"
+	dig.QueryFrom = dig.Filters
+	applyQuery()
+	if got, want := titles(dig.Commits), "fix readme, add readme"; got != want {
+		t.Errorf("query in cached commits: got %q, want %q", got, want)
+	}
+	dig.Query = ""
+	applyQuery()
+	if got, want := titles(dig.Commits), "merge feature, fix readme, add readme"; got != want {
+		t.Errorf("cached commits: got %q, want %q", got, want)
+	}
+	if err := reloadCommits(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := titles(dig.Commits), "fix readme, add readme"; got != want {
+		t.Errorf("reloaded commits: got %q, want %q", got, want)
+	}
+}
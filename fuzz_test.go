@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// These fuzz the parsers of what comes from repositories and files, those could be anything.
+// They only check the parsers don't panic, and a few invariants of them.
+// Run one with go test -fuzz, like go test -fuzz=FuzzParseCommit.
+
+func FuzzParseCommit(f *testing.F) {
+	f.Add("0123456789abcdef0123456789abcdef01234567\n0123456\n1577836800\nAlice\nalice@example.com\nfedcba9876543210fedcba9876543210fedcba98\n1577836000\nBob\nbob@example.com\nHEAD -> main, tag: v1.0\nfix the parser\x1e\nIt panicked.\n\nSigned-off-by: Alice\n", 0)
+	f.Add("h\nh\n1\n\n\n\n1\n\n\n\n\x1e", 0)
+	f.Add("h\nh\n-1\na\ne\np q r\n-1\nc\nd\n\ntitle\x1fsigner\x1f2020-01-01\x1ebody\x1e", 2)
+	f.Add("h\nh\nx\na\ne\np\nx\nc\nd\nr\nt", 1)
+	f.Add("\n\n\n\n\n\n\n\n\n\n\x1f\x1f\x1f\x1e\x1f", 3)
+	f.Fuzz(func(t *testing.T, rec string, fields int) {
+		if fields < 0 || fields > 4 {
+			return
+		}
+		defer func(fs []logField) { config.LogFields = fs }(config.LogFields)
+		config.LogFields = make([]logField, fields)
+		c, err := parseCommit(rec)
+		if err != nil {
+			return
+		}
+		if len(c.Fields) > fields {
+			t.Errorf("%d fields of %d log fields", len(c.Fields), fields)
+		}
+		for i := 0; i < fields+1; i++ {
+			// a missing field is empty.
+			c.Field(i)
+		}
+		if strings.Contains(c.Title, "\t") {
+			t.Errorf("tab in the title: %q", c.Title)
+		}
+	})
+}
+
+func FuzzDiff(f *testing.F) {
+	f.Add("commit 0123456789abcdef0123456789abcdef01234567\nAuthor: Alice <alice@example.com>\n\n    say hi\n\ndiff --git a/main.go b/main.go\nindex 1234567..89abcde 100644\n--- a/main.go\n+++ b/main.go\n@@ -1,4 +1,5 @@ package main\n package main\n \n func main() {\n+\tprintln(\"hi\") /* a\n-}\n")
+	f.Add("diff --git a/x.py b/x.py\n@@ -0,0 +1 @@\n+'''\n+\"\\\n")
+	f.Add("@@ -a,b +c,d @@\n+x\n diff --git \n@@\n@@ -1 +1 @@@\n")
+	f.Add("diff --git a/a b/b\n--- /dev/null\n+++ b/b\n@@ -0,0 +1,2 @@\n+version https://git-lfs.github.com/spec/v1\n+size 12\n")
+	f.Fuzz(func(t *testing.T, s string) {
+		// hashes are shortened with the program's commits.
+		setupApp(t, "", nil)
+		text := bytes.Split([]byte(s), []byte("\n"))
+		spans := diffSyntax(text)
+		if len(spans) != len(text) {
+			t.Fatalf("%d span lines of %d lines", len(spans), len(text))
+		}
+		for l, sp := range spans {
+			for _, s := range sp {
+				if s.Start < 0 || s.Start > s.End || s.End > len(text[l]) {
+					t.Errorf("span [%d, %d) out of line %d, of %d bytes", s.Start, s.End, l, len(text[l]))
+				}
+			}
+		}
+		inHeader := false
+		for l, ln := range text {
+			if h := fileHeaderAt(text, l); h > l {
+				t.Errorf("file header of line %d is after it: %d", l, h)
+			}
+			from, to := fileSection(text, l)
+			if from < 0 || from > to || to > len(text) {
+				t.Errorf("file section of line %d: [%d, %d)", l, from, to)
+			}
+			if _, ok := diffLineAt(text, l); ok && len(ln) == 0 {
+				t.Errorf("empty line %d is located in a file", l)
+			}
+			if isFileHeader(ln) {
+				fileName(ln)
+				inHeader = true
+			} else if bytes.HasPrefix(ln, []byte("@@")) {
+				parseHunkHeader(ln)
+				inHeader = false
+			}
+			compactLine(ln, inHeader)
+		}
+		annotateLFS(text)
+		parsePatch([]byte(s))
+	})
+}
+
+func FuzzConfig(f *testing.F) {
+	f.Add("exit_summary = true\ncolumns = author, age\nlog_fields = signer:%GS\nstatus_segments = mode, clock:208\n\n[review]\nlayout = compact\n", "review")
+	f.Add("lane_palette = red, 300\n[]\n", "")
+	f.Add("key\n[x\n = \"\\q\"\nage_buckets = 1y, -3d\n", "x")
+	f.Add("keymap = ab, c\nbreakpoints = 80, 40\nstatus_interval = 0s\n[default]\n", "default")
+	f.Fuzz(func(t *testing.T, content, profile string) {
+		dir := t.TempDir()
+		t.Setenv("DIG_CONFIG_DIR", dir)
+		if err := os.WriteFile(filepath.Join(dir, "config"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		c, err := readConfig(profile)
+		if c == nil {
+			t.Fatalf("no config, with error %v", err)
+		}
+	})
+}
+
+func FuzzQuery(f *testing.F) {
+	f.Add("parser", "fix")
+	f.Add("PARSER fix", "e")
+	f.Add("a", "lice @example")
+	f.Add("", " ")
+	f.Add("!author:bob", "glob:*.go")
+	f.Add("type:go", "text:crash")
+	commits := benchCommits(200)
+	f.Fuzz(func(t *testing.T, query, more string) {
+		// the index narrows matches of the last query, the result should be the same as from scratch.
+		x := newSearchIndex(commits)
+		x.Search(query)
+		got := x.Search(query + more)
+		want := newSearchIndex(commits).Search(query + more)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("search %q after %q: got %v, want %v", query+more, query, got, want)
+		}
+		for _, s := range []string{query, more} {
+			if strings.HasPrefix(strings.TrimPrefix(s, "!"), "files:") {
+				// it needs a repository.
+				continue
+			}
+			parseFilter(s)
+		}
+	})
+}
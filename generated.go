@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// generatedFiles returns files of a diff those are generated,
+// by linguist-generated attribute of .gitattributes or config.GeneratedPatterns.
+func generatedFiles(hash string, text [][]byte) map[string]bool {
+	gen := make(map[string]bool)
+	var files []string
+	for _, ln := range text {
+		if !isFileHeader(ln) {
+			continue
+		}
+		f := fileName(ln)
+		files = append(files, f)
+		for _, p := range config.GeneratedPatterns {
+			if matchPath(p, f) {
+				gen[f] = true
+			}
+		}
+	}
+	if len(files) == 0 {
+		return gen
+	}
+	for f := range linguistGenerated(hash, files) {
+		gen[f] = true
+	}
+	return gen
+}
+
+// linguistGenerated returns files those have linguist-generated attribute.
+// It reads .gitattributes of the commit, or of the work tree when git is too old to do that.
+func linguistGenerated(hash string, files []string) map[string]bool {
+	if i := strings.Index(hash, ".."); i != -1 {
+		hash = hash[i+2:]
+	}
+	out, err := gitOutput(append([]string{"check-attr", "--source=" + hash, "linguist-generated", "--"}, files...)...)
+	if err != nil {
+		out, err = gitOutput(append([]string{"check-attr", "linguist-generated", "--"}, files...)...)
+		if err != nil {
+			return nil
+		}
+	}
+	gen := make(map[string]bool)
+	// path: linguist-generated: set
+	for _, ln := range strings.Split(string(out), "\n") {
+		i := strings.LastIndex(ln, ": linguist-generated: ")
+		if i == -1 {
+			continue
+		}
+		v := ln[i+len(": linguist-generated: "):]
+		if v == "set" || v == "true" {
+			gen[ln[:i]] = true
+		}
+	}
+	return gen
+}
+
+// collapseGenerated replaces diff of generated files with a summary line,
+// except the files those are expanded.
+func collapseGenerated(text [][]byte, gen, expanded map[string]bool) [][]byte {
+	if len(gen) == 0 {
+		return text
+	}
+	out := make([][]byte, 0, len(text))
+	from := 0
+	for from < len(text) {
+		to := from + 1
+		for to < len(text) && !isFileHeader(text[to]) {
+			to++
+		}
+		section := text[from:to]
+		from = to
+		if !isFileHeader(section[0]) {
+			out = append(out, section...)
+			continue
+		}
+		f := fileName(section[0])
+		if !gen[f] || expanded[f] {
+			out = append(out, section...)
+			continue
+		}
+		out = append(out, section[0], []byte(generatedSummary(section)))
+	}
+	return out
+}
+
+// generatedSummary returns the summary line of a collapsed generated file.
+func generatedSummary(section [][]byte) string {
+	n := 0
+	inHunk := false
+	for _, ln := range section {
+		if bytes.HasPrefix(ln, []byte("@@")) {
+			inHunk = true
+		} else if inHunk && len(ln) != 0 && (ln[0] == '+' || ln[0] == '-') {
+			n++
+		}
+	}
+	return fmt.Sprintf("generated, %d lines changed (z: expand)", n)
+}
+
+// isGeneratedSummary reports whether the line is a summary line of a collapsed generated file.
+func isGeneratedSummary(ln []byte) bool {
+	return bytes.HasPrefix(ln, []byte("generated, ")) && bytes.HasSuffix(ln, []byte("(z: expand)"))
+}
+
+// ToggleGenerated expands the generated file at the line, or collapses it again.
+func (a *DiffArea) ToggleGenerated(l int) error {
+	h := fileHeaderAt(a.Text, l)
+	if h == -1 {
+		return fmt.Errorf("not in a file")
+	}
+	f := fileName(a.Text[h])
+	if !a.Generated[f] {
+		return fmt.Errorf("not a generated file: %s", f)
+	}
+	a.Expanded[f] = !a.Expanded[f]
+	top := a.Win.Bound.Min
+	a.Text = collapseGenerated(a.Full, a.Generated, a.Expanded)
+	a.Win.Reset(a.Text)
+	a.Win.Bound.Min = top
+	a.Win.Cursor = h
+	a.Win.follow()
+	return nil
+}
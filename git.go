@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// gitCtx is the context of git commands. It's canceled when dig exits with stopGit,
+// so git commands in background don't outlive dig.
+var gitCtx, stopGit = context.WithCancel(context.Background())
+
+// gitWrites are git commands those change the repository.
+// They aren't killed by config.GitTimeout, as it could leave the repository in the middle of them.
+var gitWrites = map[string]bool{
+	"am":          true,
+	"branch":      true,
+	"checkout":    true,
+	"cherry-pick": true,
+	"commit":      true,
+	"merge":       true,
+	"rebase":      true,
+	"reset":       true,
+	"revert":      true,
+	"stash":       true,
+	"switch":      true,
+	"tag":         true,
+	"worktree":    true,
+}
+
+// gitCommand returns a git command that runs in the directory.
+//
+// It runs in it's own process group, with lower priority by config.GitNice.
+// A command that reads the repository is killed with it's children after config.GitTimeout,
+// so a runaway git on a pathological repository can't wedge dig.
+func gitCommand(dir string, args ...string) *exec.Cmd {
+	return gitCommandTimeout(dir, config.GitTimeout, args...)
+}
+
+// gitCommandTimeout is gitCommand that kills the command after the timeout instead, 0 doesn't.
+func gitCommandTimeout(dir string, timeout time.Duration, args ...string) *exec.Cmd {
+	ctx := gitCtx
+	cancel := func() {}
+	if timeout != 0 && !gitWrites[gitSubcommand(args)] {
+		// the timer of a finished command just expires.
+		ctx, cancel = context.WithTimeout(gitCtx, timeout)
+	}
+	// paths those aren't ASCII are shown as they are. git still quotes paths
+	// with control characters, quotes or backslashes, see unquotePath.
+	prefix := []string{"-c", "core.quotepath=false"}
+	if gitDiffs[gitSubcommand(args)] {
+		prefix = append(prefix, diffDriverArgs(dir)...)
+	}
+	args = append(prefix, args...)
+	name := "git"
+	if config.GitNice != 0 {
+		name, args = niceCommand(config.GitNice, name, args)
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		cancel()
+		return killProcessGroup(cmd)
+	}
+	// children could keep the output open after git is killed.
+	cmd.WaitDelay = time.Second
+	return cmd
+}
+
+// gitSubcommand returns the subcommand of git's arguments, like log.
+func gitSubcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == "-c" || a == "-C":
+			// it's value follows.
+			i++
+		case a != "" && a[0] != '-':
+			return a
+		}
+	}
+	return ""
+}
+
+// gitKilled reports whether the git command is killed, by config.GitTimeout or exit of dig.
+func gitKilled(cmd *exec.Cmd) bool {
+	return cmd.ProcessState != nil && !cmd.ProcessState.Exited()
+}
+
+// timeoutError returns an error for the git subcommand that is killed.
+func timeoutError(subcommand string) error {
+	return fmt.Errorf("git %s took more than %v and is killed, see git_timeout", subcommand, config.GitTimeout)
+}
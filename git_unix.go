@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// niceCommand returns the command that runs the command with the niceness, by nice.
+func niceCommand(n int, name string, args []string) (string, []string) {
+	return "nice", append([]string{"-n", strconv.Itoa(n), name}, args...)
+}
+
+// setProcessGroup makes the command a leader of a new process group,
+// so it could be killed with it's children.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills the command's process group.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
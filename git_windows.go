@@ -0,0 +1,16 @@
+package main
+
+import "os/exec"
+
+// niceCommand returns the command as is. Windows doesn't have nice.
+func niceCommand(n int, name string, args []string) (string, []string) {
+	return name, args
+}
+
+// setProcessGroup does nothing, Windows doesn't have process groups like unix.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the command only.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// gitColorAttrs are attributes of git's color syntax, those the terminal could draw.
+// Others like dim and italic are ignored.
+var gitColorAttrs = map[string]Attribute{
+	"bold":    AttrBold,
+	"ul":      AttrUnderline,
+	"reverse": AttrReverse,
+}
+
+// gitIgnoredAttrs are attributes of git's color syntax, those dig doesn't draw.
+var gitIgnoredAttrs = map[string]bool{"dim": true, "blink": true, "italic": true, "strike": true}
+
+// parseGitColor parses a color of git config, like "bold red" or "#ff8700 black".
+// The first color is the foreground and the second is the background, "normal" keeps the color of c.
+// Attributes of c are replaced with the ones of the color.
+func parseGitColor(s string, c Color) (Color, error) {
+	colors := 0
+	var attr Attribute
+	// colors are below attributes like bold.
+	c.Fg &= AttrBold - 1
+	for _, f := range strings.Fields(strings.ToLower(s)) {
+		// an attribute could be turned off with no or no-, like nobold.
+		name := strings.TrimPrefix(strings.TrimPrefix(f, "no"), "-")
+		if a, ok := gitColorAttrs[f]; ok {
+			attr |= a
+			continue
+		}
+		if _, ok := gitColorAttrs[name]; ok && name != f {
+			continue
+		}
+		if gitIgnoredAttrs[name] {
+			continue
+		}
+		a, err := gitColor(f)
+		if err != nil {
+			return c, fmt.Errorf("invalid color: %s", s)
+		}
+		colors++
+		switch {
+		case colors > 2:
+			return c, fmt.Errorf("more than two colors: %s", s)
+		case f == "normal":
+		case colors == 1:
+			c.Fg = a
+		default:
+			c.Bg = a
+		}
+	}
+	c.Fg |= attr
+	return c, nil
+}
+
+// gitColor returns a color of git's color syntax, a name, bright and a name, a number of 256 colors or #rrggbb.
+func gitColor(s string) (Attribute, error) {
+	switch s {
+	case "normal":
+		return 0, nil
+	case "default":
+		return ColorDefault, nil
+	}
+	if c, ok := colorNames[s]; ok {
+		return c, nil
+	}
+	if c, ok := colorNames[strings.TrimPrefix(s, "bright")]; ok {
+		// bright colors are 8-15 of 256 colors, next to the basic ones.
+		return color256(int(c-ColorBlack) + 8), nil
+	}
+	if strings.HasPrefix(s, "#") {
+		return hexColor(s)
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 255 {
+		return 0, fmt.Errorf("unknown color: %s", s)
+	}
+	return color256(n), nil
+}
+
+// applyGitColors sets colors of the theme's diffs to the user's color.diff.* slots of git config.
+// Slots those aren't configured keep the theme's colors.
+func applyGitColors(t *Theme, repoDir string) error {
+	out, err := gitCommand(repoDir, "config", "--get-regexp", `^color\.diff\.`).Output()
+	if err != nil {
+		// git config exits with 1 when nothing matches.
+		return nil
+	}
+	slots := map[string]*Color{
+		"new":  &t.Added,
+		"old":  &t.Deleted,
+		"frag": &t.Frag,
+		"meta": &t.Meta,
+	}
+	fgs := map[string]*Attribute{
+		"func":   &t.Func,
+		"commit": &t.Hash,
+	}
+	for _, ln := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		f := strings.SplitN(ln, " ", 2)
+		if len(f) != 2 {
+			continue
+		}
+		slot := strings.ToLower(strings.TrimPrefix(f[0], "color.diff."))
+		if c, ok := slots[slot]; ok {
+			if *c, err = parseGitColor(f[1], *c); err != nil {
+				return fmt.Errorf("color.diff.%s: %v", slot, err)
+			}
+		} else if fg, ok := fgs[slot]; ok {
+			c, err := parseGitColor(f[1], Color{Fg: *fg})
+			if err != nil {
+				return fmt.Errorf("color.diff.%s: %v", slot, err)
+			}
+			*fg = c.Fg
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestGitColors(t *testing.T) {
+	r := historyFixture(t)
+	r.git("config", "color.diff.new", "bold 208")
+	r.git("config", "color.diff.frag", "#00ffff black")
+	r.git("config", "color.diff.commit", "brightyellow")
+	r.git("config", "color.diff.old", "normal ul")
+	th := darkTheme.copy()
+	if err := applyGitColors(th, r.Dir); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Color{
+		"new":  {color256(208) | AttrBold, darkTheme.Added.Bg},
+		"frag": {rgbColor(0x00ffff), ColorBlack},
+		"old":  {darkTheme.Deleted.Fg&^AttrBold | AttrUnderline, darkTheme.Deleted.Bg},
+		"meta": darkTheme.Meta,
+	}
+	got := map[string]Color{"new": th.Added, "frag": th.Frag, "old": th.Deleted, "meta": th.Meta}
+	for slot, c := range want {
+		if got[slot] != c {
+			t.Errorf("color.diff.%s: got %v, want %v", slot, got[slot], c)
+		}
+	}
+	if th.Hash != color256(11) {
+		t.Errorf("color.diff.commit: got %v", th.Hash)
+	}
+	r.git("config", "color.diff.meta", "bold purple")
+	if err := applyGitColors(darkTheme.copy(), r.Dir); err == nil {
+		t.Error("expected an error of an unknown color")
+	}
+}
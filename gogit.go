@@ -0,0 +1,969 @@
+package main
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// newBackend returns the backend of the name, one of auto, git and go-git.
+// auto runs git when it's installed, and reads repositories with go-git when it isn't.
+func newBackend(name string) GitBackend {
+	switch name {
+	case "git":
+		return execBackend{}
+	case "go-git":
+		return newGogitBackend()
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return newGogitBackend()
+	}
+	return execBackend{}
+}
+
+// gogitBackend is a GitBackend reads repositories with go-git, without the git binary.
+//
+// It walks history as git log does by default, and --all, --not, --full-history,
+// ranges and pathspecs are what it knows of targets.
+// It doesn't know log_args and log_fields, those are for git log.
+// Diffs of merges are empty, as go-git doesn't make combined diffs.
+type gogitBackend struct {
+	// mu serializes reads, as a go-git repository isn't safe for concurrent use.
+	mu    sync.Mutex
+	repos map[string]*gogitRepo
+}
+
+func newGogitBackend() *gogitBackend {
+	return &gogitBackend{repos: make(map[string]*gogitRepo)}
+}
+
+// gogitRepo is an opened repository.
+type gogitRepo struct {
+	*git.Repository
+	// prefix is the directory from the root of the work tree, that paths are relative to, like git does.
+	// It's empty at the root, otherwise it ends with a slash.
+	prefix string
+}
+
+// open opens the repository of the directory, or returns the one opened already.
+// It should be called with the lock held.
+func (b *gogitBackend) open(dir string) (*gogitRepo, error) {
+	if r, ok := b.repos[dir]; ok {
+		return r, nil
+	}
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, err
+	}
+	r := &gogitRepo{Repository: repo}
+	if wt, err := repo.Worktree(); err == nil {
+		root, err1 := filepath.EvalSymlinks(wt.Filesystem.Root())
+		abs, err2 := filepath.Abs(dir)
+		if err1 == nil && err2 == nil {
+			abs, err2 = filepath.EvalSymlinks(abs)
+		}
+		if err1 == nil && err2 == nil {
+			if rel, err := filepath.Rel(root, abs); err == nil && rel != "." {
+				r.prefix = filepath.ToSlash(rel) + "/"
+			}
+		}
+	}
+	b.repos[dir] = r
+	return r, nil
+}
+
+func (b *gogitBackend) Commits(dir string, targets []string, digUp bool) ([]*Commit, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	w, err := b.walk(dir, targets, nil)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := w.all()
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, errors.New("no commits")
+	}
+	if digUp {
+		reverseCommits(commits)
+	}
+	return commits, nil
+}
+
+func (b *gogitBackend) LogCommits(dir string, targets []string, digUp bool) (CommitLog, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	w, err := b.walk(dir, targets, nil)
+	if err != nil {
+		return nil, err
+	}
+	l := &gogitLog{b: b, w: w}
+	if digUp {
+		// the oldest is the last one of the walk, like git log --reverse.
+		l.commits, err = w.all()
+		if err != nil {
+			return nil, err
+		}
+		reverseCommits(l.commits)
+		l.w = nil
+	}
+	return l, nil
+}
+
+// gogitLog is a CommitLog of a walk, or commits those are walked already.
+type gogitLog struct {
+	b       *gogitBackend
+	w       *commitWalk
+	commits []*Commit
+	stopped atomic.Bool
+}
+
+func (l *gogitLog) Read(n int) ([]*Commit, bool, error) {
+	if l.w == nil {
+		n = min(n, len(l.commits))
+		commits := l.commits[:n]
+		l.commits = l.commits[n:]
+		return commits, len(l.commits) == 0 || l.stopped.Load(), nil
+	}
+	l.b.mu.Lock()
+	defer l.b.mu.Unlock()
+	var commits []*Commit
+	for len(commits) < n {
+		if l.stopped.Load() {
+			return nil, true, nil
+		}
+		c, err := l.w.next()
+		if err != nil {
+			return nil, true, err
+		}
+		if c == nil {
+			return commits, true, nil
+		}
+		commits = append(commits, c)
+	}
+	return commits, false, nil
+}
+
+func (l *gogitLog) Stop() {
+	l.stopped.Store(true)
+}
+
+func (b *gogitBackend) CommitDiff(dir, hash string, paths []string, full bool) ([][]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r, err := b.open(dir)
+	if err != nil {
+		return nil, err
+	}
+	specs, err := compilePathspecs(paths, r.prefix)
+	if err != nil {
+		return nil, err
+	}
+	c, err := r.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return nil, err
+	}
+	// the header is the medium format of git show.
+	var out strings.Builder
+	fmt.Fprintf(&out, "commit %s\n", c.Hash)
+	if len(c.ParentHashes) > 1 {
+		abbrevs := make([]string, len(c.ParentHashes))
+		for i, p := range c.ParentHashes {
+			abbrevs[i] = p.String()[:7]
+		}
+		fmt.Fprintf(&out, "Merge: %s\n", strings.Join(abbrevs, " "))
+	}
+	fmt.Fprintf(&out, "Author: %s <%s>\n", c.Author.Name, c.Author.Email)
+	fmt.Fprintf(&out, "Date:   %s\n\n", c.Author.When.Format("Mon Jan 2 15:04:05 2006 -0700"))
+	for _, ln := range strings.Split(strings.TrimRight(c.Message, "\n"), "\n") {
+		if ln != "" {
+			out.WriteString("    " + ln)
+		}
+		out.WriteString("\n")
+	}
+	if len(c.ParentHashes) <= 1 {
+		changes, err := r.changes(c, 0, specs)
+		if err != nil {
+			return nil, err
+		}
+		if len(changes) != 0 {
+			patch, err := changes.Patch()
+			if err != nil {
+				return nil, err
+			}
+			out.WriteString("\n" + patch.String())
+		}
+	}
+	// tab handling in screen is quite awkard. handle it here.
+	text := strings.Replace(out.String(), "\t", "    ", -1)
+	text = strings.TrimRight(text, " \n")
+	lines := [][]byte{}
+	for _, ln := range strings.Split(text, "\n") {
+		lines = append(lines, []byte(ln))
+	}
+	if limit := config.DiffLimit; !full && limit != 0 && len(lines) > limit {
+		return lines[:limit], &cutError{fmt.Sprintf("the diff is cut at %d lines by diff_limit", limit)}
+	}
+	return lines, nil
+}
+
+func (b *gogitBackend) ChangedFiles(dir string, targets []string) (map[string][]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	w, err := b.walk(dir, targets, nil)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]string)
+	for {
+		c, err := w.nextObject()
+		if err != nil {
+			return nil, err
+		}
+		if c == nil {
+			return files, nil
+		}
+		fs := []string{}
+		if len(c.ParentHashes) <= 1 {
+			// like git log --name-only, files are limited to the pathspecs.
+			changes, err := w.r.changes(c, 0, w.specs)
+			if err != nil {
+				return nil, err
+			}
+			for _, ch := range changes {
+				fs = append(fs, changeName(ch))
+			}
+			sort.Strings(fs)
+		}
+		files[c.Hash.String()] = fs
+	}
+}
+
+func (b *gogitBackend) PathCommits(dir string, targets, pathspecs []string) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	w, err := b.walk(dir, targets, pathspecs)
+	if err != nil {
+		return nil, err
+	}
+	hashes := []string{}
+	for {
+		c, err := w.nextObject()
+		if err != nil {
+			return nil, err
+		}
+		if c == nil {
+			return hashes, nil
+		}
+		hashes = append(hashes, c.Hash.String())
+	}
+}
+
+func (b *gogitBackend) RevParse(dir string, revs []string) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r, err := b.open(dir)
+	if err != nil {
+		return nil, err
+	}
+	hashes := []string{}
+	not := false
+	// negated hashes are after ^.
+	neg := func(h plumbing.Hash, not bool) string {
+		if not {
+			return "^" + h.String()
+		}
+		return h.String()
+	}
+	for _, rev := range revs {
+		switch {
+		case rev == "--not":
+			not = !not
+		case rev == "--all":
+			tips, err := r.allTips()
+			if err != nil {
+				return nil, err
+			}
+			for _, h := range tips {
+				hashes = append(hashes, neg(h, not))
+			}
+		case strings.HasPrefix(rev, "-"):
+			// options are output as they are, like git rev-parse.
+			hashes = append(hashes, rev)
+		case strings.Contains(rev, ".."):
+			from, to, err := r.resolveRange(rev)
+			if err != nil {
+				return nil, err
+			}
+			hashes = append(hashes, neg(to, not), neg(from, !not))
+		default:
+			n := not
+			if strings.HasPrefix(rev, "^") {
+				rev, n = rev[1:], !n
+			}
+			h, err := r.resolve(rev)
+			if err != nil {
+				return nil, err
+			}
+			hashes = append(hashes, neg(h, n))
+		}
+	}
+	return hashes, nil
+}
+
+func (b *gogitBackend) Blame(dir, rev, path string) ([]BlameLine, map[string]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r, err := b.open(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	h, err := r.resolve(rev)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := r.CommitObject(h)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := git.Blame(c, r.prefix+path)
+	if err != nil {
+		return nil, nil, err
+	}
+	lines := []BlameLine{}
+	authors := make(map[string]string)
+	for i, l := range res.Lines {
+		// go-git doesn't know the line number in the commit, it's the one in the file instead.
+		lines = append(lines, BlameLine{
+			Hash:     l.Hash.String(),
+			OrigLine: i + 1,
+			Text:     strings.Replace(l.Text, "\t", "    ", -1),
+		})
+		authors[l.Hash.String()] = l.AuthorName
+	}
+	return lines, authors, nil
+}
+
+func (b *gogitBackend) RenamedFrom(dir, hash, path string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r, err := b.open(dir)
+	if err != nil {
+		return "", err
+	}
+	c, err := r.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return "", err
+	}
+	if len(c.ParentHashes) == 0 {
+		return "", fmt.Errorf("%s doesn't have a parent", shortHash(hash))
+	}
+	changes, err := r.changesWith(c, 0, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return "", err
+	}
+	for _, ch := range changes {
+		if ch.To.Name == path && ch.From.Name != "" {
+			return ch.From.Name, nil
+		}
+	}
+	return path, nil
+}
+
+func (b *gogitBackend) Tree(dir, hash, treeDir string) ([]*TreeNode, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r, err := b.open(dir)
+	if err != nil {
+		return nil, err
+	}
+	c, err := r.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return nil, err
+	}
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+	// paths are relative to the directory, like git ls-tree.
+	if sub := strings.TrimSuffix(r.prefix+treeDir, "/"); sub != "" {
+		tree, err = tree.Tree(sub)
+		if err == object.ErrDirectoryNotFound {
+			return []*TreeNode{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	nodes := []*TreeNode{}
+	for _, e := range tree.Entries {
+		n := &TreeNode{
+			Mode: fmt.Sprintf("%06o", uint32(e.Mode)),
+			Type: "blob",
+			Hash: e.Hash.String(),
+			Size: "-",
+			Path: treeDir + e.Name,
+		}
+		switch e.Mode {
+		case filemode.Dir:
+			n.Type = "tree"
+		case filemode.Submodule:
+			n.Type = "commit"
+		default:
+			obj, err := r.Storer.EncodedObject(plumbing.BlobObject, e.Hash)
+			if err != nil {
+				return nil, err
+			}
+			n.Size = fmt.Sprint(obj.Size())
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+func (b *gogitBackend) Blob(dir, hash string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r, err := b.open(dir)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := r.BlobObject(plumbing.NewHash(hash))
+	if err != nil {
+		return nil, err
+	}
+	rd, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+	return io.ReadAll(rd)
+}
+
+// resolve returns the commit hash of the revision.
+func (r *gogitRepo) resolve(rev string) (plumbing.Hash, error) {
+	h, err := r.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("unknown revision: %s", rev)
+	}
+	return *h, nil
+}
+
+// resolveRange resolves a range like from..to, an empty end of it is HEAD.
+func (r *gogitRepo) resolveRange(rev string) (from, to plumbing.Hash, err error) {
+	if strings.Contains(rev, "...") {
+		return from, to, fmt.Errorf("%s: symmetric difference needs git", rev)
+	}
+	i := strings.Index(rev, "..")
+	ends := []string{rev[:i], rev[i+2:]}
+	for j, e := range ends {
+		if e == "" {
+			ends[j] = "HEAD"
+		}
+	}
+	if from, err = r.resolve(ends[0]); err != nil {
+		return from, to, err
+	}
+	to, err = r.resolve(ends[1])
+	return from, to, err
+}
+
+// allTips returns commits of HEAD and all refs, for --all.
+func (r *gogitRepo) allTips() ([]plumbing.Hash, error) {
+	var tips []plumbing.Hash
+	if h, err := r.resolve("HEAD"); err == nil {
+		tips = append(tips, h)
+	}
+	refs, err := r.References()
+	if err != nil {
+		return nil, err
+	}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference || ref.Name() == plumbing.HEAD {
+			return nil
+		}
+		if h, err := r.resolve(ref.Name().String()); err == nil {
+			tips = append(tips, h)
+		}
+		return nil
+	})
+	return tips, err
+}
+
+// refNames returns names of refs pointing to each commit, like %D of git log.
+func (r *gogitRepo) refNames() (map[plumbing.Hash][]string, error) {
+	names := make(map[plumbing.Hash][]string)
+	headBranch := ""
+	if head, err := r.Reference(plumbing.HEAD, false); err == nil && head.Type() == plumbing.SymbolicReference {
+		headBranch = head.Target().String()
+	}
+	refs, err := r.References()
+	if err != nil {
+		return nil, err
+	}
+	var others []*plumbing.Reference
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference && ref.Name() != plumbing.HEAD {
+			others = append(others, ref)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if h, err := r.resolve("HEAD"); err == nil {
+		if headBranch != "" {
+			names[h] = append(names[h], "HEAD -> "+plumbing.ReferenceName(headBranch).Short())
+		} else {
+			names[h] = append(names[h], "HEAD")
+		}
+	}
+	sort.Slice(others, func(i, j int) bool { return others[i].Name() < others[j].Name() })
+	for _, ref := range others {
+		name := ref.Name()
+		if name.String() == headBranch {
+			continue
+		}
+		h, err := r.resolve(name.String())
+		if err != nil {
+			continue
+		}
+		switch {
+		case name.IsTag():
+			names[h] = append(names[h], "tag: "+name.Short())
+		case name.IsBranch(), name.IsRemote():
+			names[h] = append(names[h], name.Short())
+		}
+	}
+	return names, nil
+}
+
+// changes returns changes of the commit from it's i-th parent, or from nothing for a root commit.
+// They're limited to the pathspecs if any.
+func (r *gogitRepo) changes(c *object.Commit, i int, specs []pathspec) (object.Changes, error) {
+	changes, err := r.changesWith(c, i, nil)
+	if err != nil || len(specs) == 0 {
+		return changes, err
+	}
+	matched := object.Changes{}
+	for _, ch := range changes {
+		if matchPathspecs(specs, ch.From.Name) || matchPathspecs(specs, ch.To.Name) {
+			matched = append(matched, ch)
+		}
+	}
+	return matched, nil
+}
+
+// changesWith is changes with options of go-git, like detecting renames.
+func (r *gogitRepo) changesWith(c *object.Commit, i int, opts *object.DiffTreeOptions) (object.Changes, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+	var from *object.Tree
+	if i < len(c.ParentHashes) {
+		p, err := r.CommitObject(c.ParentHashes[i])
+		if err != nil {
+			return nil, err
+		}
+		if from, err = p.Tree(); err != nil {
+			return nil, err
+		}
+	}
+	return object.DiffTreeWithOptions(gitCtx, from, tree, opts)
+}
+
+// changeName returns the path of the change, it's old path when it's deleted.
+func changeName(ch *object.Change) string {
+	if ch.To.Name != "" {
+		return ch.To.Name
+	}
+	return ch.From.Name
+}
+
+// commitWalk walks commits of targets from the newest, like git log.
+type commitWalk struct {
+	r           *gogitRepo
+	queue       commitQueue
+	seen        map[plumbing.Hash]bool
+	hidden      map[plumbing.Hash]bool
+	specs       []pathspec
+	fullHistory bool
+	refs        map[plumbing.Hash][]string
+	seq         int
+}
+
+// walk starts a walk of the targets, those are revisions and paths after --, and the pathspecs.
+// It should be called with the lock held.
+func (b *gogitBackend) walk(dir string, targets, pathspecs []string) (*commitWalk, error) {
+	if len(config.LogArgs) != 0 || len(config.LogFields) != 0 {
+		return nil, errors.New("log_args and log_fields need git")
+	}
+	r, err := b.open(dir)
+	if err != nil {
+		return nil, err
+	}
+	w := &commitWalk{r: r, seen: make(map[plumbing.Hash]bool), hidden: make(map[plumbing.Hash]bool)}
+	var include, exclude []plumbing.Hash
+	var paths []string
+	not, revs := false, false
+	for i := 0; i < len(targets); i++ {
+		t := targets[i]
+		switch {
+		case t == "--":
+			paths = append(paths, targets[i+1:]...)
+			i = len(targets)
+		case t == "--not":
+			not = !not
+		case t == "--all":
+			tips, err := r.allTips()
+			if err != nil {
+				return nil, err
+			}
+			if not {
+				exclude = append(exclude, tips...)
+			} else {
+				include = append(include, tips...)
+			}
+			revs = true
+		case t == "--full-history":
+			w.fullHistory = true
+		case strings.HasPrefix(t, "-"):
+			return nil, fmt.Errorf("%s needs git", t)
+		case strings.Contains(t, ".."):
+			from, to, err := r.resolveRange(t)
+			if err != nil {
+				return nil, err
+			}
+			include, exclude = append(include, to), append(exclude, from)
+			revs = true
+		default:
+			neg := not
+			if strings.HasPrefix(t, "^") {
+				t, neg = t[1:], !neg
+			}
+			h, err := r.resolve(t)
+			if err != nil {
+				return nil, err
+			}
+			if neg {
+				exclude = append(exclude, h)
+			} else {
+				include = append(include, h)
+			}
+			revs = true
+		}
+	}
+	if !revs {
+		h, err := r.resolve("HEAD")
+		if err != nil {
+			return nil, err
+		}
+		include = append(include, h)
+	}
+	if w.specs, err = compilePathspecs(append(paths, pathspecs...), r.prefix); err != nil {
+		return nil, err
+	}
+	if w.refs, err = r.refNames(); err != nil {
+		return nil, err
+	}
+	if err := w.hide(exclude); err != nil {
+		return nil, err
+	}
+	for _, h := range include {
+		if err := w.push(h); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// hide hides the commits and their ancestors from the walk.
+func (w *commitWalk) hide(hashes []plumbing.Hash) error {
+	for len(hashes) != 0 {
+		h := hashes[len(hashes)-1]
+		hashes = hashes[:len(hashes)-1]
+		if w.hidden[h] {
+			continue
+		}
+		w.hidden[h] = true
+		c, err := w.r.CommitObject(h)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, c.ParentHashes...)
+	}
+	return nil
+}
+
+// push adds the commit to the walk, unless it's walked or hidden.
+func (w *commitWalk) push(h plumbing.Hash) error {
+	if w.seen[h] || w.hidden[h] {
+		return nil
+	}
+	w.seen[h] = true
+	c, err := w.r.CommitObject(h)
+	if err != nil {
+		return err
+	}
+	w.seq++
+	heap.Push(&w.queue, queuedCommit{c, w.seq})
+	return nil
+}
+
+// nextObject returns the next commit of the walk, or nil at the end.
+// With pathspecs, a commit is walked when it's different from all of it's parents in them,
+// and only a parent that's same with a merge is followed, like git log.
+// With --full-history, all parents are followed, and a commit different from any of them is walked.
+func (w *commitWalk) nextObject() (*object.Commit, error) {
+	for w.queue.Len() != 0 {
+		c := heap.Pop(&w.queue).(queuedCommit).Commit
+		parents, show := c.ParentHashes, true
+		if len(w.specs) != 0 {
+			show = false
+			if len(c.ParentHashes) == 0 {
+				changes, err := w.r.changes(c, 0, w.specs)
+				if err != nil {
+					return nil, err
+				}
+				show = len(changes) != 0
+			}
+			for i, p := range c.ParentHashes {
+				changes, err := w.r.changes(c, i, w.specs)
+				if err != nil {
+					return nil, err
+				}
+				if len(changes) != 0 {
+					show = true
+					continue
+				}
+				if !w.fullHistory {
+					show, parents = false, []plumbing.Hash{p}
+					break
+				}
+			}
+		}
+		for _, p := range parents {
+			if err := w.push(p); err != nil {
+				return nil, err
+			}
+		}
+		if show {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+// next returns the next commit of the walk as a Commit of dig, or nil at the end.
+func (w *commitWalk) next() (*Commit, error) {
+	c, err := w.nextObject()
+	if c == nil || err != nil {
+		return nil, err
+	}
+	return w.commit(c), nil
+}
+
+// all returns all the commits of the walk.
+func (w *commitWalk) all() ([]*Commit, error) {
+	commits := []*Commit{}
+	for {
+		c, err := w.next()
+		if err != nil {
+			return nil, err
+		}
+		if c == nil {
+			return commits, nil
+		}
+		commits = append(commits, c)
+	}
+}
+
+// commit returns the Commit of dig, like parseCommit does for git log.
+func (w *commitWalk) commit(c *object.Commit) *Commit {
+	// tab handling in screen is quite awkard. handle it here.
+	tabs := strings.NewReplacer("\t", "    ")
+	title, body := splitMessage(tabs.Replace(c.Message))
+	parents := make([]string, len(c.ParentHashes))
+	for i, p := range c.ParentHashes {
+		parents[i] = p.String()
+	}
+	return &Commit{
+		Hash:           c.Hash.String(),
+		Abbrev:         c.Hash.String()[:7],
+		Time:           c.Committer.When,
+		Author:         tabs.Replace(c.Author.Name),
+		Email:          c.Author.Email,
+		Parents:        parents,
+		AuthorTime:     c.Author.When,
+		Committer:      tabs.Replace(c.Committer.Name),
+		CommitterEmail: c.Committer.Email,
+		Title:          title,
+		Body:           body,
+		Refs:           w.refs[c.Hash],
+	}
+}
+
+// splitMessage splits a commit message to it's title and body, like %s and %b of git log.
+// The title is the first paragraph in a line.
+func splitMessage(msg string) (title, body string) {
+	msg = strings.TrimLeft(msg, "\n")
+	title = msg
+	if i := strings.Index(msg, "\n\n"); i != -1 {
+		title, body = msg[:i], strings.TrimLeft(msg[i+2:], "\n")
+	}
+	title = strings.Join(strings.Split(strings.TrimSpace(title), "\n"), " ")
+	return title, strings.TrimRight(body, "\n")
+}
+
+// queuedCommit is a commit in the queue of a walk.
+// seq keeps the order of commits of the same time, as they're queued.
+type queuedCommit struct {
+	*object.Commit
+	seq int
+}
+
+// commitQueue is a heap of commits, the newest first.
+type commitQueue []queuedCommit
+
+func (q commitQueue) Len() int { return len(q) }
+func (q commitQueue) Less(i, j int) bool {
+	ti, tj := q[i].Committer.When, q[j].Committer.When
+	if ti.Equal(tj) {
+		return q[i].seq < q[j].seq
+	}
+	return ti.After(tj)
+}
+func (q commitQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x any)   { *q = append(*q, x.(queuedCommit)) }
+func (q *commitQueue) Pop() any {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+// pathspec is a compiled pathspec of git. It matches the path, a directory of it,
+// or paths those match it's wildcards.
+type pathspec struct {
+	path string
+	re   *regexp.Regexp
+}
+
+// compilePathspecs compiles pathspecs those are relative to the prefix, see gogitRepo.
+// Magic of them could be literal, glob and top, like :(glob)src/**/*.go.
+func compilePathspecs(specs []string, prefix string) ([]pathspec, error) {
+	var compiled []pathspec
+	for _, s := range specs {
+		literal, glob, top := false, false, false
+		switch {
+		case strings.HasPrefix(s, ":("):
+			i := strings.Index(s, ")")
+			if i == -1 {
+				return nil, fmt.Errorf("invalid pathspec: %s", s)
+			}
+			for _, m := range strings.Split(s[2:i], ",") {
+				switch m {
+				case "literal":
+					literal = true
+				case "glob":
+					glob = true
+				case "top":
+					top = true
+				default:
+					return nil, fmt.Errorf("pathspec magic %s needs git", m)
+				}
+			}
+			s = s[i+1:]
+		case strings.HasPrefix(s, ":/"):
+			s, top = s[2:], true
+		case strings.HasPrefix(s, ":"):
+			return nil, fmt.Errorf("pathspec magic of %s needs git", s)
+		}
+		if !top {
+			s = prefix + s
+		}
+		s = strings.TrimPrefix(s, "./")
+		p := pathspec{path: strings.TrimSuffix(s, "/")}
+		if !literal && strings.ContainsAny(s, "*?[") {
+			re, err := regexp.Compile(globRegexp(s, glob))
+			if err != nil {
+				return nil, fmt.Errorf("invalid pathspec: %s", s)
+			}
+			p.re = re
+		}
+		compiled = append(compiled, p)
+	}
+	return compiled, nil
+}
+
+// matchPathspecs reports whether the path matches any of the pathspecs.
+func matchPathspecs(specs []pathspec, path string) bool {
+	if path == "" {
+		return false
+	}
+	for _, s := range specs {
+		if s.path == "" || path == s.path || strings.HasPrefix(path, s.path+"/") {
+			return true
+		}
+		if s.re != nil && s.re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// globRegexp returns a regexp of the wildcards of git.
+// Wildcards match slashes too, unless it's a glob, then ** matches directories.
+func globRegexp(s string, glob bool) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(s); i++ {
+		switch {
+		case glob && strings.HasPrefix(s[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case glob && s[i:] == "/**":
+			b.WriteString("/.*")
+			i += 2
+		case s[i] == '*' && glob:
+			b.WriteString("[^/]*")
+		case s[i] == '*':
+			b.WriteString(".*")
+		case s[i] == '?' && glob:
+			b.WriteString("[^/]")
+		case s[i] == '?':
+			b.WriteString(".")
+		case s[i] == '[' && strings.Contains(s[i+1:], "]"):
+			j := i + 1 + strings.Index(s[i+1:], "]")
+			class := s[i+1 : j]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i = j
+		case s[i] == '\\' && i+1 < len(s):
+			b.WriteString(regexp.QuoteMeta(s[i+1 : i+2]))
+			i++
+		default:
+			b.WriteString(regexp.QuoteMeta(s[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
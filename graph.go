@@ -0,0 +1,233 @@
+package main
+
+// graphMaxColumns is how many columns of the graph are drawn at most.
+// The others are cut with …, as titles are more important.
+const graphMaxColumns = 8
+
+// graphCell is a cell of a graph row. Lane colors it.
+type graphCell struct {
+	Ch   rune
+	Lane int
+}
+
+// Graph is the graph of commits, like git log --graph. See CommitGraph.
+type Graph struct {
+	// Rows are rows of commits by their hashes.
+	Rows map[string][]graphCell
+	// Width is width of the widest row, in cells.
+	Width int
+}
+
+// CommitGraph returns the graph of all commits.
+// It's built at the first call, as it's only needed when it's shown.
+func (p *Program) CommitGraph() *Graph {
+	if p.Graph == nil {
+		p.Graph = commitGraph(p.All, p.DigUp)
+	}
+	return p.Graph
+}
+
+// ShowGraph reports whether the commit list shows the graph.
+// A filtered list doesn't, as parents of commits could be hidden.
+func (p *Program) ShowGraph() bool {
+	return config.Graph && len(p.Commits) == len(p.All)
+}
+
+// GraphWidth returns width of the graph in the commit list, 0 when it's not shown.
+func (p *Program) GraphWidth() int {
+	if !p.ShowGraph() {
+		return 0
+	}
+	return p.CommitGraph().Width
+}
+
+// graphFlip flips glyphs of the graph upside down, for digging up.
+var graphFlip = map[rune]rune{
+	'┘': '┐',
+	'┐': '┘',
+	'└': '┌',
+	'┌': '└',
+}
+
+// commitGraph draws a row for each commit, with the columns commitLanes assigns.
+// A row has a column for each line of development. The commit is ● in it's column,
+// and lines join or fork from it in the same row, so a row isn't needed for them like git log --graph.
+func commitGraph(commits []*Commit, digUp bool) *Graph {
+	g := &Graph{Rows: make(map[string][]graphCell, len(commits))}
+	// expect[i] is the commit the i-th column waits for, and lane[i] is it's lane.
+	var expect []string
+	var lane []int
+	next := 0
+	column := func() int {
+		for i, h := range expect {
+			if h == "" {
+				return i
+			}
+		}
+		expect = append(expect, "")
+		lane = append(lane, 0)
+		return len(expect) - 1
+	}
+	for n := range commits {
+		c := commits[n]
+		if digUp {
+			c = commits[len(commits)-1-n]
+		}
+		// glyphs of columns those are connected to the commit.
+		linked := make(map[int]rune)
+		col := -1
+		for i, h := range expect {
+			if h != c.Hash {
+				continue
+			}
+			if col == -1 {
+				col = i
+			} else {
+				// branches those joined here.
+				expect[i] = ""
+				linked[i] = '└'
+				if i > col {
+					linked[i] = '┘'
+				}
+			}
+		}
+		// columns those pass through the row, before the commit changes them.
+		passing := make([]bool, len(expect))
+		for i, h := range expect {
+			passing[i] = h != "" && h != c.Hash
+		}
+		if col == -1 {
+			col = column()
+			lane[col] = next
+			next++
+		}
+		expect[col] = ""
+		var merged []string
+		if len(c.Parents) != 0 {
+			expect[col] = c.Parents[0]
+			merged = c.Parents[1:]
+		}
+		for _, p := range merged {
+			waited := -1
+			for i, h := range expect {
+				if h == p {
+					waited = i
+					break
+				}
+			}
+			if waited != -1 {
+				if waited != col {
+					linked[waited] = '├'
+					if waited > col {
+						linked[waited] = '┤'
+					}
+				}
+				continue
+			}
+			i := column()
+			expect[i] = p
+			lane[i] = next
+			next++
+			_, joined := linked[i]
+			switch {
+			case joined && i > col:
+				// a branch joined in the column, and another forks from it.
+				linked[i] = '┤'
+			case joined:
+				linked[i] = '├'
+			case i > col:
+				linked[i] = '┐'
+			default:
+				linked[i] = '┌'
+			}
+		}
+		row := make([]graphCell, len(expect)*2)
+		for i := range expect {
+			row[i*2] = graphCell{' ', lane[i]}
+			row[i*2+1] = graphCell{' ', lane[i]}
+			if i < len(passing) && passing[i] {
+				row[i*2].Ch = '│'
+			}
+			if ch, ok := linked[i]; ok {
+				row[i*2].Ch = ch
+			}
+		}
+		row[col*2] = graphCell{'●', lane[col]}
+		// horizontal lines to linked columns, colored by the farther one they reach.
+		connect := func(from, step int) {
+			ln := -1
+			for i := from; i != col; i -= step {
+				if _, ok := linked[i]; ok {
+					ln = lane[i]
+				}
+				if ln == -1 {
+					continue
+				}
+				if i != from {
+					if _, ok := linked[i]; !ok {
+						if row[i*2].Ch == '│' {
+							row[i*2] = graphCell{'┼', ln}
+						} else {
+							row[i*2] = graphCell{'─', ln}
+						}
+					}
+				}
+				// the connector between the column and the one closer to the commit.
+				k := i*2 - 1
+				if step < 0 {
+					k = i*2 + 1
+				}
+				row[k] = graphCell{'─', ln}
+			}
+		}
+		connect(len(expect)-1, 1)
+		connect(0, -1)
+		// trailing empty columns aren't needed.
+		end := len(row)
+		for end > 0 && row[end-1].Ch == ' ' {
+			end--
+		}
+		row = row[:end]
+		if digUp {
+			for i, cell := range row {
+				if ch, ok := graphFlip[cell.Ch]; ok {
+					row[i].Ch = ch
+				}
+			}
+		}
+		g.Rows[c.Hash] = row
+		if len(row) > g.Width {
+			g.Width = len(row)
+		}
+	}
+	if g.Width > graphMaxColumns*2 {
+		g.Width = graphMaxColumns * 2
+	}
+	if g.Width != 0 {
+		// a space between the graph and what follows.
+		g.Width++
+	}
+	return g
+}
+
+// drawGraph draws the graph row of the commit at o of the l-th line, and returns width of it.
+// It's colored by lanes, or with the given color when the commit is selected.
+func drawGraph(bound Rect, l, o int, c *Commit, selected bool, color Color) int {
+	g := dig.CommitGraph()
+	row := g.Rows[c.Hash]
+	for k := 0; k < g.Width && o+k < bound.Size.O; k++ {
+		ch := ' '
+		fg := color.Fg
+		if k < len(row) {
+			ch = row[k].Ch
+			if !selected && len(theme.Lanes) != 0 {
+				fg = theme.Lanes[row[k].Lane%len(theme.Lanes)]
+			}
+		}
+		if k == graphMaxColumns*2-1 && len(row) > graphMaxColumns*2 {
+			ch = '…'
+		}
+		setCell(bound.Min.O+o+k, bound.Min.L+l, ch, fg, color.Bg)
+	}
+	return g.Width
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommitGraph(t *testing.T) {
+	r := historyFixture(t)
+	// rows returns graph rows of the commits, in their order.
+	rows := func(commits []*Commit, digUp bool) []string {
+		g := commitGraph(commits, digUp)
+		var s []string
+		for _, c := range commits {
+			var row []rune
+			for _, cell := range g.Rows[c.Hash] {
+				row = append(row, cell.Ch)
+			}
+			s = append(s, string(row))
+		}
+		return s
+	}
+	got := strings.Join(rows(r.Commits(false, "--all"), false), "\n")
+	want := strings.Join([]string{
+		"●",   // remove readme
+		"●─┐", // merge feature
+		"● │", // fix readme
+		"│ ●", // say hi
+		"●─┘", // add readme
+		"●",   // initial
+	}, "\n")
+	if got != want {
+		t.Errorf("graph:\n%s\nwant:\n%s", got, want)
+	}
+	got = strings.Join(rows(r.Commits(true, "HEAD"), true), "\n")
+	want = strings.Join([]string{"●", "●─┐", "│ ●", "● │", "●─┘"}, "\n")
+	if got != want {
+		t.Errorf("dug up graph:\n%s\nwant:\n%s", got, want)
+	}
+}
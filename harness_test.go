@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The fixture builds repositories scripted by steps, for tests those drive dig's logic end to end.
+// A repository is built in a temporary directory, with fixed names and dates,
+// so hashes and orders are the same for every run.
+
+// step is a step of a scripted repository. Fields are applied in the order of them.
+type step struct {
+	// Branch switches to the branch, creating it from HEAD if it doesn't exist.
+	Branch string
+	// Files are written with their contents, and Remove are removed.
+	Files  map[string]string
+	Remove []string
+	// Merge merges the branch to the current one, with Message.
+	Merge string
+	// Message commits the changes, with Author if it's given like "Bob <bob@example.com>".
+	Message string
+	Author  string
+	// Tag tags the commit.
+	Tag string
+}
+
+// fixture is a repository built by buildRepo.
+type fixture struct {
+	t   *testing.T
+	Dir string
+	// commits is how many commits are made, for their dates.
+	commits int
+}
+
+// buildRepo creates a repository by the steps.
+// The first branch is master, whatever the user's git config is.
+func buildRepo(t *testing.T, steps ...step) *fixture {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	r := &fixture{t: t, Dir: t.TempDir()}
+	r.git("init", "-q")
+	r.git("symbolic-ref", "HEAD", "refs/heads/master")
+	for _, s := range steps {
+		r.apply(s)
+	}
+	return r
+}
+
+// apply applies the step to the repository.
+func (r *fixture) apply(s step) {
+	r.t.Helper()
+	if s.Branch != "" {
+		if r.git("branch", "--list", s.Branch) == "" && r.commits != 0 {
+			r.git("checkout", "-q", "-b", s.Branch)
+		} else if r.commits != 0 {
+			r.git("checkout", "-q", s.Branch)
+		} else {
+			// no commit to branch from, it becomes the first branch.
+			r.git("symbolic-ref", "HEAD", "refs/heads/"+s.Branch)
+		}
+	}
+	for name, content := range s.Files {
+		path := filepath.Join(r.Dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			r.t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			r.t.Fatal(err)
+		}
+		r.git("add", name)
+	}
+	for _, name := range s.Remove {
+		r.git("rm", "-q", name)
+	}
+	if s.Merge != "" {
+		r.commits++
+		r.commit(s.Author, "merge", "-q", "--no-ff", "-m", s.Message, s.Merge)
+	} else if s.Message != "" {
+		r.commits++
+		r.commit(s.Author, "commit", "-q", "--allow-empty", "-m", s.Message)
+	}
+	if s.Tag != "" {
+		r.git("tag", s.Tag)
+	}
+}
+
+// commit runs a git command that makes a commit, a day after the previous one.
+func (r *fixture) commit(author string, args ...string) {
+	r.t.Helper()
+	name, email := "Alice", "alice@example.com"
+	if author != "" {
+		i := strings.Index(author, " <")
+		name, email = author[:i], strings.Trim(author[i+2:], ">")
+	}
+	date := fmt.Sprintf("%d +0000", 1577836800+r.commits*86400)
+	r.run([]string{
+		"GIT_AUTHOR_NAME=" + name, "GIT_AUTHOR_EMAIL=" + email, "GIT_AUTHOR_DATE=" + date,
+		"GIT_COMMITTER_DATE=" + date,
+	}, args...)
+}
+
+// git runs git in the repository, and returns it's output without the last newline.
+func (r *fixture) git(args ...string) string {
+	r.t.Helper()
+	return r.run(nil, args...)
+}
+
+// run runs git with the environment variables.
+func (r *fixture) run(env []string, args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.Dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Alice", "GIT_AUTHOR_EMAIL=alice@example.com",
+		"GIT_COMMITTER_NAME=Alice", "GIT_COMMITTER_EMAIL=alice@example.com",
+		"GIT_CONFIG_NOSYSTEM=1", "HOME="+r.Dir,
+	)
+	cmd.Env = append(cmd.Env, env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+// Hash returns the full hash of the revision.
+func (r *fixture) Hash(rev string) string {
+	r.t.Helper()
+	return r.git("rev-parse", rev)
+}
+
+// Commits returns commits of the targets, as dig loads them.
+func (r *fixture) Commits(digUp bool, targets ...string) []*Commit {
+	r.t.Helper()
+	commits, err := allCommits(r.Dir, targets, digUp)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return commits
+}
+
+// titles returns titles of the commits.
+func titles(commits []*Commit) string {
+	s := make([]string, len(commits))
+	for i, c := range commits {
+		s[i] = c.Title
+	}
+	return strings.Join(s, ", ")
+}
+
+// historyFixture is a history with a feature branch that is merged, and another that isn't.
+func historyFixture(t *testing.T) *fixture {
+	return buildRepo(t,
+		step{Files: map[string]string{"main.go": "package main\n\nfunc main() {\n}\n"}, Message: "initial"},
+		step{Files: map[string]string{"README": "dig\n"}, Message: "add readme", Tag: "v0.1"},
+		step{Branch: "feature", Files: map[string]string{"main.go": "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"}, Message: "say hi", Author: "Bob <bob@example.com>"},
+		step{Branch: "master", Files: map[string]string{"README": "dig, git history\n"}, Message: "fix readme"},
+		step{Merge: "feature", Message: "merge feature"},
+		step{Branch: "wip", Remove: []string{"README"}, Message: "remove readme"},
+		step{Branch: "master"},
+	)
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// helpKey is a key binding listed in the help.
+// Keys are separated by spaces, a key is a rune, a prefixed key like gp, or a name like up or ctrl+o.
+type helpKey struct {
+	Keys string
+	Desc string
+}
+
+// helpSection is key bindings of views. It's global when it doesn't have a view.
+type helpSection struct {
+	Title string
+	Views []View
+	Keys  []helpKey
+}
+
+// helpSections are key bindings of dig, those are listed by ?.
+// Keep them in sync with handlers, when a key is added or changed.
+var helpSections = []helpSection{
+	{Title: "global", Keys: []helpKey{
+		{"?", "show this help"},
+		{"enter tab .", "toggle the commit list and the diff"},
+		{"esc q", "go back"},
+		{"ctrl+q", "quit, q also quits in the commit list"},
+		{":", "run a command"},
+		{"ctrl+f", "find"},
+		{"n N", "next and previous match"},
+		{"L", "list matches"},
+		{"ctrl+o", "jump back"},
+		{"ctrl+y", "copy mode"},
+		{"< >", "shrink and expand the side"},
+		{"gt gT", "next and previous tab"},
+		{"t", "files of the commit"},
+		{"s", "stat of the commit"},
+		{"C", "changed files of the commit"},
+		{"D", "open the diff in the diff tool"},
+		{"Y", "send the hash, the file or the line"},
+		{"1-9", "count for the next key, like 5k"},
+	}},
+	{Title: "commit list", Views: []View{CommitView}, Keys: []helpKey{
+		{"i up", "up"},
+		{"k down", "down"},
+		{"b pgup", "page up"},
+		{"f pgdn", "page down"},
+		{"u d", "half page up and down"},
+		{"home end G", "first and last commit, G goes to the count-th"},
+		{"/", "filter as you type"},
+		{"A", "toggle the author filter"},
+		{"F", "toggle the files filter"},
+		{"Z", "toggle collapsing"},
+		{"z", "expand or collapse the run"},
+		{"space", "mark the commit"},
+		{"X", "actions of marked commits"},
+		{"p", "pin the commit"},
+		{"P", "pinned commits"},
+		{"a", "edit the note of the commit"},
+		{"B", "branches"},
+		{"T", "timeline"},
+		{"v", "toggle the preview"},
+		{"I", "toggle details of the commit"},
+		{"W", "snapshot of the commit"},
+		{"R", "run in the snapshot"},
+		{"gp gc", "go to the parent and the child"},
+		{"ga", "check the commit is an ancestor of a ref"},
+		{"gr", "go to the next root"},
+		{"go", "check the commit on a remote"},
+	}},
+	{Title: "diff", Views: []View{DiffView}, Keys: []helpKey{
+		{"i up", "up"},
+		{"k down", "down"},
+		{"j l left right", "scroll left and right"},
+		{"b m pgup", "page up"},
+		{"f , space pgdn", "page down"},
+		{"u d o", "half page up and down"},
+		{"] [", "next and previous hunk"},
+		{"} {", "next and previous hunk in the function"},
+		{"ctrl+n ctrl+p", "next and previous commit"},
+		{"M '", "set and jump to a mark, with a key after it"},
+		{"S", "toggle split"},
+		{"ctrl+w", "focus the other diff of split"},
+		{"B", "blame the line"},
+		{"h", "history of the file"},
+		{"H", "later commits touched the hunk"},
+		{"w", "word diff of the file between marked commits"},
+		{"y", "copy the line"},
+		{"e", "open the line in the editor"},
+		{"z", "show or hide the generated file"},
+		{"E", "load the rest of a cut diff"},
+		{"P", "cycle how a clean merge is shown"},
+	}},
+	{Title: "tree", Views: []View{TreeView}, Keys: []helpKey{
+		{"i k up down", "up and down"},
+		{"enter l right", "open"},
+		{"j left", "collapse"},
+	}},
+	{Title: "file", Views: []View{FileView}, Keys: []helpKey{
+		{"i k up down", "up and down"},
+		{"b f pgup pgdn", "page up and down"},
+		{"j l left right", "scroll left and right"},
+		{"x", "toggle hex of an image"},
+	}},
+	{Title: "report", Views: []View{ReportView}, Keys: []helpKey{
+		{"s", "sort"},
+		{"enter", "history of the file"},
+	}},
+	{Title: "pinned commits", Views: []View{TrayView}, Keys: []helpKey{
+		{"space", "mark"},
+		{"d", "diff of marked commits"},
+		{"x", "unpin"},
+		{"e", "export patches"},
+		{"r", "report"},
+	}},
+	{Title: "stat", Views: []View{StatView}, Keys: []helpKey{
+		{"space", "fold the group"},
+		{"l j", "unfold and fold the group"},
+		{"enter", "diff of the file"},
+	}},
+	{Title: "blame", Views: []View{BlameView}, Keys: []helpKey{
+		{"enter", "go to the commit"},
+		{"B", "blame before the commit"},
+		{"ctrl+o", "jump back"},
+	}},
+	{Title: "release notes", Views: []View{ReleaseView}, Keys: []helpKey{
+		{"space", "fold the group"},
+		{"enter", "diff"},
+		{"a", "group by type or author"},
+		{"e", "export"},
+	}},
+	{Title: "patch mails", Views: []View{MailView}, Keys: []helpKey{
+		{"enter", "diff"},
+		{"a", "apply"},
+		{"s", "skip"},
+		{"A", "apply all"},
+	}},
+	{Title: "files", Views: []View{FilesView}, Keys: []helpKey{
+		{"enter", "diff of the file"},
+		{"tab", "next pane"},
+	}},
+	{Title: "branches", Views: []View{BranchView}, Keys: []helpKey{
+		{"enter", "commits of the branch"},
+	}},
+}
+
+// helpLine is a line of the help. It's a title of a section when Desc is empty.
+type helpLine struct {
+	Keys string
+	Desc string
+}
+
+// helpLines returns lines of the help, with sections of the view first.
+func helpLines(v View) []helpLine {
+	var first, rest []helpLine
+	for _, s := range helpSections {
+		lines := []helpLine{{Keys: s.Title}}
+		for _, k := range s.Keys {
+			lines = append(lines, helpLine{Keys: userKeys(k.Keys), Desc: k.Desc})
+		}
+		lines = append(lines, helpLine{})
+		in := false
+		for _, sv := range s.Views {
+			in = in || sv == v
+		}
+		if in {
+			first = append(first, lines...)
+		} else {
+			rest = append(rest, lines...)
+		}
+	}
+	lines := append(first, rest...)
+	// drop the empty line after the last section.
+	return lines[:len(lines)-1]
+}
+
+// userKeys returns the keys as the user presses them, with config.Keymap.
+// A key mapped from other keys is shown as those, and a key mapped to other key isn't shown.
+func userKeys(keys string) string {
+	var user []string
+	for _, k := range strings.Fields(keys) {
+		r := []rune(k)
+		switch {
+		case len(r) == 1:
+			user = append(user, pressedKeys(r[0])...)
+		case len(r) == 2 && r[0] == 'g':
+			// a prefixed key, each of it's keys is mapped.
+			p, q := pressedKeys(r[0]), pressedKeys(r[1])
+			if len(p) != 0 && len(q) != 0 {
+				user = append(user, p[0]+q[0])
+			}
+		default:
+			user = append(user, k)
+		}
+	}
+	return strings.Join(user, " ")
+}
+
+// pressedKeys returns keys those the user presses for the key of dig.
+func pressedKeys(to rune) []string {
+	var keys []string
+	for from, t := range config.Keymap {
+		if t == to && from != to {
+			keys = append(keys, string(from))
+		}
+	}
+	sort.Strings(keys)
+	if _, ok := config.Keymap[to]; !ok || config.Keymap[to] == to {
+		keys = append(keys, string(to))
+	}
+	return keys
+}
+
+// HelpArea shows key bindings over the screen, it's opened by ?.
+type HelpArea struct {
+	Lines  []helpLine
+	TopIdx int
+}
+
+// openHelp opens the help, for the current view.
+func openHelp() {
+	screen.Popup = &HelpArea{Lines: helpLines(dig.CurView)}
+}
+
+// Handle scrolls the help, or closes it.
+func (a *HelpArea) Handle(ev Event) {
+	page := popupBound(a.size()).Size.L
+	if ev.Key == KeyArrowUp || ev.Ch == 'i' {
+		a.TopIdx -= count()
+	} else if ev.Key == KeyArrowDown || ev.Ch == 'k' {
+		a.TopIdx += count()
+	} else if ev.Key == KeyPgup || ev.Ch == 'b' {
+		a.TopIdx -= page * count()
+	} else if ev.Key == KeyPgdn || ev.Key == KeySpace || ev.Ch == 'f' {
+		a.TopIdx += page * count()
+	} else if ev.Ch == 'u' {
+		a.TopIdx -= page / 2 * count()
+	} else if ev.Ch == 'd' {
+		a.TopIdx += page / 2 * count()
+	} else if ev.Key == KeyHome {
+		a.TopIdx = 0
+	} else if ev.Key == KeyEnd {
+		a.TopIdx = len(a.Lines)
+	} else if ev.Key == KeyEsc || ev.Key == KeyEnter || ev.Ch == 'q' || ev.Ch == '?' {
+		screen.Popup = nil
+		return
+	}
+	a.scrollValidation(page)
+}
+
+// scrollValidation keeps the help filling the page.
+func (a *HelpArea) scrollValidation(page int) {
+	if a.TopIdx > len(a.Lines)-page {
+		a.TopIdx = len(a.Lines) - page
+	}
+	if a.TopIdx < 0 {
+		a.TopIdx = 0
+	}
+}
+
+// keysWidth returns width of the keys column, with the space after it.
+func (a *HelpArea) keysWidth() int {
+	w := 0
+	for _, ln := range a.Lines {
+		if ln.Desc != "" && runewidth.StringWidth(ln.Keys) > w {
+			w = runewidth.StringWidth(ln.Keys)
+		}
+	}
+	return w + 2
+}
+
+// size returns wanted size of the help.
+func (a *HelpArea) size() Pt {
+	w := 0
+	for _, ln := range a.Lines {
+		if n := runewidth.StringWidth(ln.Desc); n > w {
+			w = n
+		}
+	}
+	return Pt{len(a.Lines), a.keysWidth() + w + 2}
+}
+
+// Draw draws the help. The title tells where it is, when it doesn't fit in the screen.
+func (a *HelpArea) Draw() {
+	bound := popupBound(a.size())
+	a.scrollValidation(bound.Size.L)
+	title := "keys"
+	if len(a.Lines) > bound.Size.L {
+		title += fmt.Sprintf(" %d/%d", a.TopIdx+bound.Size.L, len(a.Lines))
+	}
+	bound = drawPopup(bound, title)
+	kw := a.keysWidth()
+	for l := 0; l < bound.Size.L; l++ {
+		i := a.TopIdx + l
+		if i >= len(a.Lines) {
+			break
+		}
+		ln := a.Lines[i]
+		if ln.Desc == "" {
+			drawLine(bound, l, []byte(ln.Keys), -1, Color{theme.Popup.Fg | AttrBold, theme.Popup.Bg})
+			continue
+		}
+		drawLine(bound, l, []byte(ln.Keys), -1, Color{theme.Hash, theme.Popup.Bg})
+		drawLine(bound, l, []byte(ln.Desc), -(1 + kw), theme.Popup)
+	}
+}
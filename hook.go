@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// runStartupHook runs dig commands of config.OnStartup, those are separated by semicolons.
+// It stops at the first failed command.
+func runStartupHook() {
+	for _, line := range strings.Split(config.OnStartup, ";") {
+		if err := runCommand(line); err != nil {
+			dig.Message = "on_startup: " + err.Error()
+			return
+		}
+	}
+}
+
+// selectHook is the on_commit_select command that is running,
+// and selectHookHash is the commit it ran for.
+var (
+	selectHook     *exec.Cmd
+	selectHookHash string
+)
+
+// commitSelected runs config.OnCommitSelect in background when the selected commit is changed.
+// The previous command is killed if it's still running, as only the last selection matters.
+// It's output is discarded, not to break the screen.
+func commitSelected() {
+	if config.OnCommitSelect == "" || len(dig.Commits) == 0 {
+		return
+	}
+	c := screen.Commit.Commit()
+	if c.Hash == selectHookHash {
+		return
+	}
+	selectHookHash = c.Hash
+	if selectHook != nil {
+		// kill the group, not to leave children of the shell.
+		killProcessGroup(selectHook)
+		selectHook = nil
+	}
+	cmd := shellCommand(expandTemplate(config.OnCommitSelect, c))
+	cmd.Dir = dig.RepoDir
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		dig.Message = "on_commit_select: " + err.Error()
+		return
+	}
+	selectHook = cmd
+	go func() {
+		send(hookDoneMsg{Cmd: cmd, Err: cmd.Wait()})
+	}()
+}
+
+// hookDoneMsg is an on_commit_select command that exited.
+type hookDoneMsg struct {
+	Cmd *exec.Cmd
+	Err error
+}
+
+// hookDone shows the error of the exited command, unless it's killed for a newer selection.
+func hookDone(msg hookDoneMsg) {
+	if selectHook != msg.Cmd {
+		return
+	}
+	selectHook = nil
+	if msg.Err != nil {
+		dig.Message = "on_commit_select: " + msg.Err.Error()
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+)
+
+// SearchIndex is an in-memory index of commits, for filtering them as the user types.
+// It keeps lowercased titles, authors and trailer values of commits in a few big buffers,
+// with a small signature of bytes and byte pairs of each commit.
+// A query only looks into texts of commits those signatures have all of the query's.
+type SearchIndex struct {
+	// parts are titles and authors first, then trailers when they're loaded.
+	parts []*indexPart
+	sigs  []signature
+	// ids finds indexes of commits by their hashes.
+	ids map[string]int
+	// trailers are lowercased trailer values of commits those aren't added yet, by their hashes.
+	// They're indexed when the commits are added.
+	trailers map[string]string
+
+	// lastQuery and lastMatches are of the last search.
+	// A query that extends the last one, like the next keystroke, only checks the last matches.
+	lastQuery   string
+	lastMatches []int
+}
+
+// signature is a bloom filter of bytes and byte pairs in texts of a commit.
+type signature [3]uint64
+
+// add adds bytes and byte pairs of the text to the signature.
+// Letters and digits have their own bits, as single letter queries are common.
+func (s *signature) add(text []byte) {
+	for i, b := range text {
+		switch {
+		case b >= 'a' && b <= 'z':
+			s[0] |= 1 << (b - 'a')
+		case b >= '0' && b <= '9':
+			s[0] |= 1 << (26 + b - '0')
+		default:
+			s[0] |= 1 << (36 + b%28)
+		}
+		if i > 0 {
+			h := (uint(text[i-1])*31 + uint(b)) % 128
+			s[1+h/64] |= 1 << (h % 64)
+		}
+	}
+}
+
+// covers reports whether the signature has every bit of the other.
+func (s signature) covers(o signature) bool {
+	return s[0]&o[0] == o[0] && s[1]&o[1] == o[1] && s[2]&o[2] == o[2]
+}
+
+// indexPart is texts of commits of a kind, like titles or trailers.
+// Text of the k-th commit in the part is text[starts[k]:starts[k+1]].
+type indexPart struct {
+	text   []byte
+	starts []int
+	// at finds k of the commit by it's index, it's -1 when the commit isn't in the part.
+	at []int32
+}
+
+// newIndexPart creates an empty part for n commits.
+func newIndexPart(n int) *indexPart {
+	p := &indexPart{starts: []int{0}}
+	p.grow(n)
+	return p
+}
+
+// grow makes room for commits up to n.
+func (p *indexPart) grow(n int) {
+	for len(p.at) < n {
+		p.at = append(p.at, -1)
+	}
+}
+
+// add adds text of the commit.
+func (p *indexPart) add(id int, text string) {
+	p.at[id] = int32(len(p.starts) - 1)
+	// fields of a commit are separated by \x00. queries don't have it, so a match doesn't span fields.
+	p.text = append(p.text, text...)
+	p.starts = append(p.starts, len(p.text))
+}
+
+// textOf returns text of the commit in the part.
+func (p *indexPart) textOf(id int) []byte {
+	k := p.at[id]
+	if k == -1 {
+		return nil
+	}
+	return p.text[p.starts[k]:p.starts[k+1]]
+}
+
+// newSearchIndex indexes titles and authors of the commits.
+func newSearchIndex(commits []*Commit) *SearchIndex {
+	p := newIndexPart(0)
+	// rough size, to not grow the buffer many times.
+	p.text = make([]byte, 0, len(commits)*80)
+	p.starts = make([]int, 1, len(commits)+1)
+	x := &SearchIndex{
+		parts: []*indexPart{p},
+		sigs:  make([]signature, 0, len(commits)),
+		ids:   make(map[string]int, len(commits)),
+	}
+	x.Add(commits)
+	return x
+}
+
+// Add indexes commits those come after the indexed ones, like ones loaded in background.
+// Their trailers are indexed too, if they're loaded already.
+func (x *SearchIndex) Add(commits []*Commit) {
+	n := len(x.sigs) + len(commits)
+	for _, p := range x.parts {
+		p.grow(n)
+	}
+	for _, c := range commits {
+		id := len(x.sigs)
+		x.ids[c.Hash] = id
+		x.parts[0].add(id, strings.ToLower(c.Title)+"\x00"+strings.ToLower(c.Author)+" "+strings.ToLower(c.Email))
+		if t, ok := x.trailers[c.Hash]; ok {
+			x.parts[1].add(id, t)
+			delete(x.trailers, c.Hash)
+		}
+		var sig signature
+		for _, p := range x.parts {
+			sig.add(p.textOf(id))
+		}
+		x.sigs = append(x.sigs, sig)
+	}
+	// the last matches miss the added commits.
+	x.lastQuery = ""
+	x.lastMatches = nil
+}
+
+// addPart adds the part, and signs it's texts.
+func (x *SearchIndex) addPart(p *indexPart) {
+	x.parts = append(x.parts, p)
+	for id := range x.sigs {
+		x.sigs[id].add(p.textOf(id))
+	}
+	// the last matches could miss commits those only match the new part.
+	x.lastQuery = ""
+	x.lastMatches = nil
+}
+
+// AddTrailers indexes values of trailers, like Reviewed-by or Fixes, by hashes of commits.
+// Trailers are loaded after titles, as they take a while for a big repository.
+// Those of commits not added yet are kept until the commits are added.
+func (x *SearchIndex) AddTrailers(trailers map[string]string) {
+	p := newIndexPart(len(x.sigs))
+	x.trailers = make(map[string]string)
+	for h, t := range trailers {
+		if id, ok := x.ids[h]; ok {
+			p.add(id, strings.ToLower(t))
+		} else {
+			x.trailers[h] = strings.ToLower(t)
+		}
+	}
+	x.addPart(p)
+}
+
+// has reports whether the commit has all the terms.
+func (x *SearchIndex) has(id int, terms [][]byte) bool {
+	for _, t := range terms {
+		found := false
+		for _, p := range x.parts {
+			if bytes.Contains(p.textOf(id), t) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Search returns indexes of commits those have all words of the query, in ascending order.
+// Words are matched case insensitively to a part of title, author or a trailer value.
+// It returns nil for an empty query.
+func (x *SearchIndex) Search(query string) []int {
+	query = strings.ToLower(query)
+	var terms [][]byte
+	var sig signature
+	for _, f := range strings.Fields(query) {
+		terms = append(terms, []byte(f))
+		sig.add([]byte(f))
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+	// bits of letters and digits are exact, their texts needn't be looked.
+	exact := true
+	for _, t := range terms {
+		if len(t) != 1 || !(t[0] >= 'a' && t[0] <= 'z' || t[0] >= '0' && t[0] <= '9') {
+			exact = false
+		}
+	}
+	matches := []int{}
+	check := func(id int) {
+		if x.sigs[id].covers(sig) && (exact || x.has(id, terms)) {
+			matches = append(matches, id)
+		}
+	}
+	if x.lastQuery != "" && strings.HasPrefix(query, x.lastQuery) {
+		// every word of the last query is still in the query, or grew.
+		// so only the last matches could match it.
+		for _, id := range x.lastMatches {
+			check(id)
+		}
+	} else {
+		for id := range x.sigs {
+			check(id)
+		}
+	}
+	x.lastQuery = query
+	x.lastMatches = matches
+	return matches
+}
+
+// SearchIndex returns the search index of all commits.
+// It's built at the first call, and trailers are added in background after it.
+func (p *Program) SearchIndex() *SearchIndex {
+	if p.Index != nil {
+		return p.Index
+	}
+	x := newSearchIndex(p.All)
+	p.Index = x
+	repoDir := p.RepoDir
+	targets := p.LogTargets()
+	go func() {
+		// it's all of the log, commits still being loaded get their trailers when they're added.
+		trailers, err := commitTrailers(repoDir, targets)
+		if err != nil {
+			// old git doesn't know trailers, titles and authors still work.
+			return
+		}
+		send(trailersMsg{Program: p, Index: x, Trailers: trailers})
+	}()
+	return x
+}
+
+// trailersMsg is loaded trailers of commits of an index.
+type trailersMsg struct {
+	Program  *Program
+	Index    *SearchIndex
+	Trailers map[string]string
+}
+
+// trailersLoaded adds the trailers to the index, if the program still uses it.
+func trailersLoaded(msg trailersMsg) {
+	p := msg.Program
+	if p.Index != msg.Index {
+		return
+	}
+	msg.Index.AddTrailers(msg.Trailers)
+	if p != dig {
+		return
+	}
+	// commits those only match the trailers were missed.
+	if p.Mode == QueryMode {
+		applyQuery()
+		return
+	}
+	for _, f := range p.Filters {
+		if f.Query != "" {
+			keepCursor(func() { p.FilterCommits() })
+			break
+		}
+	}
+}
+
+// commitTrailers returns values of trailers of the commits, by their hashes.
+// A value is like "Alice <alice@example.com>" of "Reviewed-by: Alice <alice@example.com>".
+func commitTrailers(repoDir string, targets []string) (map[string]string, error) {
+	args := []string{"log", "--format=%x00%H%n%(trailers:only,unfold)"}
+	args = append(args, targets...)
+	cmd := gitCommand(repoDir, args...)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	trailers := make(map[string]string)
+	for _, rec := range strings.Split(string(out), "\x00")[1:] {
+		lines := strings.Split(strings.TrimSpace(rec), "\n")
+		if len(lines) == 1 {
+			continue
+		}
+		values := make([]string, 0, len(lines)-1)
+		for _, ln := range lines[1:] {
+			if i := strings.Index(ln, ":"); i != -1 {
+				values = append(values, strings.TrimSpace(ln[i+1:]))
+			}
+		}
+		trailers[lines[0]] = strings.Join(values, "\x00")
+	}
+	return trailers, nil
+}
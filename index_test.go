@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// benchCommits makes n commits those look like real ones, for benchmarks.
+func benchCommits(n int) []*Commit {
+	verbs := []string{"fix", "add", "remove", "refactor", "update", "improve", "support", "revert"}
+	things := []string{"parser", "crash in renderer", "config loading", "diff view", "tests", "docs", "build script", "cache"}
+	authors := []string{"Alice", "Bob", "Carol", "Dave", "Eve", "Mallory", "Trent", "Peggy"}
+	commits := make([]*Commit, n)
+	for i := range commits {
+		a := authors[i%len(authors)]
+		commits[i] = &Commit{
+			Hash:   fmt.Sprintf("%040x", i),
+			Title:  fmt.Sprintf("%s %s of module%d (#%d)", verbs[i%len(verbs)], things[(i/3)%len(things)], i%97, i),
+			Author: a,
+			Email:  a + "@example.com",
+		}
+	}
+	return commits
+}
+
+func TestSearchIndex(t *testing.T) {
+	commits := []*Commit{
+		{Hash: "a", Title: "Fix crash in Parser", Author: "Alice", Email: "alice@example.com"},
+		{Hash: "b", Title: "add parser tests", Author: "Bob", Email: "bob@example.com"},
+		{Hash: "c", Title: "update docs", Author: "Carol", Email: "carol@example.com"},
+	}
+	x := newSearchIndex(commits)
+	x.AddTrailers(map[string]string{"c": "Alice <alice@example.com>\x00abc123"})
+	cases := []struct {
+		query string
+		want  []int
+	}{
+		{"parser", []int{0, 1}},
+		{"PARSER fix", []int{0}},
+		{"alice", []int{0, 2}},
+		{"abc123", []int{2}},
+		{"docs alice", []int{2}},
+		{"parser alice", []int{0}},
+		{"z", []int{}},
+		{"b", []int{1, 2}},
+		{"nothing", []int{}},
+		{"", nil},
+	}
+	for _, c := range cases {
+		got := x.Search(c.query)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Search(%q): got %v, want %v", c.query, got, c.want)
+		}
+	}
+	// narrowing the last matches should find the same as a fresh search.
+	x.Search("pars")
+	got := x.Search("parser t")
+	if want := []int{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("narrowed search: got %v, want %v", got, want)
+	}
+}
+
+func TestSearchIndexAdd(t *testing.T) {
+	x := newSearchIndex([]*Commit{{Hash: "a", Title: "fix parser", Author: "Alice"}})
+	// trailers come with the whole log, before later commits are loaded.
+	x.AddTrailers(map[string]string{"a": "Bob", "c": "Tested-by Bob"})
+	if got, want := x.Search("bob"), []int{0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("before add: got %v, want %v", got, want)
+	}
+	x.Add([]*Commit{
+		{Hash: "b", Title: "parser tests", Author: "Carol"},
+		{Hash: "c", Title: "docs", Author: "Dave"},
+	})
+	cases := []struct {
+		query string
+		want  []int
+	}{
+		{"parser", []int{0, 1}},
+		{"bob", []int{0, 2}},
+		{"tested docs", []int{2}},
+	}
+	for _, c := range cases {
+		if got := x.Search(c.query); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Search(%q): got %v, want %v", c.query, got, c.want)
+		}
+	}
+}
+
+func BenchmarkSearchIndexBuild(b *testing.B) {
+	commits := benchCommits(300000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		newSearchIndex(commits)
+	}
+}
+
+func BenchmarkSearch(b *testing.B) {
+	x := newSearchIndex(benchCommits(300000))
+	queries := []string{"crash", "bob", "module42", "refactor cache", "#123456"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// a fresh search each time, not narrowing the last one.
+		x.lastQuery = ""
+		x.Search(queries[i%len(queries)])
+	}
+}
+
+// BenchmarkSearchTyping measures a keystroke while typing a query.
+func BenchmarkSearchTyping(b *testing.B) {
+	x := newSearchIndex(benchCommits(300000))
+	query := "fix crash in renderer"
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n := i%len(query) + 1
+		if n == 1 {
+			x.lastQuery = ""
+		}
+		x.Search(query[:n])
+	}
+}
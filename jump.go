@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+)
+
+// Jump is a location before a linked navigation, like from a diff to blame.
+type Jump struct {
+	View   View
+	Commit string
+	// DiffTop and DiffCursor are the window position and cursor of the focused diff.
+	DiffTop    Pt
+	DiffCursor int
+	// Blame is a copy of the blame area.
+	Blame BlameArea
+}
+
+// pushJump remembers current location to the program's jump list.
+func pushJump() {
+	j := Jump{
+		View:       dig.CurView,
+		Commit:     screen.Commit.Commit().Hash,
+		DiffTop:    screen.FocusedDiff().Win.Bound.Min,
+		DiffCursor: screen.FocusedDiff().Win.Cursor,
+		Blame:      *screen.Blame,
+	}
+	dig.Jumps = append(dig.Jumps, j)
+	// old jumps are not interesting.
+	if len(dig.Jumps) > 100 {
+		dig.Jumps = dig.Jumps[1:]
+	}
+}
+
+// popJump removes the last jump of the jump list, and returns it.
+func popJump() (Jump, bool) {
+	n := len(dig.Jumps)
+	if n == 0 {
+		return Jump{}, false
+	}
+	j := dig.Jumps[n-1]
+	dig.Jumps = dig.Jumps[:n-1]
+	return j, true
+}
+
+// jumpBack goes back to the last location in the jump list, like vim's ctrl+o.
+func jumpBack() error {
+	j, ok := popJump()
+	if !ok {
+		return errors.New("no more jumps")
+	}
+	if i := findByHash(dig.Commits, j.Commit, 0); i != -1 {
+		screen.Commit.SetCursor(i)
+	}
+	bound := screen.Blame.Bound
+	*screen.Blame = j.Blame
+	screen.Blame.Bound = bound
+	dig.CurView = j.View
+	if j.View == DiffView {
+		d := screen.FocusedDiff()
+		d.Sync()
+		d.Win.Bound.Min = j.DiffTop
+		d.Win.Cursor = j.DiffCursor
+	}
+	return nil
+}
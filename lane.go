@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CommitLanes returns lanes of all commits by their hashes.
+// The lanes are built at the first call, as they're only needed for coloring.
+func (p *Program) CommitLanes() map[string]int {
+	if p.Lanes == nil {
+		p.Lanes = commitLanes(p.All, p.DigUp)
+	}
+	return p.Lanes
+}
+
+// commitLanes assigns lanes to commits, like columns of git log --graph.
+// A lane starts at a branch tip and follows it's first parents,
+// so a line of development keeps it's lane, and it's color, over the history.
+// Lanes are numbered in the order they start from the newest commit.
+func commitLanes(commits []*Commit, digUp bool) map[string]int {
+	lanes := make(map[string]int, len(commits))
+	// expect[i] is the commit the i-th column waits for, and lane[i] is it's lane.
+	var expect []string
+	var lane []int
+	next := 0
+	column := func() int {
+		for i, h := range expect {
+			if h == "" {
+				return i
+			}
+		}
+		expect = append(expect, "")
+		lane = append(lane, 0)
+		return len(expect) - 1
+	}
+	for n := range commits {
+		c := commits[n]
+		if digUp {
+			c = commits[len(commits)-1-n]
+		}
+		col := -1
+		for i, h := range expect {
+			if h != c.Hash {
+				continue
+			}
+			if col == -1 {
+				col = i
+			} else {
+				// branches those joined here.
+				expect[i] = ""
+			}
+		}
+		if col == -1 {
+			col = column()
+			lane[col] = next
+			next++
+		}
+		lanes[c.Hash] = lane[col]
+		expect[col] = ""
+		if len(c.Parents) == 0 {
+			continue
+		}
+		expect[col] = c.Parents[0]
+		for _, p := range c.Parents[1:] {
+			waited := false
+			for _, h := range expect {
+				if h == p {
+					waited = true
+					break
+				}
+			}
+			if waited {
+				continue
+			}
+			i := column()
+			expect[i] = p
+			lane[i] = next
+			next++
+		}
+	}
+	return lanes
+}
+
+// laneColor returns foreground color of the commit's lane.
+func laneColor(c *Commit) Attribute {
+	if len(theme.Lanes) == 0 {
+		return theme.Normal.Fg
+	}
+	return theme.Lanes[dig.CommitLanes()[c.Hash]%len(theme.Lanes)]
+}
+
+// colorNames are names of basic terminal colors.
+var colorNames = map[string]Attribute{
+	"black":   ColorBlack,
+	"red":     ColorRed,
+	"green":   ColorGreen,
+	"yellow":  ColorYellow,
+	"blue":    ColorBlue,
+	"magenta": ColorMagenta,
+	"cyan":    ColorCyan,
+	"white":   ColorWhite,
+}
+
+// parseColors parses colors separated by commas, like "red, cyan, 208, #ff8700".
+// A color is a name of basic colors, a number of 256 colors, or a true color.
+func parseColors(s string) ([]Attribute, error) {
+	var colors []Attribute
+	for _, f := range parseList(s) {
+		if c, ok := colorNames[strings.ToLower(f)]; ok {
+			colors = append(colors, c)
+			continue
+		}
+		if strings.HasPrefix(f, "#") {
+			c, err := hexColor(f)
+			if err != nil {
+				return nil, err
+			}
+			colors = append(colors, c)
+			continue
+		}
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 || n > 255 {
+			return nil, fmt.Errorf("unknown color: %s", f)
+		}
+		colors = append(colors, color256(n))
+	}
+	return colors, nil
+}
+
+// hexColor returns a true color like #ff8700, or #f80 for short.
+func hexColor(s string) (Attribute, error) {
+	h := strings.TrimPrefix(s, "#")
+	if len(h) == 3 {
+		h = string([]byte{h[0], h[0], h[1], h[1], h[2], h[2]})
+	}
+	v, err := strconv.ParseUint(h, 16, 32)
+	if len(h) != 6 || err != nil {
+		return 0, fmt.Errorf("unknown color: %s", s)
+	}
+	return rgbColor(uint32(v)), nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Layout is a screen layout that is remembered per repository,
+// as suitable sizes differ by repositories.
+type Layout struct {
+	SideWidth int
+	Split     bool
+}
+
+// Layout returns current layout of the screen.
+func (s *Screen) Layout() Layout {
+	return Layout{SideWidth: s.SideWidth, Split: s.Split}
+}
+
+// SetLayout sets layout of the screen.
+// A side width fixed by the profile wins over the layout's.
+func (s *Screen) SetLayout(l Layout) {
+	s.Split = l.Split
+	if config.SideWidth >= 0 {
+		l.SideWidth = config.SideWidth
+	}
+	s.SetSideWidth(l.SideWidth)
+}
+
+// layoutFile returns the file that layouts of all repositories are saved.
+func layoutFile() (string, error) {
+	return configFile("layout")
+}
+
+// readLayout reads layout of the repository.
+// A repository that isn't saved yet gets the last side width.
+//
+// Each line of the layout file is
+//
+//	"<repo>" side=<width> split=<bool>
+func readLayout(repoDir string) (Layout, error) {
+	// a broken side width is not a reason to ignore the layout.
+	side, sideErr := readSideWidth()
+	l := Layout{SideWidth: side}
+	conf, err := layoutFile()
+	if err != nil {
+		return l, err
+	}
+	content, err := ioutil.ReadFile(conf)
+	if err != nil && !os.IsNotExist(err) {
+		return l, err
+	}
+	err = checkCorrupted(conf, content, validLayoutLine)
+	if err == nil {
+		err = sideErr
+	}
+	for _, ln := range strings.Split(string(content), "\n") {
+		repo, rl, ok := parseLayoutLine(ln)
+		if ok && repo == repoDir {
+			return rl, err
+		}
+	}
+	return l, err
+}
+
+// saveLayout saves layout of the repository.
+// Layouts of other repositories are kept, up to 1000 latest ones.
+func saveLayout(repoDir string, l Layout) error {
+	conf, err := layoutFile()
+	if err != nil {
+		return err
+	}
+	line := fmt.Sprintf("%q side=%d split=%t", repoDir, l.SideWidth, l.Split)
+	return updateRepoLine(conf, repoDir, line, validLayoutLine)
+}
+
+// validLayoutLine reports whether the line of the layout file is valid.
+func validLayoutLine(ln string) bool {
+	_, _, ok := parseLayoutLine(ln)
+	return ok
+}
+
+// parseLayoutLine parses a line of the layout file.
+// Unknown fields are ignored, for layouts of newer versions.
+func parseLayoutLine(ln string) (repo string, l Layout, ok bool) {
+	repo, fields, ok := parseRepoFields(ln)
+	if !ok {
+		return "", l, false
+	}
+	var err error
+	for k, v := range fields {
+		switch k {
+		case "side":
+			l.SideWidth, err = strconv.Atoi(v)
+		case "split":
+			l.Split, err = strconv.ParseBool(v)
+		}
+		if err != nil {
+			return "", l, false
+		}
+	}
+	return repo, l, true
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// lfsPointer is a Git LFS pointer file, that stands for a large file stored outside.
+type lfsPointer struct {
+	OID  string
+	Size int
+	Text []byte
+}
+
+// parseLFSPointer parses contents of a file as a LFS pointer.
+//
+//	version https://git-lfs.github.com/spec/v1
+//	oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393
+//	size 12345
+func parseLFSPointer(lines []string) (*lfsPointer, bool) {
+	if len(lines) < 3 || !strings.HasPrefix(lines[0], "version https://git-lfs.github.com/spec/") {
+		return nil, false
+	}
+	p := &lfsPointer{Size: -1, Text: []byte(strings.Join(lines, "\n") + "\n")}
+	for _, ln := range lines[1:] {
+		if strings.HasPrefix(ln, "oid ") {
+			p.OID = strings.TrimPrefix(ln, "oid ")
+		} else if strings.HasPrefix(ln, "size ") {
+			n, err := strconv.Atoi(strings.TrimPrefix(ln, "size "))
+			if err != nil {
+				return nil, false
+			}
+			p.Size = n
+		}
+	}
+	if p.OID == "" || p.Size < 0 {
+		return nil, false
+	}
+	return p, true
+}
+
+// String returns the object readable, like "12.1 KiB sha256:4d7a2146".
+func (p *lfsPointer) String() string {
+	oid := p.OID
+	if i := strings.Index(oid, ":"); i != -1 && len(oid) > i+9 {
+		oid = oid[:i+9]
+	}
+	return humanSize(p.Size) + " " + oid
+}
+
+// annotateLFS finds LFS pointer files in a diff, and adds a line of their objects
+// before the hunks, instead of letting the pointer churn be the only story.
+// With config.LFSDiff, hunks of text objects are replaced with a diff of the real contents.
+func annotateLFS(lines [][]byte) [][]byte {
+	out := make([][]byte, 0, len(lines))
+	from := 0
+	for from < len(lines) {
+		to := from + 1
+		for to < len(lines) && !isFileHeader(lines[to]) {
+			to++
+		}
+		out = append(out, annotateLFSFile(lines[from:to])...)
+		from = to
+	}
+	return out
+}
+
+// annotateLFSFile annotates diff of a file. See annotateLFS.
+func annotateLFSFile(lines [][]byte) [][]byte {
+	hunk := -1
+	for i, ln := range lines {
+		if bytes.HasPrefix(ln, []byte("@@")) {
+			hunk = i
+			break
+		}
+	}
+	if hunk == -1 {
+		return lines
+	}
+	var oldLines, newLines []string
+	for _, ln := range lines[hunk:] {
+		if len(ln) == 0 || ln[0] == '@' || ln[0] == '\\' {
+			continue
+		}
+		switch ln[0] {
+		case '-':
+			oldLines = append(oldLines, string(ln[1:]))
+		case '+':
+			newLines = append(newLines, string(ln[1:]))
+		case ' ':
+			oldLines = append(oldLines, string(ln[1:]))
+			newLines = append(newLines, string(ln[1:]))
+		}
+	}
+	oldp, oldOK := parseLFSPointer(oldLines)
+	newp, newOK := parseLFSPointer(newLines)
+	if !oldOK && !newOK {
+		return lines
+	}
+	note := "LFS object: "
+	switch {
+	case oldOK && newOK:
+		note += oldp.String() + " -> " + newp.String()
+	case newOK:
+		note += "added " + newp.String()
+	default:
+		note += "removed " + oldp.String()
+	}
+	out := append([][]byte{}, lines[:hunk]...)
+	out = append(out, []byte(note))
+	if config.LFSDiff {
+		hunks, err := lfsDiff(oldp, newp)
+		if err != nil {
+			out = append(out, []byte("LFS diff: "+err.Error()))
+		} else if hunks != nil {
+			return append(out, hunks...)
+		}
+	}
+	return append(out, lines[hunk:]...)
+}
+
+// lfsDiff returns hunks of diff between real contents of the LFS objects.
+// A nil pointer is an empty file. It returns nil hunks for binary objects.
+func lfsDiff(oldp, newp *lfsPointer) ([][]byte, error) {
+	files := make([]string, 2)
+	for i, p := range []*lfsPointer{oldp, newp} {
+		var data []byte
+		if p != nil {
+			var err error
+			data, err = lfsSmudge(p)
+			if err != nil {
+				return nil, err
+			}
+			if isBinary(data) {
+				return nil, nil
+			}
+		}
+		f, err := ioutil.TempFile("", "dig-lfs-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(f.Name())
+		_, err = f.Write(data)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[i] = f.Name()
+	}
+	cmd := gitCommand("", "diff", "--no-index", "--no-color", files[0], files[1])
+	out, err := cmd.Output()
+	// git diff exits with 1 when the files differ.
+	if err != nil && cmd.ProcessState.ExitCode() != 1 {
+		return nil, err
+	}
+	out = bytes.Replace(out, []byte("\t"), []byte("    "), -1)
+	out = bytes.TrimRight(out, "\n")
+	i := bytes.Index(out, []byte("\n@@"))
+	if i == -1 {
+		return [][]byte{}, nil
+	}
+	return bytes.Split(out[i+1:], []byte("\n")), nil
+}
+
+// lfsSmudge returns the real contents of the LFS object, fetching it when needed.
+func lfsSmudge(p *lfsPointer) ([]byte, error) {
+	cmd := gitCommand(dig.RepoDir, "lfs", "smudge")
+	cmd.Stdin = bytes.NewReader(p.Text)
+	out, err := cmd.Output()
+	if err != nil {
+		if e, ok := err.(*exec.ExitError); ok && len(e.Stderr) != 0 {
+			// the first line is enough for a line of the diff.
+			msg := strings.SplitN(strings.TrimSpace(string(e.Stderr)), "\n", 2)[0]
+			return nil, fmt.Errorf("%s", msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
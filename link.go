@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// hyperlink is a text on the screen, that is a link to the URL.
+type hyperlink struct {
+	Pt
+	Text string
+	URL  string
+	C    Color
+}
+
+// hyperlinks are links drawn on the screen in this frame.
+// Areas add them while drawing, and they're written after the screen is shown.
+var hyperlinks []hyperlink
+
+// shownLinks is a key of the links currently on the screen, not to write them again.
+var shownLinks string
+
+// issueRe finds issue IDs in a text, like #123 or JIRA-123.
+var issueRe = regexp.MustCompile(`(^|[^\w&])(#\d+|[A-Z][A-Z0-9]+-\d+)\b`)
+
+// hyperlinksEnabled reports whether dig writes hyperlinks.
+// With "auto", it's enabled in terminals those are known to support them.
+func hyperlinksEnabled() bool {
+	switch config.Hyperlinks {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if os.Getenv("KITTY_WINDOW_ID") != "" || strings.Contains(os.Getenv("TERM"), "kitty") {
+		return true
+	}
+	switch os.Getenv("TERM_PROGRAM") {
+	case "WezTerm", "iTerm.app", "vscode":
+		return true
+	}
+	return false
+}
+
+// addLink adds a link at the l-th line and o-th column of the bound.
+// Links those don't fit in the bound are cut.
+func addLink(bound Rect, l, o int, text, link string, c Color) {
+	if link == "" || !hyperlinksEnabled() || o < 0 || l < 0 || l >= bound.Size.L || o >= bound.Size.O {
+		return
+	}
+	if runewidth.StringWidth(text) > bound.Size.O-o {
+		text = runewidth.Truncate(text, bound.Size.O-o, "")
+	}
+	hyperlinks = append(hyperlinks, hyperlink{
+		Pt:   Pt{bound.Min.L + l, bound.Min.O + o},
+		Text: text,
+		URL:  link,
+		C:    c,
+	})
+}
+
+// addIssueLinks adds links of issue IDs in a text drawn at the l-th line and o-th column.
+func addIssueLinks(bound Rect, l, o int, text string, c Color) {
+	for _, m := range issueRe.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[4], m[5]
+		id := text[start:end]
+		addLink(bound, l, o+runewidth.StringWidth(text[:start]), id, issueURL(id), c)
+	}
+}
+
+// dropLinks removes links at the screen line, when something else is drawn over it.
+func dropLinks(l int) {
+	kept := hyperlinks[:0]
+	for _, h := range hyperlinks {
+		if h.L != l {
+			kept = append(kept, h)
+		}
+	}
+	hyperlinks = kept
+}
+
+// drawLinks writes the links over the screen as OSC 8 hyperlinks.
+// tcell only knows cells, so the texts are written again with the links.
+// Terminals those don't know OSC 8 just ignore the links.
+func drawLinks() {
+	links := hyperlinks
+	hyperlinks = nil
+	if screen.Popup != nil || screen.Note != nil {
+		// links could be covered.
+		links = nil
+	}
+	var key bytes.Buffer
+	for _, h := range links {
+		fmt.Fprintf(&key, "%v%s%s%v;", h.Pt, h.Text, h.URL, h.C)
+	}
+	if key.String() == shownLinks {
+		return
+	}
+	shownLinks = key.String()
+	if len(links) == 0 {
+		return
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return
+	}
+	defer tty.Close()
+	var buf bytes.Buffer
+	buf.WriteString("\x1b7")
+	for _, h := range links {
+		fmt.Fprintf(&buf, "\x1b[%d;%dH%s\x1b]8;;%s\x1b\\%s\x1b]8;;\x1b\\\x1b[0m", h.L+1, h.O+1, sgr(h.C), h.URL, h.Text)
+	}
+	buf.WriteString("\x1b8")
+	tty.Write(buf.Bytes())
+}
+
+// sgr returns an escape sequence that sets the terminal's colors of c.
+func sgr(c Color) string {
+	s := "\x1b[0"
+	if c.Fg&AttrBold != 0 {
+		s += ";1"
+	}
+	color := func(a Attribute, base int) string {
+		a &= AttrBold - 1
+		switch {
+		case a&AttrRGB != 0:
+			return fmt.Sprintf(";%d;2;%d;%d;%d", base+8, a>>16&0xff, a>>8&0xff, a&0xff)
+		case a == ColorDefault:
+			return fmt.Sprintf(";%d", base+9)
+		case a <= ColorWhite:
+			return fmt.Sprintf(";%d", base+int(a)-1)
+		}
+		// 256 colors are 1-based, see color256.
+		return fmt.Sprintf(";%d;5;%d", base+8, int(a)-1)
+	}
+	return s + color(c.Fg, 30) + color(c.Bg, 40) + "m"
+}
+
+// forgeURLs caches web URLs of repositories by their directories.
+var forgeURLs = make(map[string]string)
+
+// forgeURL returns the web URL of the repository's origin, like https://github.com/kybin/dig.
+// It returns "" when the origin isn't known.
+func forgeURL() string {
+	if u, ok := forgeURLs[dig.RepoDir]; ok {
+		return u
+	}
+	u := ""
+	if out, err := gitOutput("remote", "get-url", "origin"); err == nil {
+		u = webURL(strings.TrimSpace(string(out)))
+	}
+	forgeURLs[dig.RepoDir] = u
+	return u
+}
+
+// webURL converts a remote URL to it's web URL.
+//
+//	git@github.com:kybin/dig.git        -> https://github.com/kybin/dig
+//	ssh://git@github.com/kybin/dig.git  -> https://github.com/kybin/dig
+//	https://github.com/kybin/dig.git    -> https://github.com/kybin/dig
+func webURL(remote string) string {
+	remote = strings.TrimSuffix(remote, ".git")
+	if strings.HasPrefix(remote, "http://") || strings.HasPrefix(remote, "https://") {
+		if u, err := url.Parse(remote); err == nil {
+			u.User = nil
+			return u.String()
+		}
+		return ""
+	}
+	if strings.HasPrefix(remote, "ssh://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return ""
+		}
+		return "https://" + u.Hostname() + u.Path
+	}
+	// scp like syntax, user@host:path
+	if i := strings.Index(remote, ":"); i != -1 && !strings.Contains(remote[:i], "/") {
+		host := remote[:i]
+		if j := strings.Index(host, "@"); j != -1 {
+			host = host[j+1:]
+		}
+		return "https://" + host + "/" + strings.TrimPrefix(remote[i+1:], "/")
+	}
+	return ""
+}
+
+// commitURL returns the web URL of the commit.
+func commitURL(hash string) string {
+	base := forgeURL()
+	if base == "" {
+		return ""
+	}
+	return base + "/commit/" + hash
+}
+
+// fileURL returns a URL of the file in the work tree.
+func fileURL(path string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(dig.RepoDir, path))}
+	return u.String()
+}
+
+// issueURL returns the web URL of the issue.
+// Issues like #123 are of the origin, others like JIRA-123 need config.IssueURL.
+func issueURL(id string) string {
+	if strings.HasPrefix(id, "#") {
+		base := forgeURL()
+		if base == "" {
+			return ""
+		}
+		return base + "/issues/" + id[1:]
+	}
+	if config.IssueURL == "" {
+		return ""
+	}
+	return strings.Replace(config.IssueURL, "{id}", id, -1)
+}
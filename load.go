@@ -0,0 +1,278 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// firstPage is how many commits are read before dig shows up, the others are loaded in background.
+// It's a variable for tests.
+var firstPage = 1000
+
+// loadInterval is how often loaded commits are added to the commit list while loading.
+const loadInterval = 200 * time.Millisecond
+
+// logFormat is the format of git log for commits. Records are separated by NUL, as titles could be empty.
+// The body is the last, after the title and fields, as it has lines. See parseCommit.
+const logFormat = "--pretty=format:%x00%H%n%h%n%ct%n%an%n%ae%n%P%n%at%n%cn%n%ce%n%D%n%s"
+
+// logBody is the end of the format, the body after a separator.
+const logBody = "%x1e%b"
+
+// logField is an extra field of git log, like the signer of a commit with %GS.
+type logField struct {
+	Name   string
+	Format string
+}
+
+// logFieldWidth is width of a log field's column.
+const logFieldWidth = 12
+
+// parseLogFields parses fields separated by commas, those are a name and a format of git log, like "signer:%GS, day:%as".
+func parseLogFields(s string) ([]logField, error) {
+	var fields []logField
+	for _, f := range parseList(s) {
+		i := strings.Index(f, ":")
+		if i == -1 {
+			return nil, fmt.Errorf("expected name:format: %s", f)
+		}
+		name := strings.TrimSpace(f[:i])
+		if _, ok := columns[name]; ok || logFieldIndex(fields, name) != -1 {
+			return nil, fmt.Errorf("duplicate name: %s", name)
+		}
+		fields = append(fields, logField{Name: name, Format: strings.TrimSpace(f[i+1:])})
+	}
+	return fields, nil
+}
+
+// logFieldIndex returns index of the named field, or -1 if not found.
+func logFieldIndex(fields []logField, name string) int {
+	for i, f := range fields {
+		if f.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// logArgs returns arguments of git log for commits of the targets.
+// config.LogArgs could have {format}, the format dig needs, and {targets}, the revisions and paths to log.
+// They're added when not in it, the format first and the targets last.
+// Other arguments are for git log as is, like --since=2.years or --perl-regexp.
+func logArgs(targets []string) []string {
+	format := logFormat
+	for _, f := range config.LogFields {
+		// fields are after the title, as it's the last line.
+		format += "%x1f" + f.Format
+	}
+	format += logBody
+	args := []string{"log"}
+	hasFormat, hasTargets := false, false
+	for _, a := range config.LogArgs {
+		switch a {
+		case "{format}":
+			args = append(args, format)
+			hasFormat = true
+		case "{targets}":
+			args = append(args, targets...)
+			hasTargets = true
+		default:
+			args = append(args, a)
+		}
+	}
+	if !hasFormat {
+		args = append(args[:1], append([]string{format}, args[1:]...)...)
+	}
+	if !hasTargets {
+		args = append(args, targets...)
+	}
+	return args
+}
+
+// parseCommit parses a record of git log with logArgs.
+func parseCommit(rec string) (*Commit, error) {
+	// tab handling in screen is quite awkard. handle it here.
+	rec = strings.Replace(rec, "\t", "    ", -1)
+	rec = strings.TrimSuffix(rec, "\n")
+	l := strings.SplitN(rec, "\n", 11)
+	if len(l) != 11 {
+		return nil, fmt.Errorf("unexpected git log output: %q", rec)
+	}
+	sec, err := strconv.ParseInt(l[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected commit time: %q", l[2])
+	}
+	authorSec, err := strconv.ParseInt(l[6], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected author time: %q", l[6])
+	}
+	rest := strings.SplitN(l[10], "\x1e", 2)
+	title := strings.SplitN(rest[0], "\x1f", len(config.LogFields)+1)
+	c := &Commit{
+		Hash:           l[0],
+		Abbrev:         l[1],
+		Time:           time.Unix(sec, 0),
+		Author:         l[3],
+		Email:          l[4],
+		Parents:        strings.Fields(l[5]),
+		AuthorTime:     time.Unix(authorSec, 0),
+		Committer:      l[7],
+		CommitterEmail: l[8],
+		Title:          title[0],
+		Fields:         title[1:],
+	}
+	if l[9] != "" {
+		c.Refs = strings.Split(l[9], ", ")
+	}
+	if len(rest) == 2 {
+		c.Body = strings.TrimRight(rest[1], "\n")
+	}
+	return c, nil
+}
+
+// CommitLoader loads commits of the backend in background, so dig shows up quickly on a huge repository.
+// Loaded commits are added to the program's commit list as they come.
+type CommitLoader struct {
+	Program *Program
+	Screen  *Screen
+	// Want is the commit the cursor should be on when it's loaded, like the last one of the previous run.
+	// It's at WantPos of the screen. It's given up when the user moves the cursor.
+	Want    string
+	WantPos Position
+	// WantDate is the date :date goes to when commits of it are loaded, it's zero when there isn't.
+	// It's given up when the user moves the cursor, too.
+	WantDate time.Time
+	// cursor is the commit the cursor was on, after the last commits are added.
+	cursor string
+
+	log CommitLog
+}
+
+// commitsMsg is commits those are loaded by a loader in background.
+// The last one of a loader is Done, with an error if it failed.
+type commitsMsg struct {
+	Loader  *CommitLoader
+	Commits []*Commit
+	Done    bool
+	Err     error
+}
+
+// startCommits starts listing commits of the repository, and reads the first page of them.
+// The others should be loaded with the returned loader's Load, if it's not nil.
+// Like allCommits, commits are in the order of digging up when digUp is set.
+// Then they're listed from the oldest, so the others are still added after them.
+func startCommits(repoDir string, targets []string, digUp bool) (*CommitLoader, []*Commit, error) {
+	log, err := backend.LogCommits(repoDir, targets, digUp)
+	if err != nil {
+		return nil, nil, err
+	}
+	commits, done, err := log.Read(firstPage)
+	if err == nil && done && len(commits) == 0 {
+		err = errors.New("no commits")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if done {
+		return nil, commits, nil
+	}
+	return &CommitLoader{log: log}, commits, nil
+}
+
+// reverseCommits reverses order of the commits in place.
+func reverseCommits(commits []*Commit) {
+	for i, j := 0, len(commits)-1; i < j; i, j = i+1, j-1 {
+		commits[i], commits[j] = commits[j], commits[i]
+	}
+}
+
+// Load loads the other commits in background. It sends them each loadInterval.
+// It should be called after the program's first commits are set, and the loader is it's Loader.
+func (l *CommitLoader) Load() {
+	l.cursor = l.Screen.Commit.Commit().Hash
+	go func() {
+		var commits []*Commit
+		last := time.Now()
+		for {
+			// a page is read at once, checking the time for each commit is slow.
+			page, done, err := l.log.Read(100)
+			commits = append(commits, page...)
+			if done || err != nil {
+				send(commitsMsg{Loader: l, Commits: commits, Done: true, Err: err})
+				return
+			}
+			if time.Since(last) >= loadInterval {
+				send(commitsMsg{Loader: l, Commits: commits})
+				commits = nil
+				last = time.Now()
+			}
+		}
+	}()
+}
+
+// Stop stops loading. Commits those are loaded but not sent yet are ignored,
+// as the program's Loader isn't the loader anymore.
+func (l *CommitLoader) Stop() {
+	l.log.Stop()
+	if l.Program.Loader == l {
+		l.Program.Loader = nil
+	}
+}
+
+// commitsLoaded adds the loaded commits to the loader's program.
+// The cursor stays on the same commit, or moves to the wanted commit when it's loaded.
+func commitsLoaded(msg commitsMsg) {
+	l := msg.Loader
+	if l.Program.Loader != l {
+		// stopped.
+		return
+	}
+	if msg.Done {
+		l.Program.Loader = nil
+		if msg.Err != nil {
+			l.Program.Message = "could not load all commits: " + msg.Err.Error()
+		}
+	}
+	if len(msg.Commits) == 0 && l.WantDate.IsZero() {
+		return
+	}
+	// the loader's tab may not be the current one.
+	curDig, curScreen := dig, screen
+	dig, screen = l.Program, l.Screen
+	defer func() {
+		dig, screen = curDig, curScreen
+	}()
+	if screen.Commit.Commit().Hash != l.cursor {
+		l.Want = ""
+		l.WantDate = time.Time{}
+	}
+	if len(msg.Commits) != 0 {
+		var err error
+		keepCursor(func() {
+			dig.AddCommits(msg.Commits)
+			err = dig.FilterCommits()
+		})
+		if err != nil {
+			dig.Message = err.Error()
+		}
+	}
+	if !l.WantDate.IsZero() && (msg.Done || dateLoaded(dig.All, l.WantDate, dig.DigUp)) {
+		t := l.WantDate
+		l.WantDate = time.Time{}
+		dig.Message = ""
+		if err := gotoDate(t); err != nil {
+			dig.Message = err.Error()
+		}
+	}
+	if l.Want != "" {
+		if i := findByHash(dig.Commits, l.Want, 0); i != -1 {
+			screen.Commit.SetCursor(i)
+			screen.SetPosition(l.WantPos, l.Want)
+			l.Want = ""
+		}
+	}
+	l.cursor = screen.Commit.Commit().Hash
+}
@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestAllCommits(t *testing.T) {
+	r := historyFixture(t)
+	commits := r.Commits(false, "HEAD")
+	if got, want := titles(commits), "merge feature, fix readme, say hi, add readme, initial"; got != want {
+		t.Errorf("commits: got %q, want %q", got, want)
+	}
+	merge := commits[0]
+	if len(merge.Parents) != 2 || merge.Parents[0] != r.Hash("HEAD^1") || merge.Parents[1] != r.Hash("feature") {
+		t.Errorf("parents of the merge: got %v", merge.Parents)
+	}
+	if c := commits[2]; c.Author != "Bob" || c.Email != "bob@example.com" {
+		t.Errorf("author of %q: got %s <%s>", c.Title, c.Author, c.Email)
+	}
+	if !commits[3].Time.After(commits[4].Time) {
+		t.Errorf("dates: %v is not after %v", commits[3].Time, commits[4].Time)
+	}
+	up := r.Commits(true, "HEAD")
+	if got, want := titles(up), "initial, add readme, say hi, fix readme, merge feature"; got != want {
+		t.Errorf("dug up commits: got %q, want %q", got, want)
+	}
+	if all := r.Commits(false, "--all"); len(all) != 6 || all[0].Title != "remove readme" {
+		t.Errorf("commits of all refs: got %q", titles(all))
+	}
+	if _, err := allCommits(r.Dir, []string{"no-such-ref"}, false); err == nil {
+		t.Error("commits of an unknown ref: no error")
+	}
+}
@@ -8,14 +8,14 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
-	"os/user"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	runewidth "github.com/mattn/go-runewidth"
-	termbox "github.com/nsf/termbox-go"
 )
 
 // dig indicates this program.
@@ -26,9 +26,172 @@ type Program struct {
 	Mode    Mode
 	CurView View
 	RepoDir string
+	Targets []string
+	DigUp   bool
+
+	// All are all commits loaded from git.
+	All []*Commit
+	// Commits are commits those are shown, with the filters.
 	Commits []*Commit
+	// Filters choose commits to show from all commits.
+	Filters []*Filter
+	// Selections are commits the cursor was on before each filter is added. See restackSelections.
+	Selections []string
+	// FilesLoaded is true when changed files of all commits are loaded.
+	FilesLoaded bool
+	// laterFiles are changed files of commits those aren't loaded yet, by their hashes.
+	// They're set to the commits when they're added. See AddCommits.
+	laterFiles map[string][]string
+
+	// Paths are pathspecs given after -- on the command line.
+	// Commits and their diffs are limited to them.
+	Paths []string
+	// History is a file path when the program is in file history mode.
+	// Then only commits that touched the file are shown.
+	History string
+	// Simplify is a history simplification of git log, like "full-history".
+	// Empty is git's default simplification.
+	Simplify string
+
+	FindString    string
+	CommandString string
+	// Query is what is typed in QueryMode, to filter commits as it's typed.
+	Query string
+	// QueryFrom are filters before QueryMode, restored when it's canceled.
+	QueryFrom []*Filter
+
+	// Copy is the cell cursor in CopyMode.
+	Copy *CopyCursor
+
+	// Timeline is the date slider in TimelineMode.
+	Timeline *Timeline
+
+	// Search is the last search, that n and N walk it's matches.
+	Search *Search
+	// FileScope limits a diff search to the file under the cursor.
+	// It's toggled with ctrl+t while finding.
+	FileScope bool
+
+	// Count is a number typed before a command, or 0 if there isn't.
+	Count int
+
+	// Prefix is a key waiting for it's following key, like vim's marks.
+	Prefix rune
+
+	// ByHash finds a commit in Commits by it's hash.
+	ByHash map[string]*Commit
+	// Children are hashes of child commits by their parent. See ChildrenOf.
+	Children map[string][]string
+	// Roots is the number of root commits.
+	// There are many when the repository has unrelated histories, like orphan branches.
+	Roots int
+	// Runs are linear runs of commits. See LinearRuns.
+	Runs *Runs
+	// Lanes are lanes of commits by their hashes. See CommitLanes.
+	Lanes map[string]int
+	// Graph is the graph of commits. See CommitGraph.
+	Graph *Graph
+	// Index is the search index of all commits. See SearchIndex.
+	Index *SearchIndex
+	// Meta loads extra columns of commits. See MetaLoader.
+	Meta *MetaLoader
+	// Expanded are anchors of linear runs those aren't collapsed.
+	Expanded map[string]bool
+
+	// Pins are hashes of pinned commits, in pinned order.
+	Pins []string
+	// Marks are commits selected in the commit list for batch actions.
+	Marks Marks
+
+	// Notes are user notes of commits, saved per repository.
+	Notes map[string]string
+
+	// WatchTips are hashes of the revisions, when the watched paths were checked last time. See checkWatch.
+	WatchTips []string
+	// Watched are commits those came while dig is running, and touched config.Watch.
+	Watched map[string]bool
+
+	// DiffFrom is a view that opened a diff with openDiff.
+	DiffFrom View
+
+	// Jumps are locations before linked navigations, latest last.
+	Jumps []Jump
+
+	// Message is shown in the status area until next key input.
+	Message string
+
+	// Loader loads the other commits in background, nil when all of them are loaded.
+	Loader *CommitLoader
+}
 
-	FindString string
+// SetCommits sets commits of the program.
+// Call FilterCommits after it, when the program has filters.
+func (p *Program) SetCommits(commits []*Commit) {
+	p.All = commits
+	p.Commits = commits
+	p.FilesLoaded = false
+	p.laterFiles = nil
+	p.Children = nil
+	p.Runs = nil
+	p.Lanes = nil
+	p.Graph = nil
+	p.Index = nil
+	for _, f := range p.Filters {
+		// the history could be changed, like by a fetch.
+		f.Hashes = nil
+	}
+	p.Roots = 0
+	p.ByHash = make(map[string]*Commit, len(commits))
+	for _, c := range commits {
+		p.ByHash[c.Hash] = c
+		if len(c.Parents) == 0 {
+			p.Roots++
+		}
+	}
+}
+
+// AddCommits adds commits those come after all commits, like ones loaded in background.
+// Data of all commits is extended with them instead of being built again, except the runs, lanes and graph.
+// Those are built again when they're needed, as the commits could change any of them.
+// Call FilterCommits after it, when the program has filters.
+func (p *Program) AddCommits(commits []*Commit) {
+	p.All = append(p.All, commits...)
+	for _, c := range commits {
+		p.ByHash[c.Hash] = c
+		if len(c.Parents) == 0 {
+			p.Roots++
+		}
+		if p.Children != nil {
+			for _, parent := range c.Parents {
+				p.Children[parent] = append(p.Children[parent], c.Hash)
+			}
+		}
+		if files, ok := p.laterFiles[c.Hash]; ok {
+			c.Files = files
+			delete(p.laterFiles, c.Hash)
+		}
+	}
+	if p.Index != nil {
+		p.Index.Add(commits)
+	}
+	p.Runs = nil
+	p.Lanes = nil
+	p.Graph = nil
+}
+
+// LogTargets returns arguments for git log, to get commits of the program.
+func (p *Program) LogTargets() []string {
+	targets := append([]string{}, p.Targets...)
+	if p.Simplify != "" {
+		targets = append(targets, "--"+p.Simplify)
+	}
+	if p.History != "" {
+		targets = append(targets, "--", p.History)
+	} else if len(p.Paths) != 0 {
+		targets = append(targets, "--")
+		targets = append(targets, p.Paths...)
+	}
+	return targets
 }
 
 // View is view of program.
@@ -37,6 +200,16 @@ type View int
 const (
 	CommitView = View(iota)
 	DiffView
+	TreeView
+	FileView
+	ReportView
+	TrayView
+	StatView
+	BlameView
+	ReleaseView
+	MailView
+	FilesView
+	BranchView
 )
 
 // Mode is mode of program.
@@ -45,6 +218,11 @@ type Mode int
 const (
 	NormalMode = Mode(iota)
 	FindMode
+	CommandMode
+	NoteMode
+	TimelineMode
+	QueryMode
+	CopyMode
 )
 
 // screen indicates this program screen.
@@ -55,20 +233,62 @@ type Screen struct {
 	size      Pt
 	SideWidth int
 
-	Commit *CommitArea
-	Diff   *DiffArea
-	Status *StatusArea
+	Commit  *CommitArea
+	Diff    *DiffArea
+	Diff2   *DiffArea // lower diff area of split window
+	Tree    *TreeArea
+	File    *FileArea
+	Report  *ReportArea
+	Tray    *TrayArea
+	Stat    *StatArea
+	Blame   *BlameArea
+	Release *ReleaseArea
+	Mail    *MailArea
+	Status  *StatusArea
+	// Preview is beside the commit list, when config.Preview is on.
+	Preview *PreviewArea
+	// Files is the file list between the commit list and the diff, in the three pane layout.
+	Files  *FilesArea
+	Branch *BranchArea
+	// Detail is below the commit list, when config.Details is on.
+	Detail *DetailArea
+
+	// Note is the note editor popup, when it's opened.
+	Note *NoteEditor
+	// Popup is a popup that takes events, when it's opened.
+	Popup Popup
+
+	// Split shows Diff and Diff2 together in DiffView.
+	Split bool
+	// Focus is index of the focused diff area when split. (0: Diff, 1: Diff2)
+	Focus int
+	// Walk is the walkthrough of commits, while presenting them.
+	Walk *Walk
 }
 
 // NewScreen creates a new Screen.
 // It will also create it's sub areas.
 func NewScreen(size Pt, sideWidth int) *Screen {
+	cache := newDiffCache(50)
 	s := &Screen{
 		size:      size,
 		SideWidth: sideWidth,
 		Commit:    &CommitArea{},
-		Diff:      &DiffArea{Win: &Window{}, WindowPoses: make(map[string]Pt)},
+		Diff:      &DiffArea{Win: &Window{HasCursor: config.CursorLine}, WindowPoses: make(map[string]Pt), Cache: cache},
+		Diff2:     &DiffArea{Win: &Window{HasCursor: config.CursorLine}, WindowPoses: make(map[string]Pt), Cache: cache},
+		Tree:      &TreeArea{},
+		File:      &FileArea{Win: &Window{}},
+		Report:    &ReportArea{},
+		Tray:      &TrayArea{},
+		Stat:      &StatArea{},
+		Blame:     &BlameArea{},
+		Release:   &ReleaseArea{},
+		Mail:      &MailArea{},
 		Status:    &StatusArea{},
+		Preview:   &PreviewArea{},
+		Files:     &FilesArea{},
+		Branch:    &BranchArea{},
+		Detail:    &DetailArea{},
 	}
 	s.Resize(size)
 	return s
@@ -76,39 +296,205 @@ func NewScreen(size Pt, sideWidth int) *Screen {
 
 // Draw draws the screen.
 func (s *Screen) Draw() {
-	if dig.CurView == CommitView {
+	term.HideCursor()
+	switch {
+	case s.Panes() > 1 && s.InPanes(dig.CurView):
+		s.drawPanes()
+	case dig.CurView == CommitView:
 		s.Commit.Draw()
-	} else {
-		s.Diff.Draw()
+		s.Detail.Draw(s.Commit.Commit())
+		if s.Preview.Bound.Size.O > 0 {
+			s.Preview.Follow(s.Commit.Commit().Hash)
+			s.Preview.Draw()
+		}
+	case dig.CurView == DiffView:
+		s.drawDiff()
+	}
+	switch dig.CurView {
+	case TreeView:
+		s.Tree.Draw()
+	case FileView:
+		s.File.Draw()
+	case ReportView:
+		s.Report.Draw()
+	case TrayView:
+		s.Tray.Draw()
+	case StatView:
+		s.Stat.Draw()
+	case FilesView:
+		if s.Files.Alone {
+			s.Files.Draw()
+		}
+	case BlameView:
+		s.Blame.Draw()
+	case ReleaseView:
+		s.Release.Draw()
+	case MailView:
+		s.Mail.Draw()
+	case BranchView:
+		s.Branch.Draw()
+	}
+	if config.Mouse {
+		s.drawDivider()
 	}
 	s.Status.Draw()
+	if s.Note != nil {
+		s.Note.Draw()
+	}
+	if s.Popup != nil {
+		s.Popup.Draw()
+	}
 }
 
+// drawDiff draws the diff, with the other diff and their headers when split.
+func (s *Screen) drawDiff() {
+	s.Diff.Draw()
+	if s.Split {
+		s.drawDiffHeader(s.Diff, s.Focus == 0)
+		s.Diff2.Draw()
+		s.drawDiffHeader(s.Diff2, s.Focus == 1)
+	}
+	if s.Walk != nil {
+		s.drawWalkHeader()
+	}
+}
+
+// minPreviewWidth is the minimum width of the preview and the commit list.
+// A narrower screen doesn't show the preview.
+const minPreviewWidth = 30
+
 // Resize resizes the screen and re-fit sub areas.
 func (s *Screen) Resize(size Pt) {
 	s.size = size
 
-	// CommitArea and DiffArea are same,
+	// main areas are all same,
 	// but ok, because only one of these is drawn.
+	status := statusHeight(size)
 	mainArea := Rect{
-		Min:  Pt{0, s.SideWidth},
-		Size: Pt{size.L - 1, size.O - s.SideWidth},
+		Min:  Pt{0, s.Side()},
+		Size: Pt{size.L - status, size.O - s.Side()},
 	}
 	s.Commit.Bound = mainArea
-	s.Diff.Bound = mainArea
+	s.Preview.Bound = Rect{}
+	diffArea := mainArea
+	if s.Panes() > 1 {
+		// the diff pane shows what the preview would.
+		diffArea = s.layoutPanes(mainArea)
+		if s.Panes() < 3 && dig != nil && dig.CurView == FilesView && !s.Files.Alone {
+			dig.CurView = DiffView
+		}
+	} else if config.Preview && mainArea.Size.O >= minPreviewWidth*2 {
+		// the preview takes the right half, after a divider.
+		w := mainArea.Size.O / 2
+		s.Commit.Bound.Size.O -= w
+		s.Preview.Bound = Rect{
+			Min:  Pt{0, mainArea.Min.O + s.Commit.Bound.Size.O + 1},
+			Size: Pt{mainArea.Size.L, w - 1},
+		}
+	}
+	if config.TitleBar {
+		// the title bar is above the commit list.
+		s.Commit.Bound.Min.L++
+		s.Commit.Bound.Size.L--
+	}
+	s.Detail.Bound = Rect{}
+	if config.Details && s.Panes() <= 1 {
+		// the details take the lower third of the commit list, after a divider.
+		if h := s.Commit.Bound.Size.L / 3; h >= minDetailHeight {
+			s.Commit.Bound.Size.L -= h
+			s.Detail.Bound = Rect{
+				Min:  Pt{s.Commit.Bound.Min.L + s.Commit.Bound.Size.L + 1, s.Commit.Bound.Min.O},
+				Size: Pt{h - 1, s.Commit.Bound.Size.O},
+			}
+		}
+	}
+	if s.Walk != nil {
+		diffArea.Min.L += walkHeaderHeight
+		diffArea.Size.L -= walkHeaderHeight
+	}
+	s.Diff.Bound = diffArea
+	if s.Split {
+		// each diff area has a header line above it.
+		top := diffArea.Size.L / 2
+		s.Diff.Bound.Min.L += 1
+		s.Diff.Bound.Size.L = top - 1
+		s.Diff2.Bound = diffArea
+		s.Diff2.Bound.Min.L += top + 1
+		s.Diff2.Bound.Size.L = diffArea.Size.L - top - 1
+	}
 	s.Diff.Win.Bound.Size = s.Diff.Bound.Size
+	s.Diff2.Win.Bound.Size = s.Diff2.Bound.Size
+	if s.Panes() < 3 {
+		s.Files.Bound = mainArea
+	}
+	s.Tree.Bound = mainArea
+	s.File.Bound = mainArea
+	s.File.Win.Bound.Size = s.File.Bound.Size
+	s.Report.Bound = mainArea
+	s.Tray.Bound = mainArea
+	s.Stat.Bound = mainArea
+	s.Blame.Bound = mainArea
+	s.Release.Bound = mainArea
+	s.Mail.Bound = mainArea
+	s.Branch.Bound = mainArea
 	s.Status.Bound = Rect{
-		Min:  Pt{size.L - 1, 0},
-		Size: Pt{1, size.O},
+		Min:  Pt{size.L - status, 0},
+		Size: Pt{status, size.O},
+	}
+}
+
+// ToggleSplit splits or unsplits DiffView.
+// The lower diff area is fixed to the currently selected commit,
+// while the upper one follows the selection.
+func (s *Screen) ToggleSplit() {
+	s.Split = !s.Split
+	s.Focus = 0
+	if s.Split {
+		s.Diff2.Fixed = s.Commit.Commit().Hash
+		s.Focus = 1
+	}
+	s.Resize(s.size)
+}
+
+// FocusedDiff returns the diff area that handles events.
+func (s *Screen) FocusedDiff() *DiffArea {
+	if s.Split && s.Focus == 1 {
+		return s.Diff2
+	}
+	return s.Diff
+}
+
+// drawDiffHeader draws a header line of the diff area, in split window.
+func (s *Screen) drawDiffHeader(a *DiffArea, focused bool) {
+	c := theme.Normal
+	if focused {
+		c = theme.Focused
 	}
+	bound := Rect{Min: Pt{a.Bound.Min.L - 1, a.Bound.Min.O}, Size: Pt{1, a.Bound.Size.O}}
+	fillColor(bound, c)
+	title := a.CommitHash
+	if i := findByHash(dig.Commits, a.CommitHash, 0); i != -1 {
+		title += " " + dig.Commits[i].Title
+	}
+	drawLine(bound, 0, []byte(title), 0, c)
 }
 
 // ExpandSide expands or shirinks it's Side screen.
 func (s *Screen) ExpandSide(n int) {
-	s.SideWidth += n
-	if s.SideWidth < 0 {
-		s.SideWidth = 0
+	s.SetSideWidth(s.SideWidth + n)
+}
+
+// SetSideWidth sets width of the side, and resizes areas.
+// The main area keeps at least a few columns.
+// The compact layout doesn't show the side, the width is kept for a wider terminal.
+func (s *Screen) SetSideWidth(w int) {
+	if w > s.size.O-10 && !s.Compact() {
+		w = s.size.O - 10
 	}
+	if w < 0 {
+		w = 0
+	}
+	s.SideWidth = w
 	s.Resize(s.size)
 }
 
@@ -118,7 +504,7 @@ func fillColor(bound Rect, c Color) {
 	max := bound.Min.Add(bound.Size)
 	for l := min.L; l < max.L; l++ {
 		for o := min.O; o < max.O; o++ {
-			termbox.SetCell(o, l, 's', c.Fg, c.Bg)
+			setCell(o, l, ' ', c.Fg, c.Bg)
 		}
 	}
 }
@@ -132,31 +518,99 @@ type CommitArea struct {
 }
 
 // Handle handles a terminal event.
-func (a *CommitArea) Handle(ev termbox.Event) bool {
-	if ev.Key == termbox.KeyArrowUp || ev.Ch == 'i' {
-		a.CursorUp(1)
+func (a *CommitArea) Handle(ev Event) bool {
+	if ev.Key == KeyArrowUp || ev.Ch == 'i' {
+		a.CursorUp(count())
 		return true
-	} else if ev.Key == termbox.KeyArrowDown || ev.Ch == 'k' {
-		a.CursorDown(1)
+	} else if ev.Key == KeyArrowDown || ev.Ch == 'k' {
+		a.CursorDown(count())
 		return true
-	} else if ev.Key == termbox.KeyPgup || ev.Ch == 'b' {
-		a.CursorUp(a.Bound.Size.L)
+	} else if ev.Key == KeyPgup || ev.Ch == 'b' {
+		a.CursorUp(a.Bound.Size.L * count())
 		return true
-	} else if ev.Key == termbox.KeyPgdn || ev.Ch == 'f' {
-		a.CursorDown(a.Bound.Size.L)
+	} else if ev.Key == KeyPgdn || ev.Ch == 'f' {
+		a.CursorDown(a.Bound.Size.L * count())
 		return true
 	} else if ev.Ch == 'u' {
-		a.CursorUp(a.Bound.Size.L / 2)
+		a.CursorUp(a.Bound.Size.L / 2 * count())
 		return true
 	} else if ev.Ch == 'd' {
-		a.CursorDown(a.Bound.Size.L / 2)
+		a.CursorDown(a.Bound.Size.L / 2 * count())
 		return true
-	} else if ev.Key == termbox.KeyHome {
+	} else if ev.Key == KeyHome {
 		a.SetCursor(0)
 		return true
-	} else if ev.Key == termbox.KeyEnd {
+	} else if ev.Key == KeyEnd {
 		a.SetCursor(len(dig.Commits) - 1)
 		return true
+	} else if ev.Ch == 'p' {
+		dig.TogglePin(a.Commit().Hash)
+		return true
+	} else if ev.Key == KeySpace {
+		// marks count commits from the cursor, and moves down.
+		for i := 0; i < count() && a.CurIdx+i < len(dig.Commits); i++ {
+			dig.Marks.Toggle(dig.Commits[a.CurIdx+i].Hash, 0)
+		}
+		a.CursorDown(count())
+		return true
+	} else if ev.Ch == 'X' {
+		openMarkActions()
+		return true
+	} else if ev.Ch == 'z' {
+		if err := toggleRun(); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	} else if ev.Ch == 'Z' {
+		if err := toggleFilter("collapse"); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	} else if ev.Ch == 'W' {
+		if err := snapshotSelected(); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	} else if ev.Ch == 'R' {
+		if err := runInSnapshot(config.Run); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	} else if ev.Ch == 'T' {
+		startTimeline()
+		return true
+	} else if ev.Ch == 'v' {
+		config.Preview = !config.Preview
+		screen.Resize(screen.size)
+		return true
+	} else if ev.Ch == 'I' {
+		config.Details = !config.Details
+		screen.Resize(screen.size)
+		return true
+	} else if ev.Ch == 'P' {
+		dig.CurView = TrayView
+		return true
+	} else if ev.Ch == 'a' {
+		editNote(a.Commit().Hash)
+		return true
+	} else if ev.Ch == 'A' {
+		if err := toggleFilter("email:" + a.Commit().Email); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	} else if ev.Ch == 'F' {
+		if err := toggleFilter("files:" + a.Commit().Abbrev); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	} else if ev.Ch == 'G' {
+		// like vim, it goes to the n-th commit when a count is given.
+		if dig.Count != 0 {
+			a.SetCursor(dig.Count - 1)
+		} else {
+			a.SetCursor(len(dig.Commits) - 1)
+		}
+		return true
 	}
 	return false
 }
@@ -199,28 +653,60 @@ func (a *CommitArea) Draw() {
 		a.TopIdx = a.CurIdx - a.Bound.Size.L + 1
 	}
 
+	a.drawTitle()
 	top := a.TopIdx
 	bottom := top + a.Bound.Size.L
+	cols := a.Columns()
+	showHash := a.ShowHash()
+	if len(cols) != 0 {
+		visible := []string{}
+		for i := top; i < bottom && i < len(dig.Commits); i++ {
+			visible = append(visible, dig.Commits[i].Hash)
+		}
+		dig.MetaLoader().Show(visible, cols)
+	}
 	for i := top; i < bottom; i++ {
 		if i == len(dig.Commits) {
 			break
 		}
 		commit := dig.Commits[i]
 
-		c := Color{Fg: termbox.ColorWhite, Bg: termbox.ColorBlack}
+		c := theme.Normal
 		if i == a.CurIdx {
-			c = Color{Fg: termbox.ColorWhite, Bg: termbox.ColorGreen}
+			c = theme.Selected
+		} else if config.LaneColors {
+			c.Fg = laneColor(commit)
+		} else if config.AgeTint {
+			c.Fg = ageColor(commit.Time)
 		}
 
-		remain := commit.Title
 		l := i - top
 		o := 0
+		if dig.ShowGraph() {
+			o += drawGraph(a.Bound, l, o, commit, i == a.CurIdx, c)
+		}
+		if badges := commitBadges(commit); badges != "" {
+			badges += " "
+			drawLine(a.Bound, l, []byte(badges), -o, Color{theme.Badge, c.Bg})
+			o += runewidth.StringWidth(badges)
+		}
+		if showHash {
+			drawLine(a.Bound, l, []byte(commit.Abbrev+" "), -o, Color{theme.Hash, c.Bg})
+			addLink(a.Bound, l, o, commit.Abbrev, commitURL(commit.Hash), Color{theme.Hash, c.Bg})
+			o += len(commit.Abbrev) + 1
+		}
+		o += drawColumns(a.Bound, l, o, commit, cols, c.Bg)
+		remain := commit.Title
+		if config.Ellipsis && runewidth.StringWidth(remain) > a.Bound.Size.O-o {
+			remain = runewidth.Truncate(remain, a.Bound.Size.O-o, "…")
+		}
+		addIssueLinks(a.Bound, l, o, remain, c)
 		for {
 			if len(remain) == 0 {
 				if i == a.CurIdx {
 					// fill the rest of current line
 					for o < a.Bound.Size.O {
-						termbox.SetCell(a.Bound.Min.O+o, a.Bound.Min.L+l, ' ', c.Fg, c.Bg)
+						setCell(a.Bound.Min.O+o, a.Bound.Min.L+l, ' ', c.Fg, c.Bg)
 						o++
 					}
 				}
@@ -231,12 +717,80 @@ func (a *CommitArea) Draw() {
 			}
 			r, size := utf8.DecodeRuneInString(remain)
 			remain = remain[size:]
-			termbox.SetCell(a.Bound.Min.O+o, a.Bound.Min.L+l, r, c.Fg, c.Bg)
+			r = printable(r)
+			setCell(a.Bound.Min.O+o, a.Bound.Min.L+l, r, c.Fg, c.Bg)
 			o += runewidth.RuneWidth(r)
 		}
 	}
 }
 
+// TitleCut reports whether title of the commit doesn't fit in the area.
+func (a *CommitArea) TitleCut(c *Commit) bool {
+	o := runewidth.StringWidth(commitBadges(c))
+	if o != 0 {
+		o++
+	}
+	o += dig.GraphWidth()
+	if a.ShowHash() {
+		o += len(c.Abbrev) + 1
+	}
+	o += columnsWidth(a.Columns())
+	return runewidth.StringWidth(c.Title) > a.Bound.Size.O-o
+}
+
+// commitBadges returns short markers of the commit those are shown before it's title.
+// Each kind of marker has a slot only when the kind is in use, to align titles.
+func commitBadges(c *Commit) string {
+	b := ""
+	if dig.Collapsing() {
+		b += runBadge(c)
+	}
+	if dig.Roots > 1 {
+		// roots are interesting only when histories are joined.
+		if len(c.Parents) == 0 {
+			b += "R"
+		} else {
+			b += " "
+		}
+	}
+	if len(dig.Marks) != 0 {
+		if dig.Marks.Has(c.Hash) {
+			b += "+"
+		} else {
+			b += " "
+		}
+	}
+	if len(dig.Pins) != 0 {
+		if dig.Pinned(c.Hash) {
+			b += "*"
+		} else {
+			b += " "
+		}
+	}
+	if len(dig.Watched) != 0 {
+		if dig.Watched[c.Hash] {
+			b += "!"
+		} else {
+			b += " "
+		}
+	}
+	if len(dig.Notes) != 0 {
+		if _, ok := dig.Notes[c.Hash]; ok {
+			b += "n"
+		} else {
+			b += " "
+		}
+	}
+	if config.TestBadge {
+		if touchesTests(c) {
+			b += "T"
+		} else {
+			b += " "
+		}
+	}
+	return b
+}
+
 // Commit is currently selected commit.
 func (a *CommitArea) Commit() *Commit {
 	return dig.Commits[a.CurIdx]
@@ -247,107 +801,538 @@ type DiffArea struct {
 	CommitHash string
 	Text       [][]byte
 
+	// Full is the diff before generated files are collapsed.
+	Full      [][]byte
+	Generated map[string]bool
+	Expanded  map[string]bool
+
 	Bound Rect
 	Win   *Window
 
 	WindowPoses map[string]Pt
+	Cache       *DiffCache
+
+	// Fixed is a commit hash that the area always shows.
+	// When it's empty, the area shows the selected commit.
+	Fixed string
+
+	// Marks are named lines of current commit's diff.
+	Marks map[rune]int
+
+	// Note explains what's shown instead of the commit's diff, like for an empty merge.
+	Note string
+
+	// syntax are spans of syntaxText's lines. See Syntax.
+	syntax     [][]span
+	syntaxText [][]byte
+}
+
+// DiffCache keeps diffs of recently viewed commits,
+// so coming back to a commit doesn't need to run git again.
+type DiffCache struct {
+	max   int
+	diffs map[string][][]byte
+	order []string // least recently added first
+}
+
+// newDiffCache creates a new DiffCache holds at maximum n diffs.
+func newDiffCache(n int) *DiffCache {
+	return &DiffCache{max: n, diffs: make(map[string][][]byte)}
+}
+
+// Get gets the diff of the commit, running git only if it isn't cached.
+// The hash could also be a range of commits like "A..B",
+// then it gets the diff between the two commits.
+func (c *DiffCache) Get(hash string) ([][]byte, error) {
+	if d, ok := c.diffs[hash]; ok {
+		return d, nil
+	}
+	var d [][]byte
+	var err error
+	if strings.HasPrefix(hash, patchKeyPrefix) {
+		d, err = patchDiff(hash)
+	} else if strings.HasPrefix(hash, mergeKeyPrefix) {
+		d, err = mergeDiff(hash)
+	} else if isWordDiffKey(hash) {
+		d, err = wordDiff(hash)
+	} else if i := strings.Index(hash, ".."); i != -1 {
+		d, err = rangeDiff(hash[:i], hash[i+2:])
+	} else {
+		d, err = commitDiff(hash)
+	}
+	cut, isCut := err.(*cutError)
+	if err != nil && !isCut {
+		return nil, err
+	}
+	d = decodeDiff(d)
+	d = describeModes(d)
+	d = annotateLFS(d)
+	if isCut {
+		d = append(d, cutLine(cut))
+	}
+	if len(c.order) == c.max {
+		delete(c.diffs, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.diffs[hash] = d
+	c.order = append(c.order, hash)
+	return d, nil
+}
+
+// Remove removes the diff from the cache, so it's loaded again.
+func (c *DiffCache) Remove(hash string) {
+	if _, ok := c.diffs[hash]; !ok {
+		return
+	}
+	delete(c.diffs, hash)
+	for i, h := range c.order {
+		if h == hash {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
 }
 
 // Handle handles a terminal event.
-func (a *DiffArea) Handle(ev termbox.Event) bool {
-	if ev.Key == termbox.KeyPgdn || ev.Key == termbox.KeySpace || ev.Ch == 'f' || ev.Ch == ',' {
-		a.Win.PageForward()
+func (a *DiffArea) Handle(ev Event) bool {
+	if ev.Key == KeyPgdn || ev.Key == KeySpace || ev.Ch == 'f' || ev.Ch == ',' {
+		a.Win.MoveDown(a.Win.Bound.Size.L * count())
 		return true
-	} else if ev.Key == termbox.KeyPgup || ev.Ch == 'b' || ev.Ch == 'm' {
-		a.Win.PageBackward()
+	} else if ev.Key == KeyPgup || ev.Ch == 'b' || ev.Ch == 'm' {
+		a.Win.MoveUp(a.Win.Bound.Size.L * count())
 		return true
 	} else if ev.Ch == 'd' || ev.Ch == 'o' {
-		a.Win.HalfPageForward()
+		a.Win.MoveDown(a.Win.Bound.Size.L / 2 * count())
 		return true
 	} else if ev.Ch == 'u' {
-		a.Win.HalfPageBackward()
+		a.Win.MoveUp(a.Win.Bound.Size.L / 2 * count())
+		return true
+	} else if ev.Key == KeyArrowUp || ev.Ch == 'i' {
+		if a.Win.HasCursor {
+			a.Win.CursorUp(count())
+		} else {
+			a.Win.MoveUp(count())
+		}
+		return true
+	} else if ev.Key == KeyArrowDown || ev.Ch == 'k' {
+		if a.Win.HasCursor {
+			a.Win.CursorDown(count())
+		} else {
+			a.Win.MoveDown(count())
+		}
+		return true
+	} else if ev.Key == KeyArrowLeft || ev.Ch == 'j' {
+		a.Win.MoveLeft(4 * count())
+		return true
+	} else if ev.Key == KeyArrowRight || ev.Ch == 'l' {
+		a.Win.MoveRight(4 * count())
+		return true
+	} else if ev.Ch == ']' {
+		a.NextHunk(count())
+		return true
+	} else if ev.Ch == '[' {
+		a.PrevHunk(count())
+		return true
+	} else if ev.Ch == '}' || ev.Ch == '{' {
+		n := count()
+		if ev.Ch == '{' {
+			n = -n
+		}
+		if err := a.NextHunkInContext(n); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	} else if ev.Ch == 'M' || ev.Ch == '\'' {
+		dig.Prefix = ev.Ch
+		return true
+	} else if ev.Ch == 'S' {
+		screen.ToggleSplit()
 		return true
-	} else if ev.Key == termbox.KeyArrowUp || ev.Ch == 'i' {
-		a.Win.MoveUp(1)
+	} else if ev.Ch == 'E' {
+		if err := a.loadRest(); err != nil {
+			dig.Message = err.Error()
+		}
 		return true
-	} else if ev.Key == termbox.KeyArrowDown || ev.Ch == 'k' {
-		a.Win.MoveDown(1)
+	} else if ev.Ch == 'P' {
+		if err := a.cycleEmptyMerge(); err != nil {
+			dig.Message = err.Error()
+		}
 		return true
-	} else if ev.Key == termbox.KeyArrowLeft || ev.Ch == 'j' {
-		a.Win.MoveLeft(4)
+	} else if ev.Ch == 'B' {
+		if err := blameFromDiff(a, a.AnchorLine()); err != nil {
+			dig.Message = err.Error()
+		}
 		return true
-	} else if ev.Key == termbox.KeyArrowRight || ev.Ch == 'l' {
-		a.Win.MoveRight(4)
+	} else if ev.Ch == 'y' {
+		if err := a.CopyLine(a.AnchorLine()); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	} else if ev.Ch == 'e' {
+		if err := a.OpenEditor(a.AnchorLine()); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	} else if ev.Ch == 'H' {
+		if err := touchedSinceAt(a, a.AnchorLine()); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	} else if ev.Ch == 'h' {
+		if err := historyAt(a, a.AnchorLine()); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	} else if ev.Ch == 'w' {
+		if err := wordDiffAt(a, a.AnchorLine()); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	} else if ev.Ch == 'z' {
+		if err := a.ToggleGenerated(a.AnchorLine()); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	} else if ev.Key == KeyCtrlW {
+		if screen.Split {
+			screen.Focus = 1 - screen.Focus
+		}
 		return true
 	}
-	if ev.Key == termbox.KeyCtrlP {
-		screen.Commit.CursorUp(1)
+	if ev.Key == KeyCtrlP {
+		screen.Commit.CursorUp(count())
 		return true
-	} else if ev.Key == termbox.KeyCtrlN {
-		screen.Commit.CursorDown(1)
+	} else if ev.Key == KeyCtrlN {
+		screen.Commit.CursorDown(count())
 		return true
 	}
 	return false
 }
 
-// Draw draws it's contents.
-func (a *DiffArea) Draw() {
-	hash := screen.Commit.Commit().Hash
-	if hash != a.CommitHash {
-		a.WindowPoses[a.CommitHash] = a.Win.Bound.Min
+// Sync loads the diff of the commit the area should show, if it isn't loaded yet.
+func (a *DiffArea) Sync() {
+	hash := a.Fixed
+	if hash == "" {
+		hash = screen.Commit.Commit().Hash
+	}
+	if hash == a.CommitHash {
+		return
+	}
+	a.WindowPoses[a.CommitHash] = a.Win.Bound.Min
+
+	a.CommitHash = hash
+	a.Marks = make(map[rune]int)
+	a.Full, _ = a.Cache.Get(hash) // ignore error for now
+	var key string
+	key, a.Note = emptyMerge(hash, a.Full)
+	if key != "" {
+		if d, err := a.Cache.Get(key); err == nil {
+			a.Full = d
+		}
+	}
+	if note := cutNote(a.Full); note != "" {
+		a.Note = note
+	}
+	a.Generated = nil
+	if config.CollapseGenerated {
+		a.Generated = generatedFiles(hash, a.Full)
+	}
+	a.Expanded = make(map[string]bool)
+	a.Text = collapseGenerated(a.Full, a.Generated, a.Expanded)
+	a.Win.Reset(a.Text)
+	// get zero value is fine when the lookup is failed.
+	a.Win.Bound.Min = a.WindowPoses[hash]
+	a.Win.Cursor = a.Win.Bound.Min.L
+	a.Win.follow()
+}
+
+// AnchorLine returns the line that actions like blame work on.
+// It's the cursor line, or the first line of a hunk in the window without a cursor.
+func (a *DiffArea) AnchorLine() int {
+	if a.Win.HasCursor {
+		return a.Win.Cursor
+	}
+	// skip the top line, it could be hidden by the sticky file header.
+	l := a.Win.Bound.Min.L + 1
+	for ; l < a.Win.Bound.Min.L+a.Win.Bound.Size.L && l < len(a.Text); l++ {
+		if _, ok := diffLineAt(a.Text, l); ok {
+			break
+		}
+	}
+	return l
+}
+
+// GotoFile moves the window to the diff of the file.
+func (a *DiffArea) GotoFile(path string) {
+	a.Sync()
+	for l, ln := range a.Text {
+		if isFileHeader(ln) && fileName(ln) == path {
+			a.Win.Goto(l)
+			return
+		}
+	}
+}
 
-		a.CommitHash = hash
-		a.Text, _ = commitDiff(hash) // ignore error for now
-		a.Win.Reset(a.Text)
-		// get zero value is fine when the lookup is failed.
-		a.Win.Bound.Min = a.WindowPoses[hash]
+// HandlePrefixed handles an event that follows a prefix key.
+func (a *DiffArea) HandlePrefixed(prefix rune, ev Event) bool {
+	if ev.Ch == 0 {
+		return false
+	}
+	switch prefix {
+	case 'M':
+		a.SetMark(ev.Ch)
+		return true
+	case '\'':
+		if err := a.JumpMark(ev.Ch); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	}
+	return false
+}
+
+// SetMark sets a named mark on the top line of the window.
+func (a *DiffArea) SetMark(name rune) {
+	a.Marks[name] = a.Win.Line()
+	dig.Message = fmt.Sprintf("mark '%c' set", name)
+}
+
+// JumpMark moves the window to a named mark.
+// Like vim, the previous position is remembered as the ' mark.
+func (a *DiffArea) JumpMark(name rune) error {
+	l, ok := a.Marks[name]
+	if !ok {
+		return fmt.Errorf("mark '%c' not set", name)
+	}
+	a.Marks['\''] = a.Win.Line()
+	a.Win.Goto(l)
+	return nil
+}
+
+// NextHunk moves the window to n-th next hunk.
+// When there are not enough hunks, it moves to the last hunk.
+func (a *DiffArea) NextHunk(n int) {
+	for l := a.Win.Line() + 1; l < len(a.Text) && n > 0; l++ {
+		if bytes.HasPrefix(a.Text[l], []byte("@@")) {
+			a.Win.Goto(l)
+			n--
+		}
 	}
+}
+
+// PrevHunk moves the window to n-th previous hunk.
+// When there are not enough hunks, it moves to the first hunk.
+func (a *DiffArea) PrevHunk(n int) {
+	for l := a.Win.Line() - 1; l >= 0 && n > 0; l-- {
+		if bytes.HasPrefix(a.Text[l], []byte("@@")) {
+			a.Win.Goto(l)
+			n--
+		}
+	}
+}
+
+// Draw draws it's contents.
+func (a *DiffArea) Draw() {
+	a.Sync()
 	minL := a.Win.Bound.Min.L
 	maxL := a.Win.Bound.Min.L + a.Win.Bound.Size.L
 	if maxL > len(a.Text) {
 		maxL = len(a.Text)
 	}
+	// word diffs mark changes in lines, instead of the first column.
+	word := isWordDiffKey(a.CommitHash)
+	if screen.Compact() && !word {
+		a.drawCompact()
+		return
+	}
+	syntax := a.Syntax()
 	for l, ln := range a.Text[minL:maxL] {
-		c := Color{termbox.ColorWhite, termbox.ColorBlack}
-		if len(ln) != 0 {
-			first := string(ln[0])
-			if first == "+" {
-				c = Color{termbox.ColorGreen, termbox.ColorBlack}
-			} else if first == "-" {
-				c = Color{termbox.ColorRed, termbox.ColorBlack}
-			}
+		c := diffLineColor(ln, word)
+		if a.Win.HasCursor && minL+l == a.Win.Cursor {
+			c.Bg = theme.Selected.Bg
+			fillColor(Rect{Min: Pt{a.Bound.Min.L + l, a.Bound.Min.O}, Size: Pt{1, a.Bound.Size.O}}, c)
 		}
-		// relative offset in window
-		// we can't just clipping remain, as we did with a.Text's lines (l).
-		// because o should be calculated rune by rune.
-		o := -a.Win.Bound.Min.O
-		remain := ln
-		for {
-			if len(remain) == 0 {
-				break
-			}
-			if o >= a.Bound.Size.O {
-				break
-			}
-			r, size := utf8.DecodeRune(remain)
-			remain = remain[size:]
-			if o >= 0 {
-				termbox.SetCell(a.Bound.Min.O+o, a.Bound.Min.L+l, r, c.Fg, c.Bg)
+		if word {
+			drawWordLine(a.Bound, l, ln, a.Win.Bound.Min.O, c)
+			continue
+		}
+		if syntax != nil && syntax[minL+l] != nil {
+			drawSpans(a.Bound, l, ln, a.Win.Bound.Min.O, c, syntax[minL+l])
+		} else {
+			drawLine(a.Bound, l, ln, a.Win.Bound.Min.O, c)
+			drawHunkContext(a.Bound, l, ln, a.Win.Bound.Min.O, c)
+		}
+		if a.Win.Bound.Min.O == 0 {
+			a.addLinks(l, ln, c)
+		}
+	}
+	// keep the file header at top, to know which file the hunks belong to.
+	if minL < len(a.Text) && !isFileHeader(a.Text[minL]) {
+		if h := fileHeaderAt(a.Text, minL); h != -1 {
+			top := Rect{Min: a.Bound.Min, Size: Pt{1, a.Bound.Size.O}}
+			fillColor(top, theme.Header)
+			drawLine(top, 0, a.Text[h], 0, theme.Header)
+			dropLinks(top.Min.L)
+			a.addLinks(0, a.Text[h], theme.Header)
+			// and the function the hunk at top is in.
+			if ctx := hunkContextAt(a.Text, minL); ctx != nil {
+				o := runewidth.StringWidth(string(a.Text[h])) + 2
+				drawLine(top, 0, ctx, -o, Color{theme.Func, theme.Header.Bg})
 			}
-			o += runewidth.RuneWidth(r)
 		}
 	}
 }
 
-// Window is a cursor which has size.
-type Window struct {
-	Bound Rect
-	Text  [][]byte
+// diffLineColor returns color of a line of a diff.
+func diffLineColor(ln []byte, word bool) Color {
+	c := theme.Normal
+	if len(ln) == 0 || word {
+		return c
+	}
+	first := string(ln[0])
+	if first == "+" {
+		c = theme.Added
+	} else if first == "-" {
+		c = theme.Deleted
+	} else if mc, ok := modeColor(ln); ok {
+		c = mc
+	} else if isGeneratedSummary(ln) {
+		c = theme.Header
+	} else if first == "@" && bytes.HasPrefix(ln, []byte("@@")) {
+		c = theme.Frag
+	} else if isMetaLine(ln) {
+		c = theme.Meta
+	} else if isCutLine(ln) {
+		c = theme.Error
+	}
+	return c
+}
+
+// metaPrefixes are prefixes of lines in file headers of a diff, except the ---/+++ lines.
+var metaPrefixes = []string{
+	"diff --git ", "diff --cc ", "diff --combined ", "index ",
+	"new file mode ", "deleted file mode ", "old mode ", "new mode ",
+	"similarity index ", "dissimilarity index ", "rename from ", "rename to ", "copy from ", "copy to ",
+}
+
+// isMetaLine reports whether the line is in a file header of a diff.
+// Lines of hunks and commit messages don't start with them.
+func isMetaLine(ln []byte) bool {
+	return hasAnyPrefix(ln, metaPrefixes)
+}
+
+// drawLine draws a line of text at l-th line of the bound.
+// The line will be shifted left by shift, and clipped by the bound.
+func drawLine(bound Rect, l int, ln []byte, shift int, c Color) {
+	// relative offset in window
+	// we can't just clipping remain, as we did with lines (l).
+	// because o should be calculated rune by rune.
+	o := -shift
+	remain := ln
+	for {
+		if len(remain) == 0 {
+			break
+		}
+		if o >= bound.Size.O {
+			break
+		}
+		r, size := utf8.DecodeRune(remain)
+		remain = remain[size:]
+		r = printable(r)
+		if o >= 0 {
+			setCell(bound.Min.O+o, bound.Min.L+l, r, c.Fg, c.Bg)
+		}
+		o += runewidth.RuneWidth(r)
+	}
+}
+
+// printable returns a rune that is safe to draw instead of a control character.
+// Control characters, like escape sequences in a committed file,
+// would be interpreted by the terminal and break the screen.
+// C0 controls and DEL are shown as their control pictures, like ␛ for escape.
+func printable(r rune) rune {
+	if r < 0x20 {
+		return 0x2400 + r
+	}
+	if r == 0x7F {
+		return '␡'
+	}
+	if r >= 0x80 && r < 0xA0 {
+		// C1 controls don't have pictures.
+		return utf8.RuneError
+	}
+	return r
+}
+
+// Window is a cursor which has size.
+type Window struct {
+	Bound Rect
+	Text  [][]byte
+
+	// Cursor is the current line, when the window has a cursor.
+	// Then the window scrolls to keep the cursor visible.
+	Cursor    int
+	HasCursor bool
+}
+
+func (w *Window) Reset(t [][]byte) {
+	w.Text = t
+	w.Bound.Min = Pt{0, 0}
+	w.Cursor = 0
+}
+
+// Line returns the current line.
+// It's the cursor, or the top line without a cursor.
+func (w *Window) Line() int {
+	if w.HasCursor {
+		return w.Cursor
+	}
+	return w.Bound.Min.L
+}
+
+// Goto moves to the line, so it's the current line.
+func (w *Window) Goto(l int) {
+	w.Bound.Min.L = l
+	if w.HasCursor {
+		w.Cursor = l
+		w.follow()
+	}
+}
+
+// CursorUp moves the cursor up at maximum n.
+func (w *Window) CursorUp(n int) {
+	w.Cursor -= n
+	w.follow()
 }
 
-func (w *Window) Reset(t [][]byte) {
-	w.Text = t
-	w.Bound.Min = Pt{0, 0}
+// CursorDown moves the cursor down at maximum n.
+func (w *Window) CursorDown(n int) {
+	w.Cursor += n
+	w.follow()
+}
+
+// follow keeps the cursor in the text, and scrolls the window to show it.
+// A line above the cursor is kept visible, since the top line could be
+// covered by a sticky header.
+func (w *Window) follow() {
+	if !w.HasCursor {
+		return
+	}
+	if w.Cursor >= len(w.Text) {
+		w.Cursor = len(w.Text) - 1
+	}
+	if w.Cursor < 0 {
+		w.Cursor = 0
+	}
+	if w.Cursor-1 < w.Bound.Min.L {
+		w.Bound.Min.L = w.Cursor - 1
+		if w.Bound.Min.L < 0 {
+			w.Bound.Min.L = 0
+		}
+	}
+	if w.Cursor >= w.Bound.Min.L+w.Bound.Size.L {
+		w.Bound.Min.L = w.Cursor - w.Bound.Size.L + 1
+	}
 }
 
 // PageForward moves a window a page forward.
@@ -377,6 +1362,8 @@ func (w *Window) MoveUp(n int) {
 	if w.Bound.Min.L < 0 {
 		w.Bound.Min.L = 0
 	}
+	// the cursor stays at the same row.
+	w.CursorUp(n)
 }
 
 // MoveDown moves down at maximum n.
@@ -386,6 +1373,8 @@ func (w *Window) MoveDown(n int) {
 	if w.Bound.Min.L >= len(w.Text) {
 		w.Bound.Min.L = len(w.Text) - 1
 	}
+	// the cursor stays at the same row.
+	w.CursorDown(n)
 }
 
 // MoveLeft moves left at maximum n.
@@ -410,9 +1399,119 @@ type StatusArea struct {
 func (a StatusArea) Draw() {
 	var drawString string
 	if dig.Mode == NormalMode {
-		drawString = "q: quit, k: down, i: up, f: page down, b: page up, <: shirink side, >: expand side"
+		switch dig.CurView {
+		case TreeView:
+			drawString = "q: back, k: down, i: up, enter: open, j: collapse, " + screen.Tree.CommitHash
+		case FileView:
+			drawString = "q: back, k: down, i: up, f: page down, b: page up, " + screen.File.Path
+		case ReportView:
+			drawString = "q: back, k: down, i: up, s: sort, enter: file history"
+		case TrayView:
+			drawString = "q: back, space: mark, d: diff marked, x: unpin, e: export patches, r: report"
+		case StatView:
+			drawString = "q: back, k: down, i: up, space: fold group, enter: diff of file"
+		case BlameView:
+			drawString = "q: back, k: down, i: up, enter: go to commit, B: blame before it, ctrl+o: jump back"
+		case ReleaseView:
+			drawString = "q: back, k: down, i: up, space: fold group, enter: diff, a: group by type/author, e: export"
+		case MailView:
+			drawString = "q: back, k: down, i: up, enter: diff, a: apply, s: skip, A: apply all"
+		case FilesView:
+			drawString = "q: back, k: down, i: up, enter: focus diff, tab: next pane"
+			if screen.Files.Alone {
+				drawString = "q: back, k: down, i: up, enter: diff of file"
+			}
+		case BranchView:
+			drawString = "q: back, k: down, i: up, enter: show commits of the branch, *: checked out"
+		default:
+			drawString = "?: help, q: quit, k: down, i: up, f: page down, b: page up, t: tree, B: branches, <: shirink side, >: expand side"
+			c := screen.Commit.Commit()
+			if dig.CurView == DiffView && screen.FocusedDiff().Note != "" {
+				drawString = screen.FocusedDiff().Note
+			} else if note, ok := dig.Notes[c.Hash]; ok {
+				drawString = "note: " + strings.Replace(note, "\n", " / ", -1)
+			} else if screen.Commit.TitleCut(c) {
+				// show the title that is cut in the commit list.
+				drawString = c.Title
+			}
+		}
 	} else if dig.Mode == FindMode {
 		drawString = "find: " + dig.FindString
+		if dig.FileScope && dig.CurView == DiffView {
+			drawString = "find in file: " + dig.FindString
+		}
+		if m := matchStatus(); m != "" && dig.Search.Word == dig.FindString {
+			drawString += " (" + m + ")"
+		}
+	} else if dig.Mode == CommandMode {
+		drawString = ":" + dig.CommandString
+	} else if dig.Mode == NoteMode {
+		drawString = "editing note"
+	} else if dig.Mode == TimelineMode {
+		drawString = "timeline " + dig.Timeline.String() + " j/l: move, b/f: faster, enter: done, esc: cancel"
+	} else if dig.Mode == CopyMode {
+		drawString = "copy: arrows: move, v: select, r: rectangle, y: copy, esc: cancel"
+	} else if dig.Mode == QueryMode {
+		drawString = fmt.Sprintf("filter: %s (%s)", dig.Query, pluralize(len(dig.Commits), "commit"))
+		if dig.Message != "" {
+			drawString += " " + dig.Message
+		}
+	}
+	if dig.Mode == NormalMode && config.Simple {
+		if s := simpleStatus(); s != "" {
+			drawString = s
+		}
+	}
+	if dig.Mode == NormalMode {
+		if m := matchStatus(); m != "" {
+			drawString = fmt.Sprintf("[%s: %s] ", m, dig.Search.Word) + drawString
+		}
+	}
+	if dig.Mode == NormalMode && dig.Message != "" {
+		drawString = dig.Message
+	}
+	if dig.Mode == NormalMode && dig.CurView == CommitView && len(dig.Filters) != 0 {
+		drawString = "[" + dig.FilterString() + "] " + drawString
+	}
+	if dig.Mode == NormalMode && dig.CurView == CommitView && dig.Simplify != "" {
+		drawString = "[" + dig.Simplify + "] " + drawString
+	}
+	if dig.Mode == NormalMode && dig.CurView == CommitView && len(dig.Marks) != 0 {
+		drawString = fmt.Sprintf("[%d marked, X: actions] ", len(dig.Marks)) + drawString
+	}
+	if dig.Loader != nil {
+		drawString = fmt.Sprintf("[loading, %d commits] ", len(dig.All)) + drawString
+	}
+	if len(tabs) > 1 {
+		drawString = fmt.Sprintf("[%d/%d] ", curTab+1, len(tabs)) + drawString
+	}
+	if dig.Mode == NormalMode && screen.Walk != nil {
+		// only the walkthrough matters while presenting.
+		drawString = screen.Walk.Status()
+		if dig.Message != "" {
+			drawString = dig.Message
+		}
+	}
+	if dig.Mode == NormalMode && (dig.Count != 0 || dig.Prefix != 0) {
+		drawString = ""
+		if dig.Count != 0 {
+			drawString = strconv.Itoa(dig.Count)
+		}
+		if dig.Prefix != 0 {
+			drawString += string(dig.Prefix)
+		}
+	}
+	fillColor(a.Bound, theme.Status)
+	// a taller status bar has it's text at the middle.
+	l := a.Bound.Min.L + a.Bound.Size.L/2
+	fg := theme.Status.Fg
+	if a.Bound.Size.L > 1 {
+		fg |= AttrBold
+	}
+	right := a.Bound.Min.O + a.Bound.Size.O
+	if len(config.StatusSegments) != 0 {
+		// leave a space between the text and segments.
+		right = drawSegments(l, right, fg) - 1
 	}
 	remain := drawString
 	o := 0
@@ -422,13 +1521,16 @@ func (a StatusArea) Draw() {
 		}
 		r, size := utf8.DecodeRuneInString(remain)
 		remain = remain[size:]
-		termbox.SetCell(o, a.Bound.Min.L, r, termbox.ColorBlack, termbox.ColorWhite)
+		r = printable(r)
+		if config.WideStatus {
+			r = wideRune(r)
+		}
+		if o+runewidth.RuneWidth(r) > right {
+			break
+		}
+		setCell(o, l, r, fg, theme.Status.Bg)
 		o += runewidth.RuneWidth(r)
 	}
-	for o < a.Bound.Size.O {
-		termbox.SetCell(o, a.Bound.Min.L, ' ', termbox.ColorBlack, termbox.ColorWhite)
-		o++
-	}
 }
 
 // Rect is a rectangle.
@@ -437,6 +1539,11 @@ type Rect struct {
 	Size Pt
 }
 
+// Contains reports whether the point is in the rectangle.
+func (r Rect) Contains(p Pt) bool {
+	return p.L >= r.Min.L && p.L < r.Min.L+r.Size.L && p.O >= r.Min.O && p.O < r.Min.O+r.Size.O
+}
+
 // Pt is a point.
 type Pt struct {
 	L int
@@ -450,87 +1557,367 @@ func (p Pt) Add(q Pt) Pt {
 
 // Color is terminal color.
 type Color struct {
-	Fg termbox.Attribute
-	Bg termbox.Attribute
+	Fg Attribute
+	Bg Attribute
 }
 
 // Commit is a git commit.
 type Commit struct {
-	Hash  string
-	Title string
+	Hash string
+	// Abbrev is an abbreviated hash that is unique in the repository.
+	Abbrev string
+	// Time is the committer time.
+	Time   time.Time
+	Author string
+	Email  string
+	Title  string
+	// Parents are hashes of parent commits, the first parent comes first.
+	Parents []string
+	// Fields are values of config.LogFields, in order.
+	Fields []string
+	// AuthorTime is when the author made the commit, it could differ from Time by a rebase.
+	AuthorTime     time.Time
+	Committer      string
+	CommitterEmail string
+	// Refs are branches and tags at the commit, like "HEAD -> main" and "tag: v1.0".
+	Refs []string
+	// Body is the commit message after the title, without the trailing newlines.
+	Body string
+	// Files are changed files of the commit. It's only loaded when needed.
+	// See Program.LoadFiles.
+	Files []string
+}
+
+// shortHash returns the unique abbreviated hash that git computed.
+// For a commit not in the list, it returns first 8 characters of the hash.
+func shortHash(hash string) string {
+	if c, ok := dig.ByHash[hash]; ok {
+		return c.Abbrev
+	}
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	return hash
+}
+
+// resolveHash resolves a revision, including an abbreviated hash,
+// to the full commit hash.
+func resolveHash(rev string) (string, error) {
+	hashes, err := backend.RevParse(dig.RepoDir, []string{rev + "^{commit}"})
+	if err != nil || len(hashes) != 1 || !isFullHash(hashes[0]) {
+		return "", fmt.Errorf("unknown revision: %s", rev)
+	}
+	return hashes[0], nil
+}
+
+// isHashLike reports whether s looks like an (abbreviated) commit hash.
+func isHashLike(s string) bool {
+	if len(s) < 4 || len(s) > 40 {
+		return false
+	}
+	for _, r := range s {
+		if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isFullHash reports whether s is a full commit hash, of SHA-1 or SHA-256.
+func isFullHash(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, r := range s {
+		if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f') {
+			return false
+		}
+	}
+	return true
 }
 
 // allCommits find a repository and get it's commits.
 func allCommits(repodir string, targets []string, digUp bool) ([]*Commit, error) {
-	args := []string{"log", "--pretty=format:%H%n%s%n"}
-	args = append(args, targets...)
-	cmd := exec.Command("git", args...)
-	cmd.Dir = repodir
-	out, err := cmd.CombinedOutput()
+	return backend.Commits(repodir, targets, digUp)
+}
+
+// splitPaths splits arguments after flags to revisions and pathspecs, those are after --.
+// The flag package drops the first --, so it's found in all arguments.
+func splitPaths(all, args []string) (targets, paths []string) {
+	for i, a := range args {
+		if a == "--" {
+			return args[:i], args[i+1:]
+		}
+	}
+	if n := len(all) - len(args); n > 0 && all[n-1] == "--" {
+		return nil, args
+	}
+	return args, nil
+}
+
+// reloadCommits reloads commits of the program with it's current targets.
+// It tries to keep the cursor on the same commit.
+func reloadCommits() error {
+	if dig.Loader != nil {
+		dig.Loader.Stop()
+	}
+	commits, err := allCommits(dig.RepoDir, dig.LogTargets(), dig.DigUp)
 	if err != nil {
-		return nil, errors.New(string(out))
+		return err
 	}
-	// tab handling in screen is quite awkard. handle it here.
-	out = bytes.Replace(out, []byte("\t"), []byte("    "), -1)
-	commits := []*Commit{}
-	commitStrings := strings.Split(string(out), "\n\n")
-	last := len(commitStrings) - 1
-	for i := range commitStrings {
-		j := i
-		if digUp {
-			j = last - i
-		}
-		c := commitStrings[j] // first commit live at last.
-		l := strings.Split(c, "\n")
-		commits = append(commits, &Commit{Hash: l[0], Title: l[1]})
+	keepCursor(func() {
+		dig.SetCommits(commits)
+		err = dig.FilterCommits()
+	})
+	return err
+}
+
+// keepCursor calls fn that changes commits of the program.
+// Then it tries to move the cursor back to the same commit, or the nearest one when it's hidden.
+func keepCursor(fn func()) {
+	a := screen.Commit
+	hash := a.Commit().Hash
+	row := a.CurIdx - a.TopIdx
+	fn()
+	a.SetCursor(0)
+	if i := nearestCommit(dig.Commits, dig.All, hash); i != -1 {
+		a.SetCursor(i)
+	}
+	// keep the commit at the same row of the screen, if possible.
+	a.TopIdx = a.CurIdx - row
+	if a.TopIdx < 0 {
+		a.TopIdx = 0
 	}
-	return commits, nil
 }
 
-// commitDiff returns changes of a commit.
+// commitDiff returns changes of a commit, in the program's paths.
+// It could be cut before it's end, see cutDiff.
 func commitDiff(hash string) ([][]byte, error) {
-	cmd := exec.Command("git", "show", hash)
-	cmd.Dir = dig.RepoDir
-	out, err := cmd.CombinedOutput()
+	return backend.CommitDiff(dig.RepoDir, hash, dig.Paths, fullDiffs[hash])
+}
+
+// pathArgs returns the arguments of git, with the paths after --.
+func pathArgs(args, paths []string) []string {
+	if len(paths) == 0 {
+		return args
+	}
+	a := append([]string{}, args...)
+	a = append(a, "--")
+	return append(a, paths...)
+}
+
+// gitOutput runs git with args in the repository, and returns it's output.
+// When git failed, the error contains what git said.
+func gitOutput(args ...string) ([]byte, error) {
+	return gitOutputIn(dig.RepoDir, args...)
+}
+
+// gitOutputIn is gitOutput in the directory, for a repository other than the current tab's.
+func gitOutputIn(dir string, args ...string) ([]byte, error) {
+	cmd := gitCommand(dir, args...)
+	out, err := cmd.Output()
+	if err != nil {
+		if gitKilled(cmd) {
+			return nil, timeoutError(gitSubcommand(args))
+		}
+		if e, ok := err.(*exec.ExitError); ok && len(e.Stderr) != 0 {
+			return nil, errors.New(strings.TrimSpace(string(e.Stderr)))
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// openDiff opens DiffView for a commit or a range of commits.
+// Leaving DiffView will go back to the current view.
+func openDiff(rev string) {
+	screen.Diff.Fixed = rev
+	dig.DiffFrom = dig.CurView
+	dig.CurView = DiffView
+}
+
+// rangeDiff returns changes between two commits, in the program's paths.
+func rangeDiff(from, to string) ([][]byte, error) {
+	out, err := gitOutput(pathArgs([]string{"diff", from, to}, dig.Paths)...)
 	if err != nil {
 		return nil, err
 	}
-	// tab handling in screen is quite awkard. handle it here.
 	out = bytes.Replace(out, []byte("\t"), []byte("    "), -1)
 	out = bytes.TrimRight(out, " \n")
-	lines := bytes.Split(out, []byte("\n"))
-	return lines, err
+	return bytes.Split(out, []byte("\n")), nil
 }
 
 // handleNormal handles NormalMode events.
 // When the event was handled, it will return true.
-func handleNormal(ev termbox.Event) {
+func handleNormal(ev Event) {
+	if screen.Popup != nil {
+		screen.Popup.Handle(ev)
+		return
+	}
+	// count prefix, like vim.
+	if ev.Ch >= '1' && ev.Ch <= '9' || ev.Ch == '0' && dig.Count != 0 {
+		if dig.Count < 100000 {
+			dig.Count = dig.Count*10 + int(ev.Ch-'0')
+		}
+		return
+	}
+	defer func() {
+		// count should be kept for the key after the prefix.
+		if dig.Prefix == 0 {
+			dig.Count = 0
+		}
+	}()
+	if dig.Prefix != 0 {
+		prefix := dig.Prefix
+		dig.Prefix = 0
+		if prefix == 'g' {
+			if !handleTabPrefixed(ev) && (dig.CurView == CommitView || dig.CurView == DiffView) {
+				handleFamilyPrefixed(ev)
+			}
+		} else if dig.CurView == DiffView {
+			screen.FocusedDiff().HandlePrefixed(prefix, ev)
+		}
+		return
+	}
+	if screen.Walk != nil && screen.Walk.Handle(ev) {
+		return
+	}
 	if ok := handleNormalGlobal(ev); ok {
 		return
 	}
 	if dig.CurView == CommitView {
 		screen.Commit.Handle(ev)
 	} else if dig.CurView == DiffView {
-		screen.Diff.Handle(ev)
+		screen.FocusedDiff().Handle(ev)
+	} else if dig.CurView == TreeView {
+		screen.Tree.Handle(ev)
+	} else if dig.CurView == FileView {
+		screen.File.Handle(ev)
+	} else if dig.CurView == ReportView {
+		screen.Report.Handle(ev)
+	} else if dig.CurView == TrayView {
+		screen.Tray.Handle(ev)
+	} else if dig.CurView == StatView {
+		screen.Stat.Handle(ev)
+	} else if dig.CurView == BlameView {
+		screen.Blame.Handle(ev)
+	} else if dig.CurView == ReleaseView {
+		screen.Release.Handle(ev)
+	} else if dig.CurView == MailView {
+		screen.Mail.Handle(ev)
+	} else if dig.CurView == FilesView {
+		screen.Files.Handle(ev)
+	} else if dig.CurView == BranchView {
+		screen.Branch.Handle(ev)
+	}
+}
+
+// count returns the count prefix of current command.
+// It returns 1 when there isn't a count prefix.
+func count() int {
+	if dig.Count == 0 {
+		return 1
 	}
+	return dig.Count
 }
 
 // handleNormalGlobal handles global NormalMode events.
 // When the event was handled, it will return true.
-func handleNormalGlobal(ev termbox.Event) bool {
-	if ev.Key == termbox.KeyEnter || ev.Key == termbox.KeyTab || ev.Ch == '.' || ev.Ch == 'q' {
+func handleNormalGlobal(ev Event) bool {
+	mainView := dig.CurView == CommitView || dig.CurView == DiffView
+	toggle := ev.Key == KeyEnter || ev.Key == KeyTab || ev.Ch == '.' || ev.Ch == 'q'
+	if dig.CurView == DiffView && screen.Diff.Fixed != "" && (toggle || ev.Key == KeyEsc) {
+		// the diff was opened from other view, go back to the view.
+		screen.Diff.Fixed = ""
+		dig.CurView = dig.DiffFrom
+		return true
+	} else if ev.Key == KeyTab && screen.Panes() > 1 && screen.InPanes(dig.CurView) {
+		screen.NextPane()
+		return true
+	} else if mainView && toggle {
 		if dig.CurView == CommitView {
+			if len(dig.Commits) != 0 && notifyDiff(screen.Commit.Commit().Hash) {
+				// an editor plugin shows it.
+				return true
+			}
 			dig.CurView = DiffView
 		} else {
 			dig.CurView = CommitView
 		}
 		return true
-	} else if ev.Key == termbox.KeyEsc {
-		dig.CurView = CommitView
+	} else if mainView && ev.Ch == 't' {
+		screen.Tree.Load(screen.Commit.Commit().Hash)
+		dig.CurView = TreeView
+		return true
+	} else if dig.CurView == CommitView && ev.Ch == 'B' {
+		if err := screen.Branch.Load(); err != nil {
+			dig.Message = err.Error()
+			return true
+		}
+		dig.CurView = BranchView
+		return true
+	} else if mainView && ev.Ch == 'C' {
+		if err := openFiles(screen.Commit.Commit().Hash); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	} else if mainView && ev.Ch == 'D' {
+		from, to, err := difftoolRange()
+		if err == nil {
+			err = openDifftool(from, to)
+		}
+		if err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	} else if mainView && ev.Ch == 's' {
+		screen.Stat.Load(screen.Commit.Commit().Hash)
+		dig.CurView = StatView
+		return true
+	} else if ev.Ch == 'Y' && (mainView || dig.CurView == FileView) {
+		openSend()
+		return true
+	} else if ev.Key == KeyEsc || !mainView && ev.Ch == 'q' {
+		if dig.CurView == FileView {
+			dig.CurView = TreeView
+		} else if dig.CurView == BlameView {
+			dig.CurView = DiffView
+		} else {
+			dig.CurView = CommitView
+		}
 		return true
-	} else if ev.Key == termbox.KeyCtrlF {
+	} else if ev.Key == KeyCtrlF {
 		dig.Mode = FindMode
 		return true
+	} else if ev.Key == KeyCtrlY {
+		startCopy()
+		return true
+	} else if dig.CurView == CommitView && ev.Ch == '/' {
+		startQuery()
+		return true
+	} else if ev.Key == KeyCtrlO {
+		if err := jumpBack(); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	} else if ev.Ch == ':' {
+		dig.Mode = CommandMode
+		return true
+	} else if ev.Ch == '?' {
+		openHelp()
+		return true
+	} else if ev.Ch == 'n' {
+		searchNext(count())
+		return true
+	} else if ev.Ch == 'N' {
+		searchNext(-count())
+		return true
+	} else if ev.Ch == 'L' {
+		listMatches()
+		return true
+	} else if ev.Ch == 'g' {
+		dig.Prefix = 'g'
+		return true
 	} else if ev.Ch == '<' {
 		screen.ExpandSide(-1)
 		return true
@@ -543,36 +1930,41 @@ func handleNormalGlobal(ev termbox.Event) bool {
 
 // handleFind handles FindMode events.
 // When the event was handled, it will return true.
-func handleFind(ev termbox.Event) {
+func handleFind(ev Event) {
 	switch ev.Key {
-	case termbox.KeyEsc, termbox.KeyCtrlQ, termbox.KeyCtrlK:
+	case KeyEsc, KeyCtrlQ, KeyCtrlK:
 		dig.FindString = ""
 		dig.Mode = NormalMode
 		return
-	case termbox.KeyEnter:
-		from := nextIdx(dig.Commits, screen.Commit.CurIdx)
-		if idx := findByHash(dig.Commits, dig.FindString, from); idx != -1 {
-			screen.Commit.CurIdx = idx
-		}
-		if idx := findByWord(dig.Commits, dig.FindString, from); idx != -1 {
-			screen.Commit.CurIdx = idx
+	case KeyEnter:
+		if dig.Search != nil && dig.Search.Word == dig.FindString && dig.Search.FileScope == dig.FileScope && dig.Search.active() {
+			searchNext(1)
+			return
 		}
+		startSearch(dig.FindString)
 		return
-	case termbox.KeyBackspace, termbox.KeyBackspace2:
+	case KeyCtrlT:
+		dig.FileScope = !dig.FileScope
+		return
+	case KeyBackspace:
 		_, size := utf8.DecodeLastRuneInString(dig.FindString)
 		dig.FindString = dig.FindString[:len(dig.FindString)-size]
 		return
+	case KeySpace:
+		dig.FindString += " "
+		return
+	}
+	if ev.Ch != 0 {
+		dig.FindString += string(ev.Ch)
 	}
-	dig.FindString += string(ev.Ch)
 }
 
-// nextIdx returns next index from commits.
-// If reached the last commit index, it will return 0.
-func nextIdx(commits []*Commit, i int) int {
-	if i == len(commits)-1 {
-		return 0
+// Field returns value of the i-th log field of the commit.
+func (c *Commit) Field(i int) string {
+	if i >= len(c.Fields) {
+		return ""
 	}
-	return i + 1
+	return c.Fields[i]
 }
 
 // findByHash finds a commit by hash.
@@ -590,138 +1982,97 @@ func findByHash(commits []*Commit, hash string, from int) int {
 	return -1
 }
 
-// findByWord finds next commit by word inside of title of commits.
-func findByWord(commits []*Commit, word string, from int) int {
-	for i, c := range commits[from:] {
-		if strings.Contains(c.Title, word) {
-			return from + i
-		}
-	}
-	for i, c := range commits[:from] {
-		if strings.Contains(c.Title, word) {
-			return i
-		}
-	}
-	return -1
-}
-
 // saveLastCommit saves currently viewed commit.
 // So can restore with readLastCommit,
 // when dig opens this repository next time.
 func saveLastCommit(repoDir, hash string) error {
-	type view struct {
-		repo string
-		hash string
-	}
-
-	u, err := user.Current()
+	conf, err := configFile("last-commit")
 	if err != nil {
 		return err
 	}
-	conf := filepath.Join(u.HomeDir, ".config", "dig", "last-commit")
-	if err := os.MkdirAll(filepath.Dir(conf), 0755); err != nil && !os.IsExist(err) {
-		return err
-	}
-
-	// read config
-	content, err := ioutil.ReadFile(conf)
-	if err != nil && !os.IsNotExist(err) {
-		return err
-	}
-	views := []view{{repoDir, hash}} // last saved repo should placed first.
-	for _, ln := range strings.Split(string(content), "\n") {
-		if !strings.HasPrefix(ln, "\"") {
-			continue
-		}
-		ln = ln[1:]
-		idx := strings.Index(ln, "\"")
-		if idx == -1 {
-			continue
-		}
-		repo := ln[:idx]
-		if repo == repoDir {
-			continue
+	return updateFile(conf, func(content []byte) ([]byte, error) {
+		// corrupted lines are backed up, ok to drop them.
+		checkCorrupted(conf, content, validLastCommitLine)
+		// last saved repo should placed first.
+		lines := []string{fmt.Sprintf("\"%s\" %s", repoDir, hash)}
+		for _, ln := range strings.Split(string(content), "\n") {
+			repo, _, ok := parseLastCommitLine(ln)
+			if !ok || repo == repoDir {
+				continue
+			}
+			lines = append(lines, ln)
 		}
-		hash := strings.TrimSpace(ln[idx+1:])
-		if strings.Contains(hash, " ") || strings.Contains(hash, "\t") {
-			continue
+		if len(lines) > 1000 {
+			lines = lines[:1000] // limiting with 1000 latest repos
 		}
-		views = append(views, view{repo, hash})
-	}
-
-	// write config
-	if len(views) > 1000 {
-		views = views[:1000] // limiting with 1000 latest repos
-	}
-	newContent := ""
-	for _, v := range views {
-		newContent += fmt.Sprintf("\"%s\" %s\n", v.repo, v.hash)
-	}
-	err = ioutil.WriteFile(conf, []byte(newContent), 0644)
-	if err != nil {
-		return err
-	}
-	return nil
+		return []byte(strings.Join(lines, "\n") + "\n"), nil
+	})
 }
 
 // readLastCommit reads lastly viewed commit in this repository.
 func readLastCommit(repoDir string) (string, error) {
-	u, err := user.Current()
+	conf, err := configFile("last-commit")
 	if err != nil {
 		return "", err
 	}
-	conf := filepath.Join(u.HomeDir, ".config", "dig", "last-commit")
-	if err := os.MkdirAll(filepath.Dir(conf), 0755); err != nil && !os.IsExist(err) {
-		return "", err
-	}
-
-	// read config
 	content, err := ioutil.ReadFile(conf)
 	if err != nil && !os.IsNotExist(err) {
 		return "", err
 	}
+	err = checkCorrupted(conf, content, validLastCommitLine)
 	for _, ln := range strings.Split(string(content), "\n") {
-		if !strings.HasPrefix(ln, "\"") {
-			continue
-		}
-		ln = ln[1:]
-		idx := strings.Index(ln, "\"")
-		if idx == -1 {
-			continue
-		}
-		repo := ln[:idx]
-		if repo != repoDir {
-			continue
-		}
-		hash := strings.TrimSpace(ln[idx+1:])
-		if strings.Contains(hash, " ") || strings.Contains(hash, "\t") {
-			return "", fmt.Errorf("invalid hash: %v", hash)
+		repo, hash, ok := parseLastCommitLine(ln)
+		if ok && repo == repoDir {
+			return hash, err
 		}
-		return hash, nil
 	}
-	return "", nil
+	return "", err
+}
+
+// parseLastCommitLine parses a line of the last-commit file.
+//
+//	"<repo>" <hash>
+func parseLastCommitLine(ln string) (repo, hash string, ok bool) {
+	if !strings.HasPrefix(ln, "\"") {
+		return "", "", false
+	}
+	ln = ln[1:]
+	idx := strings.Index(ln, "\"")
+	if idx == -1 {
+		return "", "", false
+	}
+	repo = ln[:idx]
+	hash = strings.TrimSpace(ln[idx+1:])
+	if hash == "" || strings.ContainsAny(hash, " \t") {
+		return "", "", false
+	}
+	return repo, hash, true
+}
+
+// validLastCommitLine reports whether the line of the last-commit file is valid.
+func validLastCommitLine(ln string) bool {
+	_, _, ok := parseLastCommitLine(ln)
+	return ok
 }
 
 // saveSideWidth saves current side width to config file.
+// It's the side width of repositories those don't have their layouts yet.
 func saveSideWidth(side int) error {
-	u, err := user.Current()
+	conf, err := configFile("sidewidth")
 	if err != nil {
 		return err
 	}
-	conf := filepath.Join(u.HomeDir, ".config", "dig", "sidewidth")
-	if err := os.MkdirAll(filepath.Dir(conf), 0755); err != nil && !os.IsExist(err) {
-		return err
-	}
-	return ioutil.WriteFile(conf, []byte(fmt.Sprintf("%d", side)), 0644)
+	return updateFile(conf, func([]byte) ([]byte, error) {
+		return []byte(strconv.Itoa(side)), nil
+	})
 }
 
 // readSideWidth reads latest side width from config file.
 func readSideWidth() (int, error) {
-	u, err := user.Current()
+	conf, err := configFile("sidewidth")
 	if err != nil {
 		return 20, err
 	}
-	conf := filepath.Join(u.HomeDir, ".config", "dig", "sidewidth")
 	b, err := ioutil.ReadFile(conf)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -729,26 +2080,41 @@ func readSideWidth() (int, error) {
 		}
 		return 20, err
 	}
-	i, err := strconv.Atoi(string(b))
+	i, err := strconv.Atoi(strings.TrimSpace(string(b)))
 	if err != nil {
-		return 20, err
+		return 20, checkCorrupted(conf, b, func(string) bool { return false })
 	}
 	return i, nil
 }
 
 // debugPrintln prints to parent shell.
 func debugPrintln(args ...interface{}) {
-	termbox.Close()
+	term.Suspend()
 	fmt.Println(args...)
-	termbox.Init()
+	term.Resume()
 }
 
 func main() {
 	up := flag.Bool("up", false, "dig up from initial commit (don't use with -down)")
 	down := flag.Bool("down", false, "dig down from latest commit (don't use with -up)")
 	repoDir := flag.String("C", ".", "git repository to dig")
+	profile := flag.String("profile", "", "use the config profile, like work or presentation")
+	gotoRev := flag.String("goto", "", "start at the commit, instead of the last viewed one")
+	summary := flag.Bool("summary", false, "print the selected commit on exit")
+	pickHash := flag.Bool("pick-hash", false, "print the selected commit hash on exit, for $(dig -pick-hash)")
+	exitTemplate := flag.String("exit-template", "", "print the template with the selected commit on exit, ex) \"{hash} {title}\"")
+	showVersion := flag.Bool("version", false, "print version of dig and exit")
+	allRefs := flag.Bool("all", false, "dig commits of all refs, not only HEAD")
+	controlFlag := flag.Bool("control", false, "listen on a control socket in ~/.cache/dig, for other programs to drive dig")
+	socketPath := flag.String("socket", "", "listen on the control socket at the path, for an editor plugin to embed dig")
+	mbox := flag.String("mbox", "", "review patches of the mbox or maildir, before applying them")
 	flag.Parse()
 
+	if *showVersion {
+		fmt.Println(versionString())
+		return
+	}
+
 	var digUp bool
 	if *up && *down {
 		flag.Usage()
@@ -766,95 +2132,204 @@ func main() {
 	}
 	*repoDir = repo
 
-	targets := flag.Args()
+	targets, paths := splitPaths(os.Args[1:], flag.Args())
+	if *allRefs {
+		targets = append([]string{"--all"}, targets...)
+	}
 
-	commits, err := allCommits(*repoDir, targets, digUp)
+	// read configs, it will continue running program
+	// even if these are failed.
+	config, err = readConfig(*profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not read config: %v\n", err)
+	}
+	if *summary {
+		config.ExitSummary = true
+	}
+	if *controlFlag {
+		config.Control = true
+	}
+	if *pickHash {
+		config.ExitTemplate = "{hash}"
+	}
+	if *exitTemplate != "" {
+		config.ExitTemplate = *exitTemplate
+	}
+	backend = newBackend(config.Backend)
+	// config could change arguments of git log.
+	loader, commits, err := startCommits(*repoDir, pathArgs(targets, paths), digUp)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "could not get commits: %v\n", err)
 		os.Exit(1)
 	}
 
-	// read configs, it will continue running program
-	// even if these are failed.
 	lastc, err := readLastCommit(*repoDir)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "could not get last commit: %v\n", err)
 	}
-	sideWidth, err := readSideWidth()
+	notes, err := readNotes(*repoDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not read notes: %v\n", err)
+	}
+	layout, err := readLayout(*repoDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not get layout: %v\n", err)
+	}
+
+	// the terminal should be asked before dig takes it.
+	theme = themeByName(config.Theme)
+	theme, err = readTheme(theme)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "could not get side width: %v\n", err)
+		fmt.Fprintf(os.Stderr, "could not read theme: %v\n", err)
+	}
+	if config.GitColors {
+		t := theme.copy()
+		if err := applyGitColors(t, *repoDir); err != nil {
+			fmt.Fprintf(os.Stderr, "could not use git colors: %v\n", err)
+		} else {
+			theme = t
+		}
+	}
+	if len(config.LanePalette) != 0 {
+		theme.Lanes = config.LanePalette
 	}
 
-	err = termbox.Init()
+	err = initTerm()
 	if err != nil {
 		fmt.Fprint(os.Stderr, err)
 		os.Exit(1)
 	}
-	defer termbox.Close()
+	defer closeTerm()
+	setTermModes()
 
-	w, h := termbox.Size()
+	w, h := term.Size()
 	size := Pt{h, w}
-	screen = NewScreen(size, sideWidth)
-	curIdx := 0
+	screen = NewScreen(size, layout.SideWidth)
+	screen.SetLayout(layout)
+
+	dig = &Program{
+		Mode:    NormalMode,
+		CurView: CommitView,
+		RepoDir: *repoDir,
+		Targets: targets,
+		Paths:   paths,
+		DigUp:   digUp,
+		Notes:   notes,
+	}
+	dig.SetCommits(commits)
+	if err := dig.FilterCommits(); err != nil {
+		dig.Message = err.Error()
+	}
+	if *gotoRev != "" {
+		lastc, err = resolveHash(*gotoRev)
+		if err != nil {
+			closeTerm()
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	pos, err := readPosition(*repoDir)
+	if err != nil {
+		debugPrintln(err)
+	}
+	found := false
 	for i, c := range commits {
 		if c.Hash == lastc {
-			curIdx = i
+			screen.Commit.CurIdx = i
+			screen.SetPosition(pos, c.Hash)
+			found = true
 			break
 		}
 	}
-	screen.Commit.CurIdx = curIdx
+	tabs = []*Tab{{Program: dig, Screen: screen}}
+	if loader != nil {
+		loader.Program = dig
+		loader.Screen = screen
+		if !found {
+			loader.Want = lastc
+			loader.WantPos = pos
+		}
+		dig.Loader = loader
+		loader.Load()
+	}
 
-	dig = &Program{
-		NormalMode,
-		CommitView,
-		*repoDir,
-		commits,
-		"",
+	app.PollEvents()
+	if config.Control || *socketPath != "" {
+		path := *socketPath
+		var err error
+		if path == "" {
+			path, err = controlSocket()
+		}
+		if err == nil {
+			err = listenControl(path)
+		}
+		if err != nil {
+			dig.Message = "could not listen on control socket: " + err.Error()
+		} else {
+			dig.Message = "control socket: " + path
+		}
 	}
 
-	events := make(chan termbox.Event, 20)
-	go func() {
-		for {
-			events <- termbox.PollEvent()
+	if config.UpdateCheck {
+		checkUpdate()
+	}
+	autosave()
+	startSegments()
+	startWatch()
+	if config.OnStartup != "" {
+		runStartupHook()
+	}
+	if *mbox != "" {
+		if err := openMailbox(*mbox); err != nil {
+			dig.Message = err.Error()
 		}
-	}()
+	}
 
-	for {
-		termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
-		screen.Draw()
-		termbox.Flush()
-
-		ev := <-events
-		switch ev.Type {
-		case termbox.EventKey:
-			if dig.Mode == NormalMode {
-				// exit handling is special,
-				// that it could not be inside of a function.
-				if ev.Key == termbox.KeyCtrlQ || dig.CurView == CommitView && ev.Ch == 'q' {
-					err := saveLastCommit(dig.RepoDir, screen.Commit.Commit().Hash)
-					if err != nil {
-						debugPrintln(err)
-					}
-					err = saveSideWidth(screen.SideWidth)
-					if err != nil {
-						debugPrintln(err)
-					}
-					return
-				}
-			}
-			if dig.Mode == NormalMode {
-				handleNormal(ev)
-			} else if dig.Mode == FindMode {
-				handleFind(ev)
-			}
-		case termbox.EventResize:
-			// weird, but terminal(or termbox?) should be cleared
-			// before checking the terminal size
-			// when user changes the terminal window to fullscreen.
-			termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
-			w, h := termbox.Size()
-			size := Pt{h, w}
-			screen.Resize(size)
+	app.Run()
+	closeControl()
+	stopGit()
+	closeTerm()
+	if err := saveSession(); err != nil {
+		fmt.Fprintln(os.Stderr, "could not save the session:", err)
+	}
+
+	// now we are back to the primary screen.
+	c := screen.Commit.Commit()
+	tmpl := config.ExitTemplate
+	if tmpl == "" && config.ExitSummary {
+		tmpl = "{hash} {title}"
+	}
+	if tmpl != "" {
+		fmt.Println(expandTemplate(tmpl, c))
+	}
+	if config.ExitCommand != "" {
+		cmd := shellCommand(expandTemplate(config.ExitCommand, c))
+		cmd.Dir = dig.RepoDir
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "exit command failed: %v\n", err)
+			os.Exit(1)
 		}
 	}
 }
+
+// expandTemplate replaces placeholders in the template with the commit's.
+// Placeholders are {hash}, {title} and {repo}.
+func expandTemplate(tmpl string, c *Commit) string {
+	r := strings.NewReplacer(
+		"{hash}", c.Hash,
+		"{title}", c.Title,
+		"{repo}", dig.RepoDir,
+	)
+	return r.Replace(tmpl)
+}
+
+// shellCommand returns a command that runs the line with the system shell.
+func shellCommand(line string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", line)
+	}
+	return exec.Command("sh", "-c", line)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Marks are hashes of marked commits, in marked order.
+// It's used for selecting commits for batch actions,
+// and for selecting a range of two commits to diff.
+type Marks []string
+
+// Has reports whether the commit is marked.
+func (m Marks) Has(hash string) bool {
+	return m.Index(hash) != -1
+}
+
+// Index returns index of the commit in the marks, or -1 if it isn't marked.
+func (m Marks) Index(hash string) int {
+	for i, h := range m {
+		if h == hash {
+			return i
+		}
+	}
+	return -1
+}
+
+// Toggle marks the commit, or unmarks it if already marked.
+// When max is not 0 and there are max marks already, the oldest mark is dropped.
+func (m *Marks) Toggle(hash string, max int) {
+	if m.Unmark(hash) {
+		return
+	}
+	if max != 0 && len(*m) >= max {
+		*m = (*m)[len(*m)-max+1:]
+	}
+	*m = append(*m, hash)
+}
+
+// Unmark unmarks the commit. It reports whether the commit was marked.
+func (m *Marks) Unmark(hash string) bool {
+	i := m.Index(hash)
+	if i == -1 {
+		return false
+	}
+	*m = append((*m)[:i], (*m)[i+1:]...)
+	return true
+}
+
+// markedHashes returns hashes of marked commits from old to new, as they were committed.
+// Commits those aren't loaded anymore are left out.
+func markedHashes() []string {
+	idx := make(map[string]int)
+	for i, c := range dig.All {
+		idx[c.Hash] = i
+	}
+	hashes := make([]string, 0, len(dig.Marks))
+	for _, h := range dig.Marks {
+		if _, ok := idx[h]; ok {
+			hashes = append(hashes, h)
+		}
+	}
+	// commits are listed from new to old, unless digging up.
+	sort.Slice(hashes, func(i, j int) bool {
+		if dig.DigUp {
+			return idx[hashes[i]] < idx[hashes[j]]
+		}
+		return idx[hashes[i]] > idx[hashes[j]]
+	})
+	return hashes
+}
+
+// markActions are batch actions for marked commits, in the order of the popup.
+var markActions = []struct {
+	Name string
+	Run  func(hashes []string) error
+}{
+	{"export patches", func(hashes []string) error { return exportPatches(hashes, "dig-patches") }},
+	{"cherry-pick in order", cherryPick},
+	{"walk through them", startWalk},
+	{"open the range in difftool", func(hashes []string) error {
+		if len(hashes) < 2 {
+			return fmt.Errorf("mark two commits to compare")
+		}
+		return openDifftool(hashes[0], hashes[len(hashes)-1])
+	}},
+	{"copy hashes", copyHashes},
+	{"write a combined report", func(hashes []string) error {
+		return writeReport(hashes, "dig-report.md", "Marked commits")
+	}},
+	{"clear marks", func(hashes []string) error {
+		dig.Marks = nil
+		return nil
+	}},
+}
+
+// openMarkActions opens a popup to choose a batch action for marked commits.
+func openMarkActions() {
+	hashes := markedHashes()
+	if len(hashes) == 0 {
+		dig.Message = "no marked commits. mark commits with space."
+		return
+	}
+	items := make([]string, len(markActions))
+	for i, a := range markActions {
+		items[i] = a.Name
+	}
+	title := fmt.Sprintf("%d marked commits", len(hashes))
+	openList(title, items, 0, func(i int) {
+		if err := markActions[i].Run(hashes); err != nil {
+			dig.Message = err.Error()
+		}
+	})
+}
+
+// cherryPick cherry-picks the commits onto HEAD in the order.
+// When it stops by a conflict, the user should resolve it outside.
+func cherryPick(hashes []string) error {
+	q := fmt.Sprintf("cherry-pick %d commits onto HEAD?", len(hashes))
+	return confirm(q, workTreeWarnings(), func() error {
+		if _, err := gitOutput(append([]string{"cherry-pick"}, hashes...)...); err != nil {
+			return err
+		}
+		dig.Marks = nil
+		dig.Message = fmt.Sprintf("cherry-picked %d commits", len(hashes))
+		return reloadCommits()
+	})
+}
+
+// copyHashes copies the hashes to the clipboard, one per line.
+func copyHashes(hashes []string) error {
+	if err := copyToClipboard(strings.Join(hashes, "\n")); err != nil {
+		return err
+	}
+	dig.Message = fmt.Sprintf("copied %d hashes", len(hashes))
+	// the hashes are likely pasted elsewhere, they should be found by others.
+	if local := localOnly(hashes); len(local) == 1 {
+		dig.Message += fmt.Sprintf(", but %s is not on any remote", shortHash(local[0]))
+	} else if len(local) > 1 {
+		dig.Message += fmt.Sprintf(", but %d of them are not on any remote", len(local))
+	}
+	return nil
+}
+
+// cmdMarks handles batch actions for marked commits.
+//
+//	marks                 choose an action from a popup.
+//	marks export [dir]    export marked commits as patches.
+//	marks cherry-pick     cherry-pick marked commits in order.
+//	marks copy            copy hashes of marked commits.
+//	marks walk            walk through marked commits, to present them.
+//	marks report [file]   write a combined report of marked commits.
+//	marks clear           unmark all commits.
+func cmdMarks(args []string) error {
+	if len(args) == 0 {
+		openMarkActions()
+		return nil
+	}
+	if args[0] == "clear" {
+		dig.Marks = nil
+		return nil
+	}
+	hashes := markedHashes()
+	if len(hashes) == 0 {
+		return fmt.Errorf("no marked commits")
+	}
+	switch args[0] {
+	case "export":
+		dir := "dig-patches"
+		if len(args) > 1 {
+			dir = args[1]
+		}
+		return exportPatches(hashes, dir)
+	case "cherry-pick":
+		return cherryPick(hashes)
+	case "copy":
+		return copyHashes(hashes)
+	case "walk":
+		return startWalk(hashes)
+	case "report":
+		file := "dig-report.md"
+		if len(args) > 1 {
+			file = args[1]
+		}
+		return writeReport(hashes, file, "Marked commits")
+	}
+	return fmt.Errorf("unknown marks command: %s", args[0])
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// patchKeyPrefix prefixes keys of patches, those are used instead of commit hashes for diffs.
+const patchKeyPrefix = "mbox:"
+
+// Patch is a patch mail of a series, like the ones git format-patch makes.
+type Patch struct {
+	Key     string // patchKeyPrefix, the load count and it's index
+	Subject string
+	Title   string // subject without the [PATCH n/m] tag
+	Number  int    // n of [PATCH n/m], 0 for a cover letter or a single patch
+	Total   int    // m of [PATCH n/m]
+	From    string
+	Date    string
+	HasDiff bool
+	State   string // "", "applied", "skipped" or "failed"
+
+	Raw  []byte   // the mail as it is, for git am
+	Text [][]byte // the mail decoded, shown as a diff
+}
+
+// patchTagRe finds the [PATCH n/m] tag of a subject.
+var patchTagRe = regexp.MustCompile(`^\s*\[([^\]]*)\]\s*`)
+
+// patchNumRe finds n/m in a tag.
+var patchNumRe = regexp.MustCompile(`(\d+)/(\d+)`)
+
+// readMailbox reads mails from an mbox file, or a maildir.
+func readMailbox(path string) ([][]byte, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return splitMbox(data), nil
+	}
+	// maildir keeps mails in cur and new, a directory of plain mails is also fine.
+	var files []string
+	for _, sub := range []string{"cur", "new"} {
+		fs, _ := filepath.Glob(filepath.Join(path, sub, "*"))
+		files = append(files, fs...)
+	}
+	if len(files) == 0 {
+		files, _ = filepath.Glob(filepath.Join(path, "*"))
+	}
+	sort.Strings(files)
+	var mails [][]byte
+	for _, f := range files {
+		if fi, err := os.Stat(f); err != nil || fi.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		mails = append(mails, data)
+	}
+	return mails, nil
+}
+
+// splitMbox splits an mbox into mails. A mail starts with a "From " line
+// at the beginning, or after an empty line.
+func splitMbox(data []byte) [][]byte {
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	var mails [][]byte
+	var cur []byte
+	prevEmpty := true
+	for _, ln := range lines {
+		if prevEmpty && bytes.HasPrefix(ln, []byte("From ")) && len(cur) != 0 {
+			mails = append(mails, cur)
+			cur = nil
+		}
+		cur = append(cur, ln...)
+		prevEmpty = len(bytes.TrimRight(ln, "\r\n")) == 0
+	}
+	if len(bytes.TrimSpace(cur)) != 0 {
+		mails = append(mails, cur)
+	}
+	return mails
+}
+
+// parsePatch parses a patch mail.
+func parsePatch(raw []byte) (*Patch, error) {
+	data := raw
+	if bytes.HasPrefix(data, []byte("From ")) {
+		// mbox separator isn't a header.
+		if i := bytes.IndexByte(data, '\n'); i != -1 {
+			data = data[i+1:]
+		}
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	dec := &mime.WordDecoder{}
+	header := func(key string) string {
+		v := msg.Header.Get(key)
+		if d, err := dec.DecodeHeader(v); err == nil {
+			return d
+		}
+		return v
+	}
+	p := &Patch{
+		Raw:     raw,
+		Subject: header("Subject"),
+		From:    header("From"),
+		Date:    header("Date"),
+	}
+	p.Title = p.Subject
+	if m := patchTagRe.FindStringSubmatch(p.Subject); m != nil {
+		p.Title = p.Subject[len(m[0]):]
+		if n := patchNumRe.FindStringSubmatch(m[1]); n != nil {
+			p.Number, _ = strconv.Atoi(n[1])
+			p.Total, _ = strconv.Atoi(n[2])
+		}
+	}
+	var body io.Reader = msg.Body
+	switch strings.ToLower(msg.Header.Get("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	b = bytes.Replace(b, []byte("\r\n"), []byte("\n"), -1)
+	b = bytes.Replace(b, []byte("\t"), []byte("    "), -1)
+	b = bytes.TrimRight(b, "\n")
+	text := fmt.Sprintf("From: %s\nDate: %s\nSubject: %s\n\n", p.From, p.Date, p.Subject)
+	p.Text = bytes.Split(append([]byte(text), b...), []byte("\n"))
+	for _, ln := range p.Text {
+		if isFileHeader(ln) {
+			p.HasDiff = true
+			break
+		}
+	}
+	return p, nil
+}
+
+// MailArea is an Area for reviewing a series of patch mails, before applying them.
+type MailArea struct {
+	Bound   Rect
+	Path    string
+	Patches []*Patch
+	CurIdx  int
+	TopIdx  int
+
+	// loads counts loads, so keys of patches from different loads don't meet in DiffCache.
+	loads int
+}
+
+// Load loads patches from the mbox or maildir.
+func (a *MailArea) Load(path string) error {
+	mails, err := readMailbox(path)
+	if err != nil {
+		return err
+	}
+	var patches []*Patch
+	for _, m := range mails {
+		p, err := parsePatch(m)
+		if err != nil {
+			continue
+		}
+		patches = append(patches, p)
+	}
+	if len(patches) == 0 {
+		return fmt.Errorf("no mails in %s", path)
+	}
+	// mails in a maildir aren't in order, a cover letter (0/m) comes first.
+	sort.SliceStable(patches, func(i, j int) bool {
+		return patches[i].Number < patches[j].Number
+	})
+	a.loads++
+	for i, p := range patches {
+		p.Key = fmt.Sprintf("%s%d.%d", patchKeyPrefix, a.loads, i+1)
+	}
+	a.Path = path
+	a.Patches = patches
+	a.CurIdx = 0
+	a.TopIdx = 0
+	return nil
+}
+
+// patchDiff returns the text of a patch by it's key, for DiffCache.
+func patchDiff(key string) ([][]byte, error) {
+	for _, p := range screen.Mail.Patches {
+		if p.Key == key {
+			return p.Text, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown patch: %s", key)
+}
+
+// Apply applies the patch to the repository with git am.
+// The failed am is aborted, so the repository isn't left in the middle of it.
+func (p *Patch) Apply() error {
+	if !p.HasDiff {
+		return fmt.Errorf("nothing to apply: %s", p.Title)
+	}
+	f, err := ioutil.TempFile("", "dig-patch-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(p.Raw)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	if _, err := gitOutput("am", "--3way", f.Name()); err != nil {
+		cmd := gitCommand(dig.RepoDir, "am", "--abort")
+		cmd.Run()
+		p.State = "failed"
+		return fmt.Errorf("%s", strings.SplitN(err.Error(), "\n", 2)[0])
+	}
+	p.State = "applied"
+	return nil
+}
+
+// applyPatches applies the patches in order, except skipped and applied ones.
+// It stops at the first patch that failed.
+func applyPatches(patches []*Patch) error {
+	n := 0
+	var err error
+	for _, p := range patches {
+		if !p.HasDiff || p.State == "applied" || p.State == "skipped" {
+			continue
+		}
+		if err = p.Apply(); err != nil {
+			break
+		}
+		n++
+	}
+	if n != 0 {
+		if rerr := reloadCommits(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	dig.Message = fmt.Sprintf("%d patches applied", n)
+	return nil
+}
+
+// Handle handles a terminal event.
+func (a *MailArea) Handle(ev Event) bool {
+	page := a.Bound.Size.L - 1
+	if ev.Key == KeyArrowUp || ev.Ch == 'i' {
+		a.CurIdx -= count()
+	} else if ev.Key == KeyArrowDown || ev.Ch == 'k' {
+		a.CurIdx += count()
+	} else if ev.Key == KeyPgup || ev.Ch == 'b' {
+		a.CurIdx -= page * count()
+	} else if ev.Key == KeyPgdn || ev.Ch == 'f' {
+		a.CurIdx += page * count()
+	} else if ev.Key == KeyHome {
+		a.CurIdx = 0
+	} else if ev.Key == KeyEnd {
+		a.CurIdx = len(a.Patches) - 1
+	} else if len(a.Patches) == 0 {
+		return false
+	} else if ev.Key == KeyEnter {
+		openDiff(a.Patches[a.CurIdx].Key)
+		return true
+	} else if ev.Ch == 's' {
+		p := a.Patches[a.CurIdx]
+		if p.State == "skipped" {
+			p.State = ""
+		} else if p.State != "applied" {
+			p.State = "skipped"
+		}
+		a.CurIdx++
+	} else if ev.Ch == 'a' {
+		p := a.Patches[a.CurIdx]
+		if p.State == "applied" {
+			dig.Message = "already applied"
+			return true
+		}
+		err := confirm("apply "+p.Title+"?", workTreeWarnings(), func() error {
+			if err := applyPatches([]*Patch{p}); err != nil {
+				return err
+			}
+			if a.CurIdx < len(a.Patches)-1 {
+				a.CurIdx++
+			}
+			return nil
+		})
+		if err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	} else if ev.Ch == 'A' {
+		err := confirm("apply the rest of the series?", workTreeWarnings(), func() error {
+			return applyPatches(a.Patches)
+		})
+		if err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	} else {
+		return false
+	}
+	if a.CurIdx >= len(a.Patches) {
+		a.CurIdx = len(a.Patches) - 1
+	}
+	if a.CurIdx < 0 {
+		a.CurIdx = 0
+	}
+	return true
+}
+
+// Draw draws it's contents.
+func (a *MailArea) Draw() {
+	applied := 0
+	for _, p := range a.Patches {
+		if p.State == "applied" {
+			applied++
+		}
+	}
+	header := fmt.Sprintf("%s: %d mails, %d applied", filepath.Base(a.Path), len(a.Patches), applied)
+	drawLine(a.Bound, 0, []byte(header), 0, theme.Header)
+
+	page := a.Bound.Size.L - 1
+	if a.TopIdx > a.CurIdx {
+		a.TopIdx = a.CurIdx
+	} else if a.TopIdx+page <= a.CurIdx {
+		a.TopIdx = a.CurIdx - page + 1
+	}
+	for l := 0; l < page; l++ {
+		i := a.TopIdx + l
+		if i >= len(a.Patches) {
+			break
+		}
+		p := a.Patches[i]
+		c := theme.Normal
+		switch p.State {
+		case "applied":
+			c = theme.Added
+		case "failed":
+			c = theme.Deleted
+		case "skipped":
+			c = theme.Dir
+		}
+		num := "   "
+		if p.Total != 0 {
+			num = fmt.Sprintf("%d/%d", p.Number, p.Total)
+		}
+		state := p.State
+		if !p.HasDiff {
+			state = "no diff"
+		}
+		ln := fmt.Sprintf("%-7s %-5s %s (%s)", state, num, p.Title, p.From)
+		if i == a.CurIdx {
+			c.Bg = theme.Selected.Bg
+			fillColor(Rect{Min: Pt{a.Bound.Min.L + l + 1, a.Bound.Min.O}, Size: Pt{1, a.Bound.Size.O}}, c)
+		}
+		drawLine(a.Bound, l+1, []byte(ln), 0, c)
+	}
+}
+
+// openMailbox opens patches of the mbox or maildir for review.
+func openMailbox(path string) error {
+	if err := screen.Mail.Load(path); err != nil {
+		return err
+	}
+	dig.CurView = MailView
+	return nil
+}
+
+// cmdMbox opens an mbox or a maildir of patches, or the one opened before.
+//
+//	mbox [path]
+func cmdMbox(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: mbox [path]")
+	}
+	if len(args) == 0 {
+		if screen.Mail.Path == "" {
+			return fmt.Errorf("usage: mbox [path]")
+		}
+		dig.CurView = MailView
+		return nil
+	}
+	return openMailbox(args[0])
+}
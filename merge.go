@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// mergeKeyPrefix prefixes keys of DiffCache, those are for what's shown instead of an empty merge. See emptyMerge.
+const mergeKeyPrefix = "merge:"
+
+// emptyMerges are ways to show a merge those combined diff is empty, in the order P cycles them.
+//
+//	first-parent  changes the merge brought to the first parent
+//	commits       commits those are merged
+//	combined      the combined diff as is, that has only the header
+var emptyMerges = []string{"first-parent", "commits", "combined"}
+
+// emptyMergeNotes explain what's shown for an empty merge, by the ways.
+var emptyMergeNotes = map[string]string{
+	"first-parent": "clean merge, showing changes against the first parent (P: merged commits)",
+	"commits":      "clean merge, showing the merged commits (P: combined diff)",
+	"combined":     "clean merge, the combined diff is empty (P: changes against the first parent)",
+}
+
+// isEmptyMerge reports whether the diff of git show is of a merge, without a file in it's combined diff.
+// A clean merge doesn't have changes of it's own, so it's combined diff is empty.
+func isEmptyMerge(d [][]byte) bool {
+	merge := false
+	for _, ln := range d {
+		if bytes.HasPrefix(ln, []byte("Merge: ")) {
+			merge = true
+		}
+		if bytes.HasPrefix(ln, []byte("diff --")) {
+			return false
+		}
+	}
+	return merge
+}
+
+// emptyMerge returns the key of DiffCache for what's shown instead of the commit's diff, with a note of it.
+// It's for an empty merge, shown by config.EmptyMerge. The key is "" when the diff should be shown as is.
+func emptyMerge(hash string, d [][]byte) (key, note string) {
+	if strings.Contains(hash, ":") || strings.Contains(hash, "..") || !isEmptyMerge(d) {
+		// patches and ranges.
+		return "", ""
+	}
+	note = emptyMergeNotes[config.EmptyMerge]
+	if config.EmptyMerge == "combined" {
+		return "", note
+	}
+	return mergeKeyPrefix + config.EmptyMerge + ":" + hash, note
+}
+
+// mergeDiff returns the text of a key made by emptyMerge.
+func mergeDiff(key string) ([][]byte, error) {
+	f := strings.SplitN(strings.TrimPrefix(key, mergeKeyPrefix), ":", 2)
+	if len(f) != 2 {
+		return nil, fmt.Errorf("invalid merge key: %s", key)
+	}
+	way, hash := f[0], f[1]
+	var out []byte
+	var err error
+	switch way {
+	case "first-parent":
+		out, err = gitOutput(pathArgs([]string{"show", "-m", "--first-parent", hash}, dig.Paths)...)
+	case "commits":
+		out, err = gitOutput("show", "--no-patch", hash)
+		if err != nil {
+			return nil, err
+		}
+		var log []byte
+		// commits of the other parents, those aren't in the first parent.
+		log, err = gitOutput("log", "--format=    %h %s (%an)", hash+"^@", "^"+hash+"^1")
+		out = append(out, "\nMerged commits:\n\n"...)
+		out = append(out, log...)
+	default:
+		return nil, fmt.Errorf("unknown way to show a merge: %s", way)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// tab handling in screen is quite awkard. handle it here.
+	out = bytes.Replace(out, []byte("\t"), []byte("    "), -1)
+	out = bytes.TrimRight(out, " \n")
+	return bytes.Split(out, []byte("\n")), nil
+}
+
+// cycleEmptyMerge shows the empty merge of the area by the next way of emptyMerges.
+func (a *DiffArea) cycleEmptyMerge() error {
+	if a.Note == "" {
+		return fmt.Errorf("not a clean merge")
+	}
+	for i, w := range emptyMerges {
+		if w == config.EmptyMerge {
+			config.EmptyMerge = emptyMerges[(i+1)%len(emptyMerges)]
+			break
+		}
+	}
+	// load it again, by the way.
+	a.CommitHash = ""
+	a.Sync()
+	a.Win.Goto(0)
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEmptyMerge(t *testing.T) {
+	r := historyFixture(t)
+	setupApp(t, r.Dir, r.Commits(false, "HEAD"))
+	// files returns files in the diff of the area, and whether it has the line.
+	files := func(a *DiffArea, line string) ([]string, bool) {
+		var files []string
+		found := false
+		for _, ln := range a.Text {
+			if isFileHeader(ln) {
+				files = append(files, fileName(ln))
+			}
+			if strings.TrimSpace(string(ln)) == line {
+				found = true
+			}
+		}
+		return files, found
+	}
+	a := screen.Diff
+	// merge feature is a clean merge.
+	screen.Commit.SetCursor(0)
+	a.Sync()
+	if got, _ := files(a, ""); len(got) != 1 || got[0] != "main.go" || a.Note == "" {
+		t.Errorf("first parent diff: got %q, with note %q", got, a.Note)
+	}
+	if err := a.cycleEmptyMerge(); err != nil {
+		t.Fatal(err)
+	}
+	merged := r.git("log", "-1", "--format=%h say hi (Bob)", "feature")
+	if got, ok := files(a, merged); len(got) != 0 || !ok {
+		t.Errorf("merged commits: got files %q, found %q: %v", got, merged, ok)
+	}
+	if err := a.cycleEmptyMerge(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := files(a, ""); len(got) != 0 || !strings.Contains(a.Note, "empty") {
+		t.Errorf("combined diff: got %q, with note %q", got, a.Note)
+	}
+	if err := a.cycleEmptyMerge(); err != nil || config.EmptyMerge != "first-parent" {
+		t.Errorf("cycled back to %s, %v", config.EmptyMerge, err)
+	}
+	// fix readme isn't a merge.
+	screen.Commit.SetCursor(1)
+	a.Sync()
+	if a.Note != "" || a.cycleEmptyMerge() == nil {
+		t.Errorf("not a merge: got note %q", a.Note)
+	}
+}
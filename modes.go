@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+)
+
+// fileType returns readable type of a git file mode.
+func fileType(mode string) string {
+	switch mode {
+	case "100644":
+		return "regular file"
+	case "100755":
+		return "executable file"
+	case "120000":
+		return "symlink"
+	case "160000":
+		return "submodule"
+	case "040000":
+		return "directory"
+	}
+	return "mode " + mode
+}
+
+// describeModes replaces raw mode lines of a diff, like "old mode 100644" and "new mode 100755",
+// with one line descriptions of mode changes, symlink changes and file type changes.
+// Those lines are drawn with their own colors. See modeColor.
+func describeModes(lines [][]byte) [][]byte {
+	// a file type change is a deletion and a creation of the same path.
+	deleted := make(map[string]string)
+	header := ""
+	for _, ln := range lines {
+		if isFileHeader(ln) {
+			header = fileName(ln)
+		} else if bytes.HasPrefix(ln, []byte("deleted file mode ")) {
+			deleted[header] = string(ln[len("deleted file mode "):])
+		}
+	}
+	out := make([][]byte, 0, len(lines))
+	from := 0
+	for from < len(lines) {
+		to := from + 1
+		for to < len(lines) && !isFileHeader(lines[to]) {
+			to++
+		}
+		out = append(out, describeModesFile(lines[from:to], deleted)...)
+		from = to
+	}
+	return out
+}
+
+// describeModesFile describes mode changes of a file. See describeModes.
+func describeModesFile(lines [][]byte, deleted map[string]string) [][]byte {
+	if len(lines) == 0 || !isFileHeader(lines[0]) {
+		return lines
+	}
+	name := fileName(lines[0])
+	hunk := len(lines)
+	for i, ln := range lines {
+		if bytes.HasPrefix(ln, []byte("@@")) {
+			hunk = i
+			break
+		}
+	}
+	oldTarget, newTarget := linkTargets(lines[hunk:])
+	out := make([][]byte, 0, len(lines))
+	note := ""
+	for i := 0; i < hunk; i++ {
+		ln := string(lines[i])
+		switch {
+		case strings.HasPrefix(ln, "old mode ") && i+1 < hunk && strings.HasPrefix(string(lines[i+1]), "new mode "):
+			oldMode := strings.TrimPrefix(ln, "old mode ")
+			newMode := strings.TrimPrefix(string(lines[i+1]), "new mode ")
+			out = append(out, []byte(modeChange(oldMode, newMode)))
+			i++
+			continue
+		case strings.HasPrefix(ln, "new file mode "):
+			mode := strings.TrimPrefix(ln, "new file mode ")
+			if old, ok := deleted[name]; ok && fileType(old) != fileType(mode) {
+				note = "type changed: " + fileType(old) + " -> " + fileType(mode)
+				if mode == "120000" {
+					note += " (-> " + newTarget + ")"
+				}
+				out = append(out, []byte(note))
+				continue
+			}
+			if mode == "120000" {
+				note = "symlink: added -> " + newTarget
+			}
+		case strings.HasPrefix(ln, "deleted file mode "):
+			if strings.TrimPrefix(ln, "deleted file mode ") == "120000" {
+				note = "symlink: removed (was -> " + oldTarget + ")"
+			}
+		case strings.HasPrefix(ln, "index ") && strings.HasSuffix(ln, " 120000"):
+			note = "symlink: " + oldTarget + " -> " + newTarget
+		}
+		out = append(out, lines[i])
+	}
+	if note != "" && !strings.HasPrefix(note, "type changed:") {
+		out = append(out, []byte(note))
+	}
+	return append(out, lines[hunk:]...)
+}
+
+// modeChange describes a mode change of a file.
+func modeChange(oldMode, newMode string) string {
+	s := "mode changed: " + oldMode + " -> " + newMode
+	if oldMode == "100644" && newMode == "100755" {
+		s += " (now executable)"
+	} else if oldMode == "100755" && newMode == "100644" {
+		s += " (no longer executable)"
+	} else if fileType(oldMode) != fileType(newMode) {
+		s += " (" + fileType(oldMode) + " -> " + fileType(newMode) + ")"
+	}
+	return s
+}
+
+// linkTargets returns old and new contents of a one line file from it's hunks,
+// which are targets when the file is a symlink.
+func linkTargets(hunks [][]byte) (oldTarget, newTarget string) {
+	for _, ln := range hunks {
+		if len(ln) == 0 {
+			continue
+		}
+		switch ln[0] {
+		case '-':
+			oldTarget = string(ln[1:])
+		case '+':
+			newTarget = string(ln[1:])
+		case ' ':
+			oldTarget = string(ln[1:])
+			newTarget = string(ln[1:])
+		}
+	}
+	return oldTarget, newTarget
+}
+
+// modeColor returns color of a line describing mode changes, made by describeModes.
+// It returns false if the line isn't one of them.
+func modeColor(ln []byte) (Color, bool) {
+	switch {
+	case bytes.HasPrefix(ln, []byte("mode changed: ")):
+		return theme.Mode, true
+	case bytes.HasPrefix(ln, []byte("symlink: ")):
+		return theme.Link, true
+	case bytes.HasPrefix(ln, []byte("type changed: ")):
+		return theme.TypeChange, true
+	}
+	return Color{}, false
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"time"
+)
+
+// dragging is true while the user drags the side divider with the mouse.
+var dragging bool
+
+// wheelLines is how many lines a notch of the mouse wheel scrolls.
+const wheelLines = 3
+
+// doubleClick is the time in that the second click should follow the first, to be a double click.
+const doubleClick = 400 * time.Millisecond
+
+// lastClick is the commit clicked last, and when it's clicked, to find a double click.
+var lastClick struct {
+	Idx  int
+	Time time.Time
+}
+
+// handleMouse handles mouse events.
+//
+// A click selects the commit under it, and a double click opens it's diff.
+// The wheel scrolls the commit list or the diff under the pointer,
+// and the side divider could be dragged to resize the side.
+func handleMouse(ev Event) {
+	if dig.Mode != NormalMode || screen.Popup != nil {
+		dragging = false
+		return
+	}
+	p := Pt{ev.MouseY, ev.MouseX}
+	switch ev.Key {
+	case MouseWheelUp:
+		screen.scroll(p, -wheelLines)
+	case MouseWheelDown:
+		screen.scroll(p, wheelLines)
+	case MouseLeft:
+		if ev.Mod&ModMotion == 0 {
+			// pressed. grab the divider, allow a cell of miss.
+			d := screen.Side() - 1
+			dragging = screen.Side() > 0 && d-1 <= ev.MouseX && ev.MouseX <= d+1
+			if !dragging {
+				screen.click(p)
+			}
+		}
+		if dragging {
+			screen.SetSideWidth(ev.MouseX + 1)
+		}
+	case MouseRelease:
+		dragging = false
+	}
+}
+
+// areaAt returns the commit list or a diff area those is drawn at the point, or nil.
+func (s *Screen) areaAt(p Pt) interface{} {
+	panes := s.Panes() > 1 && s.InPanes(dig.CurView)
+	if (dig.CurView == CommitView || panes) && s.Commit.Bound.Contains(p) {
+		return s.Commit
+	}
+	if dig.CurView != DiffView && !panes {
+		return nil
+	}
+	if s.Diff.Bound.Contains(p) {
+		return s.Diff
+	}
+	if s.Split && s.Diff2.Bound.Contains(p) {
+		return s.Diff2
+	}
+	return nil
+}
+
+// focus focuses the area, as it's clicked or scrolled.
+func (s *Screen) focus(area interface{}) {
+	panes := s.Panes() > 1 && s.InPanes(dig.CurView)
+	switch area {
+	case s.Commit:
+		if panes {
+			dig.CurView = CommitView
+		}
+	case s.Diff, s.Diff2:
+		if panes {
+			dig.CurView = DiffView
+		}
+		if s.Split {
+			s.Focus = 0
+			if area == s.Diff2 {
+				s.Focus = 1
+			}
+		}
+	}
+}
+
+// click selects the commit at the point, and opens it's diff when it's a double click.
+// A click on a diff area focuses it.
+func (s *Screen) click(p Pt) {
+	area := s.areaAt(p)
+	s.focus(area)
+	if area != s.Commit {
+		return
+	}
+	a := s.Commit
+	i := a.TopIdx + p.L - a.Bound.Min.L
+	if i >= len(dig.Commits) {
+		return
+	}
+	a.SetCursor(i)
+	if i == lastClick.Idx && time.Since(lastClick.Time) < doubleClick {
+		lastClick.Time = time.Time{}
+		// as enter does.
+		app.HandleKey(Event{Type: EventKey, Key: KeyEnter})
+		return
+	}
+	lastClick.Idx, lastClick.Time = i, time.Now()
+}
+
+// scroll scrolls the area at the point by n lines, up when it's negative.
+// The commit list moves it's cursor, as it follows the cursor.
+// Views other than them move their cursors as the arrow keys do.
+func (s *Screen) scroll(p Pt, n int) {
+	switch area := s.areaAt(p).(type) {
+	case *CommitArea:
+		area.CursorDown(n)
+	case *DiffArea:
+		if n < 0 {
+			area.Win.MoveUp(-n)
+		} else {
+			area.Win.MoveDown(n)
+		}
+	default:
+		if dig.CurView == CommitView || dig.CurView == DiffView {
+			// not on the list or a diff, like on the status bar.
+			return
+		}
+		key := KeyArrowDown
+		if n < 0 {
+			key = KeyArrowUp
+		}
+		app.HandleKey(Event{Type: EventKey, Key: key})
+	}
+}
+
+// drawDivider draws the divider between the side and main areas,
+// to show where to drag.
+func (s *Screen) drawDivider() {
+	if s.Side() == 0 {
+		return
+	}
+	c := theme.Normal
+	if dragging {
+		c = theme.Focused
+	}
+	for l := 0; l < s.size.L-statusHeight(s.size); l++ {
+		setCell(s.Side()-1, l, '│', c.Fg, c.Bg)
+	}
+}
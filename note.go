@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// NoteEditor is a popup for editing a note of a commit.
+type NoteEditor struct {
+	Hash string
+	Text string
+}
+
+// editNote opens the note editor for the commit.
+func editNote(hash string) {
+	screen.Note = &NoteEditor{Hash: hash, Text: dig.Notes[hash]}
+	dig.Mode = NoteMode
+}
+
+// handleNote handles NoteMode events.
+func handleNote(ev Event) {
+	e := screen.Note
+	switch ev.Key {
+	case KeyEsc:
+		screen.Note = nil
+		dig.Mode = NormalMode
+		return
+	case KeyCtrlS:
+		text := strings.TrimSpace(e.Text)
+		if text == "" {
+			delete(dig.Notes, e.Hash)
+		} else {
+			dig.Notes[e.Hash] = text
+		}
+		if err := saveNotes(dig.RepoDir, dig.Notes); err != nil {
+			dig.Message = err.Error()
+		}
+		screen.Note = nil
+		dig.Mode = NormalMode
+		return
+	case KeyEnter:
+		e.Text += "\n"
+		return
+	case KeySpace:
+		e.Text += " "
+		return
+	case KeyBackspace:
+		_, size := utf8.DecodeLastRuneInString(e.Text)
+		e.Text = e.Text[:len(e.Text)-size]
+		return
+	}
+	if ev.Ch != 0 {
+		e.Text += string(ev.Ch)
+	}
+}
+
+// Draw draws the editor as a popup.
+func (e *NoteEditor) Draw() {
+	lines := strings.Split(e.Text, "\n")
+	bound := drawPopup(popupBound(Pt{10, 60}), "note for "+shortHash(e.Hash)+" (ctrl+s: save, esc: cancel)")
+	// show the last lines, where the cursor is.
+	top := len(lines) - bound.Size.L
+	if top < 0 {
+		top = 0
+	}
+	c := theme.Popup
+	for l, ln := range lines[top:] {
+		drawLine(bound, l, []byte(ln), 0, c)
+	}
+	last := lines[len(lines)-1]
+	term.ShowCursor(bound.Min.O+runewidth.StringWidth(last), bound.Min.L+len(lines)-1-top)
+}
+
+// noteFile returns the file that notes of all repositories are saved.
+func noteFile() (string, error) {
+	return configFile("notes")
+}
+
+// readNotes reads notes of the repository.
+//
+// Each line of the notes file is
+//
+//	"<repo>" <hash> "<quoted note>"
+func readNotes(repoDir string) (map[string]string, error) {
+	notes := make(map[string]string)
+	conf, err := noteFile()
+	if err != nil {
+		return notes, err
+	}
+	content, err := ioutil.ReadFile(conf)
+	if err != nil && !os.IsNotExist(err) {
+		return notes, err
+	}
+	err = checkCorrupted(conf, content, validNoteLine)
+	for _, ln := range strings.Split(string(content), "\n") {
+		repo, hash, note, ok := parseNoteLine(ln)
+		if !ok || repo != repoDir {
+			continue
+		}
+		notes[hash] = note
+	}
+	return notes, err
+}
+
+// saveNotes saves notes of the repository.
+// Notes of other repositories are kept.
+func saveNotes(repoDir string, notes map[string]string) error {
+	conf, err := noteFile()
+	if err != nil {
+		return err
+	}
+	return updateFile(conf, func(content []byte) ([]byte, error) {
+		// corrupted lines are backed up, ok to drop them.
+		checkCorrupted(conf, content, validNoteLine)
+		newContent := ""
+		for _, ln := range strings.Split(string(content), "\n") {
+			repo, _, _, ok := parseNoteLine(ln)
+			if !ok || repo == repoDir {
+				continue
+			}
+			newContent += ln + "\n"
+		}
+		for hash, note := range notes {
+			newContent += fmt.Sprintf("%q %s %s\n", repoDir, hash, strconv.Quote(note))
+		}
+		return []byte(newContent), nil
+	})
+}
+
+// validNoteLine reports whether the line of the notes file is valid.
+func validNoteLine(ln string) bool {
+	_, _, _, ok := parseNoteLine(ln)
+	return ok
+}
+
+// parseNoteLine parses a line of the notes file.
+func parseNoteLine(ln string) (repo, hash, note string, ok bool) {
+	repo, err := strconv.QuotedPrefix(ln)
+	if err != nil {
+		return "", "", "", false
+	}
+	rest := strings.TrimSpace(ln[len(repo):])
+	repo, _ = strconv.Unquote(repo)
+	idx := strings.Index(rest, " ")
+	if idx == -1 {
+		return "", "", "", false
+	}
+	hash = rest[:idx]
+	note, err = strconv.Unquote(strings.TrimSpace(rest[idx+1:]))
+	if err != nil {
+		return "", "", "", false
+	}
+	return repo, hash, note, true
+}
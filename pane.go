@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// parseBreakpoints parses widths of a terminal separated by commas, like "140, 200".
+// From the first width, the commit list and the diff are shown side by side,
+// and the file list is shown between them from the second.
+func parseBreakpoints(s string) ([]int, error) {
+	var bps []int
+	for _, f := range parseList(s) {
+		w, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, err
+		}
+		if len(bps) != 0 && w <= bps[len(bps)-1] {
+			return nil, fmt.Errorf("breakpoints should be ascending")
+		}
+		bps = append(bps, w)
+	}
+	if len(bps) > 2 {
+		return nil, fmt.Errorf("too many breakpoints")
+	}
+	return bps, nil
+}
+
+// Panes returns how many panes are shown side by side for the commit list and the diff,
+// by config.PaneBreakpoints. One pane is the usual layout, that shows one of them.
+// Two panes are the commit list and the diff, and three panes have the file list between them.
+func (s *Screen) Panes() int {
+	if s.Compact() {
+		return 1
+	}
+	n := 1
+	for _, w := range config.PaneBreakpoints {
+		if s.size.O >= w {
+			n++
+		}
+	}
+	return n
+}
+
+// paneViews are views of the panes from the left, by the number of panes.
+var paneViews = map[int][]View{
+	2: {CommitView, DiffView},
+	3: {CommitView, FilesView, DiffView},
+}
+
+// layoutPanes splits the main area to the panes, with a divider between them.
+// It returns the diff pane.
+func (s *Screen) layoutPanes(main Rect) Rect {
+	n := s.Panes()
+	widths := []int{main.Size.O * 2 / 5}
+	if n == 3 {
+		widths = []int{main.Size.O * 3 / 10, main.Size.O / 5}
+	}
+	o := main.Min.O
+	bounds := []*Rect{&s.Commit.Bound, &s.Files.Bound}
+	for i, w := range widths {
+		*bounds[i] = Rect{Min: Pt{main.Min.L, o}, Size: Pt{main.Size.L, w}}
+		o += w + 1
+	}
+	return Rect{Min: Pt{main.Min.L, o}, Size: Pt{main.Size.L, main.Min.O + main.Size.O - o}}
+}
+
+// InPanes reports whether the view is drawn in the panes.
+func (s *Screen) InPanes(v View) bool {
+	for _, pv := range paneViews[s.Panes()] {
+		if v == pv {
+			return true
+		}
+	}
+	return false
+}
+
+// NextPane focuses the next pane to the right, or the first after the last one.
+func (s *Screen) NextPane() {
+	views := paneViews[s.Panes()]
+	for i, v := range views {
+		if v == dig.CurView {
+			dig.CurView = views[(i+1)%len(views)]
+			return
+		}
+	}
+}
+
+// drawPanes draws the panes, and dividers between them.
+// Dividers beside the focused pane are highlighted.
+func (s *Screen) drawPanes() {
+	s.drawDiff()
+	s.Commit.Draw()
+	bounds := []Rect{s.Commit.Bound}
+	if s.Panes() == 3 {
+		s.Files.Sync(s.Diff)
+		s.Files.Draw()
+		bounds = append(bounds, s.Files.Bound)
+	}
+	views := paneViews[s.Panes()]
+	for i, b := range bounds {
+		c := theme.Normal
+		if views[i] == dig.CurView || views[i+1] == dig.CurView {
+			c = theme.Focused
+		}
+		for l := b.Min.L; l < b.Min.L+b.Size.L; l++ {
+			setCell(b.Min.O+b.Size.O, l, '│', c.Fg, c.Bg)
+		}
+	}
+}
+
+// FilesArea is the file list of the selected commit's diff, in the three pane layout.
+// Moving in the list scrolls the diff to the file.
+type FilesArea struct {
+	Bound      Rect
+	CommitHash string
+	Files      []string
+	// Statuses are statuses of Files like git show --name-status, such as M and A.
+	// They're empty in the pane, as the files are found in the diff.
+	Statuses []string
+	CurIdx   int
+	TopIdx   int
+	// Alone is set when the list is opened by itself, not as a pane. See openFiles.
+	Alone bool
+}
+
+// Sync loads files of the diff area's commit, when it's changed.
+func (a *FilesArea) Sync(d *DiffArea) {
+	if d.CommitHash == a.CommitHash {
+		return
+	}
+	a.CommitHash = d.CommitHash
+	a.Files = nil
+	a.Statuses = nil
+	a.Alone = false
+	for _, ln := range d.Text {
+		if isFileHeader(ln) {
+			a.Files = append(a.Files, fileName(ln))
+		}
+	}
+	a.CurIdx = 0
+	a.TopIdx = 0
+}
+
+// Handle handles a terminal event.
+func (a *FilesArea) Handle(ev Event) bool {
+	if ev.Key == KeyArrowUp || ev.Ch == 'i' {
+		a.CurIdx -= count()
+	} else if ev.Key == KeyArrowDown || ev.Ch == 'k' {
+		a.CurIdx += count()
+	} else if ev.Key == KeyHome {
+		a.CurIdx = 0
+	} else if ev.Key == KeyEnd {
+		a.CurIdx = len(a.Files) - 1
+	} else if ev.Key == KeyEnter {
+		if a.Alone && len(a.Files) != 0 {
+			screen.Diff.GotoFile(a.Files[a.CurIdx])
+		}
+		dig.CurView = DiffView
+		return true
+	} else {
+		return false
+	}
+	if a.CurIdx >= len(a.Files) {
+		a.CurIdx = len(a.Files) - 1
+	}
+	if a.CurIdx < 0 {
+		a.CurIdx = 0
+	}
+	if len(a.Files) != 0 && !a.Alone {
+		screen.Diff.GotoFile(a.Files[a.CurIdx])
+	}
+	return true
+}
+
+// Draw draws the file list.
+func (a *FilesArea) Draw() {
+	if len(a.Files) == 0 {
+		drawLine(a.Bound, 0, []byte("no files"), 0, theme.Normal)
+		return
+	}
+	if a.TopIdx > a.CurIdx {
+		a.TopIdx = a.CurIdx
+	} else if a.TopIdx+a.Bound.Size.L <= a.CurIdx {
+		a.TopIdx = a.CurIdx - a.Bound.Size.L + 1
+	}
+	for l := 0; l < a.Bound.Size.L; l++ {
+		i := a.TopIdx + l
+		if i >= len(a.Files) {
+			break
+		}
+		c := theme.Normal
+		if i == a.CurIdx {
+			c = theme.Selected
+			if dig.CurView != FilesView {
+				c = theme.Focused
+			}
+			fillColor(Rect{Min: Pt{a.Bound.Min.L + l, a.Bound.Min.O}, Size: Pt{1, a.Bound.Size.O}}, c)
+		}
+		ln := a.Files[i]
+		if len(a.Statuses) != 0 {
+			ln = a.Statuses[i] + "  " + ln
+		}
+		drawLine(a.Bound, l, []byte(ln), 0, c)
+	}
+}
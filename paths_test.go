@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPaths(t *testing.T) {
+	cases := []struct {
+		all, args      []string
+		targets, paths []string
+	}{
+		{[]string{"-all"}, nil, nil, nil},
+		{[]string{"master"}, []string{"master"}, []string{"master"}, nil},
+		{[]string{"--", "main.go"}, []string{"main.go"}, nil, []string{"main.go"}},
+		{[]string{"-all", "--", "a", "b"}, []string{"a", "b"}, nil, []string{"a", "b"}},
+		{[]string{"master", "--", "main.go"}, []string{"master", "--", "main.go"}, []string{"master"}, []string{"main.go"}},
+	}
+	for _, c := range cases {
+		targets, paths := splitPaths(c.all, c.args)
+		if len(targets) != len(c.targets) || len(paths) != len(c.paths) || strings.Join(targets, " ") != strings.Join(c.targets, " ") || strings.Join(paths, " ") != strings.Join(c.paths, " ") {
+			t.Errorf("split %q: got %q and %q, want %q and %q", c.all, targets, paths, c.targets, c.paths)
+		}
+	}
+
+	r := historyFixture(t)
+	setupApp(t, r.Dir, r.Commits(false, "HEAD", "--", "main.go"))
+	dig.Paths = []string{"main.go"}
+	if got, want := titles(dig.Commits), "say hi, initial"; got != want {
+		t.Errorf("commits of main.go: got %q, want %q", got, want)
+	}
+	if err := reloadCommits(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := titles(dig.Commits), "say hi, initial"; got != want {
+		t.Errorf("reloaded commits of main.go: got %q, want %q", got, want)
+	}
+	// fix readme changed only README, it's diff doesn't have a file.
+	text, err := commitDiff(r.Hash("HEAD^1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ln := range text {
+		if isFileHeader(ln) {
+			t.Errorf("diff of fix readme has %s", fileName(ln))
+		}
+	}
+	text, err = rangeDiff(r.Hash("v0.1"), r.Hash("HEAD"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, ln := range text {
+		if isFileHeader(ln) {
+			files = append(files, fileName(ln))
+		}
+	}
+	if len(files) != 1 || files[0] != "main.go" {
+		t.Errorf("files of the range: got %q", files)
+	}
+}
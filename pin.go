@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Pinned reports whether the commit is pinned.
+func (p *Program) Pinned(hash string) bool {
+	for _, h := range p.Pins {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// TogglePin pins the commit, or unpins it if already pinned.
+func (p *Program) TogglePin(hash string) {
+	for i, h := range p.Pins {
+		if h == hash {
+			p.Pins = append(p.Pins[:i], p.Pins[i+1:]...)
+			return
+		}
+	}
+	p.Pins = append(p.Pins, hash)
+}
+
+// pinnedCommits returns pinned commits in pinned order.
+func pinnedCommits() []*Commit {
+	commits := make([]*Commit, 0, len(dig.Pins))
+	for _, h := range dig.Pins {
+		if i := findByHash(dig.Commits, h, 0); i != -1 {
+			commits = append(commits, dig.Commits[i])
+		} else {
+			// it's pinned, but not in the list anymore. (ex. file history)
+			commits = append(commits, &Commit{Hash: h})
+		}
+	}
+	return commits
+}
+
+// TrayArea is an Area for showing pinned commits.
+type TrayArea struct {
+	Bound  Rect
+	CurIdx int
+	TopIdx int
+
+	// Marked are hashes of marked commits for diffing, at most two.
+	Marked Marks
+}
+
+// Handle handles a terminal event.
+func (a *TrayArea) Handle(ev Event) bool {
+	pins := pinnedCommits()
+	if ev.Key == KeyArrowUp || ev.Ch == 'i' {
+		a.CurIdx -= count()
+	} else if ev.Key == KeyArrowDown || ev.Ch == 'k' {
+		a.CurIdx += count()
+	} else if ev.Key == KeyHome {
+		a.CurIdx = 0
+	} else if ev.Key == KeyEnd {
+		a.CurIdx = len(pins) - 1
+	} else if len(pins) == 0 {
+		return false
+	} else if ev.Key == KeySpace {
+		a.Marked.Toggle(pins[a.CurIdx].Hash, 2)
+	} else if ev.Ch == 'x' {
+		dig.TogglePin(pins[a.CurIdx].Hash)
+		a.Marked.Unmark(pins[a.CurIdx].Hash)
+	} else if ev.Ch == 'd' {
+		if err := a.diff(pins[a.CurIdx].Hash); err != nil {
+			dig.Message = err.Error()
+		}
+	} else if ev.Key == KeyEnter {
+		if i := findByHash(dig.Commits, pins[a.CurIdx].Hash, 0); i != -1 {
+			screen.Commit.SetCursor(i)
+			dig.CurView = CommitView
+		}
+	} else if ev.Ch == 'e' {
+		if err := cmdExportPins(nil); err != nil {
+			dig.Message = err.Error()
+		}
+	} else if ev.Ch == 'r' {
+		if err := cmdPinReport(nil); err != nil {
+			dig.Message = err.Error()
+		}
+	} else {
+		return false
+	}
+	if a.CurIdx >= len(dig.Pins) {
+		a.CurIdx = len(dig.Pins) - 1
+	}
+	if a.CurIdx < 0 {
+		a.CurIdx = 0
+	}
+	return true
+}
+
+// diff opens diff between two marked commits.
+// When only one commit is marked, it diffs the marked and the cursor.
+func (a *TrayArea) diff(cursor string) error {
+	var from, to string
+	switch len(a.Marked) {
+	case 2:
+		from, to = a.Marked[0], a.Marked[1]
+	case 1:
+		from, to = a.Marked[0], cursor
+	default:
+		return fmt.Errorf("mark commits to diff with space")
+	}
+	if from == to {
+		return fmt.Errorf("cannot diff a commit with itself")
+	}
+	if !notifyDiff(from + ".." + to) {
+		openDiff(from + ".." + to)
+	}
+	return nil
+}
+
+// Draw draws it's contents.
+func (a *TrayArea) Draw() {
+	pins := pinnedCommits()
+	if len(pins) == 0 {
+		drawLine(a.Bound, 0, []byte("no pinned commits. pin a commit with 'p' in the commit view."), 0, theme.Normal)
+		return
+	}
+	if a.TopIdx > a.CurIdx {
+		a.TopIdx = a.CurIdx
+	} else if a.TopIdx+a.Bound.Size.L <= a.CurIdx {
+		a.TopIdx = a.CurIdx - a.Bound.Size.L + 1
+	}
+	for l := 0; l < a.Bound.Size.L; l++ {
+		i := a.TopIdx + l
+		if i >= len(pins) {
+			break
+		}
+		p := pins[i]
+		c := theme.Normal
+		if i == a.CurIdx {
+			c = theme.Selected
+			fillColor(Rect{Min: Pt{a.Bound.Min.L + l, a.Bound.Min.O}, Size: Pt{1, a.Bound.Size.O}}, c)
+		}
+		mark := "  "
+		if j := a.Marked.Index(p.Hash); j != -1 {
+			mark = strconv.Itoa(j+1) + " "
+		}
+		drawLine(a.Bound, l, []byte(mark+shortHash(p.Hash)+" "+p.Title), 0, c)
+	}
+}
+
+// cmdExportPins exports pinned commits as patches into a directory.
+// The directory is "dig-patches" if not given.
+func cmdExportPins(args []string) error {
+	dir := "dig-patches"
+	if len(args) != 0 {
+		dir = args[0]
+	}
+	if len(dig.Pins) == 0 {
+		return fmt.Errorf("no pinned commits")
+	}
+	return exportPatches(dig.Pins, dir)
+}
+
+// exportPatches exports the commits as patches into a directory,
+// numbered in the order.
+func exportPatches(hashes []string, dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for i, h := range hashes {
+		_, err := gitOutput("format-patch", "-1", "--start-number", strconv.Itoa(i+1), "-o", dir, h)
+		if err != nil {
+			return err
+		}
+	}
+	dig.Message = fmt.Sprintf("exported %d patches to %s", len(hashes), dir)
+	return nil
+}
+
+// cmdPinReport writes a combined Markdown report of pinned commits.
+// The file is "dig-report.md" if not given.
+func cmdPinReport(args []string) error {
+	file := "dig-report.md"
+	if len(args) != 0 {
+		file = args[0]
+	}
+	if len(dig.Pins) == 0 {
+		return fmt.Errorf("no pinned commits")
+	}
+	return writeReport(dig.Pins, file, "Pinned commits")
+}
+
+// writeReport writes a combined Markdown report of the commits in the order.
+func writeReport(hashes []string, file, heading string) error {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "# %s of %s\n", heading, filepath.Base(dig.RepoDir))
+	for _, h := range hashes {
+		out, err := gitOutput("show", "--stat", "--format=%s%n%n%H%n%an <%ae>, %ad%n%n%b%n", h)
+		if err != nil {
+			return err
+		}
+		title := string(bytes.SplitN(out, []byte("\n"), 2)[0])
+		body := bytes.TrimSpace(bytes.TrimPrefix(out, []byte(title)))
+		// empty commit body leaves too many empty lines.
+		for bytes.Contains(body, []byte("\n\n\n")) {
+			body = bytes.Replace(body, []byte("\n\n\n"), []byte("\n\n"), -1)
+		}
+		fmt.Fprintf(buf, "\n## %s\n\n```\n%s\n```\n", title, body)
+	}
+	if err := ioutil.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	dig.Message = "report written to " + file
+	return nil
+}
+
+// cmdPins handles pin related subcommands.
+//
+//	pins            open the tray.
+//	pins export     export pinned commits as patches.
+//	pins report     write a combined report of pinned commits.
+//	pins clear      unpin all commits.
+func cmdPins(args []string) error {
+	if len(args) == 0 {
+		dig.CurView = TrayView
+		return nil
+	}
+	switch args[0] {
+	case "export":
+		return cmdExportPins(args[1:])
+	case "report":
+		return cmdPinReport(args[1:])
+	case "clear":
+		dig.Pins = nil
+		screen.Tray.Marked = nil
+		return nil
+	}
+	return fmt.Errorf("unknown pins command: %s", args[0])
+}
@@ -0,0 +1,131 @@
+package main
+
+// popupBound returns a bound for a popup at center of the screen.
+// The popup fits in the screen even if the wanted size is bigger.
+func popupBound(size Pt) Rect {
+	// leave the status line.
+	max := Pt{screen.size.L - 3, screen.size.O - 4}
+	if size.L > max.L {
+		size.L = max.L
+	}
+	if size.O > max.O {
+		size.O = max.O
+	}
+	if size.L < 1 {
+		size.L = 1
+	}
+	if size.O < 1 {
+		size.O = 1
+	}
+	min := Pt{(screen.size.L - statusHeight(screen.size) - size.L) / 2, (screen.size.O - size.O) / 2}
+	return Rect{Min: min, Size: size}
+}
+
+// drawPopup draws a bordered popup with a title, and returns the bound inside of the border.
+func drawPopup(bound Rect, title string) Rect {
+	c := theme.Popup
+	outer := Rect{
+		Min:  Pt{bound.Min.L - 1, bound.Min.O - 1},
+		Size: Pt{bound.Size.L + 2, bound.Size.O + 2},
+	}
+	fillColor(outer, c)
+	max := outer.Min.Add(outer.Size)
+	for o := outer.Min.O; o < max.O; o++ {
+		setCell(o, outer.Min.L, '─', c.Fg, c.Bg)
+		setCell(o, max.L-1, '─', c.Fg, c.Bg)
+	}
+	for l := outer.Min.L; l < max.L; l++ {
+		setCell(outer.Min.O, l, '│', c.Fg, c.Bg)
+		setCell(max.O-1, l, '│', c.Fg, c.Bg)
+	}
+	setCell(outer.Min.O, outer.Min.L, '┌', c.Fg, c.Bg)
+	setCell(max.O-1, outer.Min.L, '┐', c.Fg, c.Bg)
+	setCell(outer.Min.O, max.L-1, '└', c.Fg, c.Bg)
+	setCell(max.O-1, max.L-1, '┘', c.Fg, c.Bg)
+	if title != "" {
+		titleBound := Rect{Min: Pt{outer.Min.L, outer.Min.O + 2}, Size: Pt{1, outer.Size.O - 4}}
+		drawLine(titleBound, 0, []byte(" "+title+" "), 0, c)
+	}
+	return bound
+}
+
+// Popup is a popup that takes events while it's opened.
+type Popup interface {
+	Handle(ev Event)
+	Draw()
+}
+
+// ListPopup is a popup to choose an item from a list.
+type ListPopup struct {
+	Title  string
+	Items  []string
+	CurIdx int
+	TopIdx int
+	// Select is called with index of the chosen item, after the popup is closed.
+	Select func(i int)
+}
+
+// openList opens a list popup.
+func openList(title string, items []string, cur int, sel func(i int)) {
+	screen.Popup = &ListPopup{Title: title, Items: items, CurIdx: cur, Select: sel}
+}
+
+// Handle handles a terminal event, while the popup is opened.
+func (p *ListPopup) Handle(ev Event) {
+	page := popupBound(p.size()).Size.L
+	if ev.Key == KeyArrowUp || ev.Ch == 'i' {
+		p.CurIdx -= count()
+	} else if ev.Key == KeyArrowDown || ev.Ch == 'k' {
+		p.CurIdx += count()
+	} else if ev.Key == KeyPgup || ev.Ch == 'b' {
+		p.CurIdx -= page * count()
+	} else if ev.Key == KeyPgdn || ev.Ch == 'f' {
+		p.CurIdx += page * count()
+	} else if ev.Key == KeyHome {
+		p.CurIdx = 0
+	} else if ev.Key == KeyEnd {
+		p.CurIdx = len(p.Items) - 1
+	} else if ev.Key == KeyEnter {
+		screen.Popup = nil
+		if len(p.Items) != 0 {
+			p.Select(p.CurIdx)
+		}
+		return
+	} else if ev.Key == KeyEsc || ev.Ch == 'q' {
+		screen.Popup = nil
+		return
+	}
+	if p.CurIdx >= len(p.Items) {
+		p.CurIdx = len(p.Items) - 1
+	}
+	if p.CurIdx < 0 {
+		p.CurIdx = 0
+	}
+}
+
+// size returns wanted size of the popup.
+func (p *ListPopup) size() Pt {
+	return Pt{len(p.Items), screen.size.O * 4 / 5}
+}
+
+// Draw draws the popup.
+func (p *ListPopup) Draw() {
+	bound := drawPopup(popupBound(p.size()), p.Title)
+	if p.TopIdx > p.CurIdx {
+		p.TopIdx = p.CurIdx
+	} else if p.TopIdx+bound.Size.L <= p.CurIdx {
+		p.TopIdx = p.CurIdx - bound.Size.L + 1
+	}
+	for l := 0; l < bound.Size.L; l++ {
+		i := p.TopIdx + l
+		if i >= len(p.Items) {
+			break
+		}
+		c := theme.Popup
+		if i == p.CurIdx {
+			c = theme.Selected
+			fillColor(Rect{Min: Pt{bound.Min.L + l, bound.Min.O}, Size: Pt{1, bound.Size.O}}, c)
+		}
+		drawLine(bound, l, []byte(p.Items[i]), 0, c)
+	}
+}
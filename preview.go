@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"time"
+)
+
+// previewLines is the maximum number of lines of a preview.
+// The rest of the diff isn't read, to keep it light on big commits.
+const previewLines = 200
+
+// PreviewArea shows a short diff of the selected commit beside the commit list.
+// It loads the diff after the cursor stays on a commit for config.PreviewDelay.
+type PreviewArea struct {
+	Bound Rect
+	// Hash is the commit of Text.
+	Hash string
+	Text [][]byte
+
+	// want is the commit that the preview is going to show.
+	want  string
+	timer *time.Timer
+}
+
+// Follow makes the preview follow the commit.
+// It loads the commit's preview after the delay, if the commit is still wanted then.
+func (a *PreviewArea) Follow(hash string) {
+	if hash == a.want {
+		return
+	}
+	a.want = hash
+	if a.timer != nil {
+		a.timer.Stop()
+	}
+	repoDir, paths := dig.RepoDir, dig.Paths
+	a.timer = time.AfterFunc(config.PreviewDelay, func() {
+		text, err := commitPreview(repoDir, hash, paths)
+		send(previewMsg{Area: a, Hash: hash, Text: text, Err: err})
+	})
+}
+
+// previewMsg is a loaded preview of a commit.
+type previewMsg struct {
+	Area *PreviewArea
+	Hash string
+	Text [][]byte
+	Err  error
+}
+
+// Loaded shows the loaded preview, if it's commit is still wanted.
+func (a *PreviewArea) Loaded(msg previewMsg) {
+	if a.want != msg.Hash {
+		return
+	}
+	text := msg.Text
+	if msg.Err != nil {
+		text = [][]byte{[]byte(msg.Err.Error())}
+	}
+	a.Hash = msg.Hash
+	a.Text = describeModes(decodeDiff(text))
+}
+
+// Draw draws the preview with a divider at it's left.
+func (a *PreviewArea) Draw() {
+	if a.Bound.Size.O <= 0 {
+		return
+	}
+	c := theme.Normal
+	for l := 0; l < a.Bound.Size.L; l++ {
+		setCell(a.Bound.Min.O-1, a.Bound.Min.L+l, '│', c.Fg, c.Bg)
+	}
+	if a.Hash != a.want {
+		drawLine(a.Bound, 0, []byte("loading..."), 0, c)
+		return
+	}
+	for l, ln := range a.Text {
+		if l >= a.Bound.Size.L {
+			break
+		}
+		c := theme.Normal
+		if len(ln) != 0 {
+			if ln[0] == '+' {
+				c = theme.Added
+			} else if ln[0] == '-' {
+				c = theme.Deleted
+			} else if mc, ok := modeColor(ln); ok {
+				c = mc
+			}
+		}
+		drawLine(a.Bound, l, ln, 0, c)
+	}
+}
+
+// commitPreview returns stat and first lines of diff of the commit, in the paths.
+// It runs in background, so the repository and the paths are given instead of using dig's.
+func commitPreview(repoDir, hash string, paths []string) ([][]byte, error) {
+	cmd := gitCommand(repoDir, pathArgs([]string{"show", "--stat", "--patch", "--no-color", "--format=%h %an, %ar%n%n%s%n", hash}, paths)...)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	lines := [][]byte{}
+	sc := bufio.NewScanner(out)
+	for len(lines) < previewLines && sc.Scan() {
+		// tab handling in screen is quite awkard. handle it here.
+		lines = append(lines, bytes.Replace(sc.Bytes(), []byte("\t"), []byte("    "), -1))
+	}
+	// don't wait git to write the rest of a big diff.
+	cmd.Process.Kill()
+	cmd.Wait()
+	return lines, nil
+}
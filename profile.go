@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// defaultProfile is the name of settings those are for all profiles.
+const defaultProfile = "default"
+
+// builtinProfiles are profiles dig knows without the config file.
+// The user's profile of the same name overrides them.
+var builtinProfiles = map[string][]configLine{
+	// presentation is for walking through history on a shared screen.
+	// It hides details, and makes the status bar noticeable.
+	"presentation": {
+		{Where: "presentation profile", Key: "show_hash", Value: "false"},
+		{Where: "presentation profile", Key: "preview", Value: "false"},
+		{Where: "presentation profile", Key: "age_tint", Value: "false"},
+		{Where: "presentation profile", Key: "lane_colors", Value: "false"},
+		{Where: "presentation profile", Key: "hyperlinks", Value: "false"},
+		{Where: "presentation profile", Key: "side_width", Value: "0"},
+		{Where: "presentation profile", Key: "status_lines", Value: "3"},
+		{Where: "presentation profile", Key: "wide_status", Value: "true"},
+		{Where: "presentation profile", Key: "simple", Value: "true"},
+	},
+}
+
+// profileNames returns names of profiles in the config file and built in, sorted.
+// The default profile comes first.
+func profileNames() ([]string, error) {
+	sections, err := readConfigSections()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for name := range sections {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	for name := range builtinProfiles {
+		if _, ok := sections[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{defaultProfile}, names...), nil
+}
+
+// profileName returns name of the profile in use.
+func profileName() string {
+	if config.Profile == "" {
+		return defaultProfile
+	}
+	return config.Profile
+}
+
+// diffProfiles returns settings those differ between the profiles,
+// like "theme: dark -> light". Unset settings are shown as (default).
+func diffProfiles(from, to string) ([]string, error) {
+	a, err := readConfig(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := readConfig(to)
+	if err != nil {
+		return nil, err
+	}
+	keys := []string{}
+	for k := range a.values {
+		keys = append(keys, k)
+	}
+	for k := range b.values {
+		if _, ok := a.values[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	value := func(c *Config, k string) string {
+		v, ok := c.values[k]
+		if !ok {
+			return "(default)"
+		}
+		if v == "" || strings.ContainsAny(v, " \t") {
+			return fmt.Sprintf("%q", v)
+		}
+		return v
+	}
+	var diffs []string
+	for _, k := range keys {
+		va, vb := value(a, k), value(b, k)
+		if va != vb {
+			diffs = append(diffs, k+": "+va+" -> "+vb)
+		}
+	}
+	return diffs, nil
+}
+
+// openProfileDiff opens a popup of settings those differ between the profiles.
+func openProfileDiff(from, to string) error {
+	diffs, err := diffProfiles(from, to)
+	if err != nil {
+		return err
+	}
+	if len(diffs) == 0 {
+		dig.Message = fmt.Sprintf("profiles %s and %s are same", from, to)
+		return nil
+	}
+	openList(fmt.Sprintf("config %s -> %s", from, to), diffs, 0, func(int) {})
+	return nil
+}
+
+// cmdProfile shows profiles, or settings those differ between profiles.
+// Without an argument, it opens a popup of profiles,
+// and a chosen one is compared to the profile in use.
+// Profiles are chosen on start with the -profile flag.
+//
+//	profile
+//	profile diff <profile> [profile]
+func cmdProfile(args []string) error {
+	if len(args) == 0 {
+		names, err := profileNames()
+		if err != nil {
+			return err
+		}
+		cur := 0
+		items := make([]string, len(names))
+		for i, n := range names {
+			items[i] = "  " + n
+			if n == profileName() {
+				items[i] = "* " + n
+				cur = i
+			}
+		}
+		openList("profiles, enter: compare to "+profileName(), items, cur, func(i int) {
+			if err := openProfileDiff(profileName(), names[i]); err != nil {
+				dig.Message = err.Error()
+			}
+		})
+		return nil
+	}
+	if args[0] != "diff" || len(args) < 2 || len(args) > 3 {
+		return fmt.Errorf("usage: profile [diff <profile> [profile]]")
+	}
+	if len(args) == 2 {
+		return openProfileDiff(profileName(), args[1])
+	}
+	return openProfileDiff(args[1], args[2])
+}
+
+// parseKeymap parses key mappings separated by commas, like "n:k, p:i".
+// Each maps a pressed key to a key of dig.
+func parseKeymap(s string) (map[rune]rune, error) {
+	m := make(map[rune]rune)
+	for _, f := range parseList(s) {
+		from, size := utf8.DecodeRuneInString(f)
+		if len(f) <= size || f[size] != ':' {
+			return nil, fmt.Errorf("expected key:key, got %s", f)
+		}
+		to, n := utf8.DecodeRuneInString(f[size+1:])
+		if size+1+n != len(f) {
+			return nil, fmt.Errorf("expected key:key, got %s", f)
+		}
+		m[from] = to
+	}
+	return m, nil
+}
+
+// mapKey maps the pressed key with config.Keymap.
+func mapKey(ev Event) Event {
+	if to, ok := config.Keymap[ev.Ch]; ok && ev.Ch != 0 {
+		ev.Ch = to
+	}
+	return ev
+}
+
+// statusHeight returns height of the status bar in the screen.
+func statusHeight(size Pt) int {
+	h := config.StatusLines
+	if h < 1 {
+		h = 1
+	}
+	if h > size.L/4 {
+		// the screen is too short.
+		h = 1
+	}
+	return h
+}
+
+// simpleStatus returns what is selected, for the status bar of the simple UI.
+// It returns "" for views those still need key hints.
+func simpleStatus() string {
+	switch dig.CurView {
+	case CommitView, DiffView:
+		c := screen.Commit.Commit()
+		return shortHash(c.Hash) + "  " + c.Title
+	case TreeView:
+		return "files of " + shortHash(screen.Tree.CommitHash)
+	case FileView:
+		return screen.File.Path
+	}
+	return ""
+}
+
+// wideRune returns the full width form of an ASCII character, for the larger status text.
+func wideRune(r rune) rune {
+	switch {
+	case r == ' ':
+		return '　'
+	case r > ' ' && r <= '~':
+		return r - '!' + '！'
+	}
+	return r
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// startQuery starts QueryMode, that filters commits as the query is typed.
+// The query becomes a text filter with the other filters.
+func startQuery() {
+	dig.QueryFrom = dig.Filters
+	dig.Query = ""
+	for _, f := range dig.Filters {
+		if f.Query != "" {
+			// edit the last query, instead of adding another.
+			dig.Query = f.Query
+		}
+	}
+	dig.Mode = QueryMode
+}
+
+// applyQuery filters commits with the query, and the filters before QueryMode.
+// When no commit matches, commits are kept as before.
+func applyQuery() {
+	filters := []*Filter{}
+	for _, f := range dig.QueryFrom {
+		if f.Query == "" {
+			filters = append(filters, f)
+		}
+	}
+	if strings.TrimSpace(dig.Query) != "" {
+		f := textFilter(dig.Query)
+		f.Name = "text:" + dig.Query
+		filters = append(filters, f)
+	}
+	dig.Message = ""
+	if err := setFilters(filters); err != nil {
+		dig.Message = err.Error()
+	}
+}
+
+// handleQuery handles QueryMode events.
+// Enter keeps the filter, and escape restores the filters before.
+func handleQuery(ev Event) {
+	switch ev.Key {
+	case KeyEsc, KeyCtrlQ, KeyCtrlK:
+		dig.Mode = NormalMode
+		if err := setFilters(dig.QueryFrom); err != nil {
+			dig.Message = err.Error()
+		}
+		dig.QueryFrom = nil
+		return
+	case KeyEnter:
+		dig.Mode = NormalMode
+		dig.QueryFrom = nil
+		return
+	case KeyBackspace:
+		if dig.Query == "" {
+			return
+		}
+		_, size := utf8.DecodeLastRuneInString(dig.Query)
+		dig.Query = dig.Query[:len(dig.Query)-size]
+		applyQuery()
+		return
+	case KeySpace:
+		dig.Query += " "
+		return
+	}
+	if ev.Ch != 0 {
+		dig.Query += string(ev.Ch)
+		applyQuery()
+	}
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// releaseTypes are headings of conventional commit types, in the order of release notes.
+var releaseTypes = []struct {
+	Type    string
+	Heading string
+}{
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"perf", "Performance"},
+	{"refactor", "Refactoring"},
+	{"revert", "Reverts"},
+	{"docs", "Documentation"},
+	{"test", "Tests"},
+	{"build", "Build"},
+	{"ci", "CI"},
+	{"style", "Style"},
+	{"chore", "Chores"},
+}
+
+// conventionalRe parses a conventional commit title, like "feat(diff)!: word diff".
+var conventionalRe = regexp.MustCompile(`^([a-zA-Z]+)(\(([^)]*)\))?(!)?: (.*)$`)
+
+// ReleaseCommit is a commit in release notes.
+type ReleaseCommit struct {
+	Hash     string
+	Author   string
+	Title    string
+	Type     string // "" when it isn't a conventional commit
+	Scope    string
+	Breaking bool
+}
+
+// Summary returns the title without it's type, like "**diff:** word diff".
+func (c *ReleaseCommit) Summary() string {
+	m := conventionalRe.FindStringSubmatch(c.Title)
+	if m == nil {
+		return c.Title
+	}
+	s := m[5]
+	if c.Scope != "" {
+		s = "**" + c.Scope + ":** " + s
+	}
+	if c.Breaking {
+		s = "**BREAKING** " + s
+	}
+	return s
+}
+
+// ReleaseGroup is commits of a type or an author.
+type ReleaseGroup struct {
+	Name    string
+	Commits []*ReleaseCommit
+	Folded  bool
+}
+
+// releaseRow is a row of ReleaseArea, either a group or a commit.
+type releaseRow struct {
+	Group  *ReleaseGroup
+	Commit *ReleaseCommit
+}
+
+// ReleaseArea is an Area for showing commits between two tags, for writing release notes.
+type ReleaseArea struct {
+	Bound    Rect
+	From     string
+	To       string
+	Commits  []*ReleaseCommit
+	ByAuthor bool
+	Groups   []*ReleaseGroup
+	Rows     []releaseRow
+	CurIdx   int
+	TopIdx   int
+}
+
+// Load loads commits between the two refs.
+func (a *ReleaseArea) Load(from, to string) error {
+	commits, err := releaseCommits(from, to)
+	if err != nil {
+		return err
+	}
+	a.From = from
+	a.To = to
+	a.Commits = commits
+	a.CurIdx = 0
+	a.TopIdx = 0
+	a.group()
+	return nil
+}
+
+// releaseCommits returns commits in from..to, without merges, newest first.
+func releaseCommits(from, to string) ([]*ReleaseCommit, error) {
+	out, err := gitOutput("log", "--no-merges", "--format=%H%x00%an%x00%s", from+".."+to)
+	if err != nil {
+		return nil, err
+	}
+	var commits []*ReleaseCommit
+	for _, ln := range strings.Split(string(out), "\n") {
+		f := strings.SplitN(ln, "\x00", 3)
+		if len(f) != 3 {
+			continue
+		}
+		c := &ReleaseCommit{Hash: f[0], Author: f[1], Title: f[2]}
+		if m := conventionalRe.FindStringSubmatch(c.Title); m != nil {
+			c.Type = strings.ToLower(m[1])
+			c.Scope = m[3]
+			c.Breaking = m[4] == "!"
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
+
+// group groups the commits by type, or by author.
+func (a *ReleaseArea) group() {
+	groups := make(map[string]*ReleaseGroup)
+	var names []string
+	for _, c := range a.Commits {
+		name := releaseHeading(c.Type)
+		if a.ByAuthor {
+			name = c.Author
+		}
+		g := groups[name]
+		if g == nil {
+			g = &ReleaseGroup{Name: name}
+			groups[name] = g
+			names = append(names, name)
+		}
+		g.Commits = append(g.Commits, c)
+	}
+	if a.ByAuthor {
+		// authors with more commits first.
+		sort.SliceStable(names, func(i, j int) bool {
+			return len(groups[names[i]].Commits) > len(groups[names[j]].Commits)
+		})
+	} else {
+		sort.SliceStable(names, func(i, j int) bool {
+			return releaseOrder(names[i]) < releaseOrder(names[j])
+		})
+	}
+	a.Groups = a.Groups[:0]
+	for _, n := range names {
+		a.Groups = append(a.Groups, groups[n])
+	}
+	a.refresh()
+}
+
+// releaseHeading returns heading of the commit type.
+func releaseHeading(typ string) string {
+	for _, t := range releaseTypes {
+		if t.Type == typ {
+			return t.Heading
+		}
+	}
+	return "Other Changes"
+}
+
+// releaseOrder returns order of the heading in release notes.
+func releaseOrder(heading string) int {
+	for i, t := range releaseTypes {
+		if t.Heading == heading {
+			return i
+		}
+	}
+	return len(releaseTypes)
+}
+
+// refresh rebuilds rows from groups, considering folded groups.
+func (a *ReleaseArea) refresh() {
+	a.Rows = a.Rows[:0]
+	for _, g := range a.Groups {
+		a.Rows = append(a.Rows, releaseRow{Group: g})
+		if g.Folded {
+			continue
+		}
+		for _, c := range g.Commits {
+			a.Rows = append(a.Rows, releaseRow{Group: g, Commit: c})
+		}
+	}
+	if a.CurIdx >= len(a.Rows) {
+		a.CurIdx = len(a.Rows) - 1
+	}
+	if a.CurIdx < 0 {
+		a.CurIdx = 0
+	}
+}
+
+// Handle handles a terminal event.
+func (a *ReleaseArea) Handle(ev Event) bool {
+	page := a.Bound.Size.L - 1
+	if ev.Key == KeyArrowUp || ev.Ch == 'i' {
+		a.CurIdx -= count()
+	} else if ev.Key == KeyArrowDown || ev.Ch == 'k' {
+		a.CurIdx += count()
+	} else if ev.Key == KeyPgup || ev.Ch == 'b' {
+		a.CurIdx -= page * count()
+	} else if ev.Key == KeyPgdn || ev.Ch == 'f' {
+		a.CurIdx += page * count()
+	} else if ev.Key == KeyHome {
+		a.CurIdx = 0
+	} else if ev.Key == KeyEnd {
+		a.CurIdx = len(a.Rows) - 1
+	} else if ev.Ch == 'a' {
+		a.ByAuthor = !a.ByAuthor
+		a.CurIdx = 0
+		a.group()
+		return true
+	} else if ev.Ch == 'e' {
+		if err := a.Export(""); err != nil {
+			dig.Message = err.Error()
+		}
+		return true
+	} else if len(a.Rows) == 0 {
+		return false
+	} else if ev.Key == KeySpace || ev.Key == KeyEnter && a.Rows[a.CurIdx].Commit == nil {
+		g := a.Rows[a.CurIdx].Group
+		g.Folded = !g.Folded
+		for i, r := range a.Rows {
+			if r.Group == g {
+				a.CurIdx = i
+				break
+			}
+		}
+	} else if ev.Key == KeyEnter {
+		openDiff(a.Rows[a.CurIdx].Commit.Hash)
+		return true
+	} else {
+		return false
+	}
+	a.refresh()
+	return true
+}
+
+// Draw draws it's contents.
+func (a *ReleaseArea) Draw() {
+	by := "type"
+	if a.ByAuthor {
+		by = "author"
+	}
+	header := fmt.Sprintf("%s..%s: %d commits by %s", a.From, a.To, len(a.Commits), by)
+	drawLine(a.Bound, 0, []byte(header), 0, theme.Header)
+
+	page := a.Bound.Size.L - 1
+	if a.TopIdx > a.CurIdx {
+		a.TopIdx = a.CurIdx
+	} else if a.TopIdx+page <= a.CurIdx {
+		a.TopIdx = a.CurIdx - page + 1
+	}
+	for l := 0; l < page; l++ {
+		i := a.TopIdx + l
+		if i >= len(a.Rows) {
+			break
+		}
+		r := a.Rows[i]
+		c := theme.Normal
+		var ln string
+		if r.Commit == nil {
+			c = theme.Dir
+			marker := "- "
+			if r.Group.Folded {
+				marker = "+ "
+			}
+			ln = fmt.Sprintf("%s%s (%d)", marker, r.Group.Name, len(r.Group.Commits))
+		} else {
+			ln = fmt.Sprintf("    %s %s", shortHash(r.Commit.Hash), r.Commit.Title)
+			if !a.ByAuthor {
+				ln += " (" + r.Commit.Author + ")"
+			}
+		}
+		if i == a.CurIdx {
+			c = theme.Selected
+			fillColor(Rect{Min: Pt{a.Bound.Min.L + l + 1, a.Bound.Min.O}, Size: Pt{1, a.Bound.Size.O}}, c)
+		}
+		drawLine(a.Bound, l+1, []byte(ln), 0, c)
+	}
+}
+
+// Markdown returns release notes of the commits, grouped as the area shows.
+func (a *ReleaseArea) Markdown() []byte {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "# %s\n\nChanges since %s.\n", a.To, a.From)
+	for _, g := range a.Groups {
+		fmt.Fprintf(buf, "\n## %s\n\n", g.Name)
+		for _, c := range g.Commits {
+			if a.ByAuthor {
+				fmt.Fprintf(buf, "- %s (%s)\n", c.Title, shortHash(c.Hash))
+			} else {
+				fmt.Fprintf(buf, "- %s (%s, %s)\n", c.Summary(), shortHash(c.Hash), c.Author)
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+// Export writes the release notes as Markdown to the file.
+// The file is "release-<from>-<to>.md" if not given.
+func (a *ReleaseArea) Export(file string) error {
+	if a.From == "" {
+		return fmt.Errorf("no release notes loaded: use :release")
+	}
+	if file == "" {
+		file = fmt.Sprintf("release-%s-%s.md", a.From, a.To)
+		file = strings.Replace(file, "/", "-", -1)
+	}
+	if err := ioutil.WriteFile(file, a.Markdown(), 0644); err != nil {
+		return err
+	}
+	dig.Message = "release notes written to " + file
+	return nil
+}
+
+// tagNames returns tags of the repository, from the newest version.
+func tagNames() ([]string, error) {
+	out, err := gitOutput("tag", "--sort=-v:refname")
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	for _, t := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags, nil
+}
+
+// openRelease loads release notes between the two refs, and shows them.
+func openRelease(from, to string) error {
+	if err := screen.Release.Load(from, to); err != nil {
+		return err
+	}
+	dig.CurView = ReleaseView
+	return nil
+}
+
+// pickRelease picks the new tag and then an older tag from lists,
+// and shows release notes between them.
+func pickRelease() error {
+	tags, err := tagNames()
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return fmt.Errorf("no tags in the repository")
+	}
+	pickFrom := func(to string) {
+		older := tags
+		for i, t := range tags {
+			if t == to {
+				older = tags[i+1:]
+				break
+			}
+		}
+		if len(older) == 0 {
+			dig.Message = "no tag older than " + to
+			return
+		}
+		openList("release notes since", older, 0, func(i int) {
+			if err := openRelease(older[i], to); err != nil {
+				dig.Message = err.Error()
+			}
+		})
+	}
+	items := append([]string{"HEAD"}, tags...)
+	openList("release notes until", items, 0, func(i int) {
+		pickFrom(items[i])
+	})
+	return nil
+}
+
+// cmdRelease shows commits between two tags grouped for release notes.
+// Missing tags are picked from lists.
+//
+//	release [from] [to]
+//	release export [file]
+func cmdRelease(args []string) error {
+	if len(args) > 0 && args[0] == "export" {
+		if len(args) > 2 {
+			return fmt.Errorf("usage: release export [file]")
+		}
+		file := ""
+		if len(args) == 2 {
+			file = args[1]
+		}
+		return screen.Release.Export(file)
+	}
+	switch len(args) {
+	case 0:
+		return pickRelease()
+	case 1:
+		return openRelease(args[0], "HEAD")
+	case 2:
+		return openRelease(args[0], args[1])
+	}
+	return fmt.Errorf("usage: release [from] [to]")
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// lsRemoteTimeout is how long dig waits for a remote to list it's refs.
+const lsRemoteTimeout = 20 * time.Second
+
+// remoteNames returns names of remotes of the repository.
+func remoteNames() ([]string, error) {
+	out, err := gitOutput("remote")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// trackingRefsContaining returns remote tracking refs those contain the commit.
+// With a remote, only refs of the remote are checked.
+// They're as fresh as the last fetch.
+func trackingRefsContaining(remote, hash string) ([]string, error) {
+	pattern := "refs/remotes/"
+	if remote != "" {
+		pattern += remote + "/"
+	}
+	out, err := gitOutput("for-each-ref", "--contains", hash, "--format=%(refname:short)", pattern)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// lsRemoteContaining asks the remote for it's branches and tags, and returns those contain the commit.
+// A tip that isn't fetched yet can't be checked, they're counted as unknown.
+// It talks to the remote, so it's run in background.
+func lsRemoteContaining(repoDir, remote, hash string) (refs []string, unknown int, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lsRemoteTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", "--heads", "--tags", remote)
+	cmd.Dir = repoDir
+	// dig has the terminal, git should fail rather than asking a password.
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if os.Getenv("GIT_SSH_COMMAND") == "" {
+		cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		if e, ok := err.(*exec.ExitError); ok && len(e.Stderr) != 0 {
+			return nil, 0, fmt.Errorf("%s", strings.SplitN(strings.TrimSpace(string(e.Stderr)), "\n", 2)[0])
+		}
+		return nil, 0, err
+	}
+	for _, ln := range strings.Split(string(out), "\n") {
+		f := strings.Fields(ln)
+		if len(f) != 2 {
+			continue
+		}
+		tip, ref := f[0], f[1]
+		if strings.HasSuffix(ref, "^{}") {
+			// the commit of an annotated tag, the tag itself is skipped below.
+			ref = strings.TrimSuffix(ref, "^{}")
+		} else if strings.HasPrefix(ref, "refs/tags/") && strings.Contains(string(out), ref+"^{}") {
+			continue
+		}
+		ref = strings.TrimPrefix(strings.TrimPrefix(ref, "refs/heads/"), "refs/tags/")
+		if tip == hash {
+			refs = append(refs, ref)
+			continue
+		}
+		ok, err := isAncestorIn(repoDir, hash, tip)
+		if err != nil {
+			unknown++
+			continue
+		}
+		if ok {
+			refs = append(refs, ref)
+		}
+	}
+	return refs, unknown, nil
+}
+
+// refsSummary returns the first ref, and how many others there are.
+func refsSummary(refs []string) string {
+	if len(refs) == 1 {
+		return refs[0]
+	}
+	return fmt.Sprintf("%s and %d more", refs[0], len(refs)-1)
+}
+
+// checkRemote shows whether the selected commit is on the remote, so it's safe to share it's hash.
+// Remote tracking refs are checked first. When they don't have it,
+// the remote is asked in background, as they could be stale.
+func checkRemote(remote string) error {
+	hash := screen.Commit.Commit().Hash
+	refs, err := trackingRefsContaining(remote, hash)
+	if err != nil {
+		return err
+	}
+	short := shortHash(hash)
+	if len(refs) != 0 {
+		dig.Message = fmt.Sprintf("%s is on %s, in %s", short, remote, refsSummary(refs))
+		return nil
+	}
+	dig.Message = fmt.Sprintf("asking %s for %s...", remote, short)
+	repoDir := dig.RepoDir
+	go func() {
+		refs, unknown, err := lsRemoteContaining(repoDir, remote, hash)
+		send(remoteMsg{Remote: remote, Short: short, Refs: refs, Unknown: unknown, Err: err})
+	}()
+	return nil
+}
+
+// remoteMsg is an answer of a remote, whether it has a commit.
+type remoteMsg struct {
+	Remote  string
+	Short   string
+	Refs    []string
+	Unknown int
+	Err     error
+}
+
+// remoteChecked shows the remote's answer.
+func remoteChecked(msg remoteMsg) {
+	switch {
+	case msg.Err != nil:
+		dig.Message = fmt.Sprintf("could not ask %s: %v", msg.Remote, msg.Err)
+	case len(msg.Refs) != 0:
+		dig.Message = fmt.Sprintf("%s is on %s, in %s. fetch to update tracking refs", msg.Short, msg.Remote, refsSummary(msg.Refs))
+	case msg.Unknown != 0:
+		dig.Message = fmt.Sprintf("%s is NOT found on %s, %s not fetched could have it", msg.Short, msg.Remote, pluralize(msg.Unknown, "ref"))
+	default:
+		dig.Message = fmt.Sprintf("%s is NOT on %s, it's local only. don't share the hash yet", msg.Short, msg.Remote)
+	}
+}
+
+// pickRemote checks the selected commit against the only remote,
+// or opens a list of remotes to pick one.
+func pickRemote() error {
+	remotes, err := remoteNames()
+	if err != nil {
+		return err
+	}
+	if len(remotes) == 0 {
+		return fmt.Errorf("no remote, every commit is local only")
+	}
+	if len(remotes) == 1 {
+		return checkRemote(remotes[0])
+	}
+	openList("is it on the remote?", remotes, 0, func(i int) {
+		if err := checkRemote(remotes[i]); err != nil {
+			dig.Message = err.Error()
+		}
+	})
+	return nil
+}
+
+// localOnly returns the commits those no remote tracking ref contains.
+func localOnly(hashes []string) []string {
+	var local []string
+	for _, h := range hashes {
+		refs, err := trackingRefsContaining("", h)
+		if err == nil && len(refs) == 0 {
+			local = append(local, h)
+		}
+	}
+	return local
+}
+
+// cmdRemote checks whether the selected commit is on the remote.
+// Without a remote, it uses the only remote or opens a list of remotes.
+//
+//	remote [name]
+func cmdRemote(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: remote [name]")
+	}
+	if len(args) == 0 {
+		return pickRemote()
+	}
+	return checkRemote(args[0])
+}
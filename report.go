@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FileStat is an aggregated change statistics of a file.
+type FileStat struct {
+	Path    string
+	Commits int
+	Added   int
+	Deleted int
+}
+
+// Churn is total changed lines of the file.
+func (f *FileStat) Churn() int {
+	return f.Added + f.Deleted
+}
+
+// ReportArea is an Area for showing the hot files report.
+type ReportArea struct {
+	Bound  Rect
+	Stats  []*FileStat
+	ByLine bool // sort by changed lines instead of number of commits.
+	CurIdx int
+	TopIdx int
+}
+
+// cmdReport aggregates changes of files over the loaded range,
+// and shows the most changed files.
+func cmdReport(args []string) error {
+	stats, err := hotFiles()
+	if err != nil {
+		return err
+	}
+	screen.Report.Stats = stats
+	screen.Report.CurIdx = 0
+	screen.Report.TopIdx = 0
+	screen.Report.sort()
+	dig.CurView = ReportView
+	return nil
+}
+
+func (a *ReportArea) sort() {
+	sort.SliceStable(a.Stats, func(i, j int) bool {
+		si, sj := a.Stats[i], a.Stats[j]
+		if a.ByLine && si.Churn() != sj.Churn() {
+			return si.Churn() > sj.Churn()
+		}
+		if si.Commits != sj.Commits {
+			return si.Commits > sj.Commits
+		}
+		return si.Churn() > sj.Churn()
+	})
+}
+
+// Handle handles a terminal event.
+func (a *ReportArea) Handle(ev Event) bool {
+	// first line is used for the header.
+	page := a.Bound.Size.L - 1
+	if ev.Key == KeyArrowUp || ev.Ch == 'i' {
+		a.CurIdx -= count()
+	} else if ev.Key == KeyArrowDown || ev.Ch == 'k' {
+		a.CurIdx += count()
+	} else if ev.Key == KeyPgup || ev.Ch == 'b' {
+		a.CurIdx -= page * count()
+	} else if ev.Key == KeyPgdn || ev.Ch == 'f' {
+		a.CurIdx += page * count()
+	} else if ev.Ch == 'u' {
+		a.CurIdx -= page / 2 * count()
+	} else if ev.Ch == 'd' {
+		a.CurIdx += page / 2 * count()
+	} else if ev.Key == KeyHome {
+		a.CurIdx = 0
+	} else if ev.Key == KeyEnd {
+		a.CurIdx = len(a.Stats) - 1
+	} else if ev.Ch == 's' {
+		a.ByLine = !a.ByLine
+		a.sort()
+	} else if ev.Key == KeyEnter {
+		if len(a.Stats) == 0 {
+			return true
+		}
+		if err := cmdHistory([]string{a.Stats[a.CurIdx].Path}); err != nil {
+			dig.Message = err.Error()
+		}
+	} else {
+		return false
+	}
+	if a.CurIdx >= len(a.Stats) {
+		a.CurIdx = len(a.Stats) - 1
+	}
+	if a.CurIdx < 0 {
+		a.CurIdx = 0
+	}
+	return true
+}
+
+// Draw draws it's contents.
+func (a *ReportArea) Draw() {
+	sortBy := "commits"
+	if a.ByLine {
+		sortBy = "changed lines"
+	}
+	header := fmt.Sprintf("%7s %8s %8s  %s (%d files, sorted by %s)", "commits", "added", "deleted", "path", len(a.Stats), sortBy)
+	drawLine(a.Bound, 0, []byte(header), 0, theme.Header)
+
+	page := a.Bound.Size.L - 1
+	if a.TopIdx > a.CurIdx {
+		a.TopIdx = a.CurIdx
+	} else if a.TopIdx+page <= a.CurIdx {
+		a.TopIdx = a.CurIdx - page + 1
+	}
+	for l := 0; l < page; l++ {
+		i := a.TopIdx + l
+		if i >= len(a.Stats) {
+			break
+		}
+		f := a.Stats[i]
+		c := theme.Normal
+		if i == a.CurIdx {
+			c = theme.Selected
+			fillColor(Rect{Min: Pt{a.Bound.Min.L + l + 1, a.Bound.Min.O}, Size: Pt{1, a.Bound.Size.O}}, c)
+		}
+		ln := fmt.Sprintf("%7d %8s %8s  %s", f.Commits, "+"+strconv.Itoa(f.Added), "-"+strconv.Itoa(f.Deleted), f.Path)
+		drawLine(a.Bound, l+1, []byte(ln), 0, c)
+	}
+}
+
+// hotFiles aggregates `git log --numstat` over the program's targets.
+func hotFiles() ([]*FileStat, error) {
+	args := []string{"log", "--numstat", "--no-renames", "--format="}
+	args = append(args, dig.Targets...)
+	if dig.History != "" {
+		args = append(args, "--", dig.History)
+	}
+	out, err := gitOutput(args...)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*FileStat)
+	for _, ln := range strings.Split(string(out), "\n") {
+		// <added> TAB <deleted> TAB <path>
+		f := strings.SplitN(ln, "\t", 3)
+		if len(f) != 3 {
+			continue
+		}
+		f[2] = unquotePath(f[2])
+		st := files[f[2]]
+		if st == nil {
+			st = &FileStat{Path: f[2]}
+			files[f[2]] = st
+		}
+		st.Commits++
+		// binary files have "-" for added and deleted.
+		add, _ := strconv.Atoi(f[0])
+		del, _ := strconv.Atoi(f[1])
+		st.Added += add
+		st.Deleted += del
+	}
+	stats := make([]*FileStat, 0, len(files))
+	for _, st := range files {
+		stats = append(stats, st)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Path < stats[j].Path })
+	return stats, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// rpcEvents are events a client of the control socket could subscribe.
+//
+//	select    the selected commit is changed: "event select <hash> <title>"
+//	diff      the user opens a diff: "event diff <revision>"
+//
+// When a client subscribed diff, dig doesn't open the diff itself,
+// so an editor plugin could show it in the editor's buffer.
+var rpcEvents = []string{"select", "diff"}
+
+// rpcCommands are control commands those need the connection, for editor plugins.
+var rpcCommands = map[string]func(c *controlConn, args []string) (string, error){
+	"subscribe":   rpcSubscribe,
+	"unsubscribe": rpcUnsubscribe,
+}
+
+// subscribers are connections those subscribed any event.
+var subscribers []*controlConn
+
+// notifiedHash is the selected commit, the subscribers are notified of.
+var notifiedHash string
+
+// rpcSubscribe subscribes the events, or all of them without an argument.
+// It replies the subscribed events.
+//
+//	subscribe [event...]
+func rpcSubscribe(c *controlConn, args []string) (string, error) {
+	if len(args) == 0 {
+		args = rpcEvents
+	}
+	for _, ev := range args {
+		known := false
+		for _, e := range rpcEvents {
+			if ev == e {
+				known = true
+			}
+		}
+		if !known {
+			return "", fmt.Errorf("unknown event: %s, it's one of %s", ev, strings.Join(rpcEvents, ", "))
+		}
+	}
+	for _, ev := range args {
+		c.Events[ev] = true
+	}
+	unsubscribe(c)
+	subscribers = append(subscribers, c)
+	// let it know the current selection.
+	notifiedHash = ""
+	return strings.Join(subscribed(c), " "), nil
+}
+
+// rpcUnsubscribe unsubscribes the events, or all of them without an argument.
+//
+//	unsubscribe [event...]
+func rpcUnsubscribe(c *controlConn, args []string) (string, error) {
+	if len(args) == 0 {
+		args = rpcEvents
+	}
+	for _, ev := range args {
+		delete(c.Events, ev)
+	}
+	if len(c.Events) == 0 {
+		unsubscribe(c)
+	}
+	return strings.Join(subscribed(c), " "), nil
+}
+
+// subscribed returns events the connection subscribed, in the order of rpcEvents.
+func subscribed(c *controlConn) []string {
+	var evs []string
+	for _, e := range rpcEvents {
+		if c.Events[e] {
+			evs = append(evs, e)
+		}
+	}
+	return evs
+}
+
+// unsubscribe removes the connection from the subscribers.
+func unsubscribe(c *controlConn) {
+	for i, s := range subscribers {
+		if s == c {
+			subscribers = append(subscribers[:i], subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// notify sends the event to the subscribers of it, and reports whether anyone subscribed it.
+func notify(event, data string) bool {
+	sent := false
+	for _, c := range subscribers {
+		if c.Events[event] {
+			c.Notify("event " + event + " " + data)
+			sent = true
+		}
+	}
+	return sent
+}
+
+// notifySelected notifies the subscribers when the selected commit is changed.
+func notifySelected() {
+	if len(subscribers) == 0 || len(dig.Commits) == 0 {
+		return
+	}
+	c := screen.Commit.Commit()
+	if c.Hash == notifiedHash {
+		return
+	}
+	notifiedHash = c.Hash
+	notify("select", c.Hash+" "+c.Title)
+}
+
+// notifyDiff sends the diff to open to the subscribers,
+// and reports whether one of them will open it instead of dig.
+func notifyDiff(rev string) bool {
+	return notify("diff", rev)
+}
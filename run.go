@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// RunPopup shows output of a command running in a snapshot of a commit.
+// The output is streamed, and it follows the end until scrolled up.
+type RunPopup struct {
+	// Name is the command as the user typed, for the title.
+	Name   string
+	Hash   string
+	Lines  []string
+	TopIdx int
+	// Follow keeps the last lines visible, as the output grows.
+	Follow bool
+	// Done is true when the command exited, then Err is it's result.
+	Done bool
+	Err  error
+	// Killed is true when the command is killed by closing the popup.
+	Killed bool
+	// OnDone is called when the command exited, if it's set.
+	OnDone func()
+
+	cmd *exec.Cmd
+}
+
+// runInSnapshot runs the shell command in a snapshot of the selected commit,
+// and shows it's output in a popup.
+func runInSnapshot(command string) error {
+	if command == "" {
+		return fmt.Errorf("no command to run: set run config, or give it to :run")
+	}
+	c := screen.Commit.Commit()
+	dir, err := snapshot(c)
+	if err != nil {
+		return err
+	}
+	_, err = startRun(dir, command, command, c.Hash)
+	return err
+}
+
+// startRun runs the shell command line in the directory, and opens a popup for it's output.
+func startRun(dir, name, line, hash string) (*RunPopup, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd := shellCommand(line)
+	cmd.Dir = dir
+	cmd.Stdout = w
+	cmd.Stderr = w
+	// the shell's children are killed with it, see Handle.
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		r.Close()
+		w.Close()
+		return nil, err
+	}
+	// the command has it's own copy.
+	w.Close()
+	p := &RunPopup{Name: name, Hash: hash, Follow: true, cmd: cmd}
+	screen.Popup = p
+	go func() {
+		sc := bufio.NewScanner(r)
+		sc.Buffer(nil, 1024*1024)
+		for sc.Scan() {
+			send(runLineMsg{Popup: p, Line: sc.Text()})
+		}
+		r.Close()
+		send(runDoneMsg{Popup: p, Err: cmd.Wait()})
+	}()
+	return p, nil
+}
+
+// runLineMsg is a line of output of a running command.
+type runLineMsg struct {
+	Popup *RunPopup
+	Line  string
+}
+
+// runDoneMsg is a command that exited.
+type runDoneMsg struct {
+	Popup *RunPopup
+	Err   error
+}
+
+// Finish marks the command done, with the error it exited with.
+func (p *RunPopup) Finish(err error) {
+	p.Done = true
+	p.Err = err
+	if p.OnDone != nil {
+		p.OnDone()
+	}
+}
+
+// cmdRun runs a command in a snapshot of the selected commit.
+// Without an argument, it runs the run config.
+//
+//	run [command]
+func cmdRun(args []string) error {
+	command := config.Run
+	if len(args) != 0 {
+		command = strings.Join(args, " ")
+	}
+	return runInSnapshot(command)
+}
+
+// Handle handles a terminal event, while the popup is opened.
+// Closing the popup kills the command if it's still running.
+func (p *RunPopup) Handle(ev Event) {
+	page := popupBound(p.size()).Size.L
+	if ev.Key == KeyArrowUp || ev.Ch == 'i' {
+		p.scroll(-count())
+	} else if ev.Key == KeyArrowDown || ev.Ch == 'k' {
+		p.scroll(count())
+	} else if ev.Key == KeyPgup || ev.Ch == 'b' {
+		p.scroll(-page * count())
+	} else if ev.Key == KeyPgdn || ev.Ch == 'f' {
+		p.scroll(page * count())
+	} else if ev.Key == KeyHome {
+		p.scroll(-len(p.Lines))
+	} else if ev.Key == KeyEnd || ev.Ch == 'G' {
+		p.Follow = true
+	} else if ev.Key == KeyEsc || ev.Ch == 'q' {
+		if !p.Done {
+			killProcessGroup(p.cmd)
+			p.Killed = true
+		}
+		screen.Popup = nil
+	}
+}
+
+// scroll scrolls the output by n lines.
+// Scrolling to the end follows the output again.
+func (p *RunPopup) scroll(n int) {
+	page := popupBound(p.size()).Size.L
+	last := len(p.Lines) - page
+	if last < 0 {
+		last = 0
+	}
+	if p.Follow {
+		p.TopIdx = last
+	}
+	p.TopIdx += n
+	if p.TopIdx < 0 {
+		p.TopIdx = 0
+	}
+	p.Follow = p.TopIdx >= last
+	if p.Follow {
+		p.TopIdx = last
+	}
+}
+
+// size returns wanted size of the popup.
+func (p *RunPopup) size() Pt {
+	return Pt{screen.size.L, screen.size.O}
+}
+
+// Draw draws the popup.
+func (p *RunPopup) Draw() {
+	status := "running, q: kill"
+	if p.Done {
+		status = "exit 0"
+		if p.Err != nil {
+			status = p.Err.Error()
+		}
+	}
+	title := fmt.Sprintf("%s @ %s (%s)", p.Name, shortHash(p.Hash), status)
+	bound := drawPopup(popupBound(p.size()), title)
+	if p.Follow {
+		p.TopIdx = len(p.Lines) - bound.Size.L
+		if p.TopIdx < 0 {
+			p.TopIdx = 0
+		}
+	}
+	for l := 0; l < bound.Size.L; l++ {
+		i := p.TopIdx + l
+		if i >= len(p.Lines) {
+			break
+		}
+		drawLine(bound, l, []byte(p.Lines[i]), 0, theme.Popup)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Search is the last search of the program.
+// It's matches are kept, so they could be walked with n and N.
+type Search struct {
+	Word string
+	// View is where the search was done, CommitView or DiffView.
+	View View
+	// Hash is the commit of the searched diff.
+	Hash string
+	// FileScope limits the diff search to lines of File.
+	FileScope bool
+	// File is the header line of the searched file in the diff.
+	File int
+	// Matches are indexes of matched commits, or lines of the diff.
+	Matches []int
+}
+
+// startSearch searches the word in the current view, and moves to the next match.
+// It searches diff lines in the diff view, and commits in the others.
+func startSearch(word string) {
+	s := &Search{Word: word, View: CommitView}
+	if dig.CurView == DiffView {
+		s.View = DiffView
+		s.FileScope = dig.FileScope
+	}
+	s.match()
+	dig.Search = s
+	if len(s.Matches) == 0 {
+		dig.Message = "not found: " + word
+		return
+	}
+	searchNext(1)
+}
+
+// match finds matches of the search.
+func (s *Search) match() {
+	s.Matches = s.Matches[:0]
+	if s.View == DiffView {
+		d := screen.FocusedDiff()
+		s.Hash = d.CommitHash
+		from, to := 0, len(d.Text)
+		if s.FileScope {
+			from, to = fileSection(d.Text, d.AnchorLine())
+			s.File = from
+		}
+		word := []byte(s.Word)
+		for l := from; l < to; l++ {
+			if bytes.Contains(d.Text[l], word) {
+				s.Matches = append(s.Matches, l)
+			}
+		}
+		return
+	}
+	// a commit could be found by it's (abbreviated) hash.
+	hash := s.Word
+	if isHashLike(hash) {
+		if h, err := resolveHash(hash); err == nil {
+			hash = h
+		}
+	}
+	for i, c := range dig.Commits {
+		if c.Hash == hash || strings.Contains(c.Title, s.Word) {
+			s.Matches = append(s.Matches, i)
+		}
+	}
+}
+
+// active reports whether the search is for the current view.
+// A diff search is not active in diff of other commit.
+func (s *Search) active() bool {
+	if s == nil || s.View != dig.CurView {
+		return false
+	}
+	if s.View == DiffView {
+		return s.Hash == screen.FocusedDiff().CommitHash
+	}
+	return true
+}
+
+// pos returns the current position in the searched view.
+func (s *Search) pos() int {
+	if s.View == DiffView {
+		return screen.FocusedDiff().Win.Line()
+	}
+	return screen.Commit.CurIdx
+}
+
+// jump moves to the i-th match.
+func (s *Search) jump(i int) {
+	m := s.Matches[i]
+	if s.View == DiffView {
+		screen.FocusedDiff().Win.Goto(m)
+		return
+	}
+	screen.Commit.SetCursor(m)
+}
+
+// searchNext moves to the n-th next match, or n-th previous match when n is negative.
+// It wraps around at the ends.
+func searchNext(n int) {
+	s := dig.Search
+	if !s.active() {
+		if s != nil && s.View == DiffView && dig.CurView == DiffView {
+			// the diff is changed, search the new one.
+			s.match()
+		} else {
+			dig.Message = "no search here, search with ctrl+f"
+			return
+		}
+	}
+	if len(s.Matches) == 0 {
+		dig.Message = "not found: " + s.Word
+		return
+	}
+	pos := s.pos()
+	// i is the first match after the position.
+	i := 0
+	for i < len(s.Matches) && s.Matches[i] <= pos {
+		i++
+	}
+	if n > 0 {
+		i += n - 1
+	} else {
+		// the match at the position is not a previous one.
+		if i > 0 && s.Matches[i-1] == pos {
+			i--
+		}
+		i += n
+	}
+	k := len(s.Matches)
+	s.jump(((i % k) + k) % k)
+}
+
+// matchStatus returns like "match 3/17" for the status area,
+// or an empty string when there isn't an active search.
+func matchStatus() string {
+	s := dig.Search
+	if !s.active() || len(s.Matches) == 0 {
+		return ""
+	}
+	pos := s.pos()
+	for i, m := range s.Matches {
+		if m == pos {
+			return fmt.Sprintf("match %d/%d", i+1, len(s.Matches))
+		}
+	}
+	return fmt.Sprintf("%d matches", len(s.Matches))
+}
+
+// listMatches opens a popup that lists matches of the search.
+func listMatches() {
+	s := dig.Search
+	if !s.active() || len(s.Matches) == 0 {
+		dig.Message = "no matches here, search with ctrl+f"
+		return
+	}
+	items := make([]string, len(s.Matches))
+	cur := 0
+	pos := s.pos()
+	for i, m := range s.Matches {
+		if s.View == DiffView {
+			items[i] = fmt.Sprintf("%5d %s", m+1, screen.FocusedDiff().Text[m])
+		} else {
+			c := dig.Commits[m]
+			items[i] = shortHash(c.Hash) + " " + c.Title
+		}
+		if m <= pos {
+			cur = i
+		}
+	}
+	title := fmt.Sprintf("%d matches of %q", len(s.Matches), s.Word)
+	if s.FileScope {
+		if h := screen.FocusedDiff().Text[s.File]; isFileHeader(h) {
+			title += " in " + fileName(h)
+		}
+	}
+	openList(title, items, cur, s.jump)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	r := historyFixture(t)
+	setupApp(t, r.Dir, r.Commits(false, "HEAD"))
+	startSearch("readme")
+	if got := len(dig.Search.Matches); got != 2 {
+		t.Fatalf("matches: got %d, want 2", got)
+	}
+	if got := screen.Commit.Commit().Title; got != "fix readme" {
+		t.Errorf("first match: got %q", got)
+	}
+	searchNext(1)
+	if got := screen.Commit.Commit().Title; got != "add readme" {
+		t.Errorf("next match: got %q", got)
+	}
+	// it wraps around.
+	searchNext(1)
+	if got := screen.Commit.Commit().Title; got != "fix readme" {
+		t.Errorf("wrapped match: got %q", got)
+	}
+	// a commit is found by it's hash.
+	startSearch(r.Hash("v0.1")[:7])
+	if got := screen.Commit.Commit().Title; got != "add readme" {
+		t.Errorf("match of a hash: got %q", got)
+	}
+	startSearch("nothing like this")
+	if !strings.HasPrefix(dig.Message, "not found") {
+		t.Errorf("message of no match: got %q", dig.Message)
+	}
+}
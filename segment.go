@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// segment is a part of the status bar, drawn at the right side of it.
+type segment struct {
+	Name string
+	// Color is foreground color of it, 0 is the status bar's.
+	Color Attribute
+}
+
+// segments are values of segments by their names.
+//
+//	mode      the current mode, like NORMAL or FIND
+//	position  position of the cursor in the commit list, like 12/340
+//	branch    the branch the commit list shows, or the checked out one
+//	filter    filters of the commit list
+//	clock     the current time
+//	command   the first line of status_command's output
+var segments = map[string]func() string{
+	"mode":     segMode,
+	"position": segPosition,
+	"branch":   segBranch,
+	"filter":   segFilter,
+	"clock":    segClock,
+	"command":  segCommand,
+}
+
+// parseSegments parses segments separated by commas, with optional colors, like "mode, branch:green, clock:208".
+// A color is a name of basic colors, or a number of 256 colors.
+func parseSegments(s string) ([]segment, error) {
+	var segs []segment
+	for _, f := range parseList(s) {
+		name, color := f, ""
+		if i := strings.Index(f, ":"); i != -1 {
+			name, color = strings.TrimSpace(f[:i]), strings.TrimSpace(f[i+1:])
+		}
+		if _, ok := segments[name]; !ok {
+			return nil, fmt.Errorf("unknown segment: %s", name)
+		}
+		seg := segment{Name: name}
+		if color != "" {
+			c, err := parseColors(color)
+			if err != nil || len(c) != 1 {
+				return nil, fmt.Errorf("invalid color of %s: %s", name, color)
+			}
+			seg.Color = c[0]
+		}
+		segs = append(segs, seg)
+	}
+	return segs, nil
+}
+
+// modeNames are names of modes shown by the mode segment.
+var modeNames = map[Mode]string{
+	NormalMode:   "NORMAL",
+	FindMode:     "FIND",
+	CommandMode:  "COMMAND",
+	NoteMode:     "NOTE",
+	TimelineMode: "TIMELINE",
+	QueryMode:    "FILTER",
+	CopyMode:     "COPY",
+}
+
+func segMode() string {
+	return modeNames[dig.Mode]
+}
+
+func segPosition() string {
+	if len(dig.Commits) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", screen.Commit.CurIdx+1, len(dig.Commits))
+}
+
+func segBranch() string {
+	if b := shownBranch(); b != "" {
+		return b
+	}
+	return segmentValue("branch")
+}
+
+func segFilter() string {
+	if len(dig.Filters) == 0 {
+		return ""
+	}
+	return dig.FilterString()
+}
+
+func segClock() string {
+	return time.Now().Format("15:04")
+}
+
+func segCommand() string {
+	return segmentValue("command")
+}
+
+// segmentValues are values of segments those are loaded in background, for segmentDir.
+var segmentValues map[string]string
+
+// segmentDir is the repository segmentValues are loaded for.
+var segmentDir string
+
+// segmentLoading reports whether segment values are being loaded.
+var segmentLoading bool
+
+// segmentValue returns the loaded value of the segment for the current repository.
+// It's empty until loaded.
+func segmentValue(name string) string {
+	if segmentDir != dig.RepoDir {
+		return ""
+	}
+	return segmentValues[name]
+}
+
+// segmentMsg is values of segments those are loaded in background.
+type segmentMsg struct {
+	RepoDir string
+	Values  map[string]string
+}
+
+// segmentTickMsg is a tick to reload values of segments.
+type segmentTickMsg struct{}
+
+// startSegments loads values of segments, and reloads them each config.StatusInterval.
+// The status bar is redrawn with them, so the clock is also updated.
+// The title bar also shows the checked out branch.
+func startSegments() {
+	if len(config.StatusSegments) == 0 && !config.TitleBar {
+		return
+	}
+	loadSegments()
+	ticker := time.NewTicker(config.StatusInterval)
+	go func() {
+		for range ticker.C {
+			send(segmentTickMsg{})
+		}
+	}()
+}
+
+// loadSegments loads values of the branch and command segments in background.
+// It doesn't start again while they're being loaded, as a slow command shouldn't pile up.
+func loadSegments() {
+	if segmentLoading {
+		return
+	}
+	segmentLoading = true
+	repoDir := dig.RepoDir
+	command := config.StatusCommand
+	go func() {
+		values := make(map[string]string)
+		values["branch"] = checkedOutBranch(repoDir)
+		if command != "" {
+			cmd := shellCommand(command)
+			cmd.Dir = repoDir
+			out, err := cmd.Output()
+			if err != nil {
+				values["command"] = "?"
+			} else {
+				values["command"] = firstLine(out)
+			}
+		}
+		send(segmentMsg{RepoDir: repoDir, Values: values})
+	}()
+}
+
+// segmentsLoaded keeps the loaded values.
+func segmentsLoaded(msg segmentMsg) {
+	segmentLoading = false
+	segmentDir = msg.RepoDir
+	segmentValues = msg.Values
+}
+
+// checkedOutBranch returns the checked out branch of the repository,
+// or the short hash of HEAD when it's detached.
+func checkedOutBranch(repoDir string) string {
+	cmd := gitCommand(repoDir, "symbolic-ref", "--short", "-q", "HEAD")
+	if out, err := cmd.Output(); err == nil {
+		return firstLine(out)
+	}
+	cmd = gitCommand(repoDir, "rev-parse", "--short", "HEAD")
+	if out, err := cmd.Output(); err == nil {
+		return "(" + firstLine(out) + ")"
+	}
+	return ""
+}
+
+// firstLine returns the first line of the output, without spaces around it.
+func firstLine(out []byte) string {
+	if i := bytes.IndexByte(out, '\n'); i != -1 {
+		out = out[:i]
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// drawSegments draws the segments at the right side of the line, and returns where they start.
+// Empty ones are skipped.
+func drawSegments(l, right int, fg Attribute) int {
+	var segs []segment
+	var values []string
+	width := 0
+	for _, seg := range config.StatusSegments {
+		v := segments[seg.Name]()
+		if v == "" {
+			continue
+		}
+		segs = append(segs, seg)
+		values = append(values, v)
+		width += runewidth.StringWidth(v) + 2
+	}
+	o := right - width
+	if o < 0 {
+		// too narrow, the text is more important.
+		return right
+	}
+	start := o
+	for i, seg := range segs {
+		c := fg
+		if seg.Color != 0 {
+			c = seg.Color | fg&AttrBold
+		}
+		o++
+		for _, r := range values[i] {
+			r = printable(r)
+			setCell(o, l, r, c, theme.Status.Bg)
+			o += runewidth.RuneWidth(r)
+		}
+		o++
+	}
+	return start
+}
@@ -0,0 +1,51 @@
+package main
+
+// restackSelections keeps Selections for n filters, and returns the commit to go back to, if any.
+// Selections[i] is the commit the cursor was on before the i-th filter is added, cur when they're added now.
+// When filters are removed, the cursor goes back to the commit before the first of removed ones.
+func (p *Program) restackSelections(n int, cur string) string {
+	back := ""
+	if n < len(p.Selections) {
+		back = p.Selections[n]
+		p.Selections = p.Selections[:n]
+	}
+	for len(p.Selections) < n {
+		p.Selections = append(p.Selections, cur)
+	}
+	return back
+}
+
+// nearestCommit returns index of the commit in commits, or of the nearest one when it's not in them.
+// Nearness is by positions in all commits, commits should be in the order of them like filtered ones.
+// It returns -1 when the commit isn't in all commits either.
+func nearestCommit(commits, all []*Commit, hash string) int {
+	if i := findByHash(commits, hash, 0); i != -1 {
+		return i
+	}
+	at := findByHash(all, hash, 0)
+	if at == -1 {
+		return -1
+	}
+	nearest, dist := -1, 0
+	j := 0
+	for i, c := range commits {
+		for j < len(all) && all[j].Hash != c.Hash {
+			j++
+		}
+		if j == len(all) {
+			break
+		}
+		d := j - at
+		if d < 0 {
+			d = -d
+		}
+		if nearest == -1 || d < dist {
+			nearest, dist = i, d
+		}
+		if j > at {
+			// the others are farther.
+			break
+		}
+	}
+	return nearest
+}
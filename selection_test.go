@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestFilterSelection(t *testing.T) {
+	r := historyFixture(t)
+	setupApp(t, r.Dir, r.Commits(false, "HEAD"))
+	// current returns title of the commit at the cursor.
+	current := func() string {
+		return screen.Commit.Commit().Title
+	}
+	screen.Commit.SetCursor(1)
+	if err := cmdFilter([]string{"author:bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := current(); got != "say hi" {
+		t.Errorf("after the filter: got %q, want the nearest match", got)
+	}
+	if err := cmdFilter(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := current(); got != "fix readme" {
+		t.Errorf("after clearing the filter: got %q, want the commit before it", got)
+	}
+	// a filter is removed at a time, back to the commit before each.
+	screen.Commit.SetCursor(4)
+	if err := toggleFilter("!author:bob"); err != nil {
+		t.Fatal(err)
+	}
+	screen.Commit.SetCursor(0)
+	if err := toggleFilter("glob:README"); err != nil {
+		t.Fatal(err)
+	}
+	if got := current(); got != "fix readme" {
+		t.Errorf("after the second filter: got %q", got)
+	}
+	if err := toggleFilter("glob:README"); err != nil {
+		t.Fatal(err)
+	}
+	if got := current(); got != "merge feature" {
+		t.Errorf("after removing the second filter: got %q", got)
+	}
+	if err := toggleFilter("!author:bob"); err != nil {
+		t.Fatal(err)
+	}
+	if got := current(); got != "initial" {
+		t.Errorf("after removing the first filter: got %q", got)
+	}
+}
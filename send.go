@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// sendText sends the text to another pane, so dig could drive an editor or a shell beside it.
+// config.SendCommand gets the text as {text} and from stdin.
+// Otherwise it's typed into config.SendPane of tmux, or the last pane when dig is in tmux.
+// Enter isn't pressed, the user decides what to do with the text.
+func sendText(text string) error {
+	var cmd *exec.Cmd
+	to := ""
+	if config.SendCommand != "" {
+		line := strings.Replace(config.SendCommand, "{text}", shellQuote(text), -1)
+		line = expandTemplate(line, screen.Commit.Commit())
+		cmd = shellCommand(line)
+		cmd.Stdin = strings.NewReader(text)
+	} else {
+		pane := config.SendPane
+		if pane == "" {
+			if os.Getenv("TMUX") == "" {
+				return fmt.Errorf("nowhere to send: set send_pane or send_command config")
+			}
+			pane = "{last}"
+		}
+		// -l types the text literally, instead of looking up key names.
+		cmd = exec.Command("tmux", "send-keys", "-t", pane, "-l", text)
+		to = " to " + pane
+	}
+	cmd.Dir = dig.RepoDir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s", strings.SplitN(msg, "\n", 2)[0])
+		}
+		return err
+	}
+	dig.Message = "sent" + to
+	return nil
+}
+
+// hunkText returns the hunk at the line of the diff as a text, with it's header.
+func hunkText(text [][]byte, l int) (string, error) {
+	h := fileHeaderAt(text, l)
+	if h == -1 {
+		return "", fmt.Errorf("not in a file")
+	}
+	from := -1
+	for i := l; i > h; i-- {
+		if bytes.HasPrefix(text[i], []byte("@@ ")) {
+			from = i
+			break
+		}
+	}
+	if from == -1 {
+		return "", fmt.Errorf("not in a hunk")
+	}
+	to := from + 1
+	for to < len(text) && !bytes.HasPrefix(text[to], []byte("@@ ")) && !isFileHeader(text[to]) {
+		to++
+	}
+	return string(bytes.Join(text[from:to], []byte("\n"))) + "\n", nil
+}
+
+// sendables returns names and texts those could be sent from the current view.
+func sendables() (names, texts []string) {
+	c := screen.Commit.Commit()
+	names = append(names, "hash "+shortHash(c.Hash))
+	texts = append(texts, c.Hash)
+	switch dig.CurView {
+	case DiffView:
+		d := screen.FocusedDiff()
+		l := d.AnchorLine()
+		if h := fileHeaderAt(d.Text, l); h != -1 {
+			f := fileName(d.Text[h])
+			names = append(names, "file "+f)
+			texts = append(texts, f)
+		}
+		if hunk, err := hunkText(d.Text, l); err == nil {
+			names = append(names, "hunk at the cursor")
+			texts = append(texts, hunk)
+		}
+	case FileView:
+		names = append(names, "file "+screen.File.Path)
+		texts = append(texts, screen.File.Path)
+	}
+	return names, texts
+}
+
+// openSend opens a popup to choose what to send to another pane.
+func openSend() {
+	names, texts := sendables()
+	openList("send", names, 0, func(i int) {
+		if err := sendText(texts[i]); err != nil {
+			dig.Message = err.Error()
+		}
+	})
+}
+
+// cmdSend sends the selected hash, the file or the hunk at the diff cursor to another pane.
+// Without an argument, it opens a popup to choose one.
+//
+//	send [hash|file|hunk]
+func cmdSend(args []string) error {
+	if len(args) == 0 {
+		openSend()
+		return nil
+	}
+	if len(args) > 1 {
+		return fmt.Errorf("usage: send [hash|file|hunk]")
+	}
+	names, texts := sendables()
+	for i, n := range names {
+		if strings.HasPrefix(n, args[0]) {
+			return sendText(texts[i])
+		}
+	}
+	return fmt.Errorf("nothing to send as %s here", args[0])
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// autosaveInterval is how often the session is saved while dig is running,
+// so a dropped connection doesn't lose where the user was.
+const autosaveInterval = 30 * time.Second
+
+// Position is a scroll position of a repository's screen.
+type Position struct {
+	// Row is the cursor's row in the commit area.
+	Row int
+	// Diff is the top line of the current commit's diff.
+	Diff int
+}
+
+// Position returns current scroll position of the screen,
+// which's cursor is on the commit.
+func (s *Screen) Position(hash string) Position {
+	p := Position{Row: s.Commit.CurIdx - s.Commit.TopIdx}
+	if s.Diff.CommitHash == hash {
+		p.Diff = s.Diff.Win.Bound.Min.L
+	} else {
+		p.Diff = s.Diff.WindowPoses[hash].L
+	}
+	return p
+}
+
+// SetPosition scrolls the screen to the position.
+// The cursor should be on the commit already.
+func (s *Screen) SetPosition(p Position, hash string) {
+	s.Commit.TopIdx = s.Commit.CurIdx - p.Row
+	if s.Commit.TopIdx < 0 {
+		s.Commit.TopIdx = 0
+	}
+	s.Diff.WindowPoses[hash] = Pt{p.Diff, 0}
+}
+
+// savedSession is what saved by the last saveSession.
+var savedSession string
+
+// saveSession saves states of all tabs, to restore them on next run.
+// It does nothing when nothing is changed from the last save.
+func saveSession() error {
+	state := fmt.Sprint(screen.SideWidth)
+	for _, t := range tabs {
+		hash := t.Commit().Hash
+		state += fmt.Sprint(t.Program.RepoDir, hash, t.Screen.Layout(), t.Screen.Position(hash))
+	}
+	if state == savedSession {
+		return nil
+	}
+	for _, t := range tabs {
+		if err := saveTab(t); err != nil {
+			return err
+		}
+	}
+	// it's also the default for repositories not opened yet.
+	if config.SideWidth < 0 {
+		if err := saveSideWidth(screen.SideWidth); err != nil {
+			return err
+		}
+	}
+	// a failed save is tried again at the next time.
+	savedSession = state
+	return nil
+}
+
+// saveTab saves the last commit, the layout and the position of the tab's repository.
+func saveTab(t *Tab) error {
+	hash := t.Commit().Hash
+	if err := saveLastCommit(t.Program.RepoDir, hash); err != nil {
+		return err
+	}
+	if config.SideWidth < 0 {
+		// a layout fixed by the profile isn't the repository's.
+		if err := saveLayout(t.Program.RepoDir, t.Screen.Layout()); err != nil {
+			return err
+		}
+	}
+	return savePosition(t.Program.RepoDir, t.Screen.Position(hash))
+}
+
+// autosave saves the session periodically, and when dig is terminated
+// by a signal, like SIGHUP of a dropped ssh connection.
+func autosave() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGHUP)
+	ticker := time.NewTicker(autosaveInterval)
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				send(autosaveMsg{})
+			case <-sigs:
+				send(signalMsg{})
+			}
+		}
+	}()
+}
+
+// autosaveMsg is a tick to save the session.
+type autosaveMsg struct{}
+
+// signalMsg is a signal that terminates dig. The session is saved before exit.
+type signalMsg struct{}
+
+// positionFile returns the file that positions of all repositories are saved.
+func positionFile() (string, error) {
+	return configFile("position")
+}
+
+// readPosition reads scroll position of the repository.
+//
+// Each line of the position file is
+//
+//	"<repo>" row=<row> diff=<line>
+func readPosition(repoDir string) (Position, error) {
+	conf, err := positionFile()
+	if err != nil {
+		return Position{}, err
+	}
+	content, err := ioutil.ReadFile(conf)
+	if err != nil && !os.IsNotExist(err) {
+		return Position{}, err
+	}
+	err = checkCorrupted(conf, content, validPositionLine)
+	for _, ln := range strings.Split(string(content), "\n") {
+		repo, p, ok := parsePositionLine(ln)
+		if ok && repo == repoDir {
+			return p, err
+		}
+	}
+	return Position{}, err
+}
+
+// savePosition saves scroll position of the repository.
+func savePosition(repoDir string, p Position) error {
+	conf, err := positionFile()
+	if err != nil {
+		return err
+	}
+	line := fmt.Sprintf("%q row=%d diff=%d", repoDir, p.Row, p.Diff)
+	return updateRepoLine(conf, repoDir, line, validPositionLine)
+}
+
+// validPositionLine reports whether the line of the position file is valid.
+func validPositionLine(ln string) bool {
+	_, _, ok := parsePositionLine(ln)
+	return ok
+}
+
+// parsePositionLine parses a line of the position file.
+func parsePositionLine(ln string) (repo string, p Position, ok bool) {
+	repo, fields, ok := parseRepoFields(ln)
+	if !ok {
+		return "", p, false
+	}
+	var err error
+	for k, v := range fields {
+		switch k {
+		case "row":
+			p.Row, err = strconv.Atoi(v)
+		case "diff":
+			p.Diff, err = strconv.Atoi(v)
+		}
+		if err != nil {
+			return "", p, false
+		}
+	}
+	return repo, p, true
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSession(t *testing.T) {
+	t.Setenv("DIG_CONFIG_DIR", t.TempDir())
+	r := historyFixture(t)
+	setupApp(t, r.Dir, r.Commits(false, "HEAD"))
+	tabs = []*Tab{{Program: dig, Screen: screen}}
+	defer func() { tabs = nil }()
+	savedSession = ""
+	screen.Commit.SetCursor(3)
+	hash := screen.Commit.Commit().Hash
+	screen.Diff.WindowPoses[hash] = Pt{7, 0}
+	if err := saveSession(); err != nil {
+		t.Fatal(err)
+	}
+
+	last, err := readLastCommit(r.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != hash {
+		t.Errorf("last commit: got %s, want %s", last, hash)
+	}
+	pos, err := readPosition(r.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Position{Row: 3, Diff: 7}); pos != want {
+		t.Errorf("position: got %+v, want %+v", pos, want)
+	}
+	// another repository doesn't get it.
+	if last, _ := readLastCommit(t.TempDir()); last != "" {
+		t.Errorf("last commit of another repository: got %s", last)
+	}
+}
+
+func TestAutosaveError(t *testing.T) {
+	// the config directory is under a file, it cannot be made.
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DIG_CONFIG_DIR", filepath.Join(file, "dig"))
+	r := historyFixture(t)
+	setupApp(t, r.Dir, r.Commits(false, "HEAD"))
+	tabs = []*Tab{{Program: dig, Screen: screen}}
+	defer func() { tabs = nil }()
+	savedSession = ""
+	app.Update(autosaveMsg{})
+	if !strings.HasPrefix(dig.Message, "could not save the session: ") {
+		t.Errorf("message: got %q", dig.Message)
+	}
+	if savedSession != "" {
+		t.Error("failed session is taken as saved")
+	}
+	// it's tried again.
+	t.Setenv("DIG_CONFIG_DIR", t.TempDir())
+	dig.Message = ""
+	app.Update(autosaveMsg{})
+	if dig.Message != "" || savedSession == "" {
+		t.Errorf("saving again: got message %q", dig.Message)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// sharedVersion is version of the shared file format.
+// Increase it when the format changes in a way older dig couldn't read.
+const sharedVersion = 1
+
+// Shared is annotations of an investigation, that are shared with others as a JSON file.
+// Commits are full hashes, so they're same in every clone of the repository.
+type Shared struct {
+	Version int `json:"version"`
+	// Repo is where the annotations are from, only for people to read.
+	Repo  string            `json:"repo,omitempty"`
+	Notes map[string]string `json:"notes"`
+	Pins  []string          `json:"pins"`
+	Marks []string          `json:"marks"`
+}
+
+// exportShared writes notes, pins and marks of the session to the file.
+func exportShared(file string) error {
+	s := Shared{
+		Version: sharedVersion,
+		Repo:    forgeURL(),
+		Notes:   dig.Notes,
+		Pins:    dig.Pins,
+		Marks:   dig.Marks,
+	}
+	if s.Repo == "" {
+		s.Repo = dig.RepoDir
+	}
+	// empty ones are written as empty, not null.
+	if s.Notes == nil {
+		s.Notes = map[string]string{}
+	}
+	if s.Pins == nil {
+		s.Pins = []string{}
+	}
+	if s.Marks == nil {
+		s.Marks = []string{}
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(file, append(data, '\n')); err != nil {
+		return err
+	}
+	dig.Message = fmt.Sprintf("exported %s, %s and %s to %s",
+		pluralize(len(s.Notes), "note"), pluralize(len(s.Pins), "pin"), pluralize(len(s.Marks), "mark"), file)
+	return nil
+}
+
+// importShared merges notes, pins and marks of the file into the session.
+// A note of a commit that already has a different note is appended to it, so neither is lost.
+// Commits not in this repository are skipped.
+func importShared(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var s Shared
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("could not read %s: %v", file, err)
+	}
+	if s.Version > sharedVersion {
+		return fmt.Errorf("%s is from a newer dig, version %d", file, s.Version)
+	}
+	skipped := 0
+	known := func(hash string) bool {
+		if dig.ByHash[hash] == nil {
+			skipped++
+			return false
+		}
+		return true
+	}
+	// notes are saved, sort them to merge in the same order every time.
+	hashes := make([]string, 0, len(s.Notes))
+	for h := range s.Notes {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+	notes := 0
+	for _, h := range hashes {
+		note := strings.TrimSpace(s.Notes[h])
+		if note == "" || !known(h) {
+			continue
+		}
+		old := dig.Notes[h]
+		if strings.Contains(old, note) {
+			continue
+		}
+		if old != "" {
+			note = old + "\n" + note
+		}
+		dig.Notes[h] = note
+		notes++
+	}
+	if notes != 0 {
+		if err := saveNotes(dig.RepoDir, dig.Notes); err != nil {
+			return err
+		}
+	}
+	pins := 0
+	for _, h := range s.Pins {
+		if known(h) && !dig.Pinned(h) {
+			dig.Pins = append(dig.Pins, h)
+			pins++
+		}
+	}
+	marks := 0
+	for _, h := range s.Marks {
+		if known(h) && !dig.Marks.Has(h) {
+			dig.Marks = append(dig.Marks, h)
+			marks++
+		}
+	}
+	dig.Message = fmt.Sprintf("imported %s, %s and %s", pluralize(notes, "note"), pluralize(pins, "pin"), pluralize(marks, "mark"))
+	if skipped != 0 {
+		dig.Message += fmt.Sprintf(", skipped %s not in this repository", pluralize(skipped, "commit"))
+	}
+	return nil
+}
+
+// cmdShare exports or imports notes, pins and marks as JSON,
+// to share an investigation with others working on the same repository.
+//
+//	share export [file]   write them, into dig-shared.json by default.
+//	share import <file>   merge them into this session.
+func cmdShare(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: share export [file] | share import <file>")
+	}
+	switch args[0] {
+	case "export":
+		file := "dig-shared.json"
+		if len(args) > 1 {
+			file = args[1]
+		}
+		return exportShared(file)
+	case "import":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: share import <file>")
+		}
+		return importShared(args[1])
+	}
+	return fmt.Errorf("unknown share command: %s", args[0])
+}
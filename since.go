@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// hunkRange parses a hunk header like "@@ -10,3 +12,4 @@",
+// and returns start lines and line counts of the both sides.
+// A count is 1 when it's omitted.
+func hunkRange(ln []byte) (oldL, oldN, newL, newN int, ok bool) {
+	f := strings.Fields(string(ln))
+	if len(f) < 4 || !strings.HasPrefix(f[1], "-") || !strings.HasPrefix(f[2], "+") {
+		return 0, 0, 0, 0, false
+	}
+	parse := func(s string) (int, int, bool) {
+		p := strings.SplitN(s[1:], ",", 2)
+		l, err := strconv.Atoi(p[0])
+		if err != nil {
+			return 0, 0, false
+		}
+		if len(p) == 1 {
+			return l, 1, true
+		}
+		n, err := strconv.Atoi(p[1])
+		return l, n, err == nil
+	}
+	oldL, oldN, ok1 := parse(f[1])
+	newL, newN, ok2 := parse(f[2])
+	return oldL, oldN, newL, newN, ok1 && ok2
+}
+
+// lineRange is a range of lines in a file, from Lo to Hi inclusive.
+type lineRange struct {
+	Lo, Hi int
+}
+
+// trackRange moves the range through hunks of a change to a file,
+// and reports whether the change touched the lines.
+// Hunks should be made without context lines (-U0).
+// The range is empty (Hi < Lo) when the lines are removed.
+func trackRange(r lineRange, hunks [][]byte) (lineRange, bool) {
+	touched := false
+	lo, hi := r.Lo, r.Hi
+	newLo, newHi := lo, hi
+	for _, h := range hunks {
+		oldL, oldN, newL, newN, ok := hunkRange(h)
+		if !ok {
+			continue
+		}
+		delta := newN - oldN
+		if oldN == 0 {
+			// lines are inserted after oldL.
+			if oldL >= lo && oldL < hi {
+				touched = true
+			}
+			if oldL < lo {
+				newLo += delta
+			}
+			if oldL < hi {
+				newHi += delta
+			}
+			continue
+		}
+		oldEnd := oldL + oldN - 1
+		if oldL <= hi && oldEnd >= lo {
+			touched = true
+		}
+		if oldEnd < lo {
+			newLo += delta
+		} else if oldL <= lo {
+			newLo = newL
+		}
+		if oldEnd <= hi {
+			newHi += delta
+		} else if oldL <= hi {
+			newHi = newL + newN - 1
+		}
+	}
+	return lineRange{newLo, newHi}, touched
+}
+
+// touchedSince returns commits after the commit those changed the lines of the file,
+// following the lines as they move, from old to new.
+// It follows the first parents, comparing a merge to it's first parent.
+func touchedSince(hash, file string, r lineRange) ([]string, error) {
+	out, err := gitOutput("log", "--reverse", "--first-parent", "-m", "-p", "-U0", "--no-color",
+		"--format=%x00%H", hash+"..HEAD", "--", file)
+	if err != nil {
+		return nil, err
+	}
+	var found []string
+	for _, chunk := range bytes.Split(out, []byte("\x00"))[1:] {
+		lines := bytes.Split(chunk, []byte("\n"))
+		c := string(bytes.TrimSpace(lines[0]))
+		var hunks [][]byte
+		for _, ln := range lines[1:] {
+			if bytes.HasPrefix(ln, []byte("@@ ")) {
+				hunks = append(hunks, ln)
+			}
+		}
+		var touched bool
+		r, touched = trackRange(r, hunks)
+		if touched {
+			found = append(found, c)
+		}
+		if r.Hi < r.Lo {
+			// the lines are gone.
+			break
+		}
+	}
+	return found, nil
+}
+
+// touchedSinceAt lists later commits those changed lines of the hunk at the line of the diff.
+func touchedSinceAt(a *DiffArea, l int) error {
+	hash := a.CommitHash
+	if dig.ByHash[hash] == nil {
+		return fmt.Errorf("not a diff of a commit")
+	}
+	h := fileHeaderAt(a.Text, l)
+	if h == -1 {
+		return fmt.Errorf("not in a file")
+	}
+	hunk := -1
+	for i := l; i > h; i-- {
+		if bytes.HasPrefix(a.Text[i], []byte("@@ ")) {
+			hunk = i
+			break
+		}
+	}
+	if hunk == -1 {
+		return fmt.Errorf("not in a hunk")
+	}
+	// context lines aren't the hunk's, only added lines are followed.
+	var d DiffLine
+	r := lineRange{0, -1}
+	for i := hunk + 1; i < len(a.Text) && !bytes.HasPrefix(a.Text[i], []byte("@@ ")) && !isFileHeader(a.Text[i]); i++ {
+		if !bytes.HasPrefix(a.Text[i], []byte("+")) {
+			continue
+		}
+		dl, ok := diffLineAt(a.Text, i)
+		if !ok {
+			continue
+		}
+		d = dl
+		if r.Hi < r.Lo {
+			r.Lo = dl.NewLine
+		}
+		r.Hi = dl.NewLine
+	}
+	if r.Hi < r.Lo {
+		return fmt.Errorf("the hunk only removed lines")
+	}
+	found, err := touchedSince(hash, d.NewPath, r)
+	if err != nil {
+		return err
+	}
+	where := fmt.Sprintf("%s:%d-%d", d.NewPath, r.Lo, r.Hi)
+	if len(found) == 0 {
+		dig.Message = fmt.Sprintf("%s is not changed since %s", where, shortHash(hash))
+		return nil
+	}
+	items := make([]string, len(found))
+	for i, f := range found {
+		title := ""
+		if c := dig.ByHash[f]; c != nil {
+			title = c.Title
+		}
+		items[i] = shortHash(f) + " " + title
+	}
+	openList(fmt.Sprintf("%d changes of %s since %s", len(found), where, shortHash(hash)), items, 0, func(i int) {
+		dig.CurView = CommitView
+		gotoRelative(found[i], "commit")
+	})
+	return nil
+}
+
+// cmdSince lists later commits those changed lines of the hunk at the diff cursor.
+func cmdSince(args []string) error {
+	if dig.CurView != DiffView {
+		return fmt.Errorf("since works on a hunk of a diff")
+	}
+	d := screen.FocusedDiff()
+	return touchedSinceAt(d, d.AnchorLine())
+}
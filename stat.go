@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// languages maps file extensions to their language names.
+var languages = map[string]string{
+	".go":    "Go",
+	".c":     "C",
+	".h":     "C",
+	".cc":    "C++",
+	".cpp":   "C++",
+	".cxx":   "C++",
+	".hpp":   "C++",
+	".rs":    "Rust",
+	".py":    "Python",
+	".rb":    "Ruby",
+	".js":    "JavaScript",
+	".jsx":   "JavaScript",
+	".mjs":   "JavaScript",
+	".ts":    "TypeScript",
+	".tsx":   "TypeScript",
+	".java":  "Java",
+	".kt":    "Kotlin",
+	".swift": "Swift",
+	".cs":    "C#",
+	".php":   "PHP",
+	".sh":    "Shell",
+	".bash":  "Shell",
+	".lua":   "Lua",
+	".html":  "HTML",
+	".css":   "CSS",
+	".scss":  "CSS",
+	".sql":   "SQL",
+	".proto": "Protocol Buffers",
+	".md":    "Markdown",
+	".rst":   "reStructuredText",
+	".txt":   "Text",
+	".json":  "JSON",
+	".yaml":  "YAML",
+	".yml":   "YAML",
+	".toml":  "TOML",
+	".xml":   "XML",
+	".mod":   "Go Module",
+	".sum":   "Go Module",
+}
+
+// language returns language name of the file.
+// Unknown files are grouped by their extension.
+func language(file string) string {
+	ext := strings.ToLower(path.Ext(file))
+	if lang, ok := languages[ext]; ok {
+		return lang
+	}
+	if ext == "" {
+		return "(no extension)"
+	}
+	return ext
+}
+
+// StatGroup is changed files of a language.
+type StatGroup struct {
+	Name    string
+	Files   []*FileStat
+	Added   int
+	Deleted int
+	Folded  bool
+}
+
+// statRow is a row of StatArea, either a group or a file.
+type statRow struct {
+	Group *StatGroup
+	File  *FileStat
+}
+
+// StatArea is an Area for showing changed files of a commit grouped by language.
+type StatArea struct {
+	Bound      Rect
+	CommitHash string
+	Groups     []*StatGroup
+	Rows       []statRow
+	CurIdx     int
+	TopIdx     int
+	Err        error
+}
+
+// Load loads changes of the commit.
+func (a *StatArea) Load(hash string) {
+	if hash == a.CommitHash {
+		return
+	}
+	a.CommitHash = hash
+	a.CurIdx = 0
+	a.TopIdx = 0
+	a.Groups, a.Err = commitStat(hash)
+	a.refresh()
+}
+
+// refresh rebuilds rows from groups, considering folded groups.
+func (a *StatArea) refresh() {
+	a.Rows = a.Rows[:0]
+	for _, g := range a.Groups {
+		a.Rows = append(a.Rows, statRow{Group: g})
+		if g.Folded {
+			continue
+		}
+		for _, f := range g.Files {
+			a.Rows = append(a.Rows, statRow{Group: g, File: f})
+		}
+	}
+	if a.CurIdx >= len(a.Rows) {
+		a.CurIdx = len(a.Rows) - 1
+	}
+	if a.CurIdx < 0 {
+		a.CurIdx = 0
+	}
+}
+
+// Handle handles a terminal event.
+func (a *StatArea) Handle(ev Event) bool {
+	page := a.Bound.Size.L - 1
+	if ev.Key == KeyArrowUp || ev.Ch == 'i' {
+		a.CurIdx -= count()
+	} else if ev.Key == KeyArrowDown || ev.Ch == 'k' {
+		a.CurIdx += count()
+	} else if ev.Key == KeyPgup || ev.Ch == 'b' {
+		a.CurIdx -= page * count()
+	} else if ev.Key == KeyPgdn || ev.Ch == 'f' {
+		a.CurIdx += page * count()
+	} else if ev.Key == KeyHome {
+		a.CurIdx = 0
+	} else if ev.Key == KeyEnd {
+		a.CurIdx = len(a.Rows) - 1
+	} else if len(a.Rows) == 0 {
+		return false
+	} else if ev.Key == KeySpace || ev.Ch == 'l' || ev.Ch == 'j' || ev.Key == KeyEnter && a.Rows[a.CurIdx].File == nil {
+		g := a.Rows[a.CurIdx].Group
+		switch {
+		case ev.Ch == 'l':
+			g.Folded = false
+		case ev.Ch == 'j':
+			g.Folded = true
+		default:
+			g.Folded = !g.Folded
+		}
+		// keep the cursor on the group.
+		for i, r := range a.Rows {
+			if r.Group == g {
+				a.CurIdx = i
+				break
+			}
+		}
+	} else if ev.Key == KeyEnter {
+		screen.Diff.GotoFile(a.Rows[a.CurIdx].File.Path)
+		dig.CurView = DiffView
+	} else {
+		return false
+	}
+	a.refresh()
+	return true
+}
+
+// Draw draws it's contents.
+func (a *StatArea) Draw() {
+	if a.Err != nil {
+		drawLine(a.Bound, 0, []byte(a.Err.Error()), 0, theme.Error)
+		return
+	}
+	files, added, deleted := 0, 0, 0
+	for _, g := range a.Groups {
+		files += len(g.Files)
+		added += g.Added
+		deleted += g.Deleted
+	}
+	header := fmt.Sprintf("%s: %d files, +%d -%d", shortHash(a.CommitHash), files, added, deleted)
+	drawLine(a.Bound, 0, []byte(header), 0, theme.Header)
+
+	page := a.Bound.Size.L - 1
+	if a.TopIdx > a.CurIdx {
+		a.TopIdx = a.CurIdx
+	} else if a.TopIdx+page <= a.CurIdx {
+		a.TopIdx = a.CurIdx - page + 1
+	}
+	for l := 0; l < page; l++ {
+		i := a.TopIdx + l
+		if i >= len(a.Rows) {
+			break
+		}
+		r := a.Rows[i]
+		c := theme.Normal
+		var ln string
+		if r.File == nil {
+			c = theme.Dir
+			marker := "- "
+			if r.Group.Folded {
+				marker = "+ "
+			}
+			ln = fmt.Sprintf("%-30s %8s %8s  %d files", marker+r.Group.Name, "+"+strconv.Itoa(r.Group.Added), "-"+strconv.Itoa(r.Group.Deleted), len(r.Group.Files))
+		} else {
+			ln = fmt.Sprintf("%-30s %8s %8s  %s", "", "+"+strconv.Itoa(r.File.Added), "-"+strconv.Itoa(r.File.Deleted), r.File.Path)
+		}
+		if i == a.CurIdx {
+			c = theme.Selected
+			fillColor(Rect{Min: Pt{a.Bound.Min.L + l + 1, a.Bound.Min.O}, Size: Pt{1, a.Bound.Size.O}}, c)
+		}
+		drawLine(a.Bound, l+1, []byte(ln), 0, c)
+	}
+}
+
+// commitStat returns changed files of the commit grouped by language.
+// Groups are sorted by their changed lines.
+func commitStat(hash string) ([]*StatGroup, error) {
+	// merge commits are compared to their first parent.
+	out, err := gitOutput("show", "--numstat", "--no-renames", "--format=", "-m", "--first-parent", hash)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string]*StatGroup)
+	for _, ln := range strings.Split(string(out), "\n") {
+		// <added> TAB <deleted> TAB <path>
+		f := strings.SplitN(ln, "\t", 3)
+		if len(f) != 3 {
+			continue
+		}
+		f[2] = unquotePath(f[2])
+		// binary files have "-" for added and deleted.
+		add, _ := strconv.Atoi(f[0])
+		del, _ := strconv.Atoi(f[1])
+		lang := language(f[2])
+		g := groups[lang]
+		if g == nil {
+			g = &StatGroup{Name: lang}
+			groups[lang] = g
+		}
+		g.Files = append(g.Files, &FileStat{Path: f[2], Commits: 1, Added: add, Deleted: del})
+		g.Added += add
+		g.Deleted += del
+	}
+	sorted := make([]*StatGroup, 0, len(groups))
+	for _, g := range groups {
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		ci := sorted[i].Added + sorted[i].Deleted
+		cj := sorted[j].Added + sorted[j].Deleted
+		if ci != cj {
+			return ci > cj
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted, nil
+}
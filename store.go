@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// writeFileAtomic writes data to a file through a temporary file and rename,
+// so the file is never seen half written, even when dig is killed while writing.
+func writeFileAtomic(name string, data []byte) error {
+	dir := filepath.Dir(name)
+	if err := os.MkdirAll(dir, 0755); err != nil && !os.IsExist(err) {
+		return err
+	}
+	// the temporary file should be in the same directory to be renamed.
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(name)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp) // no-op after rename.
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// lockTimeout is how long to wait for a lock held by another dig.
+// A lock older than staleLock is considered left by a killed dig.
+const (
+	lockTimeout = 3 * time.Second
+	staleLock   = 10 * time.Second
+)
+
+// lockFile locks a config file among dig processes, and returns a function to unlock.
+// It uses a lock file created exclusively, which works on every platform.
+func lockFile(name string) (unlock func(), err error) {
+	lock := name + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lock), 0755); err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lock, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if fi, err := os.Stat(lock); err == nil && time.Since(fi.ModTime()) > staleLock {
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("could not lock %s, remove it if no other dig is running", lock)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// updateFile updates content of a config file while holding it's lock,
+// so concurrent dig processes don't lose each other's writes.
+// The update function gets nil content when the file doesn't exist yet.
+func updateFile(name string, update func(content []byte) ([]byte, error)) error {
+	unlock, err := lockFile(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	content, err := ioutil.ReadFile(name)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	content, err = update(content)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(name, content)
+}
+
+// checkCorrupted checks lines of a config file with the valid function.
+// When there are invalid lines, those will be dropped on next write,
+// it backs up the content and returns an error to notice the user.
+func checkCorrupted(name string, content []byte, valid func(ln string) bool) error {
+	bad := 0
+	for _, ln := range strings.Split(string(content), "\n") {
+		if strings.TrimSpace(ln) != "" && !valid(ln) {
+			bad++
+		}
+	}
+	if bad == 0 {
+		return nil
+	}
+	backup := name + ".corrupted"
+	if err := writeFileAtomic(backup, content); err != nil {
+		return err
+	}
+	return fmt.Errorf("%s: skipped %d corrupted lines, the original is backed up to %s", name, bad, backup)
+}
+
+// parseRepoFields parses a line of per repository config files.
+//
+//	"<repo>" <key>=<value> <key>=<value> ...
+func parseRepoFields(ln string) (repo string, fields map[string]string, ok bool) {
+	repo, err := strconv.QuotedPrefix(ln)
+	if err != nil {
+		return "", nil, false
+	}
+	rest := ln[len(repo):]
+	repo, _ = strconv.Unquote(repo)
+	fields = make(map[string]string)
+	for _, f := range strings.Fields(rest) {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			return "", nil, false
+		}
+		fields[kv[0]] = kv[1]
+	}
+	return repo, fields, true
+}
+
+// updateRepoLine updates a line of per repository config files, like layout.
+// The repository's line goes first, and lines of other repositories are kept
+// up to 1000 latest ones.
+func updateRepoLine(name, repoDir, line string, valid func(ln string) bool) error {
+	return updateFile(name, func(content []byte) ([]byte, error) {
+		// corrupted lines are backed up, ok to drop them.
+		checkCorrupted(name, content, valid)
+		lines := []string{line}
+		for _, ln := range strings.Split(string(content), "\n") {
+			repo, _, ok := parseRepoFields(ln)
+			if !ok || !valid(ln) || repo == repoDir {
+				continue
+			}
+			lines = append(lines, ln)
+		}
+		if len(lines) > 1000 {
+			lines = lines[:1000]
+		}
+		return []byte(strings.Join(lines, "\n") + "\n"), nil
+	})
+}
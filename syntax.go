@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// syntaxLang is what a tokenizer needs to know about a language.
+// It's not a parser, it only finds keywords, strings, comments and numbers of a line.
+type syntaxLang struct {
+	Keywords map[string]bool
+	// LineComments start comments those end with the line, like //.
+	LineComments []string
+	// BlockComment starts and ends a comment that could span lines, like /* and */.
+	BlockComment [2]string
+	// Quotes are characters those quote strings in a line.
+	Quotes string
+	// TripleQuotes are strings those could span lines, like """ of python.
+	TripleQuotes []string
+}
+
+// keywords returns a set of the keywords separated by spaces.
+func keywords(s string) map[string]bool {
+	m := make(map[string]bool)
+	for _, k := range strings.Fields(s) {
+		m[k] = true
+	}
+	return m
+}
+
+var (
+	goLang = &syntaxLang{
+		Keywords: keywords("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var " +
+			"true false nil iota"),
+		LineComments: []string{"//"},
+		BlockComment: [2]string{"/*", "*/"},
+		Quotes:       "\"'`",
+	}
+	cLang = &syntaxLang{
+		Keywords: keywords("auto break case char const continue default do double else enum extern float for goto if inline int long register return short signed sizeof static struct switch typedef union unsigned void volatile while " +
+			"bool true false NULL nullptr class namespace template typename public private protected virtual override new delete this using try catch throw"),
+		LineComments: []string{"//"},
+		BlockComment: [2]string{"/*", "*/"},
+		Quotes:       "\"'",
+	}
+	pythonLang = &syntaxLang{
+		Keywords: keywords("and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while with yield " +
+			"True False None self"),
+		LineComments: []string{"#"},
+		Quotes:       "\"'",
+		TripleQuotes: []string{`"""`, `'''`},
+	}
+	jsLang = &syntaxLang{
+		Keywords: keywords("async await break case catch class const continue debugger default delete do else export extends finally for from function if import in instanceof let new of return static super switch this throw try typeof var void while yield " +
+			"true false null undefined interface type enum implements"),
+		LineComments: []string{"//"},
+		BlockComment: [2]string{"/*", "*/"},
+		Quotes:       "\"'`",
+	}
+	rustLang = &syntaxLang{
+		Keywords: keywords("as async await break const continue crate dyn else enum extern fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait type unsafe use where while " +
+			"true false"),
+		LineComments: []string{"//"},
+		BlockComment: [2]string{"/*", "*/"},
+		Quotes:       "\"",
+	}
+	javaLang = &syntaxLang{
+		Keywords: keywords("abstract assert boolean break byte case catch char class const continue default do double else enum extends final finally float for if implements import instanceof int interface long native new package private protected public return short static super switch synchronized this throw throws try void volatile while " +
+			"true false null var val fun when object"),
+		LineComments: []string{"//"},
+		BlockComment: [2]string{"/*", "*/"},
+		Quotes:       "\"'",
+	}
+	shellLang = &syntaxLang{
+		Keywords:     keywords("if then else elif fi for while until do done case esac in function return local export readonly set unset shift exit"),
+		LineComments: []string{"#"},
+		Quotes:       "\"'",
+	}
+	rubyLang = &syntaxLang{
+		Keywords: keywords("alias and begin break case class def do else elsif end ensure for if in module next nil not or redo rescue retry return self super then undef unless until when while yield " +
+			"true false require"),
+		LineComments: []string{"#"},
+		Quotes:       "\"'",
+	}
+)
+
+// syntaxLangs are languages by extensions of files.
+var syntaxLangs = map[string]*syntaxLang{
+	".go":   goLang,
+	".c":    cLang,
+	".h":    cLang,
+	".cc":   cLang,
+	".cpp":  cLang,
+	".cxx":  cLang,
+	".hpp":  cLang,
+	".py":   pythonLang,
+	".js":   jsLang,
+	".jsx":  jsLang,
+	".mjs":  jsLang,
+	".ts":   jsLang,
+	".tsx":  jsLang,
+	".rs":   rustLang,
+	".java": javaLang,
+	".kt":   javaLang,
+	".sh":   shellLang,
+	".bash": shellLang,
+	".rb":   rubyLang,
+}
+
+// langOf returns the language of the file, or nil if it's not known.
+func langOf(path string) *syntaxLang {
+	return syntaxLangs[strings.ToLower(filepath.Ext(path))]
+}
+
+// span colors bytes of a line, [Start, End).
+type span struct {
+	Start int
+	End   int
+	Fg    Attribute
+}
+
+// syntaxState is where a line starts, when the previous line didn't close a comment or a string.
+type syntaxState struct {
+	// Close is what closes it, empty when it's not in one.
+	Close string
+	Fg    Attribute
+}
+
+// tokens finds spans of the line, those are colored by syntax.
+// It returns the state the next line starts with.
+func (lang *syntaxLang) tokens(ln []byte, st syntaxState) ([]span, syntaxState) {
+	var spans []span
+	i := 0
+	// closeAt finds the closing string from i, and adds a span to it.
+	closeAt := func(start int, close string, fg Attribute) {
+		if j := bytes.Index(ln[i:], []byte(close)); j != -1 {
+			i += j + len(close)
+			st = syntaxState{}
+		} else {
+			i = len(ln)
+			st = syntaxState{Close: close, Fg: fg}
+		}
+		spans = append(spans, span{start, i, fg})
+	}
+	if st.Close != "" {
+		closeAt(0, st.Close, st.Fg)
+	}
+	for i < len(ln) {
+		rest := ln[i:]
+		start := i
+		if hasAnyPrefix(rest, lang.LineComments) {
+			spans = append(spans, span{i, len(ln), theme.Comment})
+			break
+		}
+		if lang.BlockComment[0] != "" && bytes.HasPrefix(rest, []byte(lang.BlockComment[0])) {
+			i += len(lang.BlockComment[0])
+			closeAt(start, lang.BlockComment[1], theme.Comment)
+			continue
+		}
+		if q := anyPrefix(rest, lang.TripleQuotes); q != "" {
+			i += len(q)
+			closeAt(start, q, theme.String)
+			continue
+		}
+		c := ln[i]
+		switch {
+		case strings.IndexByte(lang.Quotes, c) != -1:
+			i++
+			for i < len(ln) && ln[i] != c {
+				if ln[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i < len(ln) {
+				i++
+			} else {
+				// an escape at the end of the line.
+				i = len(ln)
+			}
+			spans = append(spans, span{start, i, theme.String})
+		case c >= '0' && c <= '9':
+			for i < len(ln) && (isWordByte(ln[i]) || ln[i] == '.') {
+				i++
+			}
+			spans = append(spans, span{start, i, theme.Number})
+		case isWordByte(c):
+			for i < len(ln) && isWordByte(ln[i]) {
+				i++
+			}
+			if lang.Keywords[string(ln[start:i])] {
+				spans = append(spans, span{start, i, theme.Keyword})
+			}
+		default:
+			_, size := utf8.DecodeRune(rest)
+			i += size
+		}
+	}
+	return spans, st
+}
+
+// isWordByte reports whether the byte could be in an identifier.
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// anyPrefix returns the first of prefixes the bytes start with, or "" if none.
+func anyPrefix(b []byte, prefixes []string) string {
+	for _, p := range prefixes {
+		if bytes.HasPrefix(b, []byte(p)) {
+			return p
+		}
+	}
+	return ""
+}
+
+// hasAnyPrefix reports whether the bytes start with one of prefixes.
+func hasAnyPrefix(b []byte, prefixes []string) bool {
+	return anyPrefix(b, prefixes) != ""
+}
+
+// diffSyntax returns spans of each line of the diff, nil for lines those aren't highlighted.
+// Lines are tokenized by languages of their files, after their +/- markers.
+// Old and new sides are followed separately, as a comment could be opened only in one of them.
+func diffSyntax(text [][]byte) [][]span {
+	spans := make([][]span, len(text))
+	var lang *syntaxLang
+	var oldSt, newSt syntaxState
+	inHunk := false
+	for i, ln := range text {
+		switch {
+		case isFileHeader(ln):
+			lang = langOf(fileName(ln))
+			inHunk = false
+			continue
+		case bytes.HasPrefix(ln, []byte("@@")):
+			oldSt, newSt = syntaxState{}, syntaxState{}
+			inHunk = true
+			continue
+		}
+		if lang == nil || !inHunk || len(ln) == 0 {
+			continue
+		}
+		var sp []span
+		switch ln[0] {
+		case ' ':
+			sp, newSt = lang.tokens(ln[1:], newSt)
+			oldSt = newSt
+		case '+':
+			sp, newSt = lang.tokens(ln[1:], newSt)
+		case '-':
+			sp, oldSt = lang.tokens(ln[1:], oldSt)
+		default:
+			continue
+		}
+		for k := range sp {
+			sp[k].Start++
+			sp[k].End++
+		}
+		spans[i] = sp
+	}
+	return spans
+}
+
+// Syntax returns spans of the diff's lines, they're found once for the text.
+func (a *DiffArea) Syntax() [][]span {
+	if !config.Syntax {
+		return nil
+	}
+	if len(a.syntax) != len(a.Text) || len(a.Text) != 0 && &a.syntaxText[0] != &a.Text[0] {
+		a.syntaxText = a.Text
+		a.syntax = diffSyntax(a.Text)
+	}
+	return a.syntax
+}
+
+// drawSpans draws a line like drawLine, with foreground colors of the spans.
+// Bytes not in them are drawn with the line's color.
+func drawSpans(bound Rect, l int, ln []byte, shift int, c Color, spans []span) {
+	o := -shift
+	for i, k := 0, 0; i < len(ln) && o < bound.Size.O; {
+		for k < len(spans) && spans[k].End <= i {
+			k++
+		}
+		fg := c.Fg
+		if k < len(spans) && spans[k].Start <= i {
+			fg = spans[k].Fg
+		}
+		r, size := utf8.DecodeRune(ln[i:])
+		i += size
+		r = printable(r)
+		if o >= 0 {
+			setCell(bound.Min.O+o, bound.Min.L+l, r, fg, c.Bg)
+		}
+		o += runewidth.RuneWidth(r)
+	}
+}
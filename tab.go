@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Tab is an independent workspace of this program.
+// Each tab has it's own repository, commits and screen state.
+type Tab struct {
+	Program *Program
+	Screen  *Screen
+}
+
+// tabs are opened tabs. dig and screen are always the current tab's.
+var (
+	tabs   []*Tab
+	curTab int
+)
+
+// openTab creates a new tab for the repository.
+// It doesn't switch to the tab. Commits after the first page are loaded in background.
+func openTab(repoDir string, targets []string) (*Tab, error) {
+	repo, err := filepath.Abs(repoDir)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := readNotes(repo)
+	if err != nil {
+		return nil, err
+	}
+	loader, commits, err := startCommits(repo, targets, dig.DigUp)
+	if err != nil {
+		return nil, err
+	}
+	// lost last commit or layout is not a big deal.
+	layout, _ := readLayout(repo)
+	s := NewScreen(screen.size, layout.SideWidth)
+	s.SetLayout(layout)
+	lastc, _ := readLastCommit(repo)
+	pos, _ := readPosition(repo)
+	p := &Program{
+		Mode:    NormalMode,
+		CurView: CommitView,
+		RepoDir: repo,
+		Targets: targets,
+		DigUp:   dig.DigUp,
+		Notes:   notes,
+	}
+	p.SetCommits(commits)
+	if err := p.FilterCommits(); err != nil {
+		if loader != nil {
+			// it isn't the program's loader yet.
+			loader.log.Stop()
+		}
+		return nil, err
+	}
+	found := false
+	for i, c := range p.Commits {
+		if c.Hash == lastc {
+			s.Commit.CurIdx = i
+			s.SetPosition(pos, c.Hash)
+			found = true
+			break
+		}
+	}
+	if loader != nil {
+		loader.Program = p
+		loader.Screen = s
+		if !found {
+			loader.Want = lastc
+			loader.WantPos = pos
+		}
+		p.Loader = loader
+		loader.Load()
+	}
+	return &Tab{Program: p, Screen: s}, nil
+}
+
+// Close stops background work of the program, when it's tab is closed.
+func (p *Program) Close() {
+	if p.Loader != nil {
+		p.Loader.Stop()
+	}
+	if p.Meta != nil {
+		p.Meta.Stop()
+	}
+}
+
+// Commit returns the selected commit of the tab.
+// Unlike screen.Commit.Commit, it works for tabs those are not current.
+func (t *Tab) Commit() *Commit {
+	return t.Program.Commits[t.Screen.Commit.CurIdx]
+}
+
+// switchTab makes the i-th tab current.
+func switchTab(i int) {
+	if i < 0 || i >= len(tabs) {
+		return
+	}
+	curTab = i
+	size := screen.size
+	dig = tabs[i].Program
+	screen = tabs[i].Screen
+	// the terminal could be resized while the tab was hidden.
+	screen.Resize(size)
+}
+
+// handleTabPrefixed handles keys after 'g' for tabs, like vim.
+// With a count, gt goes to the count-th tab.
+func handleTabPrefixed(ev Event) bool {
+	n := len(tabs)
+	switch ev.Ch {
+	case 't':
+		if dig.Count != 0 {
+			switchTab(dig.Count - 1)
+		} else {
+			switchTab((curTab + 1) % n)
+		}
+		return true
+	case 'T':
+		switchTab(((curTab-count())%n + n) % n)
+		return true
+	}
+	return false
+}
+
+// cmdTabNew opens a new tab and switches to it.
+// Arguments are a repository directory and targets for git log.
+func cmdTabNew(args []string) error {
+	repoDir := dig.RepoDir
+	targets := dig.Targets
+	if len(args) != 0 {
+		repoDir = args[0]
+		targets = args[1:]
+	}
+	t, err := openTab(repoDir, targets)
+	if err != nil {
+		return err
+	}
+	tabs = append(tabs, t)
+	switchTab(len(tabs) - 1)
+	return nil
+}
+
+// cmdTabClose closes the current tab.
+// The last tab cannot be closed, quit the program instead.
+func cmdTabClose(args []string) error {
+	if len(tabs) == 1 {
+		return fmt.Errorf("cannot close the last tab")
+	}
+	// the tab is closed even when it couldn't be saved, it's told in the next tab.
+	err := saveTab(tabs[curTab])
+	dig.Close()
+	tabs = append(tabs[:curTab], tabs[curTab+1:]...)
+	i := curTab
+	if i == len(tabs) {
+		i--
+	}
+	switchTab(i)
+	if err != nil {
+		dig.Message = "could not save the closed tab: " + err.Error()
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTabs(t *testing.T) {
+	t.Setenv("DIG_CONFIG_DIR", t.TempDir())
+	r := historyFixture(t)
+	setupApp(t, r.Dir, r.Commits(false, "HEAD"))
+	tabs = []*Tab{{Program: dig, Screen: screen}}
+	curTab = 0
+	defer func() { tabs = nil }()
+	dir, _ := testRepo(t, 12)
+	defer func(n int) { firstPage = n }(firstPage)
+	firstPage = 5
+	// the new tab shows the first page, and loads the others in background.
+	if err := cmdTabNew([]string{dir, "HEAD"}); err != nil {
+		t.Fatal(err)
+	}
+	if curTab != 1 || dig.RepoDir != dir {
+		t.Fatalf("current tab %d of %s, want 1 of %s", curTab, dig.RepoDir, dir)
+	}
+	if dig.Loader == nil || len(dig.Commits) != firstPage {
+		t.Fatalf("new tab has %d commits, loading %v", len(dig.Commits), dig.Loader != nil)
+	}
+	p := dig
+	runUntil(t, func() bool { return p.Loader == nil }, nil)
+	if len(p.Commits) != 12 {
+		t.Errorf("new tab has %d commits after loading, want 12", len(p.Commits))
+	}
+	// the tab is closed even when it couldn't be saved, it's told in the next one.
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DIG_CONFIG_DIR", filepath.Join(file, "dig"))
+	if err := cmdTabClose(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(tabs) != 1 || dig.RepoDir != r.Dir {
+		t.Fatalf("%d tabs left, current one is of %s", len(tabs), dig.RepoDir)
+	}
+	if !strings.HasPrefix(dig.Message, "could not save the closed tab: ") {
+		t.Errorf("message: got %q", dig.Message)
+	}
+}
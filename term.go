@@ -0,0 +1,240 @@
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+// term is the terminal screen dig draws on. It's set by initTerm.
+var term tcell.Screen
+
+// initTerm takes the terminal, and switches to it's alternate screen if it supports.
+// So the user's scrollback will be restored when dig exits.
+func initTerm() error {
+	s, err := tcell.NewScreen()
+	if err != nil {
+		return err
+	}
+	if err := s.Init(); err != nil {
+		return err
+	}
+	term = s
+	return nil
+}
+
+// closeTerm gives the terminal back to the shell. It's fine to call it more than once.
+func closeTerm() {
+	if term != nil {
+		term.Fini()
+	}
+}
+
+// enableMouse makes the terminal report clicks, wheels and drags of the mouse.
+func enableMouse() {
+	// motions are reported only while a button is pressed.
+	term.EnableMouse(tcell.MouseButtonEvents | tcell.MouseDragEvents)
+}
+
+// Attribute is a color with attributes like bold, that is drawn on the terminal.
+//
+// Colors are below attributes. ColorDefault is 0, basic colors are 1-8
+// and 256 colors are 1-256, see color256. A true color is AttrRGB with 24 bits of it's red, green and blue.
+// The terminal draws the closest color it has, when it doesn't have the color.
+type Attribute uint32
+
+// basic colors.
+const (
+	ColorDefault Attribute = iota
+	ColorBlack
+	ColorRed
+	ColorGreen
+	ColorYellow
+	ColorBlue
+	ColorMagenta
+	ColorCyan
+	ColorWhite
+)
+
+// attributes, those are above colors.
+const (
+	AttrRGB Attribute = 1 << (24 + iota)
+	AttrBold
+	AttrUnderline
+	AttrReverse
+)
+
+// rgbColor returns a true color, like 0xff8700.
+func rgbColor(rgb uint32) Attribute {
+	return AttrRGB | Attribute(rgb&0xffffff)
+}
+
+// termColor returns the terminal's color of the attribute's color.
+func termColor(a Attribute) tcell.Color {
+	a &= AttrBold - 1
+	switch {
+	case a&AttrRGB != 0:
+		return tcell.NewHexColor(int32(a &^ AttrRGB))
+	case a == ColorDefault:
+		return tcell.ColorDefault
+	}
+	return tcell.PaletteColor(int(a) - 1)
+}
+
+// termStyle returns the terminal's style of the foreground and background.
+func termStyle(fg, bg Attribute) tcell.Style {
+	attr := fg | bg
+	return tcell.StyleDefault.
+		Foreground(termColor(fg)).
+		Background(termColor(bg)).
+		Bold(attr&AttrBold != 0).
+		Underline(attr&AttrUnderline != 0).
+		Reverse(attr&AttrReverse != 0)
+}
+
+// setCell draws the rune at the cell, with the colors.
+func setCell(o, l int, r rune, fg, bg Attribute) {
+	term.SetContent(o, l, r, nil, termStyle(fg, bg))
+}
+
+// cellRune returns the rune drawn at the cell, or 0 when there isn't.
+func cellRune(o, l int) rune {
+	r, _, _, _ := term.GetContent(o, l)
+	return r
+}
+
+// reverseCell reverses colors of the cell, as it's selected.
+func reverseCell(o, l int) {
+	r, comb, style, _ := term.GetContent(o, l)
+	term.SetContent(o, l, r, comb, style.Reverse(true))
+}
+
+// EventType is the type of an Event.
+type EventType int
+
+// types of events.
+const (
+	EventKey EventType = iota + 1
+	EventMouse
+	EventResize
+)
+
+// Key is a special key of an event, like KeyEnter, or a button of the mouse.
+type Key = tcell.Key
+
+// special keys. A key of a rune is 0, except the space.
+const (
+	KeyEnter          = tcell.KeyEnter
+	KeyEsc            = tcell.KeyEscape
+	KeyTab            = tcell.KeyTab
+	KeyBackspace      = tcell.KeyBackspace
+	KeySpace      Key = ' '
+	KeyArrowUp        = tcell.KeyUp
+	KeyArrowDown      = tcell.KeyDown
+	KeyArrowLeft      = tcell.KeyLeft
+	KeyArrowRight     = tcell.KeyRight
+	KeyHome           = tcell.KeyHome
+	KeyEnd            = tcell.KeyEnd
+	KeyPgup           = tcell.KeyPgUp
+	KeyPgdn           = tcell.KeyPgDn
+	KeyCtrlC          = tcell.KeyCtrlC
+	KeyCtrlF          = tcell.KeyCtrlF
+	KeyCtrlK          = tcell.KeyCtrlK
+	KeyCtrlN          = tcell.KeyCtrlN
+	KeyCtrlO          = tcell.KeyCtrlO
+	KeyCtrlP          = tcell.KeyCtrlP
+	KeyCtrlQ          = tcell.KeyCtrlQ
+	KeyCtrlS          = tcell.KeyCtrlS
+	KeyCtrlT          = tcell.KeyCtrlT
+	KeyCtrlW          = tcell.KeyCtrlW
+	KeyCtrlY          = tcell.KeyCtrlY
+)
+
+// buttons of the mouse, those are keys of mouse events.
+// They're after keys of the terminal, so they don't mix.
+const (
+	MouseLeft Key = tcell.KeyF64 + 1 + iota
+	MouseRelease
+	MouseWheelUp
+	MouseWheelDown
+)
+
+// Modifier is a modifier of an event.
+type Modifier int
+
+// ModMotion is set while the mouse moves with a button pressed.
+const ModMotion Modifier = 1
+
+// Event is a key, a mouse event or a resize of the terminal.
+// Handlers check it's Ch for a rune, then it's Key.
+type Event struct {
+	Type   EventType
+	Key    Key
+	Ch     rune
+	Mod    Modifier
+	MouseX int
+	MouseY int
+}
+
+// pressed is the buttons of the mouse, those were pressed at the last mouse event.
+var pressed tcell.ButtonMask
+
+// pollEvent waits for an event of the terminal.
+// Events dig doesn't handle, like a move of the mouse without a button, are skipped.
+func pollEvent() Event {
+	for {
+		switch ev := term.PollEvent().(type) {
+		case *tcell.EventKey:
+			return keyEvent(ev)
+		case *tcell.EventMouse:
+			if e, ok := mouseEvent(ev); ok {
+				return e
+			}
+		case *tcell.EventResize:
+			return Event{Type: EventResize}
+		case nil:
+			// the terminal is closed, dig is exiting.
+			select {}
+		}
+	}
+}
+
+// keyEvent returns the event of the key.
+func keyEvent(ev *tcell.EventKey) Event {
+	e := Event{Type: EventKey, Key: ev.Key()}
+	if e.Key == tcell.KeyRune {
+		e.Key = 0
+		e.Ch = ev.Rune()
+		if e.Ch == ' ' {
+			e.Key, e.Ch = KeySpace, 0
+		}
+	}
+	return e
+}
+
+// mouseEvent returns the event of the mouse. It's not ok when dig doesn't handle the event.
+func mouseEvent(ev *tcell.EventMouse) (Event, bool) {
+	o, l := ev.Position()
+	e := Event{Type: EventMouse, MouseX: o, MouseY: l}
+	btn := ev.Buttons()
+	switch {
+	case btn&tcell.WheelUp != 0:
+		e.Key = MouseWheelUp
+		return e, true
+	case btn&tcell.WheelDown != 0:
+		e.Key = MouseWheelDown
+		return e, true
+	}
+	prev := pressed
+	pressed = btn
+	switch {
+	case btn&tcell.Button1 != 0:
+		e.Key = MouseLeft
+		if prev&tcell.Button1 != 0 {
+			e.Mod = ModMotion
+		}
+	case btn == tcell.ButtonNone && prev != tcell.ButtonNone:
+		e.Key = MouseRelease
+	default:
+		return e, false
+	}
+	return e, true
+}
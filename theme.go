@@ -0,0 +1,237 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// theme is colors currently used by this program.
+var theme = darkTheme
+
+// Theme is a set of colors for drawing the program.
+type Theme struct {
+	Normal     Color // normal text
+	Selected   Color // selected line
+	Focused    Color // header of the focused area
+	Status     Color // status area
+	Popup      Color
+	Header     Color // header line of lists
+	Added      Color // added line of diff
+	Deleted    Color // deleted line of diff
+	Frag       Color // hunk header of diff
+	Meta       Color // file header of diff, except the ---/+++ lines
+	Mode       Color // file mode change of diff
+	Link       Color // symlink change of diff
+	TypeChange Color // file type change of diff
+	Dir        Color // directories of a tree
+	Error      Color
+
+	Badge Attribute // foreground color of commit badges
+	Hash  Attribute // foreground color of abbreviated hashes
+	Func  Attribute // foreground color of function names of hunk headers
+
+	// foreground colors of code in diffs, when they're highlighted by syntax.
+	Keyword Attribute
+	String  Attribute
+	Comment Attribute
+	Number  Attribute
+
+	// Lanes are foreground colors of commits by their lanes,
+	// when the commits are colored by lane.
+	Lanes []Attribute
+
+	// Ages are foreground colors of commits from recent to old,
+	// when the commits are tinted by age. These are 256 colors.
+	Ages []Attribute
+}
+
+// darkTheme is for terminals those have dark background.
+var darkTheme = &Theme{
+	Normal:     Color{ColorWhite, ColorBlack},
+	Selected:   Color{ColorWhite, ColorGreen},
+	Focused:    Color{ColorBlack, ColorCyan},
+	Status:     Color{ColorBlack, ColorWhite},
+	Popup:      Color{ColorWhite, ColorBlue},
+	Header:     Color{ColorYellow, ColorBlack},
+	Added:      Color{ColorGreen, ColorBlack},
+	Deleted:    Color{ColorRed, ColorBlack},
+	Frag:       Color{ColorWhite, ColorBlack},
+	Meta:       Color{ColorWhite, ColorBlack},
+	Mode:       Color{ColorMagenta, ColorBlack},
+	Link:       Color{ColorCyan, ColorBlack},
+	TypeChange: Color{ColorYellow | AttrBold, ColorBlack},
+	Dir:        Color{ColorBlue, ColorBlack},
+	Error:      Color{ColorRed, ColorBlack},
+	Badge:      ColorYellow,
+	Hash:       ColorCyan,
+	Func:       ColorCyan | AttrBold,
+	Keyword:    ColorMagenta | AttrBold,
+	String:     ColorYellow,
+	Comment:    ColorBlue,
+	Number:     ColorCyan,
+	Lanes:      []Attribute{ColorGreen, ColorYellow, ColorCyan, ColorMagenta, ColorBlue, ColorRed},
+	Ages:       []Attribute{ColorWhite | AttrBold, gray(20), gray(14), gray(8)},
+}
+
+// lightTheme is for terminals those have light background.
+// It uses the terminal's default background instead of painting it.
+var lightTheme = &Theme{
+	Normal:     Color{ColorBlack, ColorDefault},
+	Selected:   Color{ColorBlack, ColorCyan},
+	Focused:    Color{ColorWhite, ColorBlue},
+	Status:     Color{ColorWhite, ColorBlack},
+	Popup:      Color{ColorBlack, ColorYellow},
+	Header:     Color{ColorMagenta, ColorDefault},
+	Added:      Color{ColorGreen, ColorDefault},
+	Deleted:    Color{ColorRed, ColorDefault},
+	Frag:       Color{ColorBlack, ColorDefault},
+	Meta:       Color{ColorBlack, ColorDefault},
+	Mode:       Color{ColorMagenta, ColorDefault},
+	Link:       Color{ColorBlue, ColorDefault},
+	TypeChange: Color{ColorRed | AttrBold, ColorDefault},
+	Dir:        Color{ColorBlue, ColorDefault},
+	Error:      Color{ColorRed, ColorDefault},
+	Badge:      ColorMagenta,
+	Hash:       ColorBlue,
+	Func:       ColorBlue | AttrBold,
+	Keyword:    ColorMagenta | AttrBold,
+	String:     ColorYellow,
+	Comment:    ColorCyan,
+	Number:     ColorBlue,
+	Lanes:      []Attribute{ColorBlue, ColorMagenta, ColorGreen, ColorRed, ColorCyan, ColorYellow},
+	Ages:       []Attribute{ColorBlack | AttrBold, gray(6), gray(12), gray(17)},
+}
+
+// solarizedTheme is the dark one of Ethan Schoonover's solarized, with the closest 256 colors.
+var solarizedTheme = &Theme{
+	Normal:     Color{color256(244), color256(234)},
+	Selected:   Color{color256(254), color256(240)},
+	Focused:    Color{color256(234), color256(37)},
+	Status:     Color{color256(234), color256(245)},
+	Popup:      Color{color256(245), color256(235)},
+	Header:     Color{color256(136), color256(234)},
+	Added:      Color{color256(64), color256(234)},
+	Deleted:    Color{color256(160), color256(234)},
+	Frag:       Color{color256(244), color256(234)},
+	Meta:       Color{color256(244), color256(234)},
+	Mode:       Color{color256(125), color256(234)},
+	Link:       Color{color256(37), color256(234)},
+	TypeChange: Color{color256(166) | AttrBold, color256(234)},
+	Dir:        Color{color256(33), color256(234)},
+	Error:      Color{color256(160), color256(234)},
+	Badge:      color256(136),
+	Hash:       color256(37),
+	Func:       color256(33) | AttrBold,
+	Keyword:    color256(64) | AttrBold,
+	String:     color256(37),
+	Comment:    color256(240),
+	Number:     color256(125),
+	Lanes:      []Attribute{color256(33), color256(136), color256(37), color256(125), color256(64), color256(166), color256(61), color256(160)},
+	Ages:       []Attribute{color256(245) | AttrBold, color256(244), color256(241), color256(240)},
+}
+
+// color256 returns a color of 256 colors by it's number.
+func color256(n int) Attribute {
+	// 256 color attributes are 1-based, as 0 is the default color.
+	return Attribute(n + 1)
+}
+
+// gray returns a gray of 256 colors, from 0 (black) to 23 (white).
+func gray(n int) Attribute {
+	// grays live in 232-255.
+	return color256(232 + n)
+}
+
+// ageColor returns foreground color for a commit at the time.
+func ageColor(t time.Time) Attribute {
+	age := time.Since(t)
+	i := 0
+	for _, b := range config.AgeBuckets {
+		if age < b {
+			break
+		}
+		i++
+	}
+	if i >= len(theme.Ages) {
+		i = len(theme.Ages) - 1
+	}
+	return theme.Ages[i]
+}
+
+// themeByName returns a copy of the theme by it's name, so it could be changed.
+// "auto" detects the terminal's background to choose dark or light.
+func themeByName(name string) *Theme {
+	switch name {
+	case "light":
+		return lightTheme.copy()
+	case "dark":
+		return darkTheme.copy()
+	case "solarized":
+		return solarizedTheme.copy()
+	}
+	if lightBackground() {
+		return lightTheme.copy()
+	}
+	return darkTheme.copy()
+}
+
+// copy returns a copy of the theme, that doesn't share colors with it.
+func (t *Theme) copy() *Theme {
+	c := *t
+	c.Lanes = append([]Attribute{}, t.Lanes...)
+	c.Ages = append([]Attribute{}, t.Ages...)
+	return &c
+}
+
+// lightBackground reports whether the terminal's background is light.
+//
+// It asks the terminal it's background color with OSC 11 first.
+// When the terminal doesn't answer, it looks COLORFGBG environment variable,
+// which is set by some terminals like rxvt and konsole.
+// Unknown background is considered as dark.
+func lightBackground() bool {
+	if r, g, b, ok := queryBackground(); ok {
+		// perceived luminance, from ITU-R BT.601.
+		return 0.299*r+0.587*g+0.114*b > 0.5
+	}
+	fgbg := os.Getenv("COLORFGBG") // ex) "15;0", or "15;default;0"
+	if fgbg == "" {
+		return false
+	}
+	f := strings.Split(fgbg, ";")
+	bg, err := strconv.Atoi(f[len(f)-1])
+	if err != nil {
+		return false
+	}
+	return bg == 7 || bg > 8
+}
+
+// parseOSC11 parses the terminal's answer to OSC 11 query,
+// like "\x1b]11;rgb:ffff/ffff/ffff\x1b\\". It returns colors in [0, 1].
+func parseOSC11(s string) (r, g, b float64, ok bool) {
+	idx := strings.Index(s, "rgb:")
+	if idx == -1 {
+		return 0, 0, 0, false
+	}
+	s = s[idx+4:]
+	s = strings.TrimRight(s, "\x1b\\\a")
+	f := strings.Split(s, "/")
+	if len(f) != 3 {
+		return 0, 0, 0, false
+	}
+	var c [3]float64
+	for i, v := range f {
+		if len(v) == 0 || len(v) > 4 {
+			return 0, 0, 0, false
+		}
+		n, err := strconv.ParseUint(v, 16, 16)
+		if err != nil {
+			return 0, 0, 0, false
+		}
+		// each component has 1 to 4 hex digits.
+		c[i] = float64(n) / float64(uint64(1)<<(4*uint(len(v)))-1)
+	}
+	return c[0], c[1], c[2], true
+}
@@ -0,0 +1,64 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// queryBackground asks the terminal it's background color with OSC 11.
+// It should be called before initTerm.
+func queryBackground() (r, g, b float64, ok bool) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	defer tty.Close()
+	// the terminal should not wait for a newline, or echo the answer.
+	stty := func(args ...string) (string, error) {
+		// stty gets it's own handle, since passing tty to a command
+		// makes it blocking, and then the read deadline doesn't work.
+		in, err := os.Open("/dev/tty")
+		if err != nil {
+			return "", err
+		}
+		defer in.Close()
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = in
+		out, err := cmd.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return 0, 0, 0, false
+	}
+	defer stty(saved)
+
+	// without a deadline, a terminal that doesn't answer blocks forever.
+	if err := tty.SetReadDeadline(time.Now().Add(100 * time.Millisecond)); err != nil {
+		return 0, 0, 0, false
+	}
+	if _, err := tty.WriteString("\x1b]11;?\x1b\\"); err != nil {
+		return 0, 0, 0, false
+	}
+	ans := ""
+	buf := make([]byte, 64)
+	for {
+		n, err := tty.Read(buf)
+		ans += string(buf[:n])
+		if strings.HasSuffix(ans, "\x1b\\") || strings.HasSuffix(ans, "\a") {
+			break
+		}
+		if err != nil {
+			return 0, 0, 0, false
+		}
+	}
+	return parseOSC11(ans)
+}
@@ -0,0 +1,7 @@
+package main
+
+// queryBackground asks the terminal it's background color.
+// Windows console doesn't support the query.
+func queryBackground() (r, g, b float64, ok bool) {
+	return 0, 0, 0, false
+}
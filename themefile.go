@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// colorAttrs are names of attributes those could be added to a color, like "bold yellow".
+var colorAttrs = map[string]Attribute{
+	"bold":      AttrBold,
+	"underline": AttrUnderline,
+	"reverse":   AttrReverse,
+}
+
+// colorFields returns colors of the theme by their names in the theme file.
+func (t *Theme) colorFields() map[string]*Color {
+	return map[string]*Color{
+		"normal":      &t.Normal,
+		"selected":    &t.Selected,
+		"focused":     &t.Focused,
+		"status":      &t.Status,
+		"popup":       &t.Popup,
+		"header":      &t.Header,
+		"added":       &t.Added,
+		"deleted":     &t.Deleted,
+		"frag":        &t.Frag,
+		"meta":        &t.Meta,
+		"mode":        &t.Mode,
+		"link":        &t.Link,
+		"type_change": &t.TypeChange,
+		"dir":         &t.Dir,
+		"error":       &t.Error,
+	}
+}
+
+// fgFields returns foreground colors of the theme by their names in the theme file.
+func (t *Theme) fgFields() map[string]*Attribute {
+	return map[string]*Attribute{
+		"badge":   &t.Badge,
+		"hash":    &t.Hash,
+		"func":    &t.Func,
+		"keyword": &t.Keyword,
+		"string":  &t.String,
+		"comment": &t.Comment,
+		"number":  &t.Number,
+	}
+}
+
+// parseAttr parses a color with attributes, like "bold 208".
+// A color is default, a name of basic colors, a number of 256 colors or a true color like #ff8700.
+func parseAttr(s string) (Attribute, error) {
+	var attr Attribute
+	color := ""
+	for _, f := range strings.Fields(strings.ToLower(s)) {
+		if a, ok := colorAttrs[f]; ok {
+			attr |= a
+			continue
+		}
+		if color != "" {
+			return 0, fmt.Errorf("more than a color: %s", s)
+		}
+		color = f
+	}
+	switch color {
+	case "":
+		return 0, fmt.Errorf("no color: %s", s)
+	case "default":
+		return attr | ColorDefault, nil
+	}
+	c, err := parseColors(color)
+	if err != nil {
+		return 0, err
+	}
+	return attr | c[0], nil
+}
+
+// parseColor parses foreground and background colors, like "white on 33".
+// Without the background, it keeps bg.
+func parseColor(s string, bg Attribute) (Color, error) {
+	f := strings.SplitN(s, " on ", 2)
+	fg, err := parseAttr(f[0])
+	if err != nil {
+		return Color{}, err
+	}
+	if len(f) == 2 {
+		if bg, err = parseAttr(f[1]); err != nil {
+			return Color{}, err
+		}
+	}
+	return Color{fg, bg}, nil
+}
+
+// readTheme returns a copy of the theme, with colors of the theme file over it.
+// The file has `name = color` lines like the config file, and the theme is returned as is when it doesn't exist.
+//
+//	selected = white on 33
+//	added = bold green
+//	keyword = magenta
+//	lanes = green, yellow, 208
+func readTheme(t *Theme) (*Theme, error) {
+	name, err := configFile("theme")
+	if err != nil {
+		return t, err
+	}
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return t, nil
+		}
+		return t, err
+	}
+	c := t.copy()
+	colors, fgs := c.colorFields(), c.fgFields()
+	for i, ln := range strings.Split(string(content), "\n") {
+		ln = strings.TrimSpace(ln)
+		if ln == "" || strings.HasPrefix(ln, "#") {
+			continue
+		}
+		idx := strings.Index(ln, "=")
+		if idx == -1 {
+			return t, fmt.Errorf("%s:%d: expected name = color", name, i+1)
+		}
+		key := strings.TrimSpace(ln[:idx])
+		value := strings.TrimSpace(ln[idx+1:])
+		if uq, err := strconv.Unquote(value); err == nil {
+			value = uq
+		}
+		if cl, ok := colors[key]; ok {
+			*cl, err = parseColor(value, cl.Bg)
+		} else if fg, ok := fgs[key]; ok {
+			*fg, err = parseAttr(value)
+		} else if key == "lanes" {
+			c.Lanes, err = parseColors(value)
+		} else if key == "ages" {
+			c.Ages, err = parseColors(value)
+		} else {
+			return t, fmt.Errorf("%s:%d: unknown color: %s", name, i+1, key)
+		}
+		if err != nil {
+			return t, fmt.Errorf("%s:%d: %v", name, i+1, err)
+		}
+	}
+	return c, nil
+}
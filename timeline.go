@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// Timeline is a date slider over the time span of commits, for TimelineMode.
+// Moving the slider selects the commit near the date,
+// which is handy to find an era rather than a specific commit.
+type Timeline struct {
+	Min time.Time
+	Max time.Time
+	// Pos is position of the slider in [0, 1].
+	Pos float64
+	// Orig is the cursor before the timeline, that is restored on cancel.
+	Orig int
+}
+
+// startTimeline starts TimelineMode, from the selected commit's date.
+func startTimeline() {
+	min, max := dig.Commits[0].Time, dig.Commits[0].Time
+	for _, c := range dig.Commits {
+		// commit times are not strictly ordered.
+		if c.Time.Before(min) {
+			min = c.Time
+		}
+		if c.Time.After(max) {
+			max = c.Time
+		}
+	}
+	if !max.After(min) {
+		dig.Message = "commits don't have a time span"
+		return
+	}
+	t := &Timeline{Min: min, Max: max, Orig: screen.Commit.CurIdx}
+	t.Pos = float64(screen.Commit.Commit().Time.Sub(min)) / float64(max.Sub(min))
+	dig.Timeline = t
+	dig.Mode = TimelineMode
+}
+
+// Date returns the date at the slider.
+func (t *Timeline) Date() time.Time {
+	return t.Min.Add(time.Duration(float64(t.Max.Sub(t.Min)) * t.Pos))
+}
+
+// Move moves the slider by d of the span, and selects the commit at the date.
+func (t *Timeline) Move(d float64) {
+	t.Pos += d
+	if t.Pos < 0 {
+		t.Pos = 0
+	}
+	if t.Pos > 1 {
+		t.Pos = 1
+	}
+	i := commitAtTime(dig.Commits, t.Date(), dig.DigUp)
+	if i == -1 {
+		// every commit is before the date, choose the latest.
+		i = 0
+		if dig.DigUp {
+			i = len(dig.Commits) - 1
+		}
+	}
+	screen.Commit.SetCursor(i)
+}
+
+// String returns the slider for the status area, like "2019-03-02 |----o-----|".
+func (t *Timeline) String() string {
+	const width = 30
+	o := int(t.Pos * (width - 1))
+	bar := strings.Repeat("-", o) + "o" + strings.Repeat("-", width-1-o)
+	return t.Date().Format("2006-01-02") + " |" + bar + "|"
+}
+
+// handleTimeline handles TimelineMode events.
+func handleTimeline(ev Event) {
+	t := dig.Timeline
+	switch {
+	case ev.Key == KeyArrowLeft || ev.Ch == 'j':
+		t.Move(-0.01)
+	case ev.Key == KeyArrowRight || ev.Ch == 'l':
+		t.Move(0.01)
+	case ev.Key == KeyPgup || ev.Ch == 'b':
+		t.Move(-0.1)
+	case ev.Key == KeyPgdn || ev.Ch == 'f':
+		t.Move(0.1)
+	case ev.Key == KeyHome:
+		t.Move(-1)
+	case ev.Key == KeyEnd:
+		t.Move(1)
+	case ev.Key == KeyEnter:
+		dig.Timeline = nil
+		dig.Mode = NormalMode
+	case ev.Key == KeyEsc || ev.Ch == 'q':
+		screen.Commit.SetCursor(t.Orig)
+		dig.Timeline = nil
+		dig.Mode = NormalMode
+	}
+}
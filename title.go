@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// logOrders are names of git log's ordering options, those are shown in the title bar.
+var logOrders = map[string]string{
+	"--topo-order":        "topo order",
+	"--date-order":        "date order",
+	"--author-date-order": "author date order",
+}
+
+// commitOrder returns how commits are ordered in the commit list, like "newest first, topo order".
+func commitOrder() string {
+	order := "newest first"
+	if dig.DigUp {
+		order = "oldest first"
+	}
+	for _, a := range config.LogArgs {
+		if o, ok := logOrders[a]; ok {
+			order += ", " + o
+		}
+	}
+	return order
+}
+
+// commitCount returns how many commits are in the commit list, like "12 of 340 commits".
+// While commits are being loaded, it's how many are loaded so far.
+func commitCount() string {
+	count := pluralize(len(dig.All), "commit")
+	if dig.Loader != nil {
+		count = fmt.Sprintf("loading… %d so far", len(dig.All))
+	}
+	if len(dig.Commits) != len(dig.All) {
+		count = fmt.Sprintf("%d of %s", len(dig.Commits), count)
+	}
+	return count
+}
+
+// commitListTitle returns the title of the commit list.
+// It's the repository, the branch, the number of commits and their order.
+func commitListTitle() string {
+	branch := shownBranch()
+	if branch == "" {
+		branch = segmentValue("branch")
+	}
+	if branch == "" {
+		branch = strings.Join(dig.Targets, " ")
+	}
+	if dig.History != "" {
+		branch += " -- " + dig.History
+	} else if len(dig.Paths) != 0 {
+		branch += " -- " + strings.Join(dig.Paths, " ")
+	}
+	parts := []string{filepath.Base(dig.RepoDir), branch, commitCount(), commitOrder()}
+	return strings.Join(parts, " · ")
+}
+
+// drawTitle draws the title bar above the commit list, when config.TitleBar is set.
+// The area is below the bar.
+func (a *CommitArea) drawTitle() {
+	if !config.TitleBar {
+		return
+	}
+	c := theme.Normal
+	if dig.CurView == CommitView {
+		c = theme.Focused
+	}
+	bound := Rect{Min: Pt{a.Bound.Min.L - 1, a.Bound.Min.O}, Size: Pt{1, a.Bound.Size.O}}
+	fillColor(bound, c)
+	drawLine(bound, 0, []byte(" "+commitListTitle()), 0, c)
+}
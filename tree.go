@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"strings"
+)
+
+// TreeNode is an entry of a git tree.
+type TreeNode struct {
+	Mode string
+	Type string
+	Hash string
+	Size string
+	Path string
+
+	Depth    int
+	Expanded bool
+	Children []*TreeNode
+}
+
+// Name is the last element of it's path.
+func (n *TreeNode) Name() string {
+	return n.Path[strings.LastIndex(n.Path, "/")+1:]
+}
+
+// IsDir reports whether the node is a directory.
+func (n *TreeNode) IsDir() bool {
+	return n.Type == "tree"
+}
+
+// TreeArea is an Area for browsing the repository tree of a commit.
+type TreeArea struct {
+	Bound      Rect
+	CommitHash string
+	Nodes      []*TreeNode // top level nodes
+	Rows       []*TreeNode // currently visible nodes
+	CurIdx     int
+	TopIdx     int
+	Err        error
+}
+
+// Load loads the tree of the commit.
+// When the tree is already loaded, it keeps the previous state.
+func (a *TreeArea) Load(hash string) {
+	if hash == a.CommitHash {
+		return
+	}
+	a.CommitHash = hash
+	a.CurIdx = 0
+	a.TopIdx = 0
+	a.Nodes, a.Err = lsTree(hash, "", 0)
+	a.refresh()
+}
+
+// refresh rebuilds visible rows from expanded nodes.
+func (a *TreeArea) refresh() {
+	a.Rows = a.Rows[:0]
+	var walk func(nodes []*TreeNode)
+	walk = func(nodes []*TreeNode) {
+		for _, n := range nodes {
+			a.Rows = append(a.Rows, n)
+			if n.Expanded {
+				walk(n.Children)
+			}
+		}
+	}
+	walk(a.Nodes)
+	a.cursorValidation()
+}
+
+// Handle handles a terminal event.
+func (a *TreeArea) Handle(ev Event) bool {
+	if ev.Key == KeyArrowUp || ev.Ch == 'i' {
+		a.CurIdx -= count()
+	} else if ev.Key == KeyArrowDown || ev.Ch == 'k' {
+		a.CurIdx += count()
+	} else if ev.Key == KeyPgup || ev.Ch == 'b' {
+		a.CurIdx -= a.Bound.Size.L * count()
+	} else if ev.Key == KeyPgdn || ev.Ch == 'f' {
+		a.CurIdx += a.Bound.Size.L * count()
+	} else if ev.Ch == 'u' {
+		a.CurIdx -= a.Bound.Size.L / 2 * count()
+	} else if ev.Ch == 'd' {
+		a.CurIdx += a.Bound.Size.L / 2 * count()
+	} else if ev.Key == KeyHome {
+		a.CurIdx = 0
+	} else if ev.Key == KeyEnd {
+		a.CurIdx = len(a.Rows) - 1
+	} else if ev.Key == KeyEnter || ev.Key == KeyArrowRight || ev.Ch == 'l' {
+		a.Open()
+	} else if ev.Key == KeyArrowLeft || ev.Ch == 'j' {
+		a.Collapse()
+	} else {
+		return false
+	}
+	a.cursorValidation()
+	return true
+}
+
+func (a *TreeArea) cursorValidation() {
+	if a.CurIdx >= len(a.Rows) {
+		a.CurIdx = len(a.Rows) - 1
+	}
+	if a.CurIdx < 0 {
+		a.CurIdx = 0
+	}
+}
+
+// Open expands a directory, or opens a file at the cursor.
+func (a *TreeArea) Open() {
+	if len(a.Rows) == 0 {
+		return
+	}
+	n := a.Rows[a.CurIdx]
+	if !n.IsDir() {
+		screen.File.Open(a.CommitHash, n)
+		dig.CurView = FileView
+		return
+	}
+	if n.Expanded {
+		n.Expanded = false
+		a.refresh()
+		return
+	}
+	if n.Children == nil {
+		n.Children, a.Err = lsTree(a.CommitHash, n.Path+"/", n.Depth+1)
+		if a.Err != nil {
+			return
+		}
+	}
+	n.Expanded = true
+	a.refresh()
+}
+
+// Collapse collapses the directory at the cursor.
+// When the cursor isn't on an expanded directory,
+// it moves the cursor to it's parent directory.
+func (a *TreeArea) Collapse() {
+	if len(a.Rows) == 0 {
+		return
+	}
+	n := a.Rows[a.CurIdx]
+	if n.Expanded {
+		n.Expanded = false
+		a.refresh()
+		return
+	}
+	for i := a.CurIdx - 1; i >= 0; i-- {
+		if a.Rows[i].Depth < n.Depth {
+			a.CurIdx = i
+			return
+		}
+	}
+}
+
+// Draw draws it's contents.
+func (a *TreeArea) Draw() {
+	if a.Err != nil {
+		drawLine(a.Bound, 0, []byte(a.Err.Error()), 0, theme.Error)
+		return
+	}
+	if a.TopIdx > a.CurIdx {
+		a.TopIdx = a.CurIdx
+	} else if a.TopIdx+a.Bound.Size.L <= a.CurIdx {
+		a.TopIdx = a.CurIdx - a.Bound.Size.L + 1
+	}
+	for l := 0; l < a.Bound.Size.L; l++ {
+		i := a.TopIdx + l
+		if i >= len(a.Rows) {
+			break
+		}
+		n := a.Rows[i]
+		c := theme.Normal
+		if n.IsDir() {
+			c = theme.Dir
+		}
+		if i == a.CurIdx {
+			c = theme.Selected
+			fillColor(Rect{Min: Pt{a.Bound.Min.L + l, a.Bound.Min.O}, Size: Pt{1, a.Bound.Size.O}}, c)
+		}
+		name := n.Name()
+		if n.IsDir() {
+			marker := "+ "
+			if n.Expanded {
+				marker = "- "
+			}
+			name = marker + name + "/"
+		} else {
+			name = "  " + name
+		}
+		ln := fmt.Sprintf("%s %8s  %s%s", n.Mode, n.Size, strings.Repeat("  ", n.Depth), name)
+		drawLine(a.Bound, l, []byte(ln), 0, c)
+	}
+}
+
+// lsTree lists entries of a directory of the commit.
+// dir should be empty for the root directory, or end with a slash.
+func lsTree(hash, dir string, depth int) ([]*TreeNode, error) {
+	nodes, err := backend.Tree(dig.RepoDir, hash, dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nodes {
+		n.Depth = depth
+	}
+	return nodes, nil
+}
+
+// FileArea is an Area for showing a file at a commit.
+type FileArea struct {
+	CommitHash string
+	Path       string
+	Text       [][]byte
+	Err        error
+	// Image is the file's data when it's an image the terminal can show.
+	// See drawImage.
+	Image []byte
+	// Hex shows the hex dump of an image, instead of the image.
+	Hex bool
+
+	Bound Rect
+	Win   *Window
+}
+
+// Open reads the file node at the commit.
+func (a *FileArea) Open(hash string, n *TreeNode) {
+	a.CommitHash = hash
+	a.Path = n.Path
+	a.Image = nil
+	a.Hex = false
+	a.Text, a.Err = nil, nil
+	data, err := backend.Blob(dig.RepoDir, n.Hash)
+	if err != nil {
+		a.Err = err
+	} else if isBinary(data) {
+		// binary files are dumped, and images are also shown when the terminal can.
+		a.Text = binaryText(n.Path, n.Hash, data)
+		if _, format, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			if p := imageProtocol(); p == "iterm" || p == "kitty" && format == "png" {
+				a.Image = data
+			}
+		}
+	} else {
+		a.Text = textLines(data)
+	}
+	a.Win.Reset(a.Text)
+}
+
+// Handle handles a terminal event.
+func (a *FileArea) Handle(ev Event) bool {
+	if ev.Key == KeyPgdn || ev.Key == KeySpace || ev.Ch == 'f' || ev.Ch == ',' {
+		a.Win.MoveDown(a.Win.Bound.Size.L * count())
+	} else if ev.Key == KeyPgup || ev.Ch == 'b' || ev.Ch == 'm' {
+		a.Win.MoveUp(a.Win.Bound.Size.L * count())
+	} else if ev.Ch == 'd' || ev.Ch == 'o' {
+		a.Win.MoveDown(a.Win.Bound.Size.L / 2 * count())
+	} else if ev.Ch == 'u' {
+		a.Win.MoveUp(a.Win.Bound.Size.L / 2 * count())
+	} else if ev.Key == KeyArrowUp || ev.Ch == 'i' {
+		a.Win.MoveUp(count())
+	} else if ev.Key == KeyArrowDown || ev.Ch == 'k' {
+		a.Win.MoveDown(count())
+	} else if ev.Key == KeyArrowLeft || ev.Ch == 'j' {
+		a.Win.MoveLeft(4 * count())
+	} else if ev.Key == KeyArrowRight || ev.Ch == 'l' {
+		a.Win.MoveRight(4 * count())
+	} else if ev.Ch == 'x' && a.Image != nil {
+		a.Hex = !a.Hex
+	} else {
+		return false
+	}
+	return true
+}
+
+// Draw draws it's contents.
+func (a *FileArea) Draw() {
+	if a.Err != nil {
+		drawLine(a.Bound, 0, []byte(a.Err.Error()), 0, theme.Error)
+		return
+	}
+	minL := a.Win.Bound.Min.L
+	maxL := a.Win.Bound.Min.L + a.Win.Bound.Size.L
+	if maxL > len(a.Text) {
+		maxL = len(a.Text)
+	}
+	if a.ShowImage() {
+		// metadata only, the image is drawn below them.
+		minL = 0
+		maxL = 2
+	}
+	for l, ln := range a.Text[minL:maxL] {
+		drawLine(a.Bound, l, ln, a.Win.Bound.Min.O, theme.Normal)
+	}
+}
+
+// ShowImage reports whether the file is shown as an image. 'x' toggles it with the hex dump.
+func (a *FileArea) ShowImage() bool {
+	return a.Image != nil && !a.Hex
+}
+
+// textLines splits contents of a text file into lines.
+func textLines(out []byte) [][]byte {
+	// tab handling in screen is quite awkard. handle it here.
+	out = bytes.Replace(out, []byte("\t"), []byte("    "), -1)
+	out = bytes.TrimRight(out, "\n")
+	return bytes.Split(out, []byte("\n"))
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// version, commit and date of this build.
+// Release builds set them with -ldflags, see build.sh.
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
+
+// versionString returns a version line for bug reports.
+func versionString() string {
+	v := version
+	if v == "" {
+		v = "dev"
+		// go get puts the module version in the binary.
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			v = info.Main.Version
+		}
+	}
+	s := "dig " + v
+	if commit != "" {
+		s += " (" + commit
+		if date != "" {
+			s += ", " + date
+		}
+		s += ")"
+	}
+	return s + fmt.Sprintf(" %s %s/%s", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+// releaseURL is the endpoint for the latest release of dig.
+const releaseURL = "https://api.github.com/repos/kybin/dig/releases/latest"
+
+// checkUpdate checks the latest release, and notices in the status area
+// when it's newer than this build. It runs in background.
+func checkUpdate() {
+	if version == "" {
+		// development builds don't know what to compare.
+		return
+	}
+	go func() {
+		client := &http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Get(releaseURL)
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return
+		}
+		var release struct {
+			TagName string `json:"tag_name"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+			return
+		}
+		if !newerVersion(release.TagName, version) {
+			return
+		}
+		send(updateMsg{Tag: release.TagName})
+	}()
+}
+
+// updateMsg is a newer release of dig.
+type updateMsg struct {
+	Tag string
+}
+
+// newerVersion reports whether version a is newer than b.
+// Versions are dot separated numbers with an optional v prefix, like v1.2.3.
+func newerVersion(a, b string) bool {
+	an, ok := parseVersion(a)
+	if !ok {
+		return false
+	}
+	bn, ok := parseVersion(b)
+	if !ok {
+		return false
+	}
+	for i := 0; i < len(an) || i < len(bn); i++ {
+		var x, y int
+		if i < len(an) {
+			x = an[i]
+		}
+		if i < len(bn) {
+			y = bn[i]
+		}
+		if x != y {
+			return x > y
+		}
+	}
+	return false
+}
+
+// parseVersion parses a version to it's numbers.
+// Suffixes like -rc1 or -3-gabcdef of git describe are ignored.
+func parseVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i != -1 {
+		v = v[:i]
+	}
+	nums := []int{}
+	for _, f := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, false
+		}
+		nums = append(nums, n)
+	}
+	return nums, true
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// walkHeaderHeight is height of the enlarged commit header above the diff, while walking through.
+const walkHeaderHeight = 4
+
+// Walk is a walkthrough of commits, to show how something evolved in a meeting.
+// It steps through the commits with single keys, showing their diffs.
+type Walk struct {
+	Hashes []string
+	// Idx is index of the commit that is shown.
+	Idx int
+}
+
+// startWalk starts a walkthrough of the commits in the order.
+func startWalk(hashes []string) error {
+	if len(hashes) == 0 {
+		return fmt.Errorf("no marked commits to walk through, mark commits with space")
+	}
+	screen.Walk = &Walk{Hashes: hashes}
+	screen.Split = false
+	screen.Focus = 0
+	screen.Resize(screen.size)
+	dig.CurView = DiffView
+	screen.Walk.Show()
+	return nil
+}
+
+// stopWalk ends the walkthrough, and goes back to the commit list.
+func stopWalk() {
+	screen.Walk = nil
+	screen.Resize(screen.size)
+	dig.CurView = CommitView
+	dig.Message = "walkthrough ended"
+}
+
+// Show selects the current commit of the walkthrough, so it's diff is shown.
+func (w *Walk) Show() {
+	hash := w.Hashes[w.Idx]
+	i := findByHash(dig.Commits, hash, screen.Commit.CurIdx)
+	if i == -1 {
+		dig.Message = fmt.Sprintf("%s is not in the list", shortHash(hash))
+		return
+	}
+	screen.Commit.SetCursor(i)
+}
+
+// Step moves n commits forward in the walkthrough, or backward when n is negative.
+func (w *Walk) Step(n int) {
+	i := w.Idx + n
+	if i < 0 {
+		dig.Message = "this is the first commit"
+		return
+	}
+	if i >= len(w.Hashes) {
+		dig.Message = "this is the last commit, q: end the walkthrough"
+		return
+	}
+	w.Idx = i
+	w.Show()
+}
+
+// Handle handles keys of the walkthrough, and reports whether it handled the event.
+// Other keys, like scrolling the diff, are left to the diff.
+func (w *Walk) Handle(ev Event) bool {
+	switch {
+	case ev.Key == KeySpace || ev.Key == KeyArrowRight || ev.Ch == 'n':
+		w.Step(count())
+	case ev.Key == KeyBackspace || ev.Key == KeyArrowLeft || ev.Ch == 'p':
+		w.Step(-count())
+	case ev.Key == KeyEsc || ev.Ch == 'q':
+		stopWalk()
+	default:
+		return false
+	}
+	return true
+}
+
+// Status returns the walkthrough's status, that replaces the status bar.
+func (w *Walk) Status() string {
+	return fmt.Sprintf("%d/%d  space: next, backspace: previous, q: end", w.Idx+1, len(w.Hashes))
+}
+
+// drawWalkHeader draws the selected commit's title large, above the diff.
+func (s *Screen) drawWalkHeader() {
+	bound := Rect{
+		Min:  Pt{s.Diff.Bound.Min.L - walkHeaderHeight, s.Diff.Bound.Min.O},
+		Size: Pt{walkHeaderHeight, s.Diff.Bound.Size.O},
+	}
+	fillColor(bound, theme.Normal)
+	c := s.Commit.Commit()
+	inner := Rect{Min: Pt{bound.Min.L, bound.Min.O + 2}, Size: Pt{bound.Size.L, bound.Size.O - 4}}
+	title := ""
+	for _, r := range c.Title {
+		title += string(wideRune(r))
+	}
+	if runewidth.StringWidth(title) > inner.Size.O {
+		title = runewidth.Truncate(title, inner.Size.O, "…")
+	}
+	drawLine(inner, 1, []byte(title), 0, Color{theme.Normal.Fg | AttrBold, theme.Normal.Bg})
+	meta := fmt.Sprintf("%s  %s  %s", c.Author, c.Time.Format("2006-01-02"), shortHash(c.Hash))
+	drawLine(inner, 2, []byte(meta), 0, Color{theme.Hash, theme.Normal.Bg})
+	drawLine(bound, 3, []byte(strings.Repeat("─", bound.Size.O)), 0, theme.Normal)
+}
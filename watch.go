@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// watchMsg is what came to the watched program, since it's tips were checked last time.
+type watchMsg struct {
+	Program *Program
+	// Tips are hashes of the program's revisions.
+	Tips []string
+	// New is the number of commits those came, and Touched are ones of them touched config.Watch.
+	// Both are empty when it's the first check.
+	New     int
+	Touched []string
+	Err     error
+}
+
+// watchTickMsg is a tick to check the watched paths.
+type watchTickMsg struct{}
+
+// watchChecking reports whether the program is being checked.
+var watchChecking bool
+
+// watchStarted reports whether the watch ticker is started. It's started only once.
+var watchStarted bool
+
+// startWatch checks commits those come to the program each config.WatchInterval,
+// and reloads the commit list when they come. See checkWatch.
+func startWatch() {
+	if len(config.Watch) == 0 || watchStarted {
+		return
+	}
+	watchStarted = true
+	checkWatch()
+	ticker := time.NewTicker(config.WatchInterval)
+	go func() {
+		for range ticker.C {
+			send(watchTickMsg{})
+		}
+	}()
+}
+
+// checkWatch fetches remotes when config.WatchFetch is on, and checks tips of the program's revisions in background.
+// It doesn't start while commits are being loaded, or a previous check isn't done.
+func checkWatch() {
+	if len(config.Watch) == 0 || watchChecking || dig.Loader != nil {
+		return
+	}
+	watchChecking = true
+	p := dig
+	repoDir, paths, fetch := p.RepoDir, config.Watch, config.WatchFetch
+	revs := p.Targets
+	if len(revs) == 0 {
+		revs = []string{"HEAD"}
+	}
+	old := p.WatchTips
+	go func() {
+		msg := watchMsg{Program: p}
+		msg.Tips, msg.New, msg.Touched, msg.Err = watchedCommits(repoDir, revs, old, paths, fetch)
+		send(msg)
+	}()
+}
+
+// watchedCommits returns tips of the revisions, and commits those came since the old tips.
+// Touched are the commits those touched the paths.
+func watchedCommits(repoDir string, revs, old, paths []string, fetch bool) (tips []string, n int, touched []string, err error) {
+	if fetch {
+		if out, err := gitCommand(repoDir, "fetch", "--all", "--quiet").CombinedOutput(); err != nil {
+			return nil, 0, nil, fmt.Errorf("fetch: %s", firstLine(out))
+		}
+	}
+	tips, err = backend.RevParse(repoDir, revs)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	if old == nil || strings.Join(tips, " ") == strings.Join(old, " ") {
+		return tips, 0, nil, nil
+	}
+	since := append(append([]string{}, revs...), "--not")
+	for _, h := range old {
+		// ranges have their excluded ends like ^hash, those are excluded already.
+		if !strings.HasPrefix(h, "^") {
+			since = append(since, h)
+		}
+	}
+	came, err := backend.PathCommits(repoDir, since, nil)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	touched, err = backend.PathCommits(repoDir, since, paths)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return tips, len(came), touched, nil
+}
+
+// watchChecked reloads the commit list when commits came, and tells about ones those touched the watched paths.
+// It rings the bell for them, when config.WatchBell is on.
+func watchChecked(msg watchMsg) {
+	watchChecking = false
+	if msg.Err != nil {
+		dig.Message = "watch: " + msg.Err.Error()
+		return
+	}
+	p := msg.Program
+	if p != dig {
+		// the tab is switched, the current one is checked at the next tick.
+		return
+	}
+	changed := p.WatchTips != nil && strings.Join(p.WatchTips, " ") != strings.Join(msg.Tips, " ")
+	p.WatchTips = msg.Tips
+	if !changed {
+		return
+	}
+	if err := reloadCommits(); err != nil {
+		dig.Message = "watch: " + err.Error()
+		return
+	}
+	if msg.New == 0 {
+		// a branch is reset or force pushed.
+		return
+	}
+	dig.Message = pluralize(msg.New, "new commit") + " came"
+	if len(msg.Touched) == 0 {
+		return
+	}
+	if dig.Watched == nil {
+		dig.Watched = make(map[string]bool)
+	}
+	for _, h := range msg.Touched {
+		dig.Watched[h] = true
+	}
+	dig.Message += fmt.Sprintf(", %d touched %s", len(msg.Touched), strings.Join(config.Watch, " "))
+	if config.WatchBell {
+		term.Beep()
+	}
+}
+
+// cmdWatch watches the pathspecs, instead of config.Watch. It stops watching without them.
+//
+//	watch [pathspec...]
+func cmdWatch(args []string) error {
+	config.Watch = args
+	if len(args) == 0 {
+		dig.Watched = nil
+		dig.Message = "not watching"
+		return nil
+	}
+	startWatch()
+	dig.Message = "watching " + strings.Join(args, " ")
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestWatch(t *testing.T) {
+	r := historyFixture(t)
+	setupApp(t, r.Dir, r.Commits(false, "HEAD"))
+	config.Watch = []string{"README"}
+	check := func() {
+		t.Helper()
+		checkWatch()
+		runUntil(t, func() bool { return !watchChecking }, nil)
+	}
+	check()
+	if len(dig.WatchTips) != 1 || dig.WatchTips[0] != r.Hash("HEAD") || dig.Message != "" {
+		t.Fatalf("first check: got tips %q, with message %q", dig.WatchTips, dig.Message)
+	}
+	r.apply(step{Files: map[string]string{"main.go": "package main\n"}, Message: "empty main"})
+	r.apply(step{Files: map[string]string{"README": "dig, watched\n"}, Message: "touch readme"})
+	check()
+	if len(dig.Commits) != 7 || dig.Commits[0].Title != "touch readme" {
+		t.Errorf("reloaded commits: got %q", titles(dig.Commits))
+	}
+	if want := "2 new commits came, 1 touched README"; dig.Message != want {
+		t.Errorf("message: got %q, want %q", dig.Message, want)
+	}
+	if len(dig.Watched) != 1 || !dig.Watched[r.Hash("HEAD")] || commitBadges(dig.Commits[0]) != "!" {
+		t.Errorf("watched: got %v", dig.Watched)
+	}
+	dig.Message = ""
+	check()
+	if dig.Message != "" || len(dig.Commits) != 7 {
+		t.Errorf("check without new commits: got %d commits, with message %q", len(dig.Commits), dig.Message)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// wordDiffSep separates the commit range and the file in a key of a word diff,
+// like "v1.0..v1.1 -- main.go".
+const wordDiffSep = " -- "
+
+// wordDiffKey returns a key of DiffCache for word diff of the file between the commits.
+func wordDiffKey(from, to, file string) string {
+	return from + ".." + to + wordDiffSep + file
+}
+
+// isWordDiffKey reports whether the key is for a word diff.
+func isWordDiffKey(key string) bool {
+	return strings.Contains(key, wordDiffSep)
+}
+
+// wordDiff returns a word diff of a key made by wordDiffKey.
+// Changed words are marked like git diff --word-diff=plain, [-removed-]{+added+}.
+func wordDiff(key string) ([][]byte, error) {
+	i := strings.Index(key, wordDiffSep)
+	rng, file := key[:i], key[i+len(wordDiffSep):]
+	j := strings.Index(rng, "..")
+	out, err := gitOutput("diff", "--word-diff=plain", rng[:j], rng[j+2:], "--", file)
+	if err != nil {
+		return nil, err
+	}
+	out = bytes.Replace(out, []byte("\t"), []byte("    "), -1)
+	out = bytes.TrimRight(out, "\n")
+	if len(out) == 0 {
+		return [][]byte{[]byte(fmt.Sprintf("%s: no changes in %s", rng, file))}, nil
+	}
+	return bytes.Split(out, []byte("\n")), nil
+}
+
+// drawWordLine draws a line of a word diff, coloring removed and added words.
+func drawWordLine(bound Rect, l int, ln []byte, shift int, c Color) {
+	o := -shift
+	fg := c.Fg
+	remain := ln
+	for len(remain) != 0 && o < bound.Size.O {
+		switch {
+		case bytes.HasPrefix(remain, []byte("[-")):
+			fg = theme.Deleted.Fg
+			remain = remain[2:]
+			continue
+		case bytes.HasPrefix(remain, []byte("-]")) && fg == theme.Deleted.Fg:
+			fg = c.Fg
+			remain = remain[2:]
+			continue
+		case bytes.HasPrefix(remain, []byte("{+")):
+			fg = theme.Added.Fg
+			remain = remain[2:]
+			continue
+		case bytes.HasPrefix(remain, []byte("+}")) && fg == theme.Added.Fg:
+			fg = c.Fg
+			remain = remain[2:]
+			continue
+		}
+		r, size := utf8.DecodeRune(remain)
+		remain = remain[size:]
+		r = printable(r)
+		if o >= 0 {
+			attr := fg
+			if fg != c.Fg {
+				// changed words should stand out even when colors are close.
+				attr |= AttrBold
+			}
+			setCell(bound.Min.O+o, bound.Min.L+l, r, attr, c.Bg)
+		}
+		o += runewidth.RuneWidth(r)
+	}
+}
+
+// openWordDiff shows word diff of the file between the commits.
+func openWordDiff(from, to, file string) {
+	openDiff(wordDiffKey(from, to, file))
+}
+
+// markedRange returns two marked commits, or a marked commit and the selected one, old one first.
+func markedRange() (from, to string, err error) {
+	hashes := markedHashes()
+	switch len(hashes) {
+	case 2:
+		return hashes[0], hashes[1], nil
+	case 1:
+		from, to = hashes[0], screen.Commit.Commit().Hash
+		if from == to {
+			return "", "", fmt.Errorf("mark another commit to diff with")
+		}
+		if i, j := findByHash(dig.Commits, from, 0), screen.Commit.CurIdx; i != -1 && (i > j) == dig.DigUp {
+			from, to = to, from
+		}
+		return from, to, nil
+	}
+	return "", "", fmt.Errorf("mark two commits with space, or one to diff with the selected commit")
+}
+
+// wordDiffAt shows word diff of the file at the line between marked commits.
+func wordDiffAt(a *DiffArea, l int) error {
+	h := fileHeaderAt(a.Text, l)
+	if h == -1 {
+		return fmt.Errorf("not in a file")
+	}
+	from, to, err := markedRange()
+	if err != nil {
+		return err
+	}
+	openWordDiff(from, to, fileName(a.Text[h]))
+	return nil
+}
+
+// cmdWordDiff shows word diff of a file between two commits.
+// Without commits, the marked commits are used. Without a file, the file at the diff cursor is used.
+//
+//	worddiff [file]
+//	worddiff <from> <to> <file>
+func cmdWordDiff(args []string) error {
+	switch len(args) {
+	case 0:
+		if dig.CurView != DiffView {
+			return fmt.Errorf("usage: worddiff [from to] <file>")
+		}
+		d := screen.FocusedDiff()
+		return wordDiffAt(d, d.AnchorLine())
+	case 1:
+		from, to, err := markedRange()
+		if err != nil {
+			return err
+		}
+		openWordDiff(from, to, args[0])
+		return nil
+	case 3:
+		for _, rev := range args[:2] {
+			if _, err := resolveHash(rev); err != nil {
+				return err
+			}
+		}
+		openWordDiff(args[0], args[1], args[2])
+		return nil
+	}
+	return fmt.Errorf("usage: worddiff [from to] <file>")
+}
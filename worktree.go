@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// snapshotDir returns the directory of the commit's snapshot, under the temp directory.
+func snapshotDir(c *Commit) string {
+	return filepath.Join(os.TempDir(), "dig-"+filepath.Base(dig.RepoDir)+"-"+shortHash(c.Hash))
+}
+
+// snapshot checks out the commit into a throwaway worktree, and returns it's directory.
+// The work tree of the repository isn't touched.
+// A snapshot that is already there is reused.
+func snapshot(c *Commit) (string, error) {
+	dir := snapshotDir(c)
+	if _, err := os.Stat(dir); err == nil {
+		cmd := gitCommand(dir, "rev-parse", "HEAD")
+		out, err := cmd.Output()
+		if err != nil || strings.TrimSpace(string(out)) != c.Hash {
+			return "", fmt.Errorf("%s exists, but it isn't a snapshot of %s", dir, shortHash(c.Hash))
+		}
+		return dir, nil
+	}
+	if _, err := gitOutput("worktree", "add", "--detach", dir, c.Hash); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// snapshotSelected snapshots the selected commit, and copies the path.
+func snapshotSelected() error {
+	dir, err := snapshot(screen.Commit.Commit())
+	if err != nil {
+		return err
+	}
+	if err := copyToClipboard(dir); err != nil {
+		dig.Message = "snapshot at " + dir
+		return nil
+	}
+	dig.Message = "snapshot at " + dir + " (path copied)"
+	return nil
+}
+
+// cleanSnapshots removes snapshot worktrees of the repository, after confirmed.
+func cleanSnapshots() error {
+	out, err := gitOutput("worktree", "list", "--porcelain")
+	if err != nil {
+		return err
+	}
+	prefix := filepath.Join(os.TempDir(), "dig-"+filepath.Base(dig.RepoDir)+"-")
+	var dirs []string
+	for _, ln := range strings.Split(string(out), "\n") {
+		if !strings.HasPrefix(ln, "worktree ") {
+			continue
+		}
+		dir := strings.TrimPrefix(ln, "worktree ")
+		if strings.HasPrefix(dir, prefix) {
+			dirs = append(dirs, dir)
+		}
+	}
+	if len(dirs) == 0 {
+		dig.Message = "no snapshots"
+		return nil
+	}
+	// changes made in snapshots are removed together.
+	q := fmt.Sprintf("remove %s?", pluralize(len(dirs), "snapshot"))
+	return confirm(q, []string{"changes in the snapshots will be lost"}, func() error {
+		for _, dir := range dirs {
+			if _, err := gitOutput("worktree", "remove", "--force", dir); err != nil {
+				return err
+			}
+		}
+		dig.Message = fmt.Sprintf("removed %s", pluralize(len(dirs), "snapshot"))
+		return nil
+	})
+}
+
+// cmdSnapshot checks out the selected commit into a temporary worktree,
+// to build or run an old version without disturbing the work tree.
+//
+//	snapshot          check out, and copy the path.
+//	snapshot shell    check out, and open a shell there.
+//	snapshot clean    remove all snapshots of the repository.
+func cmdSnapshot(args []string) error {
+	if len(args) == 0 {
+		return snapshotSelected()
+	}
+	switch args[0] {
+	case "shell":
+		dir, err := snapshot(screen.Commit.Commit())
+		if err != nil {
+			return err
+		}
+		shell := os.Getenv("SHELL")
+		if shell == "" {
+			shell = "sh"
+		}
+		cmd := exec.Command(shell)
+		cmd.Dir = dir
+		return suspend(cmd)
+	case "clean":
+		return cleanSnapshots()
+	}
+	return fmt.Errorf("unknown snapshot command: %s", args[0])
+}